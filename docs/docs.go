@@ -239,7 +239,7 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Filter by artifact type (pdf|pptx|zip|log)",
+                        "description": "Filter by artifact type (pdf|pptx|zip|log|markdown)",
                         "name": "type",
                         "in": "query"
                     }
@@ -553,7 +553,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Submit a new job to be processed",
+                "description": "Submit a new job to be processed. The workflow may be given as name@version;\notherwise the job is pinned to the latest published version.\nThe input is validated against the version's inputSchema and schema defaults are applied.\nA budget caps the job's LLM tokens or cost; once it is used up, further LLM requests fail.\nA cassette records the job's LLM traffic to a file, or replays it from one without calling any provider.",
                 "consumes": [
                     "application/json"
                 ],
@@ -583,7 +583,13 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Invalid request body",
+                        "description": "Input violates the inputSchema; a malformed body or unknown workflow version returns plain text",
+                        "schema": {
+                            "$ref": "#/definitions/api.InvalidInputResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to resolve the workflow version or create the job",
                         "schema": {
                             "type": "string"
                         }
@@ -625,11 +631,17 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "500": {
+                        "description": "Failed to list child jobs",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             },
             "delete": {
-                "description": "Cancel a running or queued job",
+                "description": "Cancel a running, queued or waiting job together with its child jobs.\nCompleted steps that declare a compensation are undone before the job is marked cancelled.",
                 "consumes": [
                     "application/json"
                 ],
@@ -661,6 +673,12 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "409": {
+                        "description": "Job already finished or compensating",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
@@ -697,6 +715,107 @@ const docTemplate = `{
                 }
             }
         },
+        "/jobs/{jobId}/graph": {
+            "get": {
+                "description": "Render the step DAG of the workflow version a job is pinned to, annotated with each\nstep's live status and duration, as a Mermaid flowchart, Graphviz DOT or JSON",
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Render a job's step graph",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "jobId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "mermaid",
+                            "dot",
+                            "json"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Output format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.WorkflowGraphResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{jobId}/llm-calls": {
+            "get": {
+                "description": "Every attempt the job's steps made to get an answer from an LLM provider, oldest first.\nWhen a provider failed or its circuit breaker was open the request fell back to the next\nprovider of its route; the succeeded attempt tells which provider and model produced the output.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "List a job's LLM calls",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "jobId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only the calls of this step",
+                        "name": "stepId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of calls to return",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.LLMCallListResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/jobs/{jobId}/logs": {
             "get": {
                 "description": "Get aggregated logs for a job",
@@ -747,6 +866,65 @@ const docTemplate = `{
                 }
             }
         },
+        "/jobs/{jobId}/rerun": {
+            "post": {
+                "description": "Create a new job that repeats a finished job from the named step onwards. The results\nand artifacts of the steps before it are copied from the original job, so only fromStep\nand the steps after it run again. input overrides top-level keys of the original input.\nThe rerun is recorded as a new run (runId) of the new job whose status follows the job's;\nits params hold the lineage: rerunOf (the run of the original job, when it has one),\nrerunOfJobId, fromStep and the input overrides.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Rerun a job from a step",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "jobId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Step to rerun from and input overrides",
+                        "name": "rerun",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.RerunJobRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.RerunJobResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request, unknown step or invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/api.InvalidInputResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Job has not finished",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/jobs/{jobId}/result": {
             "get": {
                 "description": "Get the latest result summary for a job",
@@ -823,6 +1001,72 @@ const docTemplate = `{
                 }
             }
         },
+        "/jobs/{jobId}/signals/{name}": {
+            "post": {
+                "description": "Deliver an external signal, such as \"CI passed\", to a job. The JSON payload becomes the\noutput of the wait_for_signal step waiting for the signal. A signal that arrives before\nthe step starts is buffered until the step consumes it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Send a signal to a job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "jobId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signal name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Signal payload",
+                        "name": "payload",
+                        "in": "body",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/api.SignalResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Job already finished",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/jobs/{jobId}/steps": {
             "get": {
                 "description": "Get a list of workflow steps for a job",
@@ -909,9 +1153,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/jobs/{jobId}/steps/{stepId}/logs": {
-            "get": {
-                "description": "Get logs for a specific workflow step",
+        "/jobs/{jobId}/steps/{stepId}/approve": {
+            "post": {
+                "description": "Approve an approval step that is waiting for sign-off and resume its job",
                 "consumes": [
                     "application/json"
                 ],
@@ -921,7 +1165,7 @@ const docTemplate = `{
                 "tags": [
                     "jobs"
                 ],
-                "summary": "Get step logs",
+                "summary": "Approve a step",
                 "parameters": [
                     {
                         "type": "string",
@@ -936,13 +1180,28 @@ const docTemplate = `{
                         "name": "stepId",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Approver and optional comment",
+                        "name": "decision",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.StepDecisionRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.JobLogsResponse"
+                            "$ref": "#/definitions/api.JobStep"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "404": {
@@ -950,13 +1209,19 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "409": {
+                        "description": "Step is not awaiting approval",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
-        "/queue": {
+        "/jobs/{jobId}/steps/{stepId}/logs": {
             "get": {
-                "description": "Get queue statistics and metrics",
+                "description": "Get logs for a specific workflow step",
                 "consumes": [
                     "application/json"
                 ],
@@ -964,22 +1229,44 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "queue"
+                    "jobs"
+                ],
+                "summary": "Get step logs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "jobId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Step ID",
+                        "name": "stepId",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get queue statistics",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.QueueStats"
+                            "$ref": "#/definitions/api.JobLogsResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Step not found",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/queue/items": {
-            "get": {
-                "description": "Get a paginated list of queue items with optional filtering",
+        "/jobs/{jobId}/steps/{stepId}/reject": {
+            "post": {
+                "description": "Reject an approval step that is waiting for sign-off, failing its job",
                 "consumes": [
                     "application/json"
                 ],
@@ -987,60 +1274,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "queue"
+                    "jobs"
                 ],
-                "summary": "List queue items",
+                "summary": "Reject a step",
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Maximum number of items to return",
-                        "name": "limit",
-                        "in": "query"
-                    },
                     {
                         "type": "string",
-                        "description": "Cursor for pagination",
-                        "name": "cursor",
-                        "in": "query"
+                        "description": "Job ID",
+                        "name": "jobId",
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Filter by state (pending|leased|done|dead)",
-                        "name": "state",
-                        "in": "query"
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/api.QueueItemListResponse"
-                        }
-                    }
-                }
-            }
-        },
-        "/queue/requeue": {
-            "post": {
-                "description": "Requeue a job in the queue",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "queue"
-                ],
-                "summary": "Requeue a job",
-                "parameters": [
+                        "description": "Step ID",
+                        "name": "stepId",
+                        "in": "path",
+                        "required": true
+                    },
                     {
-                        "description": "Requeue request",
-                        "name": "requeue",
+                        "description": "Approver and optional comment",
+                        "name": "decision",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.RequeueRequest"
+                            "$ref": "#/definitions/api.StepDecisionRequest"
                         }
                     }
                 ],
@@ -1048,7 +1306,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.JobStep"
                         }
                     },
                     "400": {
@@ -1056,26 +1314,15 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
-                    }
-                }
-            }
-        },
-        "/readyz": {
-            "get": {
-                "description": "Returns 200 OK if the service is ready to accept requests",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "system"
-                ],
-                "summary": "Readiness check",
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    "404": {
+                        "description": "Step not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Step is not awaiting approval",
                         "schema": {
                             "type": "string"
                         }
@@ -1083,56 +1330,44 @@ const docTemplate = `{
                 }
             }
         },
-        "/runs": {
+        "/jobs/{jobId}/usage": {
             "get": {
-                "description": "Get a paginated list of runs with optional filtering",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Tokens, cost and latency of the job's LLM calls, per step, per model and in total.\nJobs started by the job's sub-workflow steps are included, as they count against its budget.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "runs"
+                    "jobs"
                 ],
-                "summary": "List runs",
+                "summary": "Get a job's LLM usage",
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Maximum number of runs to return",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Cursor for pagination",
-                        "name": "cursor",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Filter by status",
-                        "name": "status",
-                        "in": "query"
-                    },
                     {
                         "type": "string",
-                        "description": "Filter by job ID",
+                        "description": "Job ID",
                         "name": "jobId",
-                        "in": "query"
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.RunListResponse"
+                            "$ref": "#/definitions/api.JobUsageResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/matrices": {
             "post": {
-                "description": "Start a new run for a job",
+                "description": "Expand one workflow into sibling jobs, one for every combination of axis values, e.g. models,\ntemperatures or prompt versions. Each job gets the shared input with every axis name set to\nits value. All jobs are pinned to the same workflow version. Once every job finished, a markdown\ncomparison report is stored as an artifact.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1140,17 +1375,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "runs"
+                    "matrices"
                 ],
-                "summary": "Create a new run",
+                "summary": "Run a workflow across parameter axes",
                 "parameters": [
                     {
-                        "description": "Run creation request",
-                        "name": "run",
+                        "description": "Workflow, shared input and axes",
+                        "name": "matrix",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.CreateRunRequest"
+                            "$ref": "#/definitions/api.CreateMatrixRequest"
                         }
                     }
                 ],
@@ -1158,85 +1393,64 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/api.CreateRunResponse"
+                            "$ref": "#/definitions/api.CreateMatrixResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid request",
+                        "description": "Invalid request, unknown workflow or invalid input",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.InvalidInputResponse"
                         }
                     }
                 }
             }
         },
-        "/runs/{runId}": {
+        "/matrices/{matrixId}": {
             "get": {
-                "description": "Get detailed information about a specific run",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Report status, duration, token usage, cost and review scores of every job of a matrix,\nas JSON or as a markdown table. Tokens and cost add up the usage reported by the steps;\nscores are the numeric score outputs of the steps.",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/markdown"
                 ],
                 "tags": [
-                    "runs"
+                    "matrices"
                 ],
-                "summary": "Get run details",
+                "summary": "Compare the jobs of a matrix",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Run ID",
-                        "name": "runId",
+                        "description": "Matrix ID",
+                        "name": "matrixId",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "markdown"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Output format",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.Run"
+                            "$ref": "#/definitions/api.MatrixReportResponse"
                         }
                     },
-                    "404": {
-                        "description": "Run not found",
-                        "schema": {
-                            "type": "string"
-                        }
-                    }
-                }
-            },
-            "delete": {
-                "description": "Cancel a running or pending run",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "runs"
-                ],
-                "summary": "Cancel a run",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Run ID",
-                        "name": "runId",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "202": {
-                        "description": "Accepted",
+                    "400": {
+                        "description": "Invalid format",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "404": {
-                        "description": "Run not found",
+                        "description": "Matrix not found",
                         "schema": {
                             "type": "string"
                         }
@@ -1244,9 +1458,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/version": {
+        "/prompts": {
             "get": {
-                "description": "Returns the service name, version, and commit information",
+                "description": "Get the prompt templates of the prompt registry with their latest version",
                 "consumes": [
                     "application/json"
                 ],
@@ -1254,22 +1468,22 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "prompts"
                 ],
-                "summary": "Get version information",
+                "summary": "List prompts",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.VersionResponse"
+                            "$ref": "#/definitions/api.PromptListResponse"
                         }
                     }
                 }
             }
         },
-        "/workflows": {
+        "/prompts/{name}/diff": {
             "get": {
-                "description": "Get a list of all available workflows",
+                "description": "Compare two published versions of a prompt: variables added, removed or changed\nand a unified diff of the template",
                 "consumes": [
                     "application/json"
                 ],
@@ -1277,22 +1491,57 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "workflows"
+                    "prompts"
+                ],
+                "summary": "Diff prompt versions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Prompt name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Base version",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Target version",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    }
                 ],
-                "summary": "List workflows",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.WorkflowListResponse"
+                            "$ref": "#/definitions/api.PromptDiffResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid version",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Prompt version not found",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/workflows/validate": {
+        "/prompts/{name}/preview": {
             "post": {
-                "description": "Validate input parameters for a workflow",
+                "description": "Render a prompt version with sample variables, exactly as an llm step would",
                 "consumes": [
                     "application/json"
                 ],
@@ -1300,17 +1549,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "workflows"
+                    "prompts"
                 ],
-                "summary": "Validate workflow input",
+                "summary": "Preview a prompt",
                 "parameters": [
                     {
-                        "description": "Workflow validation request",
-                        "name": "workflow",
+                        "type": "string",
+                        "description": "Prompt name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Version and variables",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.ValidateWorkflowRequest"
+                            "$ref": "#/definitions/api.PreviewPromptRequest"
                         }
                     }
                 ],
@@ -1318,11 +1574,17 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.ValidateWorkflowResponse"
+                            "$ref": "#/definitions/api.PreviewPromptResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid request",
+                        "description": "Invalid variables or template error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Prompt version not found",
                         "schema": {
                             "type": "string"
                         }
@@ -1330,9 +1592,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/workflows/{name}": {
+        "/prompts/{name}/versions": {
             "get": {
-                "description": "Get schema and metadata for a specific workflow",
+                "description": "Get the immutable published versions of a prompt, oldest first",
                 "consumes": [
                     "application/json"
                 ],
@@ -1340,13 +1602,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "workflows"
+                    "prompts"
                 ],
-                "summary": "Get workflow details",
+                "summary": "List prompt versions",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Workflow name",
+                        "description": "Prompt name",
                         "name": "name",
                         "in": "path",
                         "required": true
@@ -1356,604 +1618,3021 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.Workflow"
+                            "$ref": "#/definitions/api.PromptVersionListResponse"
                         }
                     },
                     "404": {
-                        "description": "Workflow not found",
+                        "description": "Prompt not found",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "api.Agent": {
-            "type": "object",
-            "properties": {
-                "createdAt": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "lastSeen": {
-                    "type": "string"
+            },
+            "post": {
+                "description": "Store a template as the prompt's next immutable version, creating the prompt if needed.\nPublishing the same description, template and variables as the latest version returns it.\nPrompts loaded from the prompts directory get a new version whenever their file changes.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "prompts"
+                ],
+                "summary": "Publish a prompt version",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Prompt name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Prompt template",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.PublishPromptRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.PromptVersion"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid prompt",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/prompts/{name}/versions/{version}": {
+            "get": {
+                "description": "Get the template and variables of a published prompt version; \"latest\" gets the latest one",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "prompts"
+                ],
+                "summary": "Get a prompt version",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Prompt name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Prompt version number or latest",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.PromptVersion"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid version",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Prompt version not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/queue": {
+            "get": {
+                "description": "Get queue statistics and metrics",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "queue"
+                ],
+                "summary": "Get queue statistics",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.QueueStats"
+                        }
+                    }
+                }
+            }
+        },
+        "/queue/items": {
+            "get": {
+                "description": "Get a paginated list of queue items with optional filtering",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "queue"
+                ],
+                "summary": "List queue items",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of items to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor for pagination",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by state (pending|leased|done|dead)",
+                        "name": "state",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.QueueItemListResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/queue/requeue": {
+            "post": {
+                "description": "Requeue a job in the queue",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "queue"
+                ],
+                "summary": "Requeue a job",
+                "parameters": [
+                    {
+                        "description": "Requeue request",
+                        "name": "requeue",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.RequeueRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Returns 200 OK if the service is ready to accept requests",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "Readiness check",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/runs": {
+            "get": {
+                "description": "Get a paginated list of runs with optional filtering",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "runs"
+                ],
+                "summary": "List runs",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of runs to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor for pagination",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by job ID",
+                        "name": "jobId",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.RunListResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Start a new run for a job",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "runs"
+                ],
+                "summary": "Create a new run",
+                "parameters": [
+                    {
+                        "description": "Run creation request",
+                        "name": "run",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.CreateRunRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.CreateRunResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/runs/{runId}": {
+            "get": {
+                "description": "Get detailed information about a specific run",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "runs"
+                ],
+                "summary": "Get run details",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Run ID",
+                        "name": "runId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Run"
+                        }
+                    },
+                    "404": {
+                        "description": "Run not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Cancel a running or pending run",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "runs"
+                ],
+                "summary": "Cancel a run",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Run ID",
+                        "name": "runId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Run not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/triggers/firings": {
+            "get": {
+                "description": "Audit trail of workflow triggers: which event started which job, newest first.\nFirings skipped at the maximum trigger depth or failed on their input mapping are included.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "triggers"
+                ],
+                "summary": "List trigger firings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by the workflow the trigger belongs to",
+                        "name": "workflow",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by the job the event was about",
+                        "name": "sourceJobId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by the started job",
+                        "name": "jobId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of firings to return",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.TriggerFiringListResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/usage": {
+            "get": {
+                "description": "Tokens, cost and latency of LLM calls across jobs, grouped by any of workflow, agent,\nprovider, model, day and job (comma-separated; default workflow).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "usage"
+                ],
+                "summary": "Get aggregated LLM usage",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Grouping fields, e.g. workflow,model",
+                        "name": "groupBy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only calls of this workflow's jobs",
+                        "name": "workflow",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only calls to this provider",
+                        "name": "provider",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC3339 timestamp; only calls made at or after it",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC3339 timestamp; only calls made before it",
+                        "name": "until",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.UsageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid grouping or timestamp",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/version": {
+            "get": {
+                "description": "Returns the service name, version, and commit information",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "Get version information",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.VersionResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{name}": {
+            "post": {
+                "description": "Fire the triggers of published workflows that listen to the named webhook. The JSON body is\navailable to their conditions and input mappings as .payload. Every firing, including those\nthat did not start a job, is returned and recorded in the trigger audit trail.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "triggers"
+                ],
+                "summary": "Receive a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Webhook payload",
+                        "name": "payload",
+                        "in": "body",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/api.WebhookResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "No trigger listens to this webhook",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/workflows": {
+            "get": {
+                "description": "Get a list of all available workflows",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workflows"
+                ],
+                "summary": "List workflows",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.WorkflowListResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a workflow after validating its definition; optionally publish it as version 1",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workflows"
+                ],
+                "summary": "Create a workflow",
+                "parameters": [
+                    {
+                        "description": "Workflow creation request",
+                        "name": "workflow",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.CreateWorkflowRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.Workflow"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ValidateWorkflowResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Workflow already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/workflows/validate": {
+            "post": {
+                "description": "Validate input parameters against a workflow's inputSchema.\nThe workflow may be given as name@version; otherwise the latest published version is used.\nValid input is returned with schema defaults applied.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workflows"
+                ],
+                "summary": "Validate workflow input",
+                "parameters": [
+                    {
+                        "description": "Workflow validation request",
+                        "name": "workflow",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.ValidateWorkflowRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ValidateWorkflowResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/workflows/{name}": {
+            "get": {
+                "description": "Get schema and metadata for a specific workflow",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workflows"
+                ],
+                "summary": "Get workflow details",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workflow name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Workflow"
+                        }
+                    },
+                    "404": {
+                        "description": "Workflow not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace a workflow's draft definition after validating it.\nPublished versions are immutable; publish the draft to make it available to new jobs.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workflows"
+                ],
+                "summary": "Update a workflow",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workflow name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Workflow update request",
+                        "name": "workflow",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.UpdateWorkflowRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Workflow"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ValidateWorkflowResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Workflow not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
+            "delete": {
+                "description": "Delete a workflow. Deletion is refused while unfinished jobs use the workflow;\nif finished jobs still reference it, the workflow is soft-deleted so their history stays intact.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workflows"
+                ],
+                "summary": "Delete a workflow",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workflow name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.DeleteWorkflowResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Workflow not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Workflow is used by unfinished jobs",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/workflows/{name}/diff": {
+            "get": {
+                "description": "Compare two published versions of a workflow",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workflows"
+                ],
+                "summary": "Diff workflow versions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workflow name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Base version",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Target version",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.WorkflowDiffResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid version",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Workflow version not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/workflows/{name}/graph": {
+            "get": {
+                "description": "Render the step DAG of a workflow as a Mermaid flowchart, Graphviz DOT or JSON.\nWithout a version the latest published version is used, falling back to the draft.\nCompensations are linked to their steps with dashed edges.",
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "tags": [
+                    "workflows"
+                ],
+                "summary": "Render a workflow's step graph",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workflow name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Workflow version",
+                        "name": "version",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "mermaid",
+                            "dot",
+                            "json"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Output format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.WorkflowGraphResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Workflow not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/workflows/{name}/plan": {
+            "post": {
+                "description": "Dry-run a workflow: validate the input, resolve templates and conditions where the input\nalone is enough, and return the step DAG with each step's resolved input, agent, tool,\nmodel and estimated LLM calls. Nothing is persisted and nothing runs.\nA plan of invalid input or an invalid definition is returned with status 400.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workflows"
+                ],
+                "summary": "Plan a workflow run",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workflow name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Candidate input",
+                        "name": "plan",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.PlanWorkflowRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.WorkflowPlanResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Input or definition is invalid; a malformed body returns plain text",
+                        "schema": {
+                            "$ref": "#/definitions/api.WorkflowPlanResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Workflow not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to plan workflow",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/workflows/{name}/publish": {
+            "post": {
+                "description": "Snapshot the workflow's current definition as a new immutable version.\nPublishing an unchanged definition returns the latest version.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workflows"
+                ],
+                "summary": "Publish a workflow",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workflow name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.WorkflowVersion"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Workflow not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/workflows/{name}/usage": {
+            "get": {
+                "description": "Tokens, cost and latency of the LLM calls of the workflow's jobs, grouped by any of agent,\nprovider, model, day and job (comma-separated; default day).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workflows"
+                ],
+                "summary": "Get a workflow's LLM usage",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workflow name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Grouping fields, e.g. day,model",
+                        "name": "groupBy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only calls to this provider",
+                        "name": "provider",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC3339 timestamp; only calls made at or after it",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC3339 timestamp; only calls made before it",
+                        "name": "until",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.UsageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid grouping or timestamp",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/workflows/{name}/versions": {
+            "get": {
+                "description": "Get the immutable published versions of a workflow, oldest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workflows"
+                ],
+                "summary": "List workflow versions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workflow name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.WorkflowVersionListResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workflows/{name}/versions/{version}": {
+            "get": {
+                "description": "Get the definition of a published workflow version",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workflows"
+                ],
+                "summary": "Get a workflow version",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workflow name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Workflow version",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.WorkflowVersion"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid version",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Workflow version not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.Agent": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lastSeen": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "name": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.AgentListResponse": {
+            "type": "object",
+            "properties": {
+                "agents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.Agent"
+                    }
+                }
+            }
+        },
+        "api.AgentStatus": {
+            "type": "object",
+            "properties": {
+                "activeJobs": {
+                    "type": "integer"
+                },
+                "completedJobs": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "isDraining": {
+                    "type": "boolean"
+                },
+                "lastSeen": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.Artifact": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "digest": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "jobId": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "runId": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "type": {
+                    "$ref": "#/definitions/api.ArtifactType"
+                }
+            }
+        },
+        "api.ArtifactListResponse": {
+            "type": "object",
+            "properties": {
+                "artifacts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.Artifact"
+                    }
+                },
+                "cursor": {
+                    "type": "string"
+                },
+                "hasMore": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "api.ArtifactType": {
+            "type": "string",
+            "enum": [
+                "pdf",
+                "pptx",
+                "zip",
+                "log",
+                "markdown"
+            ],
+            "x-enum-varnames": [
+                "ArtifactTypePDF",
+                "ArtifactTypePPTX",
+                "ArtifactTypeZIP",
+                "ArtifactTypeLog",
+                "ArtifactTypeMarkdown"
+            ]
+        },
+        "api.CreateJobRequest": {
+            "type": "object",
+            "properties": {
+                "budget": {
+                    "description": "caps the job's LLM usage",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.JobBudget"
+                        }
+                    ]
+                },
+                "cassette": {
+                    "description": "records or replays the job's LLM traffic",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.JobCassette"
+                        }
+                    ]
+                },
+                "input": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "meta": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "noCache": {
+                    "description": "bypass the step cache for this job",
+                    "type": "boolean"
+                },
+                "workflow": {
+                    "description": "\"name\" or \"name@version\"",
+                    "type": "string"
+                }
+            }
+        },
+        "api.CreateJobResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "workflowVersion": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.CreateMatrixRequest": {
+            "type": "object",
+            "properties": {
+                "axes": {
+                    "description": "input key -\u003e values to try, e.g. {\"model\": [\"qwen2.5-coder:7b\", \"gpt-4o-mini\"]}",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {}
+                    }
+                },
+                "budget": {
+                    "description": "applies to every job on its own",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.JobBudget"
+                        }
+                    ]
+                },
+                "input": {
+                    "description": "input shared by every job",
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "noCache": {
+                    "type": "boolean"
+                },
+                "workflow": {
+                    "description": "name or name@version",
+                    "type": "string"
+                }
+            }
+        },
+        "api.CreateMatrixResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "jobs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.MatrixJob"
+                    }
+                },
+                "workflow": {
+                    "type": "string"
+                },
+                "workflowVersion": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.CreateRunRequest": {
+            "type": "object",
+            "properties": {
+                "jobId": {
+                    "type": "string"
+                },
+                "params": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "api.CreateRunResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.CreateWorkflowRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "publish": {
+                    "description": "publish the definition as a new version right away",
+                    "type": "boolean"
+                },
+                "schema": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "api.DeleteWorkflowResponse": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "softDeleted": {
+                    "description": "true when finished jobs still reference the workflow",
+                    "type": "boolean"
+                }
+            }
+        },
+        "api.GraphEdge": {
+            "type": "object",
+            "properties": {
+                "compensation": {
+                    "type": "boolean"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.GraphNode": {
+            "type": "object",
+            "properties": {
+                "cached": {
+                    "type": "boolean"
+                },
+                "childJobId": {
+                    "type": "string"
+                },
+                "compensation": {
+                    "type": "boolean"
+                },
+                "completedAt": {
+                    "type": "string"
+                },
+                "durationMs": {
+                    "description": "until now for steps that have not completed",
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "if": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/api.StepStatus"
+                },
+                "stepId": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "workflow": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.InputError": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "path": {
+                    "description": "JSON pointer into the input (\"\" is the input itself)",
+                    "type": "string"
+                }
+            }
+        },
+        "api.InvalidInputResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.InputError"
+                    }
+                }
+            }
+        },
+        "api.Job": {
+            "type": "object",
+            "properties": {
+                "children": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.JobChild"
+                    }
+                },
+                "completedAt": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "input": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "matrixId": {
+                    "description": "the matrix the job was expanded from",
+                    "type": "string"
+                },
+                "meta": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "parentJobId": {
+                    "type": "string"
+                },
+                "rerunFromStep": {
+                    "description": "the step the rerun started from",
+                    "type": "string"
+                },
+                "rerunOf": {
+                    "description": "the job this job reruns",
+                    "type": "string"
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/api.JobStatus"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "workflow": {
+                    "type": "string"
+                },
+                "workflowVersion": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.JobBudget": {
+            "type": "object",
+            "properties": {
+                "costUsd": {
+                    "description": "by the configured price table",
+                    "type": "number"
+                },
+                "tokens": {
+                    "description": "prompt plus completion tokens",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.JobCassette": {
+            "type": "object",
+            "properties": {
+                "mode": {
+                    "description": "record or replay",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.JobChild": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "parentStepId": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/api.JobStatus"
+                },
+                "workflow": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.JobListResponse": {
+            "type": "object",
+            "properties": {
+                "cursor": {
+                    "type": "string"
+                },
+                "hasMore": {
+                    "type": "boolean"
+                },
+                "jobs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.Job"
+                    }
+                }
+            }
+        },
+        "api.JobLogsResponse": {
+            "type": "object",
+            "properties": {
+                "jobId": {
+                    "type": "string"
+                },
+                "logs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.LogEntry"
+                    }
+                }
+            }
+        },
+        "api.JobResult": {
+            "type": "object",
+            "properties": {
+                "artifacts": {
+                    "description": "artifact IDs",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "completedAt": {
+                    "type": "string"
+                },
+                "jobId": {
+                    "type": "string"
+                },
+                "result": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "status": {
+                    "$ref": "#/definitions/api.JobStatus"
+                }
+            }
+        },
+        "api.JobStatus": {
+            "type": "string",
+            "enum": [
+                "queued",
+                "running",
+                "waiting_approval",
+                "waiting_child",
+                "waiting_signal",
+                "compensating",
+                "succeeded",
+                "failed",
+                "cancelled"
+            ],
+            "x-enum-varnames": [
+                "JobStatusQueued",
+                "JobStatusRunning",
+                "JobStatusWaitingApproval",
+                "JobStatusWaitingChild",
+                "JobStatusWaitingSignal",
+                "JobStatusCompensating",
+                "JobStatusSucceeded",
+                "JobStatusFailed",
+                "JobStatusCancelled"
+            ]
+        },
+        "api.JobStep": {
+            "type": "object",
+            "properties": {
+                "artifactIds": {
+                    "description": "artifacts the step produced",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "cacheKey": {
+                    "type": "string"
+                },
+                "cached": {
+                    "description": "output was reused from the step cache",
+                    "type": "boolean"
+                },
+                "completedAt": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "input": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "jobId": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "output": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/api.StepStatus"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.JobUsageResponse": {
+            "type": "object",
+            "properties": {
+                "budget": {
+                    "$ref": "#/definitions/api.JobBudget"
+                },
+                "budgetExceeded": {
+                    "description": "the limit the job reached, if any",
+                    "type": "string"
+                },
+                "jobId": {
+                    "type": "string"
+                },
+                "models": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.ModelUsage"
+                    }
+                },
+                "steps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.StepUsage"
+                    }
+                },
+                "total": {
+                    "$ref": "#/definitions/api.LLMUsage"
+                },
+                "workflow": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.LLMCall": {
+            "type": "object",
+            "properties": {
+                "agent": {
+                    "type": "string"
+                },
+                "attempt": {
+                    "description": "1 for the first provider of the route",
+                    "type": "integer"
+                },
+                "completionTokens": {
+                    "type": "integer"
+                },
+                "costUsd": {
+                    "type": "number"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errorKind": {
+                    "description": "rate_limit, context_length, model_not_found, auth, transient, invalid_request, circuit_open, ...",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "jobId": {
+                    "type": "string"
+                },
+                "latencyMs": {
+                    "type": "integer"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "promptTokens": {
+                    "type": "integer"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "requestId": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "succeeded, failed, skipped (circuit breaker open) or cached",
+                    "type": "string"
+                },
+                "stepId": {
+                    "type": "string"
+                },
+                "totalTokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.LLMCallListResponse": {
+            "type": "object",
+            "properties": {
+                "calls": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.LLMCall"
+                    }
+                }
+            }
+        },
+        "api.LLMUsage": {
+            "type": "object",
+            "properties": {
+                "calls": {
+                    "description": "attempts sent to a provider; cache hits do not count",
+                    "type": "integer"
+                },
+                "completionTokens": {
+                    "type": "integer"
+                },
+                "costUsd": {
+                    "type": "number"
+                },
+                "latencyMs": {
+                    "description": "summed over the calls",
+                    "type": "integer"
+                },
+                "promptTokens": {
+                    "type": "integer"
+                },
+                "totalTokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.LogEntry": {
+            "type": "object",
+            "properties": {
+                "level": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "stepId": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.LoginRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.LoginResponse": {
+            "type": "object",
+            "properties": {
+                "accessToken": {
+                    "type": "string"
+                },
+                "expiresIn": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.MatrixJob": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "params": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "api.MatrixReportResponse": {
+            "type": "object",
+            "properties": {
+                "axes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "completedAt": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "finished": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "reportArtifactId": {
+                    "description": "markdown report, once every job finished",
+                    "type": "string"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.MatrixRow"
+                    }
+                },
+                "scores": {
+                    "description": "steps that reported a score",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "workflow": {
+                    "type": "string"
+                },
+                "workflowVersion": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.MatrixRow": {
+            "type": "object",
+            "properties": {
+                "completionTokens": {
+                    "type": "integer"
+                },
+                "costUsd": {
+                    "type": "number"
+                },
+                "durationMs": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "jobId": {
+                    "type": "string"
+                },
+                "params": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "promptTokens": {
+                    "type": "integer"
+                },
+                "scores": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    }
+                },
+                "status": {
+                    "$ref": "#/definitions/api.JobStatus"
+                },
+                "totalTokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ModelUsage": {
+            "type": "object",
+            "properties": {
+                "model": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "usage": {
+                    "$ref": "#/definitions/api.LLMUsage"
+                }
+            }
+        },
+        "api.PlanWorkflowRequest": {
+            "type": "object",
+            "properties": {
+                "input": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "version": {
+                    "description": "defaults to the latest published version",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.PlannedStep": {
+            "type": "object",
+            "properties": {
+                "agent": {
+                    "type": "string"
+                },
+                "cache": {
+                    "type": "boolean"
+                },
+                "child": {
+                    "$ref": "#/definitions/api.WorkflowPlanResponse"
+                },
+                "compensate": {
+                    "type": "string"
+                },
+                "dependsOn": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "estimatedLlmCalls": {
+                    "type": "integer"
+                },
+                "if": {
+                    "type": "string"
+                },
+                "input": {
+                    "description": "resolved where possible",
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "model": {
+                    "type": "string"
                 },
-                "metadata": {
+                "name": {
+                    "type": "string"
+                },
+                "outcome": {
+                    "description": "run | skip | unknown",
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "tool": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "unresolved": {
+                    "description": "JSON pointers of input values that depend on earlier steps",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "workflow": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.PreviewPromptRequest": {
+            "type": "object",
+            "properties": {
+                "variables": {
                     "type": "object",
                     "additionalProperties": true
                 },
+                "version": {
+                    "description": "defaults to the latest version",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.PreviewPromptResponse": {
+            "type": "object",
+            "properties": {
+                "prompt": {
+                    "type": "string"
+                },
+                "ref": {
+                    "description": "name@version, as steps reference it",
+                    "type": "string"
+                },
+                "rendered": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.Prompt": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "latestVersion": {
+                    "type": "integer"
+                },
                 "name": {
                     "type": "string"
                 },
-                "status": {
+                "updatedAt": {
                     "type": "string"
                 }
             }
         },
-        "api.AgentListResponse": {
+        "api.PromptDiffResponse": {
             "type": "object",
             "properties": {
-                "agents": {
+                "descriptionChanged": {
+                    "type": "boolean"
+                },
+                "from": {
+                    "type": "integer"
+                },
+                "prompt": {
+                    "type": "string"
+                },
+                "templateDiff": {
+                    "description": "unified diff; empty when the template is unchanged",
+                    "type": "string"
+                },
+                "to": {
+                    "type": "integer"
+                },
+                "variablesAdded": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/api.Agent"
+                        "type": "string"
+                    }
+                },
+                "variablesChanged": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "variablesRemoved": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
                     }
                 }
             }
         },
-        "api.AgentStatus": {
+        "api.PromptListResponse": {
             "type": "object",
             "properties": {
-                "activeJobs": {
+                "prompts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.Prompt"
+                    }
+                }
+            }
+        },
+        "api.PromptVariable": {
+            "type": "object",
+            "properties": {
+                "default": {
+                    "description": "used when an optional variable is not given"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "required": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "api.PromptVersion": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "prompt": {
+                    "type": "string"
+                },
+                "source": {
+                    "description": "file the version was loaded from",
+                    "type": "string"
+                },
+                "template": {
+                    "description": "text/template body",
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.PromptVariable"
+                    }
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.PromptVersionListResponse": {
+            "type": "object",
+            "properties": {
+                "versions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.PromptVersion"
+                    }
+                }
+            }
+        },
+        "api.PublishPromptRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "template": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.PromptVariable"
+                    }
+                }
+            }
+        },
+        "api.QueueItem": {
+            "type": "object",
+            "properties": {
+                "completedAt": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "data": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "id": {
+                    "type": "string"
+                },
+                "jobId": {
+                    "type": "string"
+                },
+                "leasedAt": {
+                    "type": "string"
+                },
+                "state": {
+                    "$ref": "#/definitions/api.QueueState"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.QueueItemListResponse": {
+            "type": "object",
+            "properties": {
+                "cursor": {
+                    "type": "string"
+                },
+                "hasMore": {
+                    "type": "boolean"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.QueueItem"
+                    }
+                }
+            }
+        },
+        "api.QueueState": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "leased",
+                "done",
+                "dead"
+            ],
+            "x-enum-varnames": [
+                "QueueStatePending",
+                "QueueStateLeased",
+                "QueueStateDone",
+                "QueueStateDead"
+            ]
+        },
+        "api.QueueStats": {
+            "type": "object",
+            "properties": {
+                "dead": {
+                    "type": "integer"
+                },
+                "done": {
+                    "type": "integer"
+                },
+                "leased": {
                     "type": "integer"
                 },
-                "completedJobs": {
-                    "type": "integer"
+                "pending": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.RefreshRequest": {
+            "type": "object",
+            "properties": {
+                "refreshToken": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.RequeueRequest": {
+            "type": "object",
+            "properties": {
+                "jobId": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.RerunJobRequest": {
+            "type": "object",
+            "properties": {
+                "fromStep": {
+                    "type": "string"
+                },
+                "input": {
+                    "description": "overrides top-level keys of the original input",
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "api.RerunJobResponse": {
+            "type": "object",
+            "properties": {
+                "copiedSteps": {
+                    "description": "steps whose results were copied from the original job",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "fromStep": {
+                    "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
-                "isDraining": {
-                    "type": "boolean"
-                },
-                "lastSeen": {
+                "rerunOf": {
                     "type": "string"
                 },
-                "status": {
+                "runId": {
+                    "description": "the run recording the rerun; see GET /runs",
                     "type": "string"
                 }
             }
         },
-        "api.Artifact": {
+        "api.Run": {
             "type": "object",
             "properties": {
-                "createdAt": {
+                "completedAt": {
                     "type": "string"
                 },
-                "id": {
+                "createdAt": {
                     "type": "string"
                 },
-                "jobId": {
+                "error": {
                     "type": "string"
                 },
-                "name": {
+                "id": {
                     "type": "string"
                 },
-                "path": {
+                "jobId": {
                     "type": "string"
                 },
-                "runId": {
+                "params": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "startedAt": {
                     "type": "string"
                 },
-                "size": {
-                    "type": "integer"
+                "status": {
+                    "$ref": "#/definitions/api.RunStatus"
                 },
-                "type": {
-                    "$ref": "#/definitions/api.ArtifactType"
+                "updatedAt": {
+                    "type": "string"
                 }
             }
         },
-        "api.ArtifactListResponse": {
+        "api.RunListResponse": {
             "type": "object",
             "properties": {
-                "artifacts": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/api.Artifact"
-                    }
-                },
                 "cursor": {
                     "type": "string"
                 },
                 "hasMore": {
                     "type": "boolean"
+                },
+                "runs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.Run"
+                    }
                 }
             }
         },
-        "api.ArtifactType": {
+        "api.RunStatus": {
             "type": "string",
             "enum": [
-                "pdf",
-                "pptx",
-                "zip",
-                "log"
+                "pending",
+                "running",
+                "succeeded",
+                "failed",
+                "cancelled"
             ],
             "x-enum-varnames": [
-                "ArtifactTypePDF",
-                "ArtifactTypePPTX",
-                "ArtifactTypeZIP",
-                "ArtifactTypeLog"
+                "RunStatusPending",
+                "RunStatusRunning",
+                "RunStatusSucceeded",
+                "RunStatusFailed",
+                "RunStatusCancelled"
             ]
         },
-        "api.CreateJobRequest": {
+        "api.SignalResponse": {
             "type": "object",
             "properties": {
-                "input": {
-                    "type": "object",
-                    "additionalProperties": true
+                "consumedAt": {
+                    "type": "string"
                 },
-                "meta": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "delivered": {
+                    "description": "false while the signal is buffered",
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "jobId": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "payload": {
                     "type": "object",
                     "additionalProperties": true
                 },
-                "workflow": {
+                "stepId": {
+                    "description": "the step the signal completed",
                     "type": "string"
                 }
             }
         },
-        "api.CreateJobResponse": {
+        "api.StepDecisionRequest": {
             "type": "object",
             "properties": {
-                "id": {
+                "approver": {
+                    "type": "string"
+                },
+                "comment": {
                     "type": "string"
                 }
             }
         },
-        "api.CreateRunRequest": {
+        "api.StepStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "running",
+                "waiting_approval",
+                "waiting_child",
+                "waiting_signal",
+                "succeeded",
+                "failed",
+                "skipped",
+                "compensated"
+            ],
+            "x-enum-varnames": [
+                "StepStatusPending",
+                "StepStatusRunning",
+                "StepStatusWaitingApproval",
+                "StepStatusWaitingChild",
+                "StepStatusWaitingSignal",
+                "StepStatusSucceeded",
+                "StepStatusFailed",
+                "StepStatusSkipped",
+                "StepStatusCompensated"
+            ]
+        },
+        "api.StepUsage": {
             "type": "object",
             "properties": {
                 "jobId": {
                     "type": "string"
                 },
-                "params": {
-                    "type": "object",
-                    "additionalProperties": true
-                }
-            }
-        },
-        "api.CreateRunResponse": {
-            "type": "object",
-            "properties": {
-                "id": {
+                "step": {
+                    "type": "string"
+                },
+                "stepId": {
                     "type": "string"
+                },
+                "usage": {
+                    "$ref": "#/definitions/api.LLMUsage"
                 }
             }
         },
-        "api.Job": {
+        "api.TriggerFiring": {
             "type": "object",
             "properties": {
-                "completedAt": {
+                "artifactId": {
                     "type": "string"
                 },
                 "createdAt": {
                     "type": "string"
                 },
+                "depth": {
+                    "description": "length of the trigger chain that led to the job",
+                    "type": "integer"
+                },
                 "error": {
                     "type": "string"
                 },
-                "id": {
+                "eventId": {
                     "type": "string"
                 },
-                "input": {
-                    "type": "object",
-                    "additionalProperties": true
+                "eventType": {
+                    "description": "job.succeeded, job.failed, job.cancelled, artifact.created or webhook",
+                    "type": "string"
                 },
-                "meta": {
-                    "type": "object",
-                    "additionalProperties": true
+                "id": {
+                    "type": "string"
                 },
-                "startedAt": {
+                "jobId": {
+                    "description": "the started job",
+                    "type": "string"
+                },
+                "sourceJobId": {
+                    "description": "the job the event is about",
                     "type": "string"
                 },
                 "status": {
-                    "$ref": "#/definitions/api.JobStatus"
+                    "description": "started, skipped or failed",
+                    "type": "string"
                 },
-                "updatedAt": {
+                "trigger": {
+                    "type": "string"
+                },
+                "webhook": {
                     "type": "string"
                 },
                 "workflow": {
+                    "description": "the workflow the trigger belongs to",
                     "type": "string"
+                },
+                "workflowVersion": {
+                    "type": "integer"
                 }
             }
         },
-        "api.JobListResponse": {
+        "api.TriggerFiringListResponse": {
             "type": "object",
             "properties": {
-                "cursor": {
-                    "type": "string"
-                },
-                "hasMore": {
-                    "type": "boolean"
-                },
-                "jobs": {
+                "firings": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/api.Job"
+                        "$ref": "#/definitions/api.TriggerFiring"
                     }
                 }
             }
         },
-        "api.JobLogsResponse": {
+        "api.UpdateWorkflowRequest": {
             "type": "object",
             "properties": {
-                "jobId": {
+                "description": {
                     "type": "string"
                 },
-                "logs": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/api.LogEntry"
-                    }
+                "publish": {
+                    "description": "publish the definition as a new version right away",
+                    "type": "boolean"
+                },
+                "schema": {
+                    "type": "object",
+                    "additionalProperties": true
                 }
             }
         },
-        "api.JobResult": {
+        "api.UsageGroup": {
             "type": "object",
             "properties": {
-                "artifacts": {
-                    "description": "artifact IDs",
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
+                "agent": {
+                    "type": "string"
                 },
-                "completedAt": {
+                "day": {
+                    "description": "YYYY-MM-DD",
                     "type": "string"
                 },
                 "jobId": {
                     "type": "string"
                 },
-                "result": {
-                    "type": "object",
-                    "additionalProperties": true
+                "model": {
+                    "type": "string"
                 },
-                "status": {
-                    "$ref": "#/definitions/api.JobStatus"
+                "provider": {
+                    "type": "string"
+                },
+                "usage": {
+                    "$ref": "#/definitions/api.LLMUsage"
+                },
+                "workflow": {
+                    "type": "string"
                 }
             }
         },
-        "api.JobStatus": {
-            "type": "string",
-            "enum": [
-                "queued",
-                "running",
-                "succeeded",
-                "failed",
-                "cancelled"
-            ],
-            "x-enum-varnames": [
-                "JobStatusQueued",
-                "JobStatusRunning",
-                "JobStatusSucceeded",
-                "JobStatusFailed",
-                "JobStatusCancelled"
-            ]
-        },
-        "api.JobStep": {
+        "api.UsageResponse": {
             "type": "object",
             "properties": {
-                "completedAt": {
-                    "type": "string"
-                },
-                "createdAt": {
-                    "type": "string"
-                },
-                "error": {
-                    "type": "string"
+                "groupBy": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "id": {
-                    "type": "string"
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.UsageGroup"
+                    }
                 },
+                "total": {
+                    "$ref": "#/definitions/api.LLMUsage"
+                }
+            }
+        },
+        "api.ValidateWorkflowRequest": {
+            "type": "object",
+            "properties": {
                 "input": {
                     "type": "object",
                     "additionalProperties": true
                 },
-                "jobId": {
+                "workflow": {
                     "type": "string"
+                }
+            }
+        },
+        "api.ValidateWorkflowResponse": {
+            "type": "object",
+            "properties": {
+                "details": {
+                    "description": "input violations by JSON pointer",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.InputError"
+                    }
                 },
-                "name": {
-                    "type": "string"
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "output": {
+                "input": {
+                    "description": "input with schema defaults applied",
                     "type": "object",
                     "additionalProperties": true
                 },
-                "startedAt": {
-                    "type": "string"
-                },
-                "status": {
-                    "$ref": "#/definitions/api.StepStatus"
-                },
-                "updatedAt": {
-                    "type": "string"
+                "valid": {
+                    "type": "boolean"
                 }
             }
         },
-        "api.LogEntry": {
+        "api.VersionResponse": {
             "type": "object",
             "properties": {
-                "level": {
-                    "type": "string"
-                },
-                "message": {
+                "commit": {
                     "type": "string"
                 },
-                "stepId": {
+                "name": {
                     "type": "string"
                 },
-                "timestamp": {
+                "version": {
                     "type": "string"
                 }
             }
         },
-        "api.LoginRequest": {
+        "api.WebhookResponse": {
             "type": "object",
             "properties": {
-                "password": {
-                    "type": "string"
+                "firings": {
+                    "description": "empty when every listening trigger's condition was false",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.TriggerFiring"
+                    }
                 },
-                "username": {
-                    "type": "string"
-                }
-            }
-        },
-        "api.LoginResponse": {
-            "type": "object",
-            "properties": {
-                "accessToken": {
+                "webhook": {
                     "type": "string"
-                },
-                "expiresIn": {
-                    "type": "integer"
                 }
             }
         },
-        "api.QueueItem": {
+        "api.Workflow": {
             "type": "object",
             "properties": {
-                "completedAt": {
+                "description": {
                     "type": "string"
                 },
-                "createdAt": {
+                "name": {
                     "type": "string"
                 },
-                "data": {
+                "schema": {
                     "type": "object",
                     "additionalProperties": true
                 },
-                "id": {
-                    "type": "string"
-                },
-                "jobId": {
-                    "type": "string"
-                },
-                "leasedAt": {
-                    "type": "string"
-                },
-                "state": {
-                    "$ref": "#/definitions/api.QueueState"
-                },
-                "updatedAt": {
+                "version": {
                     "type": "string"
                 }
             }
         },
-        "api.QueueItemListResponse": {
+        "api.WorkflowChange": {
             "type": "object",
             "properties": {
-                "cursor": {
+                "from": {},
+                "op": {
+                    "description": "added | removed | changed",
                     "type": "string"
                 },
-                "hasMore": {
-                    "type": "boolean"
+                "path": {
+                    "description": "JSON pointer into the definition",
+                    "type": "string"
                 },
-                "items": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/api.QueueItem"
-                    }
-                }
+                "to": {}
             }
         },
-        "api.QueueState": {
-            "type": "string",
-            "enum": [
-                "pending",
-                "leased",
-                "done",
-                "dead"
-            ],
-            "x-enum-varnames": [
-                "QueueStatePending",
-                "QueueStateLeased",
-                "QueueStateDone",
-                "QueueStateDead"
-            ]
-        },
-        "api.QueueStats": {
+        "api.WorkflowDiffResponse": {
             "type": "object",
             "properties": {
-                "dead": {
-                    "type": "integer"
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.WorkflowChange"
+                    }
                 },
-                "done": {
+                "from": {
                     "type": "integer"
                 },
-                "leased": {
-                    "type": "integer"
+                "stepsAdded": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "pending": {
-                    "type": "integer"
+                "stepsChanged": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "total": {
+                "stepsRemoved": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "to": {
                     "type": "integer"
-                }
-            }
-        },
-        "api.RefreshRequest": {
-            "type": "object",
-            "properties": {
-                "refreshToken": {
-                    "type": "string"
-                }
-            }
-        },
-        "api.RequeueRequest": {
-            "type": "object",
-            "properties": {
-                "jobId": {
-                    "type": "string"
                 },
-                "reason": {
+                "workflow": {
                     "type": "string"
                 }
             }
         },
-        "api.Run": {
+        "api.WorkflowGraphResponse": {
             "type": "object",
             "properties": {
-                "completedAt": {
-                    "type": "string"
-                },
-                "createdAt": {
-                    "type": "string"
-                },
-                "error": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
+                "edges": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.GraphEdge"
+                    }
                 },
                 "jobId": {
+                    "description": "set for job graphs",
                     "type": "string"
                 },
-                "params": {
-                    "type": "object",
-                    "additionalProperties": true
-                },
-                "startedAt": {
-                    "type": "string"
+                "nodes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.GraphNode"
+                    }
                 },
                 "status": {
-                    "$ref": "#/definitions/api.RunStatus"
+                    "description": "job status",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.JobStatus"
+                        }
+                    ]
                 },
-                "updatedAt": {
+                "version": {
+                    "description": "0 for an unpublished draft",
+                    "type": "integer"
+                },
+                "workflow": {
                     "type": "string"
                 }
             }
         },
-        "api.RunListResponse": {
+        "api.WorkflowListResponse": {
             "type": "object",
             "properties": {
-                "cursor": {
-                    "type": "string"
-                },
-                "hasMore": {
-                    "type": "boolean"
-                },
-                "runs": {
+                "workflows": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/api.Run"
+                        "$ref": "#/definitions/api.Workflow"
                     }
                 }
             }
         },
-        "api.RunStatus": {
-            "type": "string",
-            "enum": [
-                "pending",
-                "running",
-                "succeeded",
-                "failed",
-                "cancelled"
-            ],
-            "x-enum-varnames": [
-                "RunStatusPending",
-                "RunStatusRunning",
-                "RunStatusSucceeded",
-                "RunStatusFailed",
-                "RunStatusCancelled"
-            ]
-        },
-        "api.StepStatus": {
-            "type": "string",
-            "enum": [
-                "pending",
-                "running",
-                "succeeded",
-                "failed",
-                "skipped"
-            ],
-            "x-enum-varnames": [
-                "StepStatusPending",
-                "StepStatusRunning",
-                "StepStatusSucceeded",
-                "StepStatusFailed",
-                "StepStatusSkipped"
-            ]
-        },
-        "api.ValidateWorkflowRequest": {
+        "api.WorkflowPlanResponse": {
             "type": "object",
             "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "estimatedLlmCalls": {
+                    "description": "upper bound",
+                    "type": "integer"
+                },
                 "input": {
+                    "description": "with schema defaults applied",
                     "type": "object",
                     "additionalProperties": true
                 },
-                "workflow": {
-                    "type": "string"
-                }
-            }
-        },
-        "api.ValidateWorkflowResponse": {
-            "type": "object",
-            "properties": {
-                "errors": {
+                "inputErrors": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/api.InputError"
+                    }
+                },
+                "steps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.PlannedStep"
                     }
                 },
                 "valid": {
                     "type": "boolean"
-                }
-            }
-        },
-        "api.VersionResponse": {
-            "type": "object",
-            "properties": {
-                "commit": {
-                    "type": "string"
-                },
-                "name": {
-                    "type": "string"
                 },
                 "version": {
+                    "type": "integer"
+                },
+                "workflow": {
                     "type": "string"
                 }
             }
         },
-        "api.Workflow": {
+        "api.WorkflowVersion": {
             "type": "object",
             "properties": {
-                "description": {
+                "createdAt": {
                     "type": "string"
                 },
-                "name": {
+                "description": {
                     "type": "string"
                 },
                 "schema": {
@@ -1961,21 +4640,32 @@ const docTemplate = `{
                     "additionalProperties": true
                 },
                 "version": {
+                    "type": "integer"
+                },
+                "workflow": {
                     "type": "string"
                 }
             }
         },
-        "api.WorkflowListResponse": {
+        "api.WorkflowVersionListResponse": {
             "type": "object",
             "properties": {
-                "workflows": {
+                "versions": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/api.Workflow"
+                        "$ref": "#/definitions/api.WorkflowVersion"
                     }
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "description": "Type \"Bearer\" followed by a space and the configured auth token.",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -6,17 +6,24 @@ import (
 	"net/http"
 	"time"
 
+	"agent-project-manager/internal/agents"
 	"agent-project-manager/internal/api"
 	"agent-project-manager/internal/config"
+	"agent-project-manager/internal/llm"
 	"agent-project-manager/internal/logger"
 	"agent-project-manager/internal/obs"
+	"agent-project-manager/internal/orchestrator"
+	"agent-project-manager/internal/promptdir"
 	"agent-project-manager/internal/state"
+	"agent-project-manager/internal/workflowdir"
 )
 
 type App struct {
-	Store    state.Store
-	Server   *http.Server
-	Shutdown func(ctx context.Context) error
+	Store        state.Store
+	Orchestrator *orchestrator.Orchestrator
+	LLM          *llm.Gateway
+	Server       *http.Server
+	Shutdown     func(ctx context.Context) error
 }
 
 type Options struct {
@@ -26,7 +33,7 @@ type Options struct {
 	MigrationsDir   string
 }
 
-// Init wires logger, DB store (with retry), migrations, OpenTelemetry, and HTTP server.
+// Init wires logger, DB store (with retry), migrations, OpenTelemetry, orchestrator, and HTTP server.
 func Init(cfg config.Config, opts Options) (*App, error) {
 	// Defaults
 	if opts.ShutdownTimeout == 0 {
@@ -87,14 +94,63 @@ func Init(cfg config.Config, opts Options) (*App, error) {
 		}
 	}
 
+	// Workflow management endpoints require a bearer token
+	api.AuthToken = cfg.Auth.Token
+	if api.AuthToken == "" {
+		logger.Warn("agentd: auth.token is not set; workflow management endpoints are disabled")
+	}
+
+	// LLM providers and the gateway routing between them
+	gateway, err := initLLM(cfg.LLM, store)
+	if err != nil {
+		_ = store.Close()
+		return nil, err
+	}
+
+	// Orchestrator (workers + timeout sweeper + trigger engine)
+	orch := orchestrator.New(store, orchestrator.Options{
+		Workers:         cfg.Queue.Workers,
+		ArtifactDir:     cfg.Artifacts.WorkDir,
+		MaxTriggerDepth: cfg.Triggers.MaxDepth,
+		Providers:       gateway.Registry().Names(),
+		CheckModel:      checkModel(gateway.Registry()),
+	})
+	toolRegistry, toolLoop := initTools(cfg)
+	orch.Register(agents.StepTypeLLM, agents.NewLLMStep(gateway, toolRegistry, toolLoop))
+	orch.Start(context.Background())
+
+	// Workflow definitions checked into a directory
+	var registry *workflowdir.Registry
+	if cfg.Workflows.Dir != "" {
+		interval, _ := time.ParseDuration(cfg.Workflows.ScanInterval)
+		registry = workflowdir.New(store, orch, workflowdir.Options{
+			Dir:          cfg.Workflows.Dir,
+			ScanInterval: interval,
+		})
+		registry.Start(context.Background())
+	}
+
+	// Prompt templates checked into a directory
+	var promptRegistry *promptdir.Registry
+	if cfg.Prompts.Dir != "" {
+		interval, _ := time.ParseDuration(cfg.Prompts.ScanInterval)
+		promptRegistry = promptdir.New(store, promptdir.Options{
+			Dir:          cfg.Prompts.Dir,
+			ScanInterval: interval,
+		})
+		promptRegistry.Start(context.Background())
+	}
+
 	srv := &http.Server{
 		Addr:    cfg.API.Addr,
-		Handler: api.Router(store),
+		Handler: api.Router(store, orch),
 	}
 
 	app := &App{
-		Store:  store,
-		Server: srv,
+		Store:        store,
+		Orchestrator: orch,
+		LLM:          gateway,
+		Server:       srv,
 		Shutdown: func(ctx context.Context) error {
 			// stop HTTP server first
 			shutdownCtx, cancel := context.WithTimeout(ctx, opts.ShutdownTimeout)
@@ -104,6 +160,15 @@ func Init(cfg config.Config, opts Options) (*App, error) {
 				return err
 			}
 
+			// stop workers before the store goes away
+			if registry != nil {
+				registry.Stop()
+			}
+			if promptRegistry != nil {
+				promptRegistry.Stop()
+			}
+			orch.Stop()
+
 			// shutdown OTel (if it was initialized, obs.Shutdown should be safe/no-op per your impl)
 			if err := obs.Shutdown(ctx); err != nil {
 				logger.Errorf("agentd: failed to shutdown OpenTelemetry: %v", err)
@@ -0,0 +1,290 @@
+package agentd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"agent-project-manager/internal/config"
+	"agent-project-manager/internal/llm"
+	"agent-project-manager/internal/logger"
+	"agent-project-manager/internal/orchestrator"
+	"agent-project-manager/internal/state"
+	"agent-project-manager/internal/tools"
+)
+
+// modelCheckTimeout bounds a model lookup so a hung provider does not hold up a job
+const modelCheckTimeout = 10 * time.Second
+
+// llmCachePruneEvery is how many stored responses pass between evictions from the LLM cache
+const llmCachePruneEvery = 50
+
+// maxJobCassettes is how many jobs' cassettes stay open; the least recently opened is dropped
+const maxJobCassettes = 64
+
+// initLLM creates the configured LLM providers and the gateway that routes between them,
+// records every call, enforces job budgets, caches responses if enabled and records or replays
+// the traffic of jobs submitted with a cassette, and checks that the default model of the
+// default provider is available. A provider that cannot be reached is not fatal: it may come up after agentd.
+func initLLM(cfg config.LLMConfig, store state.Store) (*llm.Gateway, error) {
+	registry, err := llm.NewRegistry(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var cache *llm.ResponseCache
+	if cfg.Cache.Enabled {
+		cache = llm.NewResponseCache(newLLMCacheStore(store, cfg.Cache), cfg.Cache)
+	}
+	gateway, err := llm.NewGateway(registry, cfg, llm.GatewayOptions{
+		Record:   recordLLMCall(store),
+		Admit:    checkBudget(store),
+		Cache:    cache,
+		Cassette: newJobCassettes(store, cfg.Cassettes).get,
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("agentd: LLM providers %v (default %q)", registry.Names(), registry.Default())
+
+	if registry.Default() == "" {
+		return gateway, nil
+	}
+	if err := checkModel(registry)(context.Background(), "", ""); err != nil {
+		logger.Warnf("agentd: %v", err)
+	}
+	return gateway, nil
+}
+
+// initTools creates the registry of the tools llm steps offer to models, confined to
+// tools.root (or the artifacts work directory), and the default limits of the tool loop
+func initTools(cfg config.Config) (*tools.Registry, llm.ToolLoop) {
+	root := cfg.Tools.Root
+	if root == "" {
+		root = cfg.Artifacts.WorkDir
+	}
+	registry := tools.NewRegistry(tools.Options{Root: root, TestCommand: cfg.Tools.TestCommand})
+	maxToolTime, _ := time.ParseDuration(cfg.Tools.MaxToolTime)
+	loop := llm.ToolLoop{MaxIterations: cfg.Tools.MaxIterations, MaxToolTime: maxToolTime}
+	if root != "" {
+		logger.Infof("agentd: tools %v work in %s", registry.Names(), root)
+	}
+	return registry, loop
+}
+
+// llmCacheStore keeps cached LLM responses in the llm_cache table
+type llmCacheStore struct {
+	store    state.Store
+	maxBytes int64
+	puts     atomic.Int64
+}
+
+// newLLMCacheStore creates the cache's table store and evicts what no longer fits
+func newLLMCacheStore(store state.Store, cfg config.LLMCacheConfig) *llmCacheStore {
+	maxSizeMB := cfg.MaxSizeMB
+	if maxSizeMB == 0 {
+		maxSizeMB = 100
+	}
+	s := &llmCacheStore{store: store, maxBytes: int64(maxSizeMB) << 20}
+	s.prune()
+	return s
+}
+
+// GetResponse implements llm.CacheStore
+func (s *llmCacheStore) GetResponse(ctx context.Context, key string) (*llm.Response, error) {
+	entry, err := s.store.GetLLMCacheEntry(key)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	raw, _ := json.Marshal(entry.Response)
+	resp := &llm.Response{}
+	if err := json.Unmarshal(raw, resp); err != nil {
+		return nil, fmt.Errorf("failed to decode cached response %s: %w", key, err)
+	}
+	if err := s.store.RecordLLMCacheHit(key); err != nil {
+		logger.Warnf("agentd: failed to record LLM cache hit %s: %v", key, err)
+	}
+	return resp, nil
+}
+
+// PutResponse implements llm.CacheStore
+func (s *llmCacheStore) PutResponse(ctx context.Context, key string, resp *llm.Response, ttl time.Duration) error {
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	var response state.JSONMap
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return err
+	}
+	err = s.store.SaveLLMCacheEntry(&state.LLMCacheEntry{
+		Key:       key,
+		Provider:  resp.Provider,
+		Model:     resp.Model,
+		Response:  response,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return err
+	}
+	if s.puts.Add(1)%llmCachePruneEvery == 0 {
+		s.prune()
+	}
+	return nil
+}
+
+// prune drops expired responses and the least recently used ones beyond the size cap
+func (s *llmCacheStore) prune() {
+	n, err := s.store.PruneLLMCache(s.maxBytes)
+	if err != nil {
+		logger.Warnf("agentd: failed to prune LLM cache: %v", err)
+		return
+	}
+	if n > 0 {
+		logger.Debugf("agentd: pruned %d LLM cache entries", n)
+	}
+}
+
+// recordLLMCall stores every gateway attempt in the llm_calls table
+func recordLLMCall(store state.Store) func(a *llm.Attempt) {
+	return func(a *llm.Attempt) {
+		call := &state.LLMCall{
+			RequestID: a.RequestID,
+			JobID:     a.Call.JobID,
+			StepID:    a.Call.StepID,
+			Agent:     a.Call.Agent,
+			Attempt:   a.Number,
+			Provider:  a.Provider,
+			Model:     a.Model,
+			Status:    a.Status,
+			ErrorKind: llm.ErrorKind(a.Err),
+			LatencyMs: a.Latency.Milliseconds(),
+		}
+		if a.Err != nil {
+			call.Error = a.Err.Error()
+		}
+		if a.Response != nil {
+			if a.Response.Model != "" {
+				call.Model = a.Response.Model // the model that actually answered
+			}
+			call.PromptTokens = a.Response.Usage.PromptTokens
+			call.CompletionTokens = a.Response.Usage.CompletionTokens
+			call.TotalTokens = a.Response.Usage.TotalTokens
+			if call.TotalTokens == 0 {
+				call.TotalTokens = call.PromptTokens + call.CompletionTokens
+			}
+			call.CostUSD = a.Response.CostUSD
+		}
+		if err := store.CreateLLMCall(call); err != nil {
+			logger.Warnf("agentd: failed to record LLM call: %v", err)
+		}
+	}
+}
+
+// checkBudget refuses LLM requests of a job once it, or a job that started it through a
+// sub-workflow step, has used up its budget
+func checkBudget(store state.Store) func(call *llm.Call) error {
+	return func(call *llm.Call) error {
+		for jobID := call.JobID; jobID != ""; {
+			job, err := store.GetJob(jobID)
+			if err != nil {
+				return fmt.Errorf("failed to load job %s for its budget: %w", jobID, err)
+			}
+			if budget := orchestrator.JobBudget(job); budget != nil {
+				usage, err := store.AggregateLLMUsage(state.LLMUsageFilter{JobTree: job.ID}, nil)
+				if err != nil {
+					return err
+				}
+				if reason := budget.Exceeded(&usage[0].LLMUsage); reason != "" {
+					return fmt.Errorf("%w: job %s %s", llm.ErrBudgetExceeded, job.ID, reason)
+				}
+			}
+			jobID = job.ParentJobID
+		}
+		return nil
+	}
+}
+
+// jobCassettes opens the cassettes jobs were submitted with. A cassette is opened once per
+// job and shared with the jobs its sub-workflow steps start, so a replay answers repeated
+// requests in the order they were recorded. Jobs recording with the same cassette add to
+// its file, as does a job whose cassette was closed and opened again.
+type jobCassettes struct {
+	store state.Store
+	dir   string
+
+	mu    sync.Mutex
+	open  map[string]*llm.Cassette // by the job that has the cassette
+	order []string
+}
+
+func newJobCassettes(store state.Store, cfg config.CassettesConfig) *jobCassettes {
+	return &jobCassettes{store: store, dir: cfg.Dir, open: map[string]*llm.Cassette{}}
+}
+
+// get implements llm.GatewayOptions.Cassette: it returns the cassette of the call's job,
+// or of the nearest job that started it; nil when there is none
+func (c *jobCassettes) get(call *llm.Call) (*llm.Cassette, error) {
+	for jobID := call.JobID; jobID != ""; {
+		job, err := c.store.GetJob(jobID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load job %s for its cassette: %w", jobID, err)
+		}
+		if cassette := orchestrator.JobCassette(job); cassette != nil {
+			return c.openFor(job.ID, cassette)
+		}
+		jobID = job.ParentJobID
+	}
+	return nil, nil
+}
+
+// openFor returns the open cassette of a job, opening it the first time
+func (c *jobCassettes) openFor(jobID string, cassette *orchestrator.Cassette) (*llm.Cassette, error) {
+	if c.dir == "" {
+		return nil, fmt.Errorf("job %s has cassette %q but llm.cassettes.dir is not set", jobID, cassette.Name)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if tape, ok := c.open[jobID]; ok {
+		return tape, nil
+	}
+	tape, err := llm.OpenCassette(filepath.Join(c.dir, cassette.Name+".json"), cassette.Mode)
+	if err != nil {
+		return nil, fmt.Errorf("job %s: %w", jobID, err)
+	}
+	if len(c.order) == maxJobCassettes {
+		delete(c.open, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.open[jobID] = tape
+	c.order = append(c.order, jobID)
+	logger.Infof("agentd: job %s %ss LLM traffic with cassette %s", jobID, cassette.Mode, tape.Path())
+	return tape, nil
+}
+
+// checkModel returns the orchestrator's model check.
+// Only an unknown provider or a model the provider reports missing fails the check;
+// other errors (provider down, rate limited) are left for the steps themselves to run into.
+func checkModel(registry *llm.Registry) func(ctx context.Context, provider, model string) error {
+	return func(ctx context.Context, provider, model string) error {
+		p, err := registry.Get(provider)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, modelCheckTimeout)
+		defer cancel()
+
+		err = llm.CheckModel(ctx, p, model)
+		if err != nil && !errors.Is(err, llm.ErrModelNotFound) {
+			logger.Warnf("agentd: could not check model %q of provider %s: %v", model, p.Name(), err)
+			return nil
+		}
+		return err
+	}
+}
@@ -0,0 +1,423 @@
+// Package agents implements the workflow steps that talk to language models.
+package agents
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"agent-project-manager/internal/llm"
+	"agent-project-manager/internal/orchestrator"
+	"agent-project-manager/internal/prompts"
+	"agent-project-manager/internal/state"
+	"agent-project-manager/internal/tools"
+)
+
+// StepTypeLLM is the step type that sends one chat completion request
+const StepTypeLLM = "llm"
+
+// LLMStep runs llm steps through the gateway. The step's input is
+//
+//	{"system": "You review Go code.", "prompt": "Review {{ .steps.diff.output.patch }}",
+//	 "temperature": 0.2, "maxTokens": 1024}
+//
+// or, instead of system and prompt, a list of {"role", "content"} messages. With the LLM
+// response cache enabled, requests with temperature 0 are answered from it; "cache": "force"
+// caches a request whatever its temperature, "cache": "off" never uses the cache. The step's
+// provider, model and fallbacks route the request; steps without them use the route of
+// their agent. They may be templates such as "{{ .input.model }}", so the job input, e.g. a
+// matrix axis, picks the model. The output holds the answer and which provider and model
+// produced it:
+//
+//	{"content": "...", "provider": "ollama", "model": "qwen2.5-coder:7b",
+//	 "finishReason": "stop", "cached": false,
+//	 "usage": {"promptTokens": 812, "completionTokens": 95, "totalTokens": 907, "costUsd": 0}}
+//
+// With "outputSchema" (a JSON Schema) the reply must be a JSON document matching it, for
+// agents whose output other steps read, such as an architect's file plan or a review's
+// findings. Invalid replies are sent back with the violations, up to "maxRepairs" times
+// (default 2); the step fails when the reply is still invalid. The document is in the
+// output's "json", the number of repair prompts it took in "repairs".
+//
+// Instead of literal text, "template" and "systemTemplate" name prompts of the prompt
+// registry as "name@version" ("name" alone is the latest version), rendered with "vars":
+//
+//	{"systemTemplate": "reviewer@2", "template": "review@3",
+//	 "vars": {"patch": "{{ .steps.diff.output.patch }}"}}
+//
+// The versions rendered are recorded in the output's "prompts", e.g. ["reviewer@2", "review@3"],
+// and are part of the step cache key, so a cached step naming a prompt without a version
+// runs again once a new version is published.
+//
+// With "tools" the model may call tools of the tool registry before it answers:
+//
+//	{"prompt": "Why does TestParse fail?", "tools": ["read_file", "grep", "run_tests"],
+//	 "workdir": "checkouts/api", "maxIterations": 8, "maxToolTime": "2m"}
+//
+// The tools work in "workdir", relative to the tools root, and the loop stops with an error
+// when the model still calls tools after "maxIterations" replies or the calls took longer
+// than "maxToolTime" (defaults from the tools config). Every call is recorded as a
+// step.tool_call event; the output lists them in "toolCalls" and the replies it took in
+// "iterations". Tools and outputSchema cannot be combined.
+//
+// A job that has used up its budget fails the step without sending the request.
+type LLMStep struct {
+	gateway *llm.Gateway
+	tools   *tools.Registry
+	loop    llm.ToolLoop
+}
+
+// NewLLMStep creates the executor of llm steps; loop holds the default limits of steps using tools
+func NewLLMStep(gateway *llm.Gateway, toolRegistry *tools.Registry, loop llm.ToolLoop) *LLMStep {
+	return &LLMStep{gateway: gateway, tools: toolRegistry, loop: loop}
+}
+
+// Execute implements orchestrator.StepExecutor
+func (s *LLMStep) Execute(ctx context.Context, sc *orchestrator.StepContext) (*orchestrator.StepResult, error) {
+	input, rendered, err := renderTemplates(sc.Repo, sc.Step.Input)
+	if err != nil {
+		return nil, err
+	}
+	req, err := buildRequest(input)
+	if err != nil {
+		return nil, err
+	}
+
+	call := stepCall(sc)
+	if v, ok := sc.Step.Input["cache"]; ok {
+		mode, _ := v.(string)
+		if mode != llm.CacheForce && mode != llm.CacheOff {
+			return nil, fmt.Errorf("input.cache must be %q or %q", llm.CacheForce, llm.CacheOff)
+		}
+		call.Cache = mode
+	}
+
+	output := map[string]interface{}{}
+	if len(rendered) > 0 {
+		output["prompts"] = rendered
+	}
+	var resp *llm.Response
+	_, usesTools := sc.Step.Input["tools"]
+	if _, ok := sc.Step.Input["outputSchema"]; ok && usesTools {
+		return nil, fmt.Errorf("input.tools and input.outputSchema are mutually exclusive")
+	}
+	if usesTools {
+		box, loop, err := s.toolLoop(sc)
+		if err != nil {
+			return nil, err
+		}
+		tr, err := s.gateway.ChatTools(ctx, call, req, box, loop)
+		if err != nil {
+			return nil, fmt.Errorf("llm request failed: %w", err)
+		}
+		resp = tr.Response
+		calls := make([]interface{}, len(tr.Calls))
+		for i, c := range tr.Calls {
+			calls[i] = toolCallData(c)
+		}
+		output["toolCalls"] = calls
+		output["iterations"] = tr.Iterations
+	} else if raw, ok := sc.Step.Input["outputSchema"]; ok {
+		spec, err := outputSpec(sc.Def.Name, raw, sc.Step.Input["maxRepairs"])
+		if err != nil {
+			return nil, err
+		}
+		jr, err := s.gateway.ChatJSON(ctx, call, req, spec, nil)
+		if err != nil {
+			return nil, fmt.Errorf("llm request failed: %w", err)
+		}
+		resp = jr.Response
+		output["json"] = jr.Value
+		output["repairs"] = jr.Repairs
+	} else {
+		resp, err = s.gateway.Chat(ctx, call, req)
+		if err != nil {
+			return nil, fmt.Errorf("llm request failed: %w", err)
+		}
+	}
+
+	output["content"] = resp.Content
+	output["provider"] = resp.Provider
+	output["model"] = resp.Model
+	output["finishReason"] = resp.FinishReason
+	output["cached"] = resp.Cached
+	output["usage"] = map[string]interface{}{
+		"promptTokens":     resp.Usage.PromptTokens,
+		"completionTokens": resp.Usage.CompletionTokens,
+		"totalTokens":      resp.Usage.TotalTokens,
+		"costUsd":          resp.CostUSD,
+	}
+	return &orchestrator.StepResult{Output: output}, nil
+}
+
+// outputSpec reads the structured output settings of a step's input
+func outputSpec(name string, schema, maxRepairs interface{}) (llm.OutputSpec, error) {
+	spec := llm.OutputSpec{Name: name}
+	m, ok := schema.(map[string]interface{})
+	if !ok {
+		return spec, fmt.Errorf("input.outputSchema must be a JSON Schema object")
+	}
+	spec.Schema = m
+	if maxRepairs != nil {
+		n, ok := number(maxRepairs)
+		if !ok || n < 0 {
+			return spec, fmt.Errorf("input.maxRepairs must not be a negative number")
+		}
+		spec.MaxRepairs = int(n)
+		if n == 0 {
+			spec.MaxRepairs = -1 // no repairs
+		}
+	}
+	return spec, nil
+}
+
+// toolLoop binds the tools a step's input names and reads its loop limits
+func (s *LLMStep) toolLoop(sc *orchestrator.StepContext) (*tools.Toolbox, llm.ToolLoop, error) {
+	loop := s.loop
+	input := sc.Step.Input
+
+	list, ok := input["tools"].([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, loop, fmt.Errorf("input.tools must be a list of tool names")
+	}
+	names := make([]string, len(list))
+	for i, item := range list {
+		if names[i], ok = item.(string); !ok {
+			return nil, loop, fmt.Errorf("input.tools[%d] must be a tool name", i)
+		}
+	}
+	workdir, _ := input["workdir"].(string)
+	box, err := s.tools.Bind(workdir, names)
+	if err != nil {
+		return nil, loop, fmt.Errorf("input.tools: %w", err)
+	}
+
+	if v, ok := input["maxIterations"]; ok {
+		n, ok := number(v)
+		if !ok || n < 1 {
+			return nil, loop, fmt.Errorf("input.maxIterations must be a positive number")
+		}
+		loop.MaxIterations = int(n)
+	}
+	if v, ok := input["maxToolTime"]; ok {
+		str, _ := v.(string)
+		d, err := time.ParseDuration(str)
+		if err != nil || d <= 0 {
+			return nil, loop, fmt.Errorf("input.maxToolTime must be a positive duration such as \"2m\"")
+		}
+		loop.MaxToolTime = d
+	}
+	loop.OnToolCall = func(rec llm.ToolCallRecord) {
+		if sc.Emit == nil {
+			return
+		}
+		data := toolCallData(rec)
+		data["step"] = sc.Def.Name
+		sc.Emit(orchestrator.EventStepToolCall, rec.Name, data)
+	}
+	return box, loop, nil
+}
+
+// toolCallData describes a tool call for the step output and its event
+func toolCallData(rec llm.ToolCallRecord) map[string]interface{} {
+	data := map[string]interface{}{
+		"tool":       rec.Name,
+		"arguments":  rec.Arguments,
+		"iteration":  rec.Iteration,
+		"durationMs": rec.Duration.Milliseconds(),
+	}
+	if rec.Error != "" {
+		data["error"] = rec.Error
+	}
+	return data
+}
+
+// EstimateLLMCalls implements orchestrator.LLMCallEstimator: the most requests the step may
+// send, which is every reply of its tool loop, or the first reply and every repair prompt
+func (s *LLMStep) EstimateLLMCalls(sd orchestrator.StepDef, input map[string]interface{}) int {
+	if _, ok := input["tools"]; ok {
+		n := s.loop.MaxIterations
+		if v, ok := number(input["maxIterations"]); ok && v >= 1 {
+			n = int(v)
+		}
+		if n <= 0 {
+			n = llm.DefaultMaxIterations
+		}
+		return n
+	}
+	if _, ok := input["outputSchema"]; ok {
+		repairs := llm.DefaultMaxRepairs
+		if v, ok := number(input["maxRepairs"]); ok && v >= 0 {
+			repairs = int(v)
+		}
+		return 1 + repairs
+	}
+	return 1
+}
+
+// CacheKeyMaterial implements orchestrator.CacheKeyer: a template named without a version
+// renders the latest one, so the versions the templates resolve to are part of the key
+func (s *LLMStep) CacheKeyMaterial(repo state.Repository, sd orchestrator.StepDef, input map[string]interface{}) (interface{}, error) {
+	var versions []string
+	for _, key := range []string{"systemTemplate", "template"} {
+		raw, ok := input[key]
+		if !ok {
+			continue
+		}
+		str, _ := raw.(string)
+		ref, err := prompts.ParseRef(str)
+		if err != nil {
+			return nil, fmt.Errorf("input.%s: %w", key, err)
+		}
+		pv, err := prompts.Resolve(repo, ref)
+		if err != nil {
+			return nil, fmt.Errorf("input.%s: %w", key, err)
+		}
+		versions = append(versions, prompts.Ref{Name: pv.Prompt, Version: pv.Version}.String())
+	}
+	if len(versions) == 0 {
+		return nil, nil
+	}
+	return versions, nil
+}
+
+// stepCall describes the step to the gateway
+func stepCall(sc *orchestrator.StepContext) *llm.Call {
+	call := &llm.Call{JobID: sc.Job.ID, StepID: sc.Step.ID, Agent: sc.Def.Agent}
+	for _, m := range sc.Def.Models() {
+		call.Route = append(call.Route, llm.Target{Provider: m.Provider, Model: m.Model})
+	}
+	return call
+}
+
+// renderTemplates renders the registry prompts a step's input names into its system and
+// prompt, returning the input to build the request from and the versions rendered.
+// Each template gets the vars it declares; a var neither declares is an error.
+func renderTemplates(repo state.Repository, input map[string]interface{}) (map[string]interface{}, []string, error) {
+	if input["template"] == nil && input["systemTemplate"] == nil {
+		return input, nil, nil
+	}
+	vars := map[string]interface{}{}
+	if raw, ok := input["vars"]; ok {
+		if vars, ok = raw.(map[string]interface{}); !ok {
+			return nil, nil, fmt.Errorf("input.vars must be an object")
+		}
+	}
+
+	type promptInput struct {
+		key, target string
+		version     *state.PromptVersion
+	}
+	var templates []promptInput
+	declared := map[string]bool{}
+	for _, f := range []promptInput{{key: "systemTemplate", target: "system"}, {key: "template", target: "prompt"}} {
+		raw, ok := input[f.key]
+		if !ok {
+			continue
+		}
+		if _, ok := input[f.target]; ok {
+			return nil, nil, fmt.Errorf("input.%s and input.%s are mutually exclusive", f.key, f.target)
+		}
+		s, _ := raw.(string)
+		ref, err := prompts.ParseRef(s)
+		if err != nil {
+			return nil, nil, fmt.Errorf("input.%s: %w", f.key, err)
+		}
+		if f.version, err = prompts.Resolve(repo, ref); err != nil {
+			return nil, nil, fmt.Errorf("input.%s: %w", f.key, err)
+		}
+		for _, v := range f.version.Variables {
+			declared[v.Name] = true
+		}
+		templates = append(templates, f)
+	}
+	for name := range vars {
+		if !declared[name] {
+			return nil, nil, fmt.Errorf("input.vars.%s is not a variable of the prompts used", name)
+		}
+	}
+
+	rendered := map[string]interface{}{}
+	for k, v := range input {
+		rendered[k] = v
+	}
+	versions := make([]string, len(templates))
+	for i, t := range templates {
+		own := map[string]interface{}{}
+		for _, v := range t.version.Variables {
+			if value, ok := vars[v.Name]; ok {
+				own[v.Name] = value
+			}
+		}
+		text, err := prompts.Render(t.version, own)
+		if err != nil {
+			return nil, nil, fmt.Errorf("input.%s: %w", t.key, err)
+		}
+		rendered[t.target] = text
+		versions[i] = prompts.Ref{Name: t.version.Prompt, Version: t.version.Version}.String()
+	}
+	return rendered, versions, nil
+}
+
+// buildRequest reads a chat request from a step's input
+func buildRequest(input map[string]interface{}) (*llm.Request, error) {
+	req := &llm.Request{}
+
+	if raw, ok := input["messages"]; ok {
+		list, ok := raw.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("input.messages must be a list of {role, content} objects")
+		}
+		for i, item := range list {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("input.messages[%d] must be a {role, content} object", i)
+			}
+			role, _ := m["role"].(string)
+			content, _ := m["content"].(string)
+			if role == "" {
+				return nil, fmt.Errorf("input.messages[%d].role is required", i)
+			}
+			req.Messages = append(req.Messages, llm.Message{Role: role, Content: content})
+		}
+	}
+	if system, _ := input["system"].(string); system != "" {
+		req.Messages = append([]llm.Message{{Role: llm.RoleSystem, Content: system}}, req.Messages...)
+	}
+	if prompt, _ := input["prompt"].(string); prompt != "" {
+		req.Messages = append(req.Messages, llm.Message{Role: llm.RoleUser, Content: prompt})
+	}
+	if len(req.Messages) == 0 {
+		return nil, fmt.Errorf("input.prompt or input.messages is required")
+	}
+
+	if v, ok := input["temperature"]; ok {
+		t, ok := number(v)
+		if !ok {
+			return nil, fmt.Errorf("input.temperature must be a number")
+		}
+		req.Temperature = &t
+	}
+	if v, ok := input["maxTokens"]; ok {
+		n, ok := number(v)
+		if !ok || n < 0 {
+			return nil, fmt.Errorf("input.maxTokens must be a positive number")
+		}
+		req.MaxTokens = int(n)
+	}
+	return req, nil
+}
+
+// number reads a JSON number, which may arrive as float64 or, from YAML or Go callers, as int
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
@@ -19,7 +19,7 @@ import (
 // @Param        runId   query     string  false  "Filter by run ID"
 // @Param        limit   query     int     false  "Maximum number of artifacts to return"
 // @Param        cursor  query     string  false  "Cursor for pagination"
-// @Param        type    query     string  false  "Filter by artifact type (pdf|pptx|zip|log)"
+// @Param        type    query     string  false  "Filter by artifact type (pdf|pptx|zip|log|markdown)"
 // @Success      200     {object}  ArtifactListResponse
 // @Router       /artifacts [get]
 func handleListArtifacts(repo repository.IArtifactRepository) http.HandlerFunc {
@@ -56,6 +56,7 @@ func handleListArtifacts(repo repository.IArtifactRepository) http.HandlerFunc {
 				Name:      sa.Name,
 				Size:      sa.Size,
 				Path:      sa.Path,
+				Digest:    sa.Digest,
 				CreatedAt: sa.CreatedAt,
 			}
 		}
@@ -103,6 +104,7 @@ func handleGetArtifact(repo repository.IArtifactRepository) http.HandlerFunc {
 			Name:      sa.Name,
 			Size:      sa.Size,
 			Path:      sa.Path,
+			Digest:    sa.Digest,
 			CreatedAt: sa.CreatedAt,
 		}
 
@@ -2,25 +2,33 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"agent-project-manager/internal/orchestrator"
 	"agent-project-manager/internal/repository"
 	"agent-project-manager/internal/state"
 )
 
 // handleCreateJob handles POST /jobs
 // @Summary      Create a new job
-// @Description  Submit a new job to be processed
+// @Description  Submit a new job to be processed. The workflow may be given as name@version;
+// @Description  otherwise the job is pinned to the latest published version.
+// @Description  The input is validated against the version's inputSchema and schema defaults are applied.
+// @Description  A budget caps the job's LLM tokens or cost; once it is used up, further LLM requests fail.
+// @Description  A cassette records the job's LLM traffic to a file, or replays it from one without calling any provider.
 // @Tags         jobs
 // @Accept       json
 // @Produce      json
 // @Param        job  body      CreateJobRequest  true  "Job creation request"
 // @Success      201  {object}  CreateJobResponse
-// @Failure      400  {string}  string  "Invalid request body"
+// @Failure      400  {object}  InvalidInputResponse  "Input violates the inputSchema; a malformed body or unknown workflow version returns plain text"
+// @Failure      500  {string}  string  "Failed to resolve the workflow version or create the job"
 // @Router       /jobs [post]
-func handleCreateJob(repo repository.IJobRepository) http.HandlerFunc {
+func handleCreateJob(repo repository.IJobRepository, workflowRepo repository.IWorkflowRepository, versionRepo repository.IWorkflowVersionRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req CreateJobRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -28,21 +36,79 @@ func handleCreateJob(repo repository.IJobRepository) http.HandlerFunc {
 			return
 		}
 	
+	// Pin the job to a published workflow version
+	wv, err := orchestrator.ResolveWorkflowVersion(workflowLookup{workflowRepo, versionRepo}, req.Workflow)
+	if err != nil {
+		if errors.Is(err, orchestrator.ErrWorkflowNotFound) {
+			http.Error(w, "Unknown workflow version: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to resolve workflow version: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Check the input against the version's inputSchema and fill in defaults
+	input, verrs, err := orchestrator.ValidateInput(wv.Schema, req.Input)
+	if err != nil {
+		http.Error(w, "Invalid workflow definition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(verrs) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(InvalidInputResponse{
+			Error:  "Job input does not match the workflow's inputSchema",
+			Errors: toInputErrors(verrs),
+		})
+		return
+	}
+
+	meta := state.JSONMap(req.Meta)
+	if meta == nil && (req.NoCache || req.Budget != nil || req.Cassette != nil) {
+		meta = state.JSONMap{}
+	}
+	if req.NoCache {
+		meta["noCache"] = true
+	}
+	if req.Budget != nil {
+		budget := toBudget(req.Budget)
+		if err := budget.Validate(); err != nil {
+			http.Error(w, "Invalid budget: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		meta["budget"] = budget.Meta()
+	}
+	if req.Cassette != nil {
+		cassette := &orchestrator.Cassette{Mode: req.Cassette.Mode, Name: req.Cassette.Name}
+		if err := cassette.Validate(); err != nil {
+			http.Error(w, "Invalid cassette: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		meta["cassette"] = cassette.Meta()
+	}
+
 	// Convert API model to state model
 	job := &state.Job{
-		Workflow: req.Workflow,
-		Status:   string(JobStatusQueued),
-		Input:    state.JSONMap(req.Input),
-		Meta:     state.JSONMap(req.Meta),
+		Workflow:        wv.Workflow,
+		WorkflowVersion: wv.Version,
+		Status:          string(JobStatusQueued),
+		Input:           state.JSONMap(input),
+		Meta:            meta,
 	}
 
-	if err := repo.CreateJob(job); err != nil {
+	// Create the job and hand it to the orchestrator's workers
+	if err := repo.EnqueueJob(job); err != nil {
+		if errors.Is(err, state.ErrNotFound) { // deleted since it was resolved
+			http.Error(w, "Unknown workflow version: "+err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to create job: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	response := CreateJobResponse{
-		ID: job.ID,
+		ID:              job.ID,
+		WorkflowVersion: job.WorkflowVersion,
 	}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -90,16 +156,21 @@ func handleListJobs(repo repository.IJobRepository) http.HandlerFunc {
 		for i, sj := range stateJobs {
 			status, _ := JobStatusFromString(sj.Status)
 			jobs[i] = Job{
-				ID:          sj.ID,
-				Workflow:    sj.Workflow,
-				Status:      status,
-				Input:       map[string]interface{}(sj.Input),
-				Meta:        map[string]interface{}(sj.Meta),
-				CreatedAt:   sj.CreatedAt,
-				UpdatedAt:   sj.UpdatedAt,
-				StartedAt:   sj.StartedAt,
-				CompletedAt: sj.CompletedAt,
-				Error:       sj.Error,
+				ID:              sj.ID,
+				Workflow:        sj.Workflow,
+				Status:          status,
+				Input:           map[string]interface{}(sj.Input),
+				Meta:            map[string]interface{}(sj.Meta),
+				CreatedAt:       sj.CreatedAt,
+				UpdatedAt:       sj.UpdatedAt,
+				StartedAt:       sj.StartedAt,
+				CompletedAt:     sj.CompletedAt,
+				Error:           sj.Error,
+				ParentJobID:     sj.ParentJobID,
+				WorkflowVersion: sj.WorkflowVersion,
+				RerunOfJobID:    sj.RerunOfJobID,
+				RerunFromStep:   sj.RerunFromStep,
+				MatrixID:        sj.MatrixID,
 			}
 		}
 
@@ -124,6 +195,7 @@ func handleListJobs(repo repository.IJobRepository) http.HandlerFunc {
 // @Param        jobId   path      string  true  "Job ID"
 // @Success      200     {object}  Job
 // @Failure      404     {string}  string  "Job not found"
+// @Failure      500     {string}  string  "Failed to list child jobs"
 // @Router       /jobs/{jobId} [get]
 func handleGetJob(repo repository.IJobRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -139,16 +211,37 @@ func handleGetJob(repo repository.IJobRepository) http.HandlerFunc {
 		// Convert state model to API model
 		status, _ := JobStatusFromString(sj.Status)
 		job := Job{
-			ID:          sj.ID,
-			Workflow:    sj.Workflow,
-			Status:      status,
-			Input:       map[string]interface{}(sj.Input),
-			Meta:        map[string]interface{}(sj.Meta),
-			CreatedAt:   sj.CreatedAt,
-			UpdatedAt:   sj.UpdatedAt,
-			StartedAt:   sj.StartedAt,
-			CompletedAt: sj.CompletedAt,
-			Error:       sj.Error,
+			ID:              sj.ID,
+			Workflow:        sj.Workflow,
+			Status:          status,
+			Input:           map[string]interface{}(sj.Input),
+			Meta:            map[string]interface{}(sj.Meta),
+			CreatedAt:       sj.CreatedAt,
+			UpdatedAt:       sj.UpdatedAt,
+			StartedAt:       sj.StartedAt,
+			CompletedAt:     sj.CompletedAt,
+			Error:           sj.Error,
+			ParentJobID:     sj.ParentJobID,
+			WorkflowVersion: sj.WorkflowVersion,
+			RerunOfJobID:    sj.RerunOfJobID,
+			RerunFromStep:   sj.RerunFromStep,
+			MatrixID:        sj.MatrixID,
+		}
+
+		// Include jobs started by this job's sub-workflow steps
+		children, err := repo.ListChildJobs(jobID)
+		if err != nil {
+			http.Error(w, "Failed to list child jobs: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, c := range children {
+			childStatus, _ := JobStatusFromString(c.Status)
+			job.Children = append(job.Children, JobChild{
+				ID:           c.ID,
+				Workflow:     c.Workflow,
+				Status:       childStatus,
+				ParentStepID: c.ParentStepID,
+			})
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -159,28 +252,73 @@ func handleGetJob(repo repository.IJobRepository) http.HandlerFunc {
 
 // handleDeleteJob handles DELETE /jobs/{jobId}
 // @Summary      Cancel a job
-// @Description  Cancel a running or queued job
+// @Description  Cancel a running, queued or waiting job together with its child jobs.
+// @Description  Completed steps that declare a compensation are undone before the job is marked cancelled.
 // @Tags         jobs
 // @Accept       json
 // @Produce      json
 // @Param        jobId   path      string  true  "Job ID"
 // @Success      202     {string}  string  "Accepted"
 // @Failure      404     {string}  string  "Job not found"
+// @Failure      409     {string}  string  "Job already finished or compensating"
 // @Router       /jobs/{jobId} [delete]
-func handleDeleteJob(repo repository.IJobRepository) http.HandlerFunc {
+func handleDeleteJob(orch *orchestrator.Orchestrator) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		jobID := chi.URLParam(r, "jobId")
 
-	// Delete job from repository
-	if err := repo.DeleteJob(jobID); err != nil {
-		http.Error(w, "Job not found", http.StatusNotFound)
-		return
-	}
+		if err := orch.CancelJob(jobID); err != nil {
+			switch {
+			case errors.Is(err, orchestrator.ErrJobNotFound):
+				http.Error(w, "Job not found", http.StatusNotFound)
+			case errors.Is(err, orchestrator.ErrJobFinished):
+				http.Error(w, "Job already finished", http.StatusConflict)
+			default:
+				http.Error(w, "Failed to cancel job: "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
 
 		w.WriteHeader(http.StatusAccepted)
 	}
 }
 
+// handleJobGraph handles GET /jobs/{jobId}/graph
+// @Summary      Render a job's step graph
+// @Description  Render the step DAG of the workflow version a job is pinned to, annotated with each
+// @Description  step's live status and duration, as a Mermaid flowchart, Graphviz DOT or JSON
+// @Tags         jobs
+// @Produce      json
+// @Produce      plain
+// @Param        jobId   path      string  true   "Job ID"
+// @Param        format  query     string  false  "Output format"  Enums(mermaid, dot, json)  default(json)
+// @Success      200     {object}  WorkflowGraphResponse
+// @Failure      400     {string}  string  "Invalid format"
+// @Failure      404     {string}  string  "Job not found"
+// @Router       /jobs/{jobId}/graph [get]
+func handleJobGraph(orch *orchestrator.Orchestrator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobID := chi.URLParam(r, "jobId")
+
+		format, ok := parseGraphFormat(r)
+		if !ok {
+			http.Error(w, "Invalid format: must be mermaid, dot or json", http.StatusBadRequest)
+			return
+		}
+
+		graph, err := orch.JobGraph(jobID)
+		if err != nil {
+			if errors.Is(err, orchestrator.ErrJobNotFound) {
+				http.Error(w, "Job not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to build graph: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeGraph(w, graph, format)
+	}
+}
+
 // handleRetryJob handles POST /jobs/{jobId}/retry
 // @Summary      Retry a job
 // @Description  Retry a failed or cancelled job
@@ -207,6 +345,75 @@ func handleRetryJob(repo repository.IJobRepository) http.HandlerFunc {
 	}
 }
 
+// handleRerunJob handles POST /jobs/{jobId}/rerun
+// @Summary      Rerun a job from a step
+// @Description  Create a new job that repeats a finished job from the named step onwards. The results
+// @Description  and artifacts of the steps before it are copied from the original job, so only fromStep
+// @Description  and the steps after it run again. input overrides top-level keys of the original input.
+// @Description  The rerun is recorded as a new run (runId) of the new job whose status follows the job's;
+// @Description  its params hold the lineage: rerunOf (the run of the original job, when it has one),
+// @Description  rerunOfJobId, fromStep and the input overrides.
+// @Tags         jobs
+// @Accept       json
+// @Produce      json
+// @Param        jobId  path      string          true  "Job ID"
+// @Param        rerun  body      RerunJobRequest  true  "Step to rerun from and input overrides"
+// @Success      201    {object}  RerunJobResponse
+// @Failure      400    {object}  InvalidInputResponse  "Invalid request, unknown step or invalid input"
+// @Failure      404    {string}  string  "Job not found"
+// @Failure      409    {string}  string  "Job has not finished"
+// @Router       /jobs/{jobId}/rerun [post]
+func handleRerunJob(orch *orchestrator.Orchestrator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobID := chi.URLParam(r, "jobId")
+
+		var req RerunJobRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.FromStep == "" {
+			http.Error(w, "fromStep is required", http.StatusBadRequest)
+			return
+		}
+
+		rerun, err := orch.RerunJob(jobID, req.FromStep, req.Input)
+		if err != nil {
+			var invalid *orchestrator.InvalidInputError
+			switch {
+			case errors.As(err, &invalid):
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(InvalidInputResponse{
+					Error:  "Job input does not match the workflow's inputSchema",
+					Errors: toInputErrors(invalid.Errors),
+				})
+			case errors.Is(err, orchestrator.ErrJobNotFound):
+				http.Error(w, "Job not found", http.StatusNotFound)
+			case errors.Is(err, orchestrator.ErrJobNotFinished):
+				http.Error(w, "Job has not finished", http.StatusConflict)
+			case errors.Is(err, orchestrator.ErrUnknownStep):
+				http.Error(w, "Unknown step: "+req.FromStep, http.StatusBadRequest)
+			default:
+				http.Error(w, "Failed to rerun job: "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		response := RerunJobResponse{
+			ID:          rerun.Job.ID,
+			RunID:       rerun.Run.ID,
+			RerunOf:     jobID,
+			FromStep:    req.FromStep,
+			CopiedSteps: rerun.Copied,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
 // handleJobEvents handles GET /jobs/{jobId}/events
 // @Summary      Stream job events
 // @Description  Stream job status updates via Server-Sent Events (SSE)
@@ -295,6 +502,9 @@ func handleJobSteps(repo repository.IStepRepository) http.HandlerFunc {
 				StartedAt:   ss.StartedAt,
 				CompletedAt: ss.CompletedAt,
 				Error:       ss.Error,
+				Cached:      ss.Cached,
+				CacheKey:    ss.CacheKey,
+				ArtifactIDs: ss.ArtifactIDs,
 			}
 		}
 
@@ -340,6 +550,99 @@ func handleGetStep(repo repository.IStepRepository) http.HandlerFunc {
 			StartedAt:   ss.StartedAt,
 			CompletedAt: ss.CompletedAt,
 			Error:       ss.Error,
+			Cached:      ss.Cached,
+			CacheKey:    ss.CacheKey,
+			ArtifactIDs: ss.ArtifactIDs,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(step)
+	}
+}
+
+// handleApproveStep handles POST /jobs/{jobId}/steps/{stepId}/approve
+// @Summary      Approve a step
+// @Description  Approve an approval step that is waiting for sign-off and resume its job
+// @Tags         jobs
+// @Accept       json
+// @Produce      json
+// @Param        jobId     path      string               true  "Job ID"
+// @Param        stepId    path      string               true  "Step ID"
+// @Param        decision  body      StepDecisionRequest  true  "Approver and optional comment"
+// @Success      200       {object}  JobStep
+// @Failure      400       {string}  string  "Invalid request"
+// @Failure      404       {string}  string  "Step not found"
+// @Failure      409       {string}  string  "Step is not awaiting approval"
+// @Router       /jobs/{jobId}/steps/{stepId}/approve [post]
+func handleApproveStep(orch *orchestrator.Orchestrator) http.HandlerFunc {
+	return handleStepDecision(orch, orchestrator.DecisionApproved)
+}
+
+// handleRejectStep handles POST /jobs/{jobId}/steps/{stepId}/reject
+// @Summary      Reject a step
+// @Description  Reject an approval step that is waiting for sign-off, failing its job
+// @Tags         jobs
+// @Accept       json
+// @Produce      json
+// @Param        jobId     path      string               true  "Job ID"
+// @Param        stepId    path      string               true  "Step ID"
+// @Param        decision  body      StepDecisionRequest  true  "Approver and optional comment"
+// @Success      200       {object}  JobStep
+// @Failure      400       {string}  string  "Invalid request"
+// @Failure      404       {string}  string  "Step not found"
+// @Failure      409       {string}  string  "Step is not awaiting approval"
+// @Router       /jobs/{jobId}/steps/{stepId}/reject [post]
+func handleRejectStep(orch *orchestrator.Orchestrator) http.HandlerFunc {
+	return handleStepDecision(orch, orchestrator.DecisionRejected)
+}
+
+// handleStepDecision records an approval decision for a step
+func handleStepDecision(orch *orchestrator.Orchestrator, decision string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobID := chi.URLParam(r, "jobId")
+		stepID := chi.URLParam(r, "stepId")
+
+		var req StepDecisionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Approver == "" {
+			http.Error(w, "approver is required", http.StatusBadRequest)
+			return
+		}
+
+		ss, err := orch.DecideApproval(jobID, stepID, decision, req.Approver, req.Comment)
+		if err != nil {
+			switch {
+			case errors.Is(err, orchestrator.ErrStepNotFound):
+				http.Error(w, "Step not found", http.StatusNotFound)
+			case errors.Is(err, orchestrator.ErrNotAwaitingApproval):
+				http.Error(w, "Step is not awaiting approval", http.StatusConflict)
+			default:
+				http.Error(w, "Failed to record decision: "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		// Convert state model to API model
+		status, _ := StepStatusFromString(ss.Status)
+		step := JobStep{
+			ID:          ss.ID,
+			JobID:       ss.JobID,
+			Name:        ss.Name,
+			Status:      status,
+			Input:       map[string]interface{}(ss.Input),
+			Output:      map[string]interface{}(ss.Output),
+			CreatedAt:   ss.CreatedAt,
+			UpdatedAt:   ss.UpdatedAt,
+			StartedAt:   ss.StartedAt,
+			CompletedAt: ss.CompletedAt,
+			Error:       ss.Error,
+			Cached:      ss.Cached,
+			CacheKey:    ss.CacheKey,
+			ArtifactIDs: ss.ArtifactIDs,
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -348,6 +651,63 @@ func handleGetStep(repo repository.IStepRepository) http.HandlerFunc {
 	}
 }
 
+// handleSendSignal handles POST /jobs/{jobId}/signals/{name}
+// @Summary      Send a signal to a job
+// @Description  Deliver an external signal, such as "CI passed", to a job. The JSON payload becomes the
+// @Description  output of the wait_for_signal step waiting for the signal. A signal that arrives before
+// @Description  the step starts is buffered until the step consumes it.
+// @Tags         jobs
+// @Accept       json
+// @Produce      json
+// @Param        jobId    path      string                  true   "Job ID"
+// @Param        name     path      string                  true   "Signal name"
+// @Param        payload  body      map[string]interface{}  false  "Signal payload"
+// @Success      202      {object}  SignalResponse
+// @Failure      400      {string}  string  "Invalid request body"
+// @Failure      404      {string}  string  "Job not found"
+// @Failure      409      {string}  string  "Job already finished"
+// @Router       /jobs/{jobId}/signals/{name} [post]
+func handleSendSignal(orch *orchestrator.Orchestrator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobID := chi.URLParam(r, "jobId")
+		name := chi.URLParam(r, "name")
+
+		payload := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "Invalid request body: payload must be a JSON object", http.StatusBadRequest)
+			return
+		}
+
+		signal, err := orch.SendSignal(jobID, name, payload)
+		if err != nil {
+			switch {
+			case errors.Is(err, orchestrator.ErrJobNotFound):
+				http.Error(w, "Job not found", http.StatusNotFound)
+			case errors.Is(err, orchestrator.ErrJobFinished):
+				http.Error(w, "Job already finished", http.StatusConflict)
+			default:
+				http.Error(w, "Failed to send signal: "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		response := SignalResponse{
+			ID:         signal.ID,
+			JobID:      signal.JobID,
+			Name:       signal.Name,
+			Payload:    map[string]interface{}(signal.Payload),
+			Delivered:  signal.StepID != "",
+			StepID:     signal.StepID,
+			CreatedAt:  signal.CreatedAt,
+			ConsumedAt: signal.ConsumedAt,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
 // handleStepLogs handles GET /jobs/{jobId}/steps/{stepId}/logs
 // @Summary      Get step logs
 // @Description  Get logs for a specific workflow step
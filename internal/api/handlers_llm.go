@@ -0,0 +1,266 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"agent-project-manager/internal/orchestrator"
+	"agent-project-manager/internal/repository"
+	"agent-project-manager/internal/state"
+)
+
+// handleJobLLMCalls handles GET /jobs/{jobId}/llm-calls
+// @Summary      List a job's LLM calls
+// @Description  Every attempt the job's steps made to get an answer from an LLM provider, oldest first.
+// @Description  When a provider failed or its circuit breaker was open the request fell back to the next
+// @Description  provider of its route; the succeeded attempt tells which provider and model produced the output.
+// @Tags         jobs
+// @Produce      json
+// @Param        jobId   path      string  true   "Job ID"
+// @Param        stepId  query     string  false  "Only the calls of this step"
+// @Param        limit   query     int     false  "Maximum number of calls to return"
+// @Success      200     {object}  LLMCallListResponse
+// @Failure      404     {string}  string  "Job not found"
+// @Router       /jobs/{jobId}/llm-calls [get]
+func handleJobLLMCalls(jobRepo repository.IJobRepository, llmCallRepo repository.ILLMCallRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobID := chi.URLParam(r, "jobId")
+		if _, err := jobRepo.GetJob(jobID); err != nil {
+			http.Error(w, "Job not found", http.StatusNotFound)
+			return
+		}
+
+		limit := 500 // default
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+				limit = parsed
+			}
+		}
+
+		filter := state.LLMCallFilter{JobID: jobID, StepID: r.URL.Query().Get("stepId")}
+		calls, err := llmCallRepo.ListLLMCalls(filter, limit)
+		if err != nil {
+			http.Error(w, "Failed to list LLM calls: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := LLMCallListResponse{Calls: make([]LLMCall, len(calls))}
+		for i, c := range calls {
+			response.Calls[i] = toLLMCall(c)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// toLLMCall converts a state LLM call to its API model
+func toLLMCall(c *state.LLMCall) LLMCall {
+	return LLMCall{
+		ID:        c.ID,
+		RequestID: c.RequestID,
+		JobID:     c.JobID,
+		StepID:    c.StepID,
+		Agent:     c.Agent,
+		Attempt:   c.Attempt,
+		Provider:  c.Provider,
+		Model:     c.Model,
+		Status:    c.Status,
+		ErrorKind: c.ErrorKind,
+		Error:     c.Error,
+		LatencyMs: c.LatencyMs,
+		CreatedAt: c.CreatedAt,
+
+		PromptTokens:     c.PromptTokens,
+		CompletionTokens: c.CompletionTokens,
+		TotalTokens:      c.TotalTokens,
+		CostUSD:          c.CostUSD,
+	}
+}
+
+// handleJobUsage handles GET /jobs/{jobId}/usage
+// @Summary      Get a job's LLM usage
+// @Description  Tokens, cost and latency of the job's LLM calls, per step, per model and in total.
+// @Description  Jobs started by the job's sub-workflow steps are included, as they count against its budget.
+// @Tags         jobs
+// @Produce      json
+// @Param        jobId  path      string  true  "Job ID"
+// @Success      200    {object}  JobUsageResponse
+// @Failure      404    {string}  string  "Job not found"
+// @Router       /jobs/{jobId}/usage [get]
+func handleJobUsage(jobRepo repository.IJobRepository, llmCallRepo repository.ILLMCallRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := jobRepo.GetJob(chi.URLParam(r, "jobId"))
+		if err != nil {
+			http.Error(w, "Job not found", http.StatusNotFound)
+			return
+		}
+
+		filter := state.LLMUsageFilter{JobTree: job.ID}
+		total, err := llmCallRepo.AggregateLLMUsage(filter, nil)
+		if err != nil {
+			http.Error(w, "Failed to get LLM usage: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		steps, err := llmCallRepo.AggregateLLMUsage(filter, []string{state.LLMUsageByJob, state.LLMUsageByStep})
+		if err != nil {
+			http.Error(w, "Failed to get LLM usage: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		models, err := llmCallRepo.AggregateLLMUsage(filter, []string{state.LLMUsageByProvider, state.LLMUsageByModel})
+		if err != nil {
+			http.Error(w, "Failed to get LLM usage: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := JobUsageResponse{
+			JobID:    job.ID,
+			Workflow: job.Workflow,
+			Total:    toLLMUsage(&total[0].LLMUsage),
+			Steps:    make([]StepUsage, len(steps)),
+			Models:   make([]ModelUsage, len(models)),
+		}
+		if budget := orchestrator.JobBudget(job); budget != nil {
+			response.Budget = &JobBudget{Tokens: budget.Tokens, CostUSD: budget.CostUSD}
+			response.BudgetExceeded = budget.Exceeded(&total[0].LLMUsage)
+		}
+		for i, g := range steps {
+			response.Steps[i] = StepUsage{JobID: g.JobID, StepID: g.StepID, Step: g.StepName, Usage: toLLMUsage(&g.LLMUsage)}
+		}
+		for i, g := range models {
+			response.Models[i] = ModelUsage{Provider: g.Provider, Model: g.Model, Usage: toLLMUsage(&g.LLMUsage)}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// handleUsage handles GET /usage
+// @Summary      Get aggregated LLM usage
+// @Description  Tokens, cost and latency of LLM calls across jobs, grouped by any of workflow, agent,
+// @Description  provider, model, day and job (comma-separated; default workflow).
+// @Tags         usage
+// @Produce      json
+// @Param        groupBy   query     string  false  "Grouping fields, e.g. workflow,model"
+// @Param        workflow  query     string  false  "Only calls of this workflow's jobs"
+// @Param        provider  query     string  false  "Only calls to this provider"
+// @Param        since     query     string  false  "RFC3339 timestamp; only calls made at or after it"
+// @Param        until     query     string  false  "RFC3339 timestamp; only calls made before it"
+// @Success      200       {object}  UsageResponse
+// @Failure      400       {string}  string  "Invalid grouping or timestamp"
+// @Router       /usage [get]
+func handleUsage(llmCallRepo repository.ILLMCallRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeUsage(w, r, llmCallRepo, state.LLMUsageFilter{
+			Workflow: r.URL.Query().Get("workflow"),
+			Provider: r.URL.Query().Get("provider"),
+		}, state.LLMUsageByWorkflow)
+	}
+}
+
+// handleWorkflowUsage handles GET /workflows/{name}/usage
+// @Summary      Get a workflow's LLM usage
+// @Description  Tokens, cost and latency of the LLM calls of the workflow's jobs, grouped by any of agent,
+// @Description  provider, model, day and job (comma-separated; default day).
+// @Tags         workflows
+// @Produce      json
+// @Param        name      path      string  true   "Workflow name"
+// @Param        groupBy   query     string  false  "Grouping fields, e.g. day,model"
+// @Param        provider  query     string  false  "Only calls to this provider"
+// @Param        since     query     string  false  "RFC3339 timestamp; only calls made at or after it"
+// @Param        until     query     string  false  "RFC3339 timestamp; only calls made before it"
+// @Success      200       {object}  UsageResponse
+// @Failure      400       {string}  string  "Invalid grouping or timestamp"
+// @Router       /workflows/{name}/usage [get]
+func handleWorkflowUsage(llmCallRepo repository.ILLMCallRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeUsage(w, r, llmCallRepo, state.LLMUsageFilter{
+			Workflow: chi.URLParam(r, "name"),
+			Provider: r.URL.Query().Get("provider"),
+		}, state.LLMUsageByDay)
+	}
+}
+
+// writeUsage answers a usage query, reading groupBy, since and until from the request
+func writeUsage(w http.ResponseWriter, r *http.Request, llmCallRepo repository.ILLMCallRepository,
+	filter state.LLMUsageFilter, defaultGroupBy string) {
+	groupBy := []string{defaultGroupBy}
+	if v := r.URL.Query().Get("groupBy"); v != "" {
+		groupBy = strings.Split(v, ",")
+	}
+	for _, field := range groupBy {
+		switch field {
+		case state.LLMUsageByWorkflow, state.LLMUsageByAgent, state.LLMUsageByProvider,
+			state.LLMUsageByModel, state.LLMUsageByDay, state.LLMUsageByJob:
+		default:
+			http.Error(w, "Invalid groupBy field: "+field, http.StatusBadRequest)
+			return
+		}
+	}
+	for name, dest := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := r.URL.Query().Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "Invalid "+name+": "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			*dest = &t
+		}
+	}
+
+	groups, err := llmCallRepo.AggregateLLMUsage(filter, groupBy)
+	if err != nil {
+		http.Error(w, "Failed to get LLM usage: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := UsageResponse{GroupBy: groupBy, Groups: make([]UsageGroup, len(groups))}
+	var total state.LLMUsage
+	for i, g := range groups {
+		response.Groups[i] = UsageGroup{
+			Workflow: g.Workflow,
+			Agent:    g.Agent,
+			Provider: g.Provider,
+			Model:    g.Model,
+			Day:      g.Day,
+			JobID:    g.JobID,
+			Usage:    toLLMUsage(&g.LLMUsage),
+		}
+		total.Calls += g.Calls
+		total.PromptTokens += g.PromptTokens
+		total.CompletionTokens += g.CompletionTokens
+		total.TotalTokens += g.TotalTokens
+		total.CostUSD += g.CostUSD
+		total.LatencyMs += g.LatencyMs
+	}
+	response.Total = toLLMUsage(&total)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// toLLMUsage converts state LLM usage to its API model
+func toLLMUsage(u *state.LLMUsage) LLMUsage {
+	return LLMUsage{
+		Calls:            u.Calls,
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+		CostUSD:          u.CostUSD,
+		LatencyMs:        u.LatencyMs,
+	}
+}
+
+// toBudget converts an API budget to the orchestrator's
+func toBudget(b *JobBudget) *orchestrator.Budget {
+	return &orchestrator.Budget{Tokens: b.Tokens, CostUSD: b.CostUSD}
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"agent-project-manager/internal/orchestrator"
+)
+
+// handleCreateMatrix handles POST /matrices
+// @Summary      Run a workflow across parameter axes
+// @Description  Expand one workflow into sibling jobs, one for every combination of axis values, e.g. models,
+// @Description  temperatures or prompt versions. Each job gets the shared input with every axis name set to
+// @Description  its value. All jobs are pinned to the same workflow version. Once every job finished, a markdown
+// @Description  comparison report is stored as an artifact.
+// @Tags         matrices
+// @Accept       json
+// @Produce      json
+// @Param        matrix  body      CreateMatrixRequest   true  "Workflow, shared input and axes"
+// @Success      201     {object}  CreateMatrixResponse
+// @Failure      400     {object}  InvalidInputResponse  "Invalid request, unknown workflow or invalid input"
+// @Router       /matrices [post]
+func handleCreateMatrix(orch *orchestrator.Orchestrator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreateMatrixRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		mreq := orchestrator.MatrixRequest{
+			Workflow: req.Workflow,
+			Input:    req.Input,
+			Axes:     req.Axes,
+			NoCache:  req.NoCache,
+		}
+		if req.Budget != nil {
+			mreq.Budget = toBudget(req.Budget)
+			if err := mreq.Budget.Validate(); err != nil {
+				http.Error(w, "Invalid budget: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		matrix, jobs, err := orch.SubmitMatrix(mreq)
+		if err != nil {
+			var invalid *orchestrator.InvalidInputError
+			switch {
+			case errors.As(err, &invalid):
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(InvalidInputResponse{
+					Error:  "Job input does not match the workflow's inputSchema",
+					Errors: toInputErrors(invalid.Errors),
+				})
+			case errors.Is(err, orchestrator.ErrInvalidMatrix):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, orchestrator.ErrWorkflowNotFound):
+				http.Error(w, "Unknown workflow version: "+req.Workflow, http.StatusBadRequest)
+			default:
+				http.Error(w, "Failed to create matrix: "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		response := CreateMatrixResponse{
+			ID:              matrix.ID,
+			Workflow:        matrix.Workflow,
+			WorkflowVersion: matrix.WorkflowVersion,
+			Jobs:            make([]MatrixJob, len(jobs)),
+		}
+		for i, job := range jobs {
+			params, _ := job.Meta["matrixParams"].(map[string]interface{})
+			response.Jobs[i] = MatrixJob{ID: job.ID, Params: params}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// handleGetMatrix handles GET /matrices/{matrixId}
+// @Summary      Compare the jobs of a matrix
+// @Description  Report status, duration, token usage, cost and review scores of every job of a matrix,
+// @Description  as JSON or as a markdown table. Tokens and cost add up the usage reported by the steps;
+// @Description  scores are the numeric score outputs of the steps.
+// @Tags         matrices
+// @Produce      json
+// @Produce      text/markdown
+// @Param        matrixId  path      string  true   "Matrix ID"
+// @Param        format    query     string  false  "Output format"  Enums(json, markdown)  default(json)
+// @Success      200       {object}  MatrixReportResponse
+// @Failure      400       {string}  string  "Invalid format"
+// @Failure      404       {string}  string  "Matrix not found"
+// @Router       /matrices/{matrixId} [get]
+func handleGetMatrix(orch *orchestrator.Orchestrator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "json" && format != "markdown" {
+			http.Error(w, "Invalid format: "+format, http.StatusBadRequest)
+			return
+		}
+
+		report, err := orch.MatrixReport(chi.URLParam(r, "matrixId"))
+		if err != nil {
+			if errors.Is(err, orchestrator.ErrMatrixNotFound) {
+				http.Error(w, "Matrix not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to build matrix report: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if format == "markdown" {
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(report.Markdown()))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(toMatrixReport(report))
+	}
+}
+
+// toMatrixReport converts an orchestrator matrix report to its API model
+func toMatrixReport(report *orchestrator.MatrixReport) *MatrixReportResponse {
+	m := report.Matrix
+	response := &MatrixReportResponse{
+		ID:               m.ID,
+		Workflow:         m.Workflow,
+		WorkflowVersion:  m.WorkflowVersion,
+		Axes:             report.Axes,
+		Scores:           report.Scores,
+		Finished:         report.Finished,
+		ReportArtifactID: m.ReportArtifactID,
+		CreatedAt:        m.CreatedAt,
+		CompletedAt:      m.CompletedAt,
+		Rows:             make([]MatrixRow, len(report.Rows)),
+	}
+	for i, row := range report.Rows {
+		response.Rows[i] = MatrixRow{
+			JobID:            row.JobID,
+			Params:           row.Params,
+			Status:           JobStatus(row.Status),
+			DurationMs:       row.Duration.Milliseconds(),
+			PromptTokens:     row.PromptTokens,
+			CompletionTokens: row.CompletionTokens,
+			TotalTokens:      row.TotalTokens,
+			CostUSD:          row.CostUSD,
+			Scores:           row.Scores,
+			Error:            row.Error,
+		}
+	}
+	return response
+}
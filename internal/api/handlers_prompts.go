@@ -0,0 +1,280 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"agent-project-manager/internal/prompts"
+	"agent-project-manager/internal/repository"
+	"agent-project-manager/internal/state"
+)
+
+// handleListPrompts handles GET /prompts
+// @Summary      List prompts
+// @Description  Get the prompt templates of the prompt registry with their latest version
+// @Tags         prompts
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  PromptListResponse
+// @Router       /prompts [get]
+func handleListPrompts(repo repository.IPromptRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statePrompts, err := repo.ListPrompts()
+		if err != nil {
+			http.Error(w, "Failed to list prompts: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := PromptListResponse{Prompts: make([]Prompt, len(statePrompts))}
+		for i, sp := range statePrompts {
+			response.Prompts[i] = Prompt{
+				Name:          sp.Name,
+				Description:   sp.Description,
+				LatestVersion: sp.LatestVersion,
+				CreatedAt:     sp.CreatedAt,
+				UpdatedAt:     sp.UpdatedAt,
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// handleListPromptVersions handles GET /prompts/{name}/versions
+// @Summary      List prompt versions
+// @Description  Get the immutable published versions of a prompt, oldest first
+// @Tags         prompts
+// @Accept       json
+// @Produce      json
+// @Param        name  path      string  true  "Prompt name"
+// @Success      200   {object}  PromptVersionListResponse
+// @Failure      404   {string}  string  "Prompt not found"
+// @Router       /prompts/{name}/versions [get]
+func handleListPromptVersions(repo repository.IPromptRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+
+		stateVersions, err := repo.ListPromptVersions(name)
+		if err != nil {
+			http.Error(w, "Failed to list prompt versions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(stateVersions) == 0 {
+			http.Error(w, "Prompt not found", http.StatusNotFound)
+			return
+		}
+
+		response := PromptVersionListResponse{Versions: make([]PromptVersion, len(stateVersions))}
+		for i, sv := range stateVersions {
+			response.Versions[i] = toPromptVersion(sv)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// handleGetPromptVersion handles GET /prompts/{name}/versions/{version}
+// @Summary      Get a prompt version
+// @Description  Get the template and variables of a published prompt version; "latest" gets the latest one
+// @Tags         prompts
+// @Accept       json
+// @Produce      json
+// @Param        name     path      string  true  "Prompt name"
+// @Param        version  path      string  true  "Prompt version number or latest"
+// @Success      200      {object}  PromptVersion
+// @Failure      400      {string}  string  "Invalid version"
+// @Failure      404      {string}  string  "Prompt version not found"
+// @Router       /prompts/{name}/versions/{version} [get]
+func handleGetPromptVersion(repo repository.IPromptRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ref, err := prompts.ParseRef(chi.URLParam(r, "name") + "@" + chi.URLParam(r, "version"))
+		if err != nil {
+			http.Error(w, "Invalid version", http.StatusBadRequest)
+			return
+		}
+
+		sv, err := prompts.Resolve(repo, ref)
+		if err != nil {
+			http.Error(w, "Prompt version not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(toPromptVersion(sv))
+	}
+}
+
+// handlePublishPrompt handles POST /prompts/{name}/versions
+// @Summary      Publish a prompt version
+// @Description  Store a template as the prompt's next immutable version, creating the prompt if needed.
+// @Description  Publishing the same description, template and variables as the latest version returns it.
+// @Description  Prompts loaded from the prompts directory get a new version whenever their file changes.
+// @Tags         prompts
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        name     path      string                true  "Prompt name"
+// @Param        request  body      PublishPromptRequest  true  "Prompt template"
+// @Success      200      {object}  PromptVersion
+// @Failure      400      {string}  string  "Invalid prompt"
+// @Failure      401      {string}  string  "Unauthorized"
+// @Router       /prompts/{name}/versions [post]
+func handlePublishPrompt(repo repository.IPromptRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PublishPromptRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		pv := &state.PromptVersion{
+			Prompt:      chi.URLParam(r, "name"),
+			Description: req.Description,
+			Template:    req.Template,
+			Variables:   make([]state.PromptVariable, len(req.Variables)),
+		}
+		for i, v := range req.Variables {
+			pv.Variables[i] = state.PromptVariable(v)
+		}
+		if err := prompts.Check(pv); err != nil {
+			http.Error(w, "Invalid prompt: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		published, err := repo.PublishPrompt(pv)
+		if err != nil {
+			http.Error(w, "Failed to publish prompt: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(toPromptVersion(published))
+	}
+}
+
+// handlePreviewPrompt handles POST /prompts/{name}/preview
+// @Summary      Preview a prompt
+// @Description  Render a prompt version with sample variables, exactly as an llm step would
+// @Tags         prompts
+// @Accept       json
+// @Produce      json
+// @Param        name     path      string                true  "Prompt name"
+// @Param        request  body      PreviewPromptRequest  true  "Version and variables"
+// @Success      200      {object}  PreviewPromptResponse
+// @Failure      400      {string}  string  "Invalid variables or template error"
+// @Failure      404      {string}  string  "Prompt version not found"
+// @Router       /prompts/{name}/preview [post]
+func handlePreviewPrompt(repo repository.IPromptRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PreviewPromptRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Version < 0 {
+			http.Error(w, "Invalid version", http.StatusBadRequest)
+			return
+		}
+
+		sv, err := prompts.Resolve(repo, prompts.Ref{Name: chi.URLParam(r, "name"), Version: req.Version})
+		if err != nil {
+			http.Error(w, "Prompt version not found", http.StatusNotFound)
+			return
+		}
+		rendered, err := prompts.Render(sv, req.Variables)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		response := PreviewPromptResponse{
+			Prompt:   sv.Prompt,
+			Version:  sv.Version,
+			Ref:      prompts.Ref{Name: sv.Prompt, Version: sv.Version}.String(),
+			Rendered: rendered,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// handleDiffPromptVersions handles GET /prompts/{name}/diff
+// @Summary      Diff prompt versions
+// @Description  Compare two published versions of a prompt: variables added, removed or changed
+// @Description  and a unified diff of the template
+// @Tags         prompts
+// @Accept       json
+// @Produce      json
+// @Param        name  path      string   true  "Prompt name"
+// @Param        from  query     integer  true  "Base version"
+// @Param        to    query     integer  true  "Target version"
+// @Success      200   {object}  PromptDiffResponse
+// @Failure      400   {string}  string  "Invalid version"
+// @Failure      404   {string}  string  "Prompt version not found"
+// @Router       /prompts/{name}/diff [get]
+func handleDiffPromptVersions(repo repository.IPromptRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		from, errFrom := strconv.Atoi(r.URL.Query().Get("from"))
+		to, errTo := strconv.Atoi(r.URL.Query().Get("to"))
+		if errFrom != nil || errTo != nil || from <= 0 || to <= 0 {
+			http.Error(w, "Invalid version: from and to must be positive integers", http.StatusBadRequest)
+			return
+		}
+
+		fromVersion, err := repo.GetPromptVersion(name, from)
+		if err != nil {
+			http.Error(w, "Prompt version not found: "+strconv.Itoa(from), http.StatusNotFound)
+			return
+		}
+		toVersion, err := repo.GetPromptVersion(name, to)
+		if err != nil {
+			http.Error(w, "Prompt version not found: "+strconv.Itoa(to), http.StatusNotFound)
+			return
+		}
+
+		diff := prompts.Diff(fromVersion, toVersion)
+		response := PromptDiffResponse{
+			Prompt:             name,
+			From:               from,
+			To:                 to,
+			DescriptionChanged: diff.DescriptionChanged,
+			VariablesAdded:     diff.VariablesAdded,
+			VariablesRemoved:   diff.VariablesRemoved,
+			VariablesChanged:   diff.VariablesChanged,
+			TemplateDiff:       diff.Template,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// toPromptVersion converts a stored prompt version to its API representation
+func toPromptVersion(sv *state.PromptVersion) PromptVersion {
+	pv := PromptVersion{
+		Prompt:      sv.Prompt,
+		Version:     sv.Version,
+		Description: sv.Description,
+		Template:    sv.Template,
+		Variables:   make([]PromptVariable, len(sv.Variables)),
+		Source:      sv.Source,
+		CreatedAt:   sv.CreatedAt,
+	}
+	for i, v := range sv.Variables {
+		pv.Variables[i] = PromptVariable(v)
+	}
+	return pv
+}
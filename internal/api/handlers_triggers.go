@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"agent-project-manager/internal/orchestrator"
+	"agent-project-manager/internal/repository"
+	"agent-project-manager/internal/state"
+)
+
+// handleWebhook handles POST /webhooks/{name}
+// @Summary      Receive a webhook
+// @Description  Fire the triggers of published workflows that listen to the named webhook. The JSON body is
+// @Description  available to their conditions and input mappings as .payload. Every firing, including those
+// @Description  that did not start a job, is returned and recorded in the trigger audit trail.
+// @Tags         triggers
+// @Accept       json
+// @Produce      json
+// @Param        name     path      string                  true   "Webhook name"
+// @Param        payload  body      map[string]interface{}  false  "Webhook payload"
+// @Success      202      {object}  WebhookResponse
+// @Failure      400      {string}  string  "Invalid request body"
+// @Failure      404      {string}  string  "No trigger listens to this webhook"
+// @Router       /webhooks/{name} [post]
+func handleWebhook(orch *orchestrator.Orchestrator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+
+		payload := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "Invalid request body: payload must be a JSON object", http.StatusBadRequest)
+			return
+		}
+
+		firings, err := orch.Webhook(name, payload)
+		if err != nil {
+			if errors.Is(err, orchestrator.ErrNoWebhookTrigger) {
+				http.Error(w, "No trigger listens to webhook "+name, http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to handle webhook: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := WebhookResponse{
+			Webhook: name,
+			Firings: make([]TriggerFiring, len(firings)),
+		}
+		for i, f := range firings {
+			response.Firings[i] = toTriggerFiring(f)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// handleListTriggerFirings handles GET /triggers/firings
+// @Summary      List trigger firings
+// @Description  Audit trail of workflow triggers: which event started which job, newest first.
+// @Description  Firings skipped at the maximum trigger depth or failed on their input mapping are included.
+// @Tags         triggers
+// @Produce      json
+// @Param        workflow     query     string  false  "Filter by the workflow the trigger belongs to"
+// @Param        sourceJobId  query     string  false  "Filter by the job the event was about"
+// @Param        jobId        query     string  false  "Filter by the started job"
+// @Param        limit        query     int     false  "Maximum number of firings to return"
+// @Success      200          {object}  TriggerFiringListResponse
+// @Router       /triggers/firings [get]
+func handleListTriggerFirings(repo repository.ITriggerFiringRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter := state.TriggerFiringFilter{
+			Workflow:    r.URL.Query().Get("workflow"),
+			SourceJobID: r.URL.Query().Get("sourceJobId"),
+			JobID:       r.URL.Query().Get("jobId"),
+		}
+		limit := 50 // default
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+				limit = parsed
+			}
+		}
+
+		firings, err := repo.ListTriggerFirings(filter, limit)
+		if err != nil {
+			http.Error(w, "Failed to list trigger firings: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := TriggerFiringListResponse{Firings: make([]TriggerFiring, len(firings))}
+		for i, f := range firings {
+			response.Firings[i] = toTriggerFiring(f)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// toTriggerFiring converts a state trigger firing to its API model
+func toTriggerFiring(f *state.TriggerFiring) TriggerFiring {
+	return TriggerFiring{
+		ID:              f.ID,
+		Workflow:        f.Workflow,
+		WorkflowVersion: f.WorkflowVersion,
+		Trigger:         f.TriggerName,
+		EventType:       f.EventType,
+		EventID:         f.EventID,
+		SourceJobID:     f.SourceJobID,
+		ArtifactID:      f.ArtifactID,
+		Webhook:         f.Webhook,
+		JobID:           f.JobID,
+		Depth:           f.Depth,
+		Status:          f.Status,
+		Error:           f.Error,
+		CreatedAt:       f.CreatedAt,
+	}
+}
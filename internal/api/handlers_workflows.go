@@ -2,10 +2,15 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"agent-project-manager/internal/jsonschema"
+	"agent-project-manager/internal/orchestrator"
 	"agent-project-manager/internal/repository"
+	"agent-project-manager/internal/state"
 )
 
 // handleListWorkflows handles GET /workflows
@@ -81,9 +86,563 @@ func handleGetWorkflow(repo repository.IWorkflowRepository) http.HandlerFunc {
 	}
 }
 
+// handleCreateWorkflow handles POST /workflows
+// @Summary      Create a workflow
+// @Description  Create a workflow after validating its definition; optionally publish it as version 1
+// @Tags         workflows
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        workflow  body      CreateWorkflowRequest  true  "Workflow creation request"
+// @Success      201       {object}  Workflow
+// @Failure      400       {object}  ValidateWorkflowResponse
+// @Failure      401       {string}  string  "Unauthorized"
+// @Failure      409       {string}  string  "Workflow already exists"
+// @Router       /workflows [post]
+func handleCreateWorkflow(repo repository.IWorkflowRepository, versionRepo repository.IWorkflowVersionRepository, orch *orchestrator.Orchestrator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreateWorkflowRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if errs := orch.CheckDefinition(req.Name, state.JSONMap(req.Schema)); len(errs) > 0 {
+			writeInvalidDefinition(w, errs)
+			return
+		}
+
+		sw := &state.Workflow{
+			Name:        req.Name,
+			Description: req.Description,
+			Schema:      state.JSONMap(req.Schema),
+		}
+		var err error
+		if req.Publish {
+			// Created and published in one transaction, so a failed publish leaves nothing behind
+			var wv *state.WorkflowVersion
+			if wv, err = versionRepo.CreateAndPublishWorkflow(sw); err == nil {
+				sw.Version = strconv.Itoa(wv.Version)
+			}
+		} else {
+			err = repo.CreateWorkflow(sw)
+		}
+		if err != nil {
+			if errors.Is(err, state.ErrAlreadyExists) {
+				http.Error(w, "Workflow already exists", http.StatusConflict)
+				return
+			}
+			http.Error(w, "Failed to create workflow: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(toWorkflow(sw))
+	}
+}
+
+// handleUpdateWorkflow handles PUT /workflows/{name}
+// @Summary      Update a workflow
+// @Description  Replace a workflow's draft definition after validating it.
+// @Description  Published versions are immutable; publish the draft to make it available to new jobs.
+// @Tags         workflows
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        name      path      string                 true  "Workflow name"
+// @Param        workflow  body      UpdateWorkflowRequest  true  "Workflow update request"
+// @Success      200       {object}  Workflow
+// @Failure      400       {object}  ValidateWorkflowResponse
+// @Failure      401       {string}  string  "Unauthorized"
+// @Failure      404       {string}  string  "Workflow not found"
+// @Router       /workflows/{name} [put]
+func handleUpdateWorkflow(repo repository.IWorkflowRepository, versionRepo repository.IWorkflowVersionRepository, orch *orchestrator.Orchestrator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+
+		var req UpdateWorkflowRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		sw, err := repo.GetWorkflow(name)
+		if err != nil {
+			http.Error(w, "Workflow not found", http.StatusNotFound)
+			return
+		}
+		if errs := orch.CheckDefinition(name, state.JSONMap(req.Schema)); len(errs) > 0 {
+			writeInvalidDefinition(w, errs)
+			return
+		}
+
+		sw.Description = req.Description
+		sw.Schema = state.JSONMap(req.Schema)
+		if err := repo.UpdateWorkflow(sw); err != nil {
+			http.Error(w, "Failed to update workflow: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if req.Publish {
+			wv, err := versionRepo.PublishWorkflow(name)
+			if err != nil {
+				http.Error(w, "Failed to publish workflow: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			sw.Version = strconv.Itoa(wv.Version)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(toWorkflow(sw))
+	}
+}
+
+// handlePublishWorkflow handles POST /workflows/{name}/publish
+// @Summary      Publish a workflow
+// @Description  Snapshot the workflow's current definition as a new immutable version.
+// @Description  Publishing an unchanged definition returns the latest version.
+// @Tags         workflows
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        name  path      string  true  "Workflow name"
+// @Success      200   {object}  WorkflowVersion
+// @Failure      401   {string}  string  "Unauthorized"
+// @Failure      404   {string}  string  "Workflow not found"
+// @Router       /workflows/{name}/publish [post]
+func handlePublishWorkflow(repo repository.IWorkflowRepository, versionRepo repository.IWorkflowVersionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+
+		if _, err := repo.GetWorkflow(name); err != nil {
+			http.Error(w, "Workflow not found", http.StatusNotFound)
+			return
+		}
+		wv, err := versionRepo.PublishWorkflow(name)
+		if err != nil {
+			http.Error(w, "Failed to publish workflow: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(toWorkflowVersion(wv))
+	}
+}
+
+// handleDeleteWorkflow handles DELETE /workflows/{name}
+// @Summary      Delete a workflow
+// @Description  Delete a workflow. Deletion is refused while unfinished jobs use the workflow;
+// @Description  if finished jobs still reference it, the workflow is soft-deleted so their history stays intact.
+// @Tags         workflows
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        name  path      string  true  "Workflow name"
+// @Success      200   {object}  DeleteWorkflowResponse
+// @Failure      401   {string}  string  "Unauthorized"
+// @Failure      404   {string}  string  "Workflow not found"
+// @Failure      409   {string}  string  "Workflow is used by unfinished jobs"
+// @Router       /workflows/{name} [delete]
+func handleDeleteWorkflow(repo repository.IWorkflowRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+
+		softDeleted, active, err := repo.DeleteUnusedWorkflow(name)
+		switch {
+		case errors.Is(err, state.ErrNotFound):
+			http.Error(w, "Workflow not found", http.StatusNotFound)
+			return
+		case err != nil:
+			http.Error(w, "Failed to delete workflow: "+err.Error(), http.StatusInternalServerError)
+			return
+		case active > 0:
+			http.Error(w, "Workflow is used by "+strconv.Itoa(active)+" unfinished jobs", http.StatusConflict)
+			return
+		}
+
+		response := DeleteWorkflowResponse{
+			Name:        name,
+			SoftDeleted: softDeleted,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// writeInvalidDefinition responds with the validation errors of a rejected workflow definition
+func writeInvalidDefinition(w http.ResponseWriter, errs []string) {
+	response := ValidateWorkflowResponse{
+		Valid:  false,
+		Errors: errs,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(response)
+}
+
+// toWorkflow converts a state workflow to its API model
+func toWorkflow(sw *state.Workflow) Workflow {
+	return Workflow{
+		Name:        sw.Name,
+		Description: sw.Description,
+		Schema:      map[string]interface{}(sw.Schema),
+		Version:     sw.Version,
+	}
+}
+
+// handleListWorkflowVersions handles GET /workflows/{name}/versions
+// @Summary      List workflow versions
+// @Description  Get the immutable published versions of a workflow, oldest first
+// @Tags         workflows
+// @Accept       json
+// @Produce      json
+// @Param        name   path      string  true  "Workflow name"
+// @Success      200    {object}  WorkflowVersionListResponse
+// @Router       /workflows/{name}/versions [get]
+func handleListWorkflowVersions(repo repository.IWorkflowVersionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+
+		stateVersions, err := repo.ListWorkflowVersions(name)
+		if err != nil {
+			http.Error(w, "Failed to list workflow versions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		versions := make([]WorkflowVersion, len(stateVersions))
+		for i, sv := range stateVersions {
+			versions[i] = toWorkflowVersion(sv)
+		}
+
+		response := WorkflowVersionListResponse{
+			Versions: versions,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// handleGetWorkflowVersion handles GET /workflows/{name}/versions/{version}
+// @Summary      Get a workflow version
+// @Description  Get the definition of a published workflow version
+// @Tags         workflows
+// @Accept       json
+// @Produce      json
+// @Param        name     path      string   true  "Workflow name"
+// @Param        version  path      integer  true  "Workflow version"
+// @Success      200      {object}  WorkflowVersion
+// @Failure      400      {string}  string  "Invalid version"
+// @Failure      404      {string}  string  "Workflow version not found"
+// @Router       /workflows/{name}/versions/{version} [get]
+func handleGetWorkflowVersion(repo repository.IWorkflowVersionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		version, err := strconv.Atoi(chi.URLParam(r, "version"))
+		if err != nil || version <= 0 {
+			http.Error(w, "Invalid version", http.StatusBadRequest)
+			return
+		}
+
+		sv, err := repo.GetWorkflowVersion(name, version)
+		if err != nil {
+			http.Error(w, "Workflow version not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(toWorkflowVersion(sv))
+	}
+}
+
+// handleDiffWorkflowVersions handles GET /workflows/{name}/diff
+// @Summary      Diff workflow versions
+// @Description  Compare two published versions of a workflow
+// @Tags         workflows
+// @Accept       json
+// @Produce      json
+// @Param        name  path      string   true  "Workflow name"
+// @Param        from  query     integer  true  "Base version"
+// @Param        to    query     integer  true  "Target version"
+// @Success      200   {object}  WorkflowDiffResponse
+// @Failure      400   {string}  string  "Invalid version"
+// @Failure      404   {string}  string  "Workflow version not found"
+// @Router       /workflows/{name}/diff [get]
+func handleDiffWorkflowVersions(repo repository.IWorkflowVersionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		from, errFrom := strconv.Atoi(r.URL.Query().Get("from"))
+		to, errTo := strconv.Atoi(r.URL.Query().Get("to"))
+		if errFrom != nil || errTo != nil || from <= 0 || to <= 0 {
+			http.Error(w, "Invalid version: from and to must be positive integers", http.StatusBadRequest)
+			return
+		}
+
+		fromVersion, err := repo.GetWorkflowVersion(name, from)
+		if err != nil {
+			http.Error(w, "Workflow version not found: "+strconv.Itoa(from), http.StatusNotFound)
+			return
+		}
+		toVersion, err := repo.GetWorkflowVersion(name, to)
+		if err != nil {
+			http.Error(w, "Workflow version not found: "+strconv.Itoa(to), http.StatusNotFound)
+			return
+		}
+
+		diff := orchestrator.DiffDefinitions(fromVersion.Schema, toVersion.Schema)
+		response := WorkflowDiffResponse{
+			Workflow:     name,
+			From:         from,
+			To:           to,
+			StepsAdded:   diff.StepsAdded,
+			StepsRemoved: diff.StepsRemoved,
+			StepsChanged: diff.StepsChanged,
+			Changes:      make([]WorkflowChange, len(diff.Changes)),
+		}
+		for i, c := range diff.Changes {
+			response.Changes[i] = WorkflowChange{Path: c.Path, Op: c.Op, From: c.From, To: c.To}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// handlePlanWorkflow handles POST /workflows/{name}/plan
+// @Summary      Plan a workflow run
+// @Description  Dry-run a workflow: validate the input, resolve templates and conditions where the input
+// @Description  alone is enough, and return the step DAG with each step's resolved input, agent, tool,
+// @Description  model and estimated LLM calls. Nothing is persisted and nothing runs.
+// @Description  A plan of invalid input or an invalid definition is returned with status 400.
+// @Tags         workflows
+// @Accept       json
+// @Produce      json
+// @Param        name  path      string               true  "Workflow name"
+// @Param        plan  body      PlanWorkflowRequest  true  "Candidate input"
+// @Success      200   {object}  WorkflowPlanResponse
+// @Failure      400   {object}  WorkflowPlanResponse  "Input or definition is invalid; a malformed body returns plain text"
+// @Failure      404   {string}  string  "Workflow not found"
+// @Failure      500   {string}  string  "Failed to plan workflow"
+// @Router       /workflows/{name}/plan [post]
+func handlePlanWorkflow(orch *orchestrator.Orchestrator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+
+		var req PlanWorkflowRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		ref := name
+		if req.Version > 0 {
+			ref += "@" + strconv.Itoa(req.Version)
+		}
+		plan, err := orch.Plan(ref, req.Input)
+		if err != nil {
+			if errors.Is(err, orchestrator.ErrWorkflowNotFound) {
+				http.Error(w, "Workflow not found: "+ref, http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to plan workflow: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		status := http.StatusOK
+		if !plan.Valid {
+			status = http.StatusBadRequest
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(toWorkflowPlan(plan))
+	}
+}
+
+// handleWorkflowGraph handles GET /workflows/{name}/graph
+// @Summary      Render a workflow's step graph
+// @Description  Render the step DAG of a workflow as a Mermaid flowchart, Graphviz DOT or JSON.
+// @Description  Without a version the latest published version is used, falling back to the draft.
+// @Description  Compensations are linked to their steps with dashed edges.
+// @Tags         workflows
+// @Produce      json
+// @Produce      plain
+// @Param        name     path      string  true   "Workflow name"
+// @Param        version  query     int     false  "Workflow version"
+// @Param        format   query     string  false  "Output format"  Enums(mermaid, dot, json)  default(json)
+// @Success      200      {object}  WorkflowGraphResponse
+// @Failure      400      {string}  string  "Invalid format"
+// @Failure      404      {string}  string  "Workflow not found"
+// @Router       /workflows/{name}/graph [get]
+func handleWorkflowGraph(orch *orchestrator.Orchestrator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+
+		format, ok := parseGraphFormat(r)
+		if !ok {
+			http.Error(w, "Invalid format: must be mermaid, dot or json", http.StatusBadRequest)
+			return
+		}
+
+		ref := name
+		if v := r.URL.Query().Get("version"); v != "" {
+			version, err := strconv.Atoi(v)
+			if err != nil || version < 1 {
+				http.Error(w, "Invalid version", http.StatusBadRequest)
+				return
+			}
+			ref += "@" + v
+		}
+
+		graph, err := orch.WorkflowGraph(ref)
+		if err != nil {
+			if errors.Is(err, orchestrator.ErrWorkflowNotFound) {
+				http.Error(w, "Workflow not found: "+ref, http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to build graph: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeGraph(w, graph, format)
+	}
+}
+
+// parseGraphFormat reads the format query parameter, defaulting to JSON
+func parseGraphFormat(r *http.Request) (GraphFormat, bool) {
+	switch format := GraphFormat(r.URL.Query().Get("format")); format {
+	case "":
+		return GraphFormatJSON, true
+	case GraphFormatMermaid, GraphFormatDOT, GraphFormatJSON:
+		return format, true
+	default:
+		return "", false
+	}
+}
+
+// writeGraph writes a graph in the requested format
+func writeGraph(w http.ResponseWriter, g *orchestrator.Graph, format GraphFormat) {
+	switch format {
+	case GraphFormatMermaid:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(g.Mermaid()))
+	case GraphFormatDOT:
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(g.DOT()))
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(toWorkflowGraph(g))
+	}
+}
+
+// toWorkflowGraph converts an orchestrator graph to its API model
+func toWorkflowGraph(g *orchestrator.Graph) *WorkflowGraphResponse {
+	response := &WorkflowGraphResponse{
+		Workflow: g.Workflow,
+		Version:  g.Version,
+		JobID:    g.JobID,
+		Status:   JobStatus(g.Status),
+		Nodes:    make([]GraphNode, len(g.Nodes)),
+		Edges:    make([]GraphEdge, len(g.Edges)),
+	}
+	for i, n := range g.Nodes {
+		response.Nodes[i] = GraphNode{
+			ID:           n.ID,
+			Name:         n.Name,
+			Type:         n.Type,
+			If:           n.If,
+			Workflow:     n.Workflow,
+			Compensation: n.Compensation,
+			StepID:       n.StepID,
+			Status:       StepStatus(n.Status),
+			StartedAt:    n.StartedAt,
+			CompletedAt:  n.CompletedAt,
+			DurationMs:   n.Duration.Milliseconds(),
+			Cached:       n.Cached,
+			Error:        n.Error,
+			ChildJobID:   n.ChildJobID,
+		}
+	}
+	for i, e := range g.Edges {
+		response.Edges[i] = GraphEdge{From: e.From, To: e.To, Compensation: e.Compensation}
+	}
+	return response
+}
+
+// toWorkflowPlan converts an orchestrator plan to its API model
+func toWorkflowPlan(p *orchestrator.Plan) *WorkflowPlanResponse {
+	response := &WorkflowPlanResponse{
+		Workflow:          p.Workflow,
+		Version:           p.Version,
+		Valid:             p.Valid,
+		Errors:            p.Errors,
+		Input:             p.Input,
+		Steps:             make([]PlannedStep, len(p.Steps)),
+		EstimatedLLMCalls: p.EstimatedLLMCalls,
+	}
+	if len(p.InputErrors) > 0 {
+		response.InputErrors = toInputErrors(p.InputErrors)
+	}
+	for i, sp := range p.Steps {
+		step := PlannedStep{
+			Name:              sp.Name,
+			Type:              sp.Type,
+			DependsOn:         sp.DependsOn,
+			If:                sp.If,
+			Outcome:           sp.Outcome,
+			Input:             sp.Input,
+			Unresolved:        sp.Unresolved,
+			Agent:             sp.Agent,
+			Tool:              sp.Tool,
+			Provider:          sp.Provider,
+			Model:             sp.Model,
+			Workflow:          sp.Workflow,
+			Cache:             sp.Cache,
+			Compensate:        sp.Compensate,
+			EstimatedLLMCalls: sp.EstimatedLLMCalls,
+		}
+		if step.DependsOn == nil {
+			step.DependsOn = []string{}
+		}
+		if sp.Child != nil {
+			step.Child = toWorkflowPlan(sp.Child)
+		}
+		response.Steps[i] = step
+	}
+	return response
+}
+
+// toWorkflowVersion converts a state workflow version to its API model
+func toWorkflowVersion(sv *state.WorkflowVersion) WorkflowVersion {
+	return WorkflowVersion{
+		Workflow:    sv.Workflow,
+		Version:     sv.Version,
+		Description: sv.Description,
+		Schema:      map[string]interface{}(sv.Schema),
+		CreatedAt:   sv.CreatedAt,
+	}
+}
+
+// workflowLookup resolves workflow references against the workflows that are not deleted
+type workflowLookup struct {
+	repository.IWorkflowRepository
+	repository.IWorkflowVersionRepository
+}
+
 // handleValidateWorkflow handles POST /workflows/validate
 // @Summary      Validate workflow input
-// @Description  Validate input parameters for a workflow
+// @Description  Validate input parameters against a workflow's inputSchema.
+// @Description  The workflow may be given as name@version; otherwise the latest published version is used.
+// @Description  Valid input is returned with schema defaults applied.
 // @Tags         workflows
 // @Accept       json
 // @Produce      json
@@ -91,7 +650,7 @@ func handleGetWorkflow(repo repository.IWorkflowRepository) http.HandlerFunc {
 // @Success      200       {object}  ValidateWorkflowResponse
 // @Failure      400       {string}  string  "Invalid request"
 // @Router       /workflows/validate [post]
-func handleValidateWorkflow(repo repository.IWorkflowRepository) http.HandlerFunc {
+func handleValidateWorkflow(workflowRepo repository.IWorkflowRepository, versionRepo repository.IWorkflowVersionRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req ValidateWorkflowRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -100,7 +659,11 @@ func handleValidateWorkflow(repo repository.IWorkflowRepository) http.HandlerFun
 		}
 
 		// Get workflow to validate against
-		_, err := repo.GetWorkflow(req.Workflow)
+		wv, err := orchestrator.ResolveWorkflowVersion(workflowLookup{workflowRepo, versionRepo}, req.Workflow)
+		if err != nil && !errors.Is(err, orchestrator.ErrWorkflowNotFound) {
+			http.Error(w, "Failed to resolve workflow version: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
 		if err != nil {
 			response := ValidateWorkflowResponse{
 				Valid:  false,
@@ -112,11 +675,22 @@ func handleValidateWorkflow(repo repository.IWorkflowRepository) http.HandlerFun
 			return
 		}
 
-		// TODO: Implement workflow validation logic against schema
+		input, verrs, err := orchestrator.ValidateInput(wv.Schema, req.Input)
 		response := ValidateWorkflowResponse{
-			Valid:  true,
+			Valid:  err == nil && len(verrs) == 0,
 			Errors: []string{},
 		}
+		switch {
+		case err != nil:
+			response.Errors = append(response.Errors, err.Error())
+		case len(verrs) > 0:
+			for _, e := range verrs {
+				response.Errors = append(response.Errors, e.Error())
+			}
+			response.Details = toInputErrors(verrs)
+		default:
+			response.Input = input
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
@@ -124,3 +698,12 @@ func handleValidateWorkflow(repo repository.IWorkflowRepository) http.HandlerFun
 	}
 }
 
+// toInputErrors converts input schema violations to their API model
+func toInputErrors(errs []jsonschema.ValidationError) []InputError {
+	out := make([]InputError, len(errs))
+	for i, e := range errs {
+		out[i] = InputError{Path: e.Path, Message: e.Message}
+	}
+	return out
+}
+
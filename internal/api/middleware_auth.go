@@ -0,0 +1,27 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// RequireAuth rejects requests that do not carry the configured bearer token.
+// When no token is configured the protected endpoints are unavailable.
+func RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if AuthToken == "" {
+			http.Error(w, "Authentication is not configured", http.StatusUnauthorized)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(AuthToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="agent-project-manager"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
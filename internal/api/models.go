@@ -17,11 +17,15 @@ type UUID = uuid.UUID
 type JobStatus string
 
 const (
-	JobStatusQueued    JobStatus = "queued"
-	JobStatusRunning   JobStatus = "running"
-	JobStatusSucceeded JobStatus = "succeeded"
-	JobStatusFailed    JobStatus = "failed"
-	JobStatusCancelled JobStatus = "cancelled"
+	JobStatusQueued          JobStatus = "queued"
+	JobStatusRunning         JobStatus = "running"
+	JobStatusWaitingApproval JobStatus = "waiting_approval"
+	JobStatusWaitingChild    JobStatus = "waiting_child"
+	JobStatusWaitingSignal   JobStatus = "waiting_signal"
+	JobStatusCompensating    JobStatus = "compensating"
+	JobStatusSucceeded       JobStatus = "succeeded"
+	JobStatusFailed          JobStatus = "failed"
+	JobStatusCancelled       JobStatus = "cancelled"
 )
 
 // String returns the string representation of JobStatus
@@ -32,7 +36,7 @@ func (s JobStatus) String() string {
 // IsValid checks if the JobStatus value is valid
 func (s JobStatus) IsValid() bool {
 	switch s {
-	case JobStatusQueued, JobStatusRunning, JobStatusSucceeded, JobStatusFailed, JobStatusCancelled:
+	case JobStatusQueued, JobStatusRunning, JobStatusWaitingApproval, JobStatusWaitingChild, JobStatusWaitingSignal, JobStatusCompensating, JobStatusSucceeded, JobStatusFailed, JobStatusCancelled:
 		return true
 	default:
 		return false
@@ -50,6 +54,10 @@ func AllJobStatuses() []JobStatus {
 	return []JobStatus{
 		JobStatusQueued,
 		JobStatusRunning,
+		JobStatusWaitingApproval,
+		JobStatusWaitingChild,
+		JobStatusWaitingSignal,
+		JobStatusCompensating,
 		JobStatusSucceeded,
 		JobStatusFailed,
 		JobStatusCancelled,
@@ -131,11 +139,15 @@ func (s *RunStatus) UnmarshalJSON(data []byte) error {
 type StepStatus string
 
 const (
-	StepStatusPending   StepStatus = "pending"
-	StepStatusRunning   StepStatus = "running"
-	StepStatusSucceeded StepStatus = "succeeded"
-	StepStatusFailed    StepStatus = "failed"
-	StepStatusSkipped   StepStatus = "skipped"
+	StepStatusPending         StepStatus = "pending"
+	StepStatusRunning         StepStatus = "running"
+	StepStatusWaitingApproval StepStatus = "waiting_approval"
+	StepStatusWaitingChild    StepStatus = "waiting_child"
+	StepStatusWaitingSignal   StepStatus = "waiting_signal"
+	StepStatusSucceeded       StepStatus = "succeeded"
+	StepStatusFailed          StepStatus = "failed"
+	StepStatusSkipped         StepStatus = "skipped"
+	StepStatusCompensated     StepStatus = "compensated"
 )
 
 // String returns the string representation of StepStatus
@@ -146,7 +158,7 @@ func (s StepStatus) String() string {
 // IsValid checks if the StepStatus value is valid
 func (s StepStatus) IsValid() bool {
 	switch s {
-	case StepStatusPending, StepStatusRunning, StepStatusSucceeded, StepStatusFailed, StepStatusSkipped:
+	case StepStatusPending, StepStatusRunning, StepStatusWaitingApproval, StepStatusWaitingChild, StepStatusWaitingSignal, StepStatusSucceeded, StepStatusFailed, StepStatusSkipped, StepStatusCompensated:
 		return true
 	default:
 		return false
@@ -164,9 +176,13 @@ func AllStepStatuses() []StepStatus {
 	return []StepStatus{
 		StepStatusPending,
 		StepStatusRunning,
+		StepStatusWaitingApproval,
+		StepStatusWaitingChild,
+		StepStatusWaitingSignal,
 		StepStatusSucceeded,
 		StepStatusFailed,
 		StepStatusSkipped,
+		StepStatusCompensated,
 	}
 }
 
@@ -243,10 +259,11 @@ func (s *QueueState) UnmarshalJSON(data []byte) error {
 type ArtifactType string
 
 const (
-	ArtifactTypePDF  ArtifactType = "pdf"
-	ArtifactTypePPTX ArtifactType = "pptx"
-	ArtifactTypeZIP  ArtifactType = "zip"
-	ArtifactTypeLog  ArtifactType = "log"
+	ArtifactTypePDF      ArtifactType = "pdf"
+	ArtifactTypePPTX     ArtifactType = "pptx"
+	ArtifactTypeZIP      ArtifactType = "zip"
+	ArtifactTypeLog      ArtifactType = "log"
+	ArtifactTypeMarkdown ArtifactType = "markdown"
 )
 
 // String returns the string representation of ArtifactType
@@ -257,7 +274,7 @@ func (t ArtifactType) String() string {
 // IsValid checks if the ArtifactType value is valid
 func (t ArtifactType) IsValid() bool {
 	switch t {
-	case ArtifactTypePDF, ArtifactTypePPTX, ArtifactTypeZIP, ArtifactTypeLog:
+	case ArtifactTypePDF, ArtifactTypePPTX, ArtifactTypeZIP, ArtifactTypeLog, ArtifactTypeMarkdown:
 		return true
 	default:
 		return false
@@ -277,6 +294,7 @@ func AllArtifactTypes() []ArtifactType {
 		ArtifactTypePPTX,
 		ArtifactTypeZIP,
 		ArtifactTypeLog,
+		ArtifactTypeMarkdown,
 	}
 }
 
@@ -326,14 +344,33 @@ type RefreshRequest struct {
 
 // CreateJobRequest represents a job creation request
 type CreateJobRequest struct {
-	Workflow string                 `json:"workflow"`
+	Workflow string                 `json:"workflow"` // "name" or "name@version"
 	Input    map[string]interface{} `json:"input"`
 	Meta     map[string]interface{} `json:"meta,omitempty"`
+	NoCache  bool                   `json:"noCache,omitempty"`  // bypass the step cache for this job
+	Budget   *JobBudget             `json:"budget,omitempty"`   // caps the job's LLM usage
+	Cassette *JobCassette           `json:"cassette,omitempty"` // records or replays the job's LLM traffic
+}
+
+// JobBudget caps the LLM usage of a job and the jobs its sub-workflow steps start.
+// Once either limit is reached, further LLM requests of the job fail.
+type JobBudget struct {
+	Tokens  int64   `json:"tokens,omitempty"`  // prompt plus completion tokens
+	CostUSD float64 `json:"costUsd,omitempty"` // by the configured price table
+}
+
+// JobCassette has a job's LLM traffic recorded to, or replayed from, the cassette file
+// <llm.cassettes.dir>/<name>.json. Replaying calls no provider; a request the cassette
+// does not hold fails the step.
+type JobCassette struct {
+	Mode string `json:"mode"` // record or replay
+	Name string `json:"name"`
 }
 
 // CreateJobResponse represents a job creation response
 type CreateJobResponse struct {
-	ID string `json:"id"`
+	ID              string `json:"id"`
+	WorkflowVersion int    `json:"workflowVersion,omitempty"`
 }
 
 // Job represents a job entity
@@ -348,6 +385,20 @@ type Job struct {
 	StartedAt *time.Time             `json:"startedAt,omitempty"`
 	CompletedAt *time.Time           `json:"completedAt,omitempty"`
 	Error     string                 `json:"error,omitempty"`
+	ParentJobID string               `json:"parentJobId,omitempty"`
+	WorkflowVersion int              `json:"workflowVersion,omitempty"`
+	RerunOfJobID    string           `json:"rerunOf,omitempty"`       // the job this job reruns
+	RerunFromStep   string           `json:"rerunFromStep,omitempty"` // the step the rerun started from
+	MatrixID        string           `json:"matrixId,omitempty"`      // the matrix the job was expanded from
+	Children  []JobChild             `json:"children,omitempty"`
+}
+
+// JobChild summarizes a child job started by a sub-workflow step
+type JobChild struct {
+	ID           string    `json:"id"`
+	Workflow     string    `json:"workflow"`
+	Status       JobStatus `json:"status"`
+	ParentStepID string    `json:"parentStepId,omitempty"`
 }
 
 // JobListResponse represents a paginated list of jobs
@@ -370,6 +421,42 @@ type JobStep struct {
 	StartedAt *time.Time             `json:"startedAt,omitempty"`
 	CompletedAt *time.Time           `json:"completedAt,omitempty"`
 	Error     string                 `json:"error,omitempty"`
+	Cached    bool                   `json:"cached,omitempty"`   // output was reused from the step cache
+	CacheKey  string                 `json:"cacheKey,omitempty"`
+	ArtifactIDs []string             `json:"artifactIds,omitempty"` // artifacts the step produced
+}
+
+// RerunJobRequest represents a request to rerun a job from one of its steps
+type RerunJobRequest struct {
+	FromStep string                 `json:"fromStep"`
+	Input    map[string]interface{} `json:"input,omitempty"` // overrides top-level keys of the original input
+}
+
+// RerunJobResponse represents a rerun job
+type RerunJobResponse struct {
+	ID          string   `json:"id"`
+	RunID       string   `json:"runId"` // the run recording the rerun; see GET /runs
+	RerunOf     string   `json:"rerunOf"`
+	FromStep    string   `json:"fromStep"`
+	CopiedSteps []string `json:"copiedSteps"` // steps whose results were copied from the original job
+}
+
+// SignalResponse represents a signal sent to a job
+type SignalResponse struct {
+	ID         string                 `json:"id"`
+	JobID      string                 `json:"jobId"`
+	Name       string                 `json:"name"`
+	Payload    map[string]interface{} `json:"payload"`
+	Delivered  bool                   `json:"delivered"`        // false while the signal is buffered
+	StepID     string                 `json:"stepId,omitempty"` // the step the signal completed
+	CreatedAt  time.Time              `json:"createdAt"`
+	ConsumedAt *time.Time             `json:"consumedAt,omitempty"`
+}
+
+// StepDecisionRequest represents an approval or rejection of an approval step
+type StepDecisionRequest struct {
+	Approver string `json:"approver"`
+	Comment  string `json:"comment,omitempty"`
 }
 
 // JobResult represents the result summary of a job
@@ -443,6 +530,377 @@ type WorkflowListResponse struct {
 	Workflows []Workflow `json:"workflows"`
 }
 
+// CreateWorkflowRequest represents a workflow creation request
+type CreateWorkflowRequest struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Schema      map[string]interface{} `json:"schema"`
+	Publish     bool                   `json:"publish,omitempty"` // publish the definition as a new version right away
+}
+
+// UpdateWorkflowRequest represents a workflow update request
+type UpdateWorkflowRequest struct {
+	Description string                 `json:"description,omitempty"`
+	Schema      map[string]interface{} `json:"schema"`
+	Publish     bool                   `json:"publish,omitempty"` // publish the definition as a new version right away
+}
+
+// DeleteWorkflowResponse represents the outcome of a workflow deletion
+type DeleteWorkflowResponse struct {
+	Name        string `json:"name"`
+	SoftDeleted bool   `json:"softDeleted"` // true when finished jobs still reference the workflow
+}
+
+// WorkflowVersion represents an immutable published version of a workflow
+type WorkflowVersion struct {
+	Workflow    string                 `json:"workflow"`
+	Version     int                    `json:"version"`
+	Description string                 `json:"description,omitempty"`
+	Schema      map[string]interface{} `json:"schema"`
+	CreatedAt   time.Time              `json:"createdAt"`
+}
+
+// WorkflowVersionListResponse represents the published versions of a workflow
+type WorkflowVersionListResponse struct {
+	Versions []WorkflowVersion `json:"versions"`
+}
+
+// WorkflowChange represents a single difference between two workflow versions
+type WorkflowChange struct {
+	Path string      `json:"path"` // JSON pointer into the definition
+	Op   string      `json:"op"`   // added | removed | changed
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// WorkflowDiffResponse represents the differences between two workflow versions
+type WorkflowDiffResponse struct {
+	Workflow     string           `json:"workflow"`
+	From         int              `json:"from"`
+	To           int              `json:"to"`
+	StepsAdded   []string         `json:"stepsAdded"`
+	StepsRemoved []string         `json:"stepsRemoved"`
+	StepsChanged []string         `json:"stepsChanged"`
+	Changes      []WorkflowChange `json:"changes"`
+}
+
+// Prompt represents a prompt template of the prompt registry
+type Prompt struct {
+	Name          string    `json:"name"`
+	Description   string    `json:"description,omitempty"`
+	LatestVersion int       `json:"latestVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// PromptListResponse represents the prompts of the prompt registry
+type PromptListResponse struct {
+	Prompts []Prompt `json:"prompts"`
+}
+
+// PromptVariable declares a variable of a prompt template
+type PromptVariable struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Default     interface{} `json:"default,omitempty"` // used when an optional variable is not given
+}
+
+// PromptVersion represents an immutable published version of a prompt template
+type PromptVersion struct {
+	Prompt      string           `json:"prompt"`
+	Version     int              `json:"version"`
+	Description string           `json:"description,omitempty"`
+	Template    string           `json:"template"` // text/template body
+	Variables   []PromptVariable `json:"variables"`
+	Source      string           `json:"source,omitempty"` // file the version was loaded from
+	CreatedAt   time.Time        `json:"createdAt"`
+}
+
+// PromptVersionListResponse represents the published versions of a prompt
+type PromptVersionListResponse struct {
+	Versions []PromptVersion `json:"versions"`
+}
+
+// PublishPromptRequest represents a new version of a prompt template
+type PublishPromptRequest struct {
+	Description string           `json:"description,omitempty"`
+	Template    string           `json:"template"`
+	Variables   []PromptVariable `json:"variables,omitempty"`
+}
+
+// PreviewPromptRequest represents a prompt render with sample variables
+type PreviewPromptRequest struct {
+	Version   int                    `json:"version,omitempty"` // defaults to the latest version
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// PreviewPromptResponse represents a rendered prompt
+type PreviewPromptResponse struct {
+	Prompt   string `json:"prompt"`
+	Version  int    `json:"version"`
+	Ref      string `json:"ref"` // name@version, as steps reference it
+	Rendered string `json:"rendered"`
+}
+
+// PromptDiffResponse represents the differences between two prompt versions
+type PromptDiffResponse struct {
+	Prompt             string   `json:"prompt"`
+	From               int      `json:"from"`
+	To                 int      `json:"to"`
+	DescriptionChanged bool     `json:"descriptionChanged"`
+	VariablesAdded     []string `json:"variablesAdded"`
+	VariablesRemoved   []string `json:"variablesRemoved"`
+	VariablesChanged   []string `json:"variablesChanged"`
+	TemplateDiff       string   `json:"templateDiff,omitempty"` // unified diff; empty when the template is unchanged
+}
+
+// PlanWorkflowRequest represents a workflow dry-run request
+type PlanWorkflowRequest struct {
+	Version int                    `json:"version,omitempty"` // defaults to the latest published version
+	Input   map[string]interface{} `json:"input"`
+}
+
+// WorkflowPlanResponse describes what a job would do, without running or persisting anything
+type WorkflowPlanResponse struct {
+	Workflow          string                 `json:"workflow"`
+	Version           int                    `json:"version"`
+	Valid             bool                   `json:"valid"`
+	Errors            []string               `json:"errors,omitempty"`
+	InputErrors       []InputError           `json:"inputErrors,omitempty"`
+	Input             map[string]interface{} `json:"input"` // with schema defaults applied
+	Steps             []PlannedStep          `json:"steps"`
+	EstimatedLLMCalls int                    `json:"estimatedLlmCalls"` // upper bound
+}
+
+// PlannedStep is one step of a workflow plan
+type PlannedStep struct {
+	Name              string                 `json:"name"`
+	Type              string                 `json:"type"`
+	DependsOn         []string               `json:"dependsOn"`
+	If                string                 `json:"if,omitempty"`
+	Outcome           string                 `json:"outcome"`              // run | skip | unknown
+	Input             map[string]interface{} `json:"input"`                // resolved where possible
+	Unresolved        []string               `json:"unresolved,omitempty"` // JSON pointers of input values that depend on earlier steps
+	Agent             string                 `json:"agent,omitempty"`
+	Tool              string                 `json:"tool,omitempty"`
+	Provider          string                 `json:"provider,omitempty"`
+	Model             string                 `json:"model,omitempty"`
+	Workflow          string                 `json:"workflow,omitempty"`
+	Child             *WorkflowPlanResponse  `json:"child,omitempty"`
+	Cache             bool                   `json:"cache,omitempty"`
+	Compensate        string                 `json:"compensate,omitempty"`
+	EstimatedLLMCalls int                    `json:"estimatedLlmCalls"`
+}
+
+// GraphFormat is the format of a rendered step graph
+type GraphFormat string
+
+const (
+	GraphFormatMermaid GraphFormat = "mermaid"
+	GraphFormatDOT     GraphFormat = "dot"
+	GraphFormatJSON    GraphFormat = "json"
+)
+
+// WorkflowGraphResponse represents the step DAG of a workflow or of a job
+type WorkflowGraphResponse struct {
+	Workflow string      `json:"workflow"`
+	Version  int         `json:"version"`          // 0 for an unpublished draft
+	JobID    string      `json:"jobId,omitempty"`  // set for job graphs
+	Status   JobStatus   `json:"status,omitempty"` // job status
+	Nodes    []GraphNode `json:"nodes"`
+	Edges    []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a step, or the compensation of a step, in a graph.
+// Status and timing are only reported for job graphs.
+type GraphNode struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Type         string     `json:"type"`
+	If           string     `json:"if,omitempty"`
+	Workflow     string     `json:"workflow,omitempty"`
+	Compensation bool       `json:"compensation,omitempty"`
+	StepID       string     `json:"stepId,omitempty"`
+	Status       StepStatus `json:"status,omitempty"`
+	StartedAt    *time.Time `json:"startedAt,omitempty"`
+	CompletedAt  *time.Time `json:"completedAt,omitempty"`
+	DurationMs   int64      `json:"durationMs,omitempty"` // until now for steps that have not completed
+	Cached       bool       `json:"cached,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	ChildJobID   string     `json:"childJobId,omitempty"`
+}
+
+// GraphEdge links a step to a step that depends on it, or to its compensation
+type GraphEdge struct {
+	From         string `json:"from"`
+	To           string `json:"to"`
+	Compensation bool   `json:"compensation,omitempty"`
+}
+
+// CreateMatrixRequest represents a request to run a workflow across parameter axes
+type CreateMatrixRequest struct {
+	Workflow string                   `json:"workflow"` // name or name@version
+	Input    map[string]interface{}   `json:"input"`    // input shared by every job
+	Axes     map[string][]interface{} `json:"axes"`     // input key -> values to try, e.g. {"model": ["qwen2.5-coder:7b", "gpt-4o-mini"]}
+	NoCache  bool                     `json:"noCache,omitempty"`
+	Budget   *JobBudget               `json:"budget,omitempty"` // applies to every job on its own
+}
+
+// MatrixJob is one job of a matrix and the axis values it runs with
+type MatrixJob struct {
+	ID     string                 `json:"id"`
+	Params map[string]interface{} `json:"params"`
+}
+
+// CreateMatrixResponse represents a submitted matrix
+type CreateMatrixResponse struct {
+	ID              string      `json:"id"`
+	Workflow        string      `json:"workflow"`
+	WorkflowVersion int         `json:"workflowVersion"`
+	Jobs            []MatrixJob `json:"jobs"`
+}
+
+// MatrixReportResponse compares the jobs of a matrix
+type MatrixReportResponse struct {
+	ID               string      `json:"id"`
+	Workflow         string      `json:"workflow"`
+	WorkflowVersion  int         `json:"workflowVersion"`
+	Axes             []string    `json:"axes"`
+	Scores           []string    `json:"scores"` // steps that reported a score
+	Finished         bool        `json:"finished"`
+	ReportArtifactID string      `json:"reportArtifactId,omitempty"` // markdown report, once every job finished
+	CreatedAt        time.Time   `json:"createdAt"`
+	CompletedAt      *time.Time  `json:"completedAt,omitempty"`
+	Rows             []MatrixRow `json:"rows"`
+}
+
+// MatrixRow reports how one job of a matrix did.
+// Tokens and cost add up the usage reported by its steps; scores are by step name.
+type MatrixRow struct {
+	JobID            string                 `json:"jobId"`
+	Params           map[string]interface{} `json:"params"`
+	Status           JobStatus              `json:"status"`
+	DurationMs       int64                  `json:"durationMs"`
+	PromptTokens     int                    `json:"promptTokens"`
+	CompletionTokens int                    `json:"completionTokens"`
+	TotalTokens      int                    `json:"totalTokens"`
+	CostUSD          float64                `json:"costUsd"`
+	Scores           map[string]float64     `json:"scores"`
+	Error            string                 `json:"error,omitempty"`
+}
+
+// TriggerFiring records a workflow trigger matching an event: the job it started, or why none was started
+type TriggerFiring struct {
+	ID              string    `json:"id"`
+	Workflow        string    `json:"workflow"` // the workflow the trigger belongs to
+	WorkflowVersion int       `json:"workflowVersion"`
+	Trigger         string    `json:"trigger"`
+	EventType       string    `json:"eventType"` // job.succeeded, job.failed, job.cancelled, artifact.created or webhook
+	EventID         string    `json:"eventId,omitempty"`
+	SourceJobID     string    `json:"sourceJobId,omitempty"` // the job the event is about
+	ArtifactID      string    `json:"artifactId,omitempty"`
+	Webhook         string    `json:"webhook,omitempty"`
+	JobID           string    `json:"jobId,omitempty"` // the started job
+	Depth           int       `json:"depth"`           // length of the trigger chain that led to the job
+	Status          string    `json:"status"`          // started, skipped or failed
+	Error           string    `json:"error,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// TriggerFiringListResponse represents a list of trigger firings
+type TriggerFiringListResponse struct {
+	Firings []TriggerFiring `json:"firings"`
+}
+
+// WebhookResponse lists the trigger firings caused by a webhook
+type WebhookResponse struct {
+	Webhook string          `json:"webhook"`
+	Firings []TriggerFiring `json:"firings"` // empty when every listening trigger's condition was false
+}
+
+// LLMCall is one attempt of an LLM request. Attempts with the same requestId belong to one
+// request that fell back from provider to provider; the succeeded one produced the output.
+type LLMCall struct {
+	ID        string    `json:"id"`
+	RequestID string    `json:"requestId"`
+	JobID     string    `json:"jobId,omitempty"`
+	StepID    string    `json:"stepId,omitempty"`
+	Agent     string    `json:"agent,omitempty"`
+	Attempt   int       `json:"attempt"` // 1 for the first provider of the route
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model,omitempty"`
+	Status    string    `json:"status"`              // succeeded, failed, skipped (circuit breaker open) or cached
+	ErrorKind string    `json:"errorKind,omitempty"` // rate_limit, context_length, model_not_found, auth, transient, invalid_request, circuit_open, ...
+	Error     string    `json:"error,omitempty"`
+	LatencyMs int64     `json:"latencyMs"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	TotalTokens      int     `json:"totalTokens"`
+	CostUSD          float64 `json:"costUsd"`
+}
+
+// LLMCallListResponse represents a list of LLM calls
+type LLMCallListResponse struct {
+	Calls []LLMCall `json:"calls"`
+}
+
+// LLMUsage adds up LLM calls
+type LLMUsage struct {
+	Calls            int64   `json:"calls"` // attempts sent to a provider; cache hits do not count
+	PromptTokens     int64   `json:"promptTokens"`
+	CompletionTokens int64   `json:"completionTokens"`
+	TotalTokens      int64   `json:"totalTokens"`
+	CostUSD          float64 `json:"costUsd"`
+	LatencyMs        int64   `json:"latencyMs"` // summed over the calls
+}
+
+// StepUsage is the LLM usage of one step
+type StepUsage struct {
+	JobID  string   `json:"jobId"`
+	StepID string   `json:"stepId"`
+	Step   string   `json:"step"`
+	Usage  LLMUsage `json:"usage"`
+}
+
+// ModelUsage is the LLM usage of one provider's model
+type ModelUsage struct {
+	Provider string   `json:"provider"`
+	Model    string   `json:"model"`
+	Usage    LLMUsage `json:"usage"`
+}
+
+// JobUsageResponse is the LLM usage of a job, including the jobs its sub-workflow steps started
+type JobUsageResponse struct {
+	JobID          string       `json:"jobId"`
+	Workflow       string       `json:"workflow"`
+	Budget         *JobBudget   `json:"budget,omitempty"`
+	BudgetExceeded string       `json:"budgetExceeded,omitempty"` // the limit the job reached, if any
+	Total          LLMUsage     `json:"total"`
+	Steps          []StepUsage  `json:"steps"`
+	Models         []ModelUsage `json:"models"`
+}
+
+// UsageGroup is the LLM usage of one group; only the grouping fields are set
+type UsageGroup struct {
+	Workflow string   `json:"workflow,omitempty"`
+	Agent    string   `json:"agent,omitempty"`
+	Provider string   `json:"provider,omitempty"`
+	Model    string   `json:"model,omitempty"`
+	Day      string   `json:"day,omitempty"` // YYYY-MM-DD
+	JobID    string   `json:"jobId,omitempty"`
+	Usage    LLMUsage `json:"usage"`
+}
+
+// UsageResponse is LLM usage grouped by the requested fields
+type UsageResponse struct {
+	GroupBy []string     `json:"groupBy"`
+	Groups  []UsageGroup `json:"groups"`
+	Total   LLMUsage     `json:"total"`
+}
+
 // ValidateWorkflowRequest represents a workflow validation request
 type ValidateWorkflowRequest struct {
 	Workflow string                 `json:"workflow"`
@@ -451,8 +909,22 @@ type ValidateWorkflowRequest struct {
 
 // ValidateWorkflowResponse represents workflow validation response
 type ValidateWorkflowResponse struct {
-	Valid  bool     `json:"valid"`
-	Errors []string `json:"errors,omitempty"`
+	Valid   bool                   `json:"valid"`
+	Errors  []string               `json:"errors,omitempty"`
+	Details []InputError           `json:"details,omitempty"` // input violations by JSON pointer
+	Input   map[string]interface{} `json:"input,omitempty"`   // input with schema defaults applied
+}
+
+// InputError describes a job input value that violates the workflow's inputSchema
+type InputError struct {
+	Path    string `json:"path"` // JSON pointer into the input ("" is the input itself)
+	Message string `json:"message"`
+}
+
+// InvalidInputResponse is returned when a job's input fails validation
+type InvalidInputResponse struct {
+	Error  string       `json:"error"`
+	Errors []InputError `json:"errors"`
 }
 
 // Artifact Models
@@ -466,6 +938,7 @@ type Artifact struct {
 	Name      string      `json:"name"`
 	Size      int64       `json:"size"`
 	Path      string      `json:"path,omitempty"`
+	Digest    string      `json:"digest,omitempty"`
 	CreatedAt time.Time   `json:"createdAt"`
 }
 
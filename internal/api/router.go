@@ -9,6 +9,7 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger"
 	
 	"agent-project-manager/internal/obs"
+	"agent-project-manager/internal/orchestrator"
 	"agent-project-manager/internal/repository"
 	"agent-project-manager/internal/state"
 )
@@ -18,11 +19,14 @@ var (
 	EnableTracing bool
 	// PrometheusMetricsPath is the HTTP path for Prometheus metrics endpoint (empty to disable)
 	PrometheusMetricsPath string
+	// AuthToken is the bearer token required by endpoints that modify configuration
+	AuthToken string
 )
 
 // Router returns a new HTTP router with all routes configured.
-// It accepts a repository for database operations.
-func Router(repo state.Repository) http.Handler {
+// It accepts a repository for database operations and the orchestrator
+// that drives job execution.
+func Router(repo state.Repository, orch *orchestrator.Orchestrator) http.Handler {
 	r := chi.NewRouter()
 
 	// Middleware
@@ -76,24 +80,37 @@ func Router(repo state.Repository) http.Handler {
 		agentRepo := repository.NewAgentRepository(db)
 		stepRepo := repository.NewStepRepository(db)
 		workflowRepo := repository.NewWorkflowRepository(db)
+		versionRepo := repository.NewWorkflowVersionRepository(db)
 		artifactRepo := repository.NewArtifactRepository(db)
 		queueRepo := repository.NewQueueRepository(db)
+		triggerRepo := repository.NewTriggerFiringRepository(db)
+		llmCallRepo := repository.NewLLMCallRepository(db)
+		promptRepo := repository.NewPromptRepository(db)
 
 		// Jobs endpoints
 		r.Route("/jobs", func(r chi.Router) {
-			r.Post("/", handleCreateJob(jobRepo))
+			r.Post("/", handleCreateJob(jobRepo, workflowRepo, versionRepo))
 			r.Get("/", handleListJobs(jobRepo))
 			r.Get("/{jobId}", handleGetJob(jobRepo))
-			r.Delete("/{jobId}", handleDeleteJob(jobRepo))
+			r.Delete("/{jobId}", handleDeleteJob(orch))
 			r.Post("/{jobId}/retry", handleRetryJob(jobRepo))
+			r.Post("/{jobId}/rerun", handleRerunJob(orch))
 			r.Get("/{jobId}/events", handleJobEvents(jobRepo))
 			r.Get("/{jobId}/logs", handleJobLogs(jobRepo))
 			r.Get("/{jobId}/result", handleJobResult(jobRepo))
+			r.Get("/{jobId}/graph", handleJobGraph(orch))
+			r.Get("/{jobId}/llm-calls", handleJobLLMCalls(jobRepo, llmCallRepo))
+			r.Get("/{jobId}/usage", handleJobUsage(jobRepo, llmCallRepo))
 
 			// Job steps
 			r.Get("/{jobId}/steps", handleJobSteps(stepRepo))
 			r.Get("/{jobId}/steps/{stepId}", handleGetStep(stepRepo))
 			r.Get("/{jobId}/steps/{stepId}/logs", handleStepLogs(stepRepo))
+			r.Post("/{jobId}/steps/{stepId}/approve", handleApproveStep(orch))
+			r.Post("/{jobId}/steps/{stepId}/reject", handleRejectStep(orch))
+
+			// Job signals
+			r.Post("/{jobId}/signals/{name}", handleSendSignal(orch))
 		})
 
 		// Runs endpoints
@@ -108,9 +125,50 @@ func Router(repo state.Repository) http.Handler {
 		r.Route("/workflows", func(r chi.Router) {
 			r.Get("/", handleListWorkflows(workflowRepo))
 			r.Get("/{name}", handleGetWorkflow(workflowRepo))
-			r.Post("/validate", handleValidateWorkflow(workflowRepo))
+			r.Get("/{name}/versions", handleListWorkflowVersions(versionRepo))
+			r.Get("/{name}/versions/{version}", handleGetWorkflowVersion(versionRepo))
+			r.Get("/{name}/diff", handleDiffWorkflowVersions(versionRepo))
+			r.Post("/{name}/plan", handlePlanWorkflow(orch))
+			r.Get("/{name}/graph", handleWorkflowGraph(orch))
+			r.Get("/{name}/usage", handleWorkflowUsage(llmCallRepo))
+
+			r.Group(func(r chi.Router) {
+				r.Use(RequireAuth)
+				r.Post("/", handleCreateWorkflow(workflowRepo, versionRepo, orch))
+				r.Put("/{name}", handleUpdateWorkflow(workflowRepo, versionRepo, orch))
+				r.Delete("/{name}", handleDeleteWorkflow(workflowRepo))
+				r.Post("/{name}/publish", handlePublishWorkflow(workflowRepo, versionRepo))
+			})
+			r.Post("/validate", handleValidateWorkflow(workflowRepo, versionRepo))
+		})
+
+		// Prompt registry endpoints
+		r.Route("/prompts", func(r chi.Router) {
+			r.Get("/", handleListPrompts(promptRepo))
+			r.Get("/{name}/versions", handleListPromptVersions(promptRepo))
+			r.Get("/{name}/versions/{version}", handleGetPromptVersion(promptRepo))
+			r.Get("/{name}/diff", handleDiffPromptVersions(promptRepo))
+			r.Post("/{name}/preview", handlePreviewPrompt(promptRepo))
+
+			r.Group(func(r chi.Router) {
+				r.Use(RequireAuth)
+				r.Post("/{name}/versions", handlePublishPrompt(promptRepo))
+			})
 		})
 
+		// Matrix endpoints
+		r.Route("/matrices", func(r chi.Router) {
+			r.Post("/", handleCreateMatrix(orch))
+			r.Get("/{matrixId}", handleGetMatrix(orch))
+		})
+
+		// LLM usage across jobs
+		r.Get("/usage", handleUsage(llmCallRepo))
+
+		// Trigger endpoints
+		r.Post("/webhooks/{name}", handleWebhook(orch))
+		r.Get("/triggers/firings", handleListTriggerFirings(triggerRepo))
+
 		// Artifacts endpoints
 		r.Route("/artifacts", func(r chi.Router) {
 			r.Get("/", handleListArtifacts(artifactRepo))
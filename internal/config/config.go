@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -19,6 +20,10 @@ type Config struct {
 	Artifacts ArtifactsConfig `yaml:"artifacts"`
 	LLM       LLMConfig       `yaml:"llm"`
 	Auth      AuthConfig      `yaml:"auth"`
+	Workflows WorkflowsConfig `yaml:"workflows"`
+	Prompts   PromptsConfig   `yaml:"prompts"`
+	Tools     ToolsConfig     `yaml:"tools"`
+	Triggers  TriggersConfig  `yaml:"triggers"`
 	Logger    LoggerConfig    `yaml:"logger"`
 	Obs       ObsConfig       `yaml:"obs"`
 }
@@ -32,6 +37,28 @@ type AuthConfig struct {
 	Token string `yaml:"token"`
 }
 
+type WorkflowsConfig struct {
+	Dir          string `yaml:"dir"`          // directory of workflow YAML files synced into the database; empty disables it
+	ScanInterval string `yaml:"scanInterval"` // how often to rescan the directory, e.g. "10s" (default: 10s)
+}
+
+type PromptsConfig struct {
+	Dir          string `yaml:"dir"`          // directory of prompt YAML files synced into the database; empty disables it
+	ScanInterval string `yaml:"scanInterval"` // how often to rescan the directory, e.g. "10s" (default: 10s)
+}
+
+// ToolsConfig configures the tools llm steps let the model call
+type ToolsConfig struct {
+	Root          string   `yaml:"root"`          // directory the file tools are confined to (default: artifacts.workDir)
+	TestCommand   []string `yaml:"testCommand"`   // command run_tests runs, followed by the packages (default: ["go", "test"])
+	MaxIterations int      `yaml:"maxIterations"` // model turns a step may spend calling tools (default: 10)
+	MaxToolTime   string   `yaml:"maxToolTime"`   // total time a step's tool calls may take, e.g. "5m" (default: 5m)
+}
+
+type TriggersConfig struct {
+	MaxDepth int `yaml:"maxDepth"` // longest chain of jobs started by workflow triggers (default: 5)
+}
+
 type StateConfig struct {
 	ConnectionString string `yaml:"connectionString"`
 }
@@ -45,19 +72,177 @@ type ArtifactsConfig struct {
 }
 
 type LLMConfig struct {
-	Provider string       `yaml:"provider"`
-	OpenAI   OpenAIConfig  `yaml:"openai"`
-	Ollama   OllamaConfig  `yaml:"ollama"`
+	Provider  string                    `yaml:"provider"` // name of the default provider
+	OpenAI    OpenAIConfig              `yaml:"openai"`
+	Ollama    OllamaConfig              `yaml:"ollama"`
+	Providers map[string]ProviderConfig `yaml:"providers"` // named providers; steps refer to them by name
+
+	// Routes are fallback chains by agent name; "default" covers agents without a route.
+	// Without routes, requests go to the default provider alone.
+	Routes         map[string][]RouteTarget `yaml:"routes"`
+	CircuitBreaker CircuitBreakerConfig     `yaml:"circuitBreaker"`
+
+	// Prices is the price table for cost accounting, keyed by "provider/model" or by model
+	// name alone; calls to models that are not listed cost nothing
+	Prices map[string]ModelPrice `yaml:"prices"`
+
+	Cache LLMCacheConfig `yaml:"cache"`
+
+	// Cassettes holds the named cassettes jobs submitted with a cassette record to or replay from
+	Cassettes CassettesConfig `yaml:"cassettes"`
+}
+
+// CassettesConfig configures per-job cassettes
+type CassettesConfig struct {
+	Dir string `yaml:"dir"` // directory of the cassette files, <name>.json; empty disables per-job cassettes
+}
+
+// Cassette modes
+const (
+	CassetteRecord = "record" // send requests to the provider and write each request and its outcome to the cassette
+	CassetteReplay = "replay" // answer requests from the cassette only; a request it does not hold fails
+)
+
+// CassetteConfig puts a provider in record or replay mode
+type CassetteConfig struct {
+	Mode string `yaml:"mode"` // record or replay; empty talks to the provider as usual
+	Path string `yaml:"path"` // the cassette file, e.g. "testdata/cassettes/ollama.json"
+}
+
+// LLMCacheConfig configures the LLM response cache. Only requests with temperature 0
+// are answered from it, unless a step forces the cache.
+type LLMCacheConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	TTL       string `yaml:"ttl"`       // how long a response is reused, e.g. "24h" (default: 24h)
+	MaxSizeMB int    `yaml:"maxSizeMB"` // responses beyond this size are evicted, least recently used first (default: 100)
+}
+
+// ModelPrice is what a model costs, in USD per million tokens
+type ModelPrice struct {
+	Input  float64 `yaml:"input"`  // prompt tokens
+	Output float64 `yaml:"output"` // completion tokens
+}
+
+// RouteTarget is one provider of a fallback chain
+type RouteTarget struct {
+	Provider string `yaml:"provider"`
+	Model    string `yaml:"model"` // empty uses the provider's default model
+}
+
+// CircuitBreakerConfig configures the circuit breaker every provider has.
+// A breaker opens when, among its last window calls, the share of failed or of slow calls
+// reaches its threshold; requests then skip the provider until openFor has passed and a
+// probe call succeeds.
+type CircuitBreakerConfig struct {
+	Window    int     `yaml:"window"`    // number of recent calls considered (default: 20)
+	MinCalls  int     `yaml:"minCalls"`  // calls needed before the breaker can open (default: 5)
+	ErrorRate float64 `yaml:"errorRate"` // share of failed calls that opens the breaker (default: 0.5)
+	SlowCall  string  `yaml:"slowCall"`  // calls taking longer count as slow, e.g. "2m" (default: 2m)
+	SlowRate  float64 `yaml:"slowRate"`  // share of slow calls that opens the breaker (default: 0.8)
+	OpenFor   string  `yaml:"openFor"`   // how long the breaker stays open before a probe, e.g. "30s" (default: 30s)
+}
+
+// JSON modes: how a provider is asked for output matching a JSON Schema
+const (
+	JSONModeSchema = "schema" // the API's native schema support (OpenAI json_schema, Ollama format)
+	JSONModeObject = "object" // the API's plain JSON mode, with the schema in the prompt
+	JSONModePrompt = "prompt" // the schema in the prompt only, for servers without a JSON mode
+)
+
+// Tool modes: how a provider is offered tools the model may call
+const (
+	ToolModeNative = "native" // the API's function calling
+	ToolModePrompt = "prompt" // a text protocol in the prompt, for models without function calling
+)
+
+// LLM provider types
+const (
+	ProviderTypeOpenAI = "openai" // OpenAI or any server speaking its Chat Completions API (LM Studio, llama.cpp, vLLM)
+	ProviderTypeOllama = "ollama" // the native Ollama API
+)
+
+// ProviderConfig configures one named LLM provider
+type ProviderConfig struct {
+	Type       string            `yaml:"type"`       // openai or ollama
+	BaseURL    string            `yaml:"baseURL"`    // empty uses the type's default
+	Headers    map[string]string `yaml:"headers"`    // extra HTTP headers sent with every request
+	APIKey     string            `yaml:"apiKey"`     // the key itself; prefer apiKeyEnv or apiKeyFile
+	APIKeyEnv  string            `yaml:"apiKeyEnv"`  // environment variable holding the key
+	APIKeyFile string            `yaml:"apiKeyFile"` // file holding the key, e.g. a mounted secret
+	Model      string            `yaml:"model"`      // default model
+	JSONMode   string            `yaml:"jsonMode"`   // how structured output is requested: schema (default), object or prompt
+	ToolMode   string            `yaml:"toolMode"`   // how tools are offered: native (default) or prompt
+
+	// Ollama only
+	KeepAlive string                 `yaml:"keepAlive"`
+	NumCtx    int                    `yaml:"numCtx"`
+	Options   map[string]interface{} `yaml:"options"`
+
+	CircuitBreaker *CircuitBreakerConfig `yaml:"circuitBreaker"` // overrides llm.circuitBreaker for this provider
+	Cassette       CassetteConfig        `yaml:"cassette"`       // records or replays the provider's traffic
+}
+
+// ResolveAPIKey reads the provider's API key from its configured source.
+// An empty key is not an error: local servers usually do not need one.
+func (p ProviderConfig) ResolveAPIKey() (string, error) {
+	switch {
+	case p.APIKeyEnv != "":
+		return os.Getenv(p.APIKeyEnv), nil
+	case p.APIKeyFile != "":
+		data, err := os.ReadFile(p.APIKeyFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read API key file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	default:
+		return p.APIKey, nil
+	}
+}
+
+// AllProviders returns the named providers together with the openai and ollama
+// blocks, which are providers named "openai" and "ollama" unless a named
+// provider takes their name
+func (c LLMConfig) AllProviders() map[string]ProviderConfig {
+	all := map[string]ProviderConfig{
+		ProviderTypeOpenAI: {
+			Type:     ProviderTypeOpenAI,
+			BaseURL:  c.OpenAI.BaseURL,
+			APIKey:   c.OpenAI.APIKey,
+			Model:    c.OpenAI.Model,
+			Cassette: c.OpenAI.Cassette,
+		},
+		ProviderTypeOllama: {
+			Type:      ProviderTypeOllama,
+			BaseURL:   c.Ollama.BaseURL,
+			Model:     c.Ollama.Model,
+			KeepAlive: c.Ollama.KeepAlive,
+			NumCtx:    c.Ollama.NumCtx,
+			Options:   c.Ollama.Options,
+			Cassette:  c.Ollama.Cassette,
+		},
+	}
+	for name, p := range c.Providers {
+		all[name] = p
+	}
+	return all
 }
 
 type OpenAIConfig struct {
-	APIKey string `yaml:"apiKey"`
-	Model  string `yaml:"model"`
+	APIKey  string `yaml:"apiKey"`
+	Model   string `yaml:"model"`
+	BaseURL string `yaml:"baseURL"` // empty uses https://api.openai.com/v1
+
+	Cassette CassetteConfig `yaml:"cassette"`
 }
 
 type OllamaConfig struct {
-	BaseURL string `yaml:"baseURL"`
-	Model   string `yaml:"model"`
+	BaseURL   string                 `yaml:"baseURL"`
+	Model     string                 `yaml:"model"`
+	KeepAlive string                 `yaml:"keepAlive"` // how long the model stays loaded after a request, e.g. "10m"; "-1" keeps it loaded
+	NumCtx    int                    `yaml:"numCtx"`    // context window in tokens; zero uses the model's default
+	Options   map[string]interface{} `yaml:"options"`   // other model options, e.g. num_thread or top_p
+
+	Cassette CassetteConfig `yaml:"cassette"`
 }
 
 type LoggerConfig struct {
@@ -172,18 +357,71 @@ func applyEnvironmentOverrides(c *Config) {
 	if v := os.Getenv("LLM_OPENAI_MODEL"); v != "" {
 		c.LLM.OpenAI.Model = v
 	}
+	if v := os.Getenv("LLM_OPENAI_BASE_URL"); v != "" {
+		c.LLM.OpenAI.BaseURL = v
+	}
 	if v := os.Getenv("LLM_OLLAMA_BASE_URL"); v != "" {
 		c.LLM.Ollama.BaseURL = v
 	}
 	if v := os.Getenv("LLM_OLLAMA_MODEL"); v != "" {
 		c.LLM.Ollama.Model = v
 	}
+	if v := os.Getenv("LLM_OLLAMA_KEEP_ALIVE"); v != "" {
+		c.LLM.Ollama.KeepAlive = v
+	}
+	if v := os.Getenv("LLM_OLLAMA_NUM_CTX"); v != "" {
+		if numCtx, err := strconv.Atoi(v); err == nil {
+			c.LLM.Ollama.NumCtx = numCtx
+		}
+	}
+	if v := os.Getenv("LLM_CACHE_ENABLED"); v != "" {
+		c.LLM.Cache.Enabled = strings.ToLower(v) == "true"
+	}
+	if v := os.Getenv("LLM_CASSETTES_DIR"); v != "" {
+		c.LLM.Cassettes.Dir = v
+	}
 
 	// Auth
 	if v := os.Getenv("AUTH_TOKEN"); v != "" {
 		c.Auth.Token = v
 	}
 
+	// Workflows
+	if v := os.Getenv("WORKFLOWS_DIR"); v != "" {
+		c.Workflows.Dir = v
+	}
+	if v := os.Getenv("WORKFLOWS_SCAN_INTERVAL"); v != "" {
+		c.Workflows.ScanInterval = v
+	}
+
+	// Prompts
+	if v := os.Getenv("PROMPTS_DIR"); v != "" {
+		c.Prompts.Dir = v
+	}
+	if v := os.Getenv("PROMPTS_SCAN_INTERVAL"); v != "" {
+		c.Prompts.ScanInterval = v
+	}
+
+	// Tools
+	if v := os.Getenv("TOOLS_ROOT"); v != "" {
+		c.Tools.Root = v
+	}
+	if v := os.Getenv("TOOLS_MAX_ITERATIONS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Tools.MaxIterations = n
+		}
+	}
+	if v := os.Getenv("TOOLS_MAX_TOOL_TIME"); v != "" {
+		c.Tools.MaxToolTime = v
+	}
+
+	// Triggers
+	if v := os.Getenv("TRIGGERS_MAX_DEPTH"); v != "" {
+		if depth, err := strconv.Atoi(v); err == nil {
+			c.Triggers.MaxDepth = depth
+		}
+	}
+
 	// Logger
 	if v := os.Getenv("LOGGER_LEVEL"); v != "" {
 		c.Logger.Level = v
@@ -236,5 +474,135 @@ func (c Config) Validate() error {
 	if c.State.ConnectionString == "" {
 		return errors.New("state.connectionString must be set (or STATE_CONNECTION_STRING/DATABASE_URL environment variable)")
 	}
+	if c.Workflows.ScanInterval != "" {
+		if _, err := time.ParseDuration(c.Workflows.ScanInterval); err != nil {
+			return fmt.Errorf("workflows.scanInterval: %w", err)
+		}
+	}
+	if c.Prompts.ScanInterval != "" {
+		if _, err := time.ParseDuration(c.Prompts.ScanInterval); err != nil {
+			return fmt.Errorf("prompts.scanInterval: %w", err)
+		}
+	}
+	if c.Tools.MaxIterations < 0 {
+		return errors.New("tools.maxIterations must not be negative")
+	}
+	if c.Tools.MaxToolTime != "" {
+		if _, err := time.ParseDuration(c.Tools.MaxToolTime); err != nil {
+			return fmt.Errorf("tools.maxToolTime: %w", err)
+		}
+	}
+	if c.Triggers.MaxDepth < 0 {
+		return errors.New("triggers.maxDepth must not be negative")
+	}
+	providers := c.LLM.AllProviders()
+	if c.LLM.Provider != "" {
+		if _, ok := providers[c.LLM.Provider]; !ok {
+			return fmt.Errorf("llm.provider: unknown provider %q", c.LLM.Provider)
+		}
+	}
+	for agent, route := range c.LLM.Routes {
+		if len(route) == 0 {
+			return fmt.Errorf("llm.routes.%s: route has no providers", agent)
+		}
+		for i, t := range route {
+			if _, ok := providers[t.Provider]; !ok {
+				return fmt.Errorf("llm.routes.%s[%d]: unknown provider %q", agent, i, t.Provider)
+			}
+		}
+	}
+	if err := c.LLM.CircuitBreaker.validate(); err != nil {
+		return fmt.Errorf("llm.circuitBreaker.%w", err)
+	}
+	if v := c.LLM.Cache.TTL; v != "" {
+		if _, err := time.ParseDuration(v); err != nil {
+			return fmt.Errorf("llm.cache.ttl: %w", err)
+		}
+	}
+	if c.LLM.Cache.MaxSizeMB < 0 {
+		return errors.New("llm.cache.maxSizeMB must not be negative")
+	}
+	for key, price := range c.LLM.Prices {
+		if price.Input < 0 || price.Output < 0 {
+			return fmt.Errorf("llm.prices.%s: prices must not be negative", key)
+		}
+	}
+	for name, p := range providers {
+		if err := p.validate(); err != nil {
+			if name == ProviderTypeOpenAI || name == ProviderTypeOllama {
+				if _, named := c.LLM.Providers[name]; !named {
+					return fmt.Errorf("llm.%s.%w", name, err)
+				}
+			}
+			return fmt.Errorf("llm.providers.%s.%w", name, err)
+		}
+	}
+	return nil
+}
+
+// validate checks a provider's settings; errors start with the offending field
+func (p ProviderConfig) validate() error {
+	if p.Type != ProviderTypeOpenAI && p.Type != ProviderTypeOllama {
+		return fmt.Errorf("type: unknown provider type %q (want %s or %s)", p.Type, ProviderTypeOpenAI, ProviderTypeOllama)
+	}
+	if v := p.KeepAlive; v != "" {
+		if _, err := strconv.Atoi(v); err != nil {
+			if _, err := time.ParseDuration(v); err != nil {
+				return fmt.Errorf("keepAlive: %q is neither a duration nor a number of seconds", v)
+			}
+		}
+	}
+	if p.NumCtx < 0 {
+		return errors.New("numCtx: must not be negative")
+	}
+	switch p.JSONMode {
+	case "", JSONModeSchema, JSONModeObject, JSONModePrompt:
+	default:
+		return fmt.Errorf("jsonMode: unknown mode %q (want %s, %s or %s)", p.JSONMode, JSONModeSchema, JSONModeObject, JSONModePrompt)
+	}
+	switch p.ToolMode {
+	case "", ToolModeNative, ToolModePrompt:
+	default:
+		return fmt.Errorf("toolMode: unknown mode %q (want %s or %s)", p.ToolMode, ToolModeNative, ToolModePrompt)
+	}
+	switch p.Cassette.Mode {
+	case "":
+	case CassetteRecord, CassetteReplay:
+		if p.Cassette.Path == "" {
+			return errors.New("cassette.path: must be set")
+		}
+	default:
+		return fmt.Errorf("cassette.mode: unknown mode %q (want %s or %s)", p.Cassette.Mode, CassetteRecord, CassetteReplay)
+	}
+	if p.CircuitBreaker != nil {
+		if err := p.CircuitBreaker.validate(); err != nil {
+			return fmt.Errorf("circuitBreaker.%w", err)
+		}
+	}
+	return nil
+}
+
+// validate checks the breaker settings; errors start with the offending field
+func (b CircuitBreakerConfig) validate() error {
+	if b.Window < 0 {
+		return errors.New("window: must not be negative")
+	}
+	if b.MinCalls < 0 {
+		return errors.New("minCalls: must not be negative")
+	}
+	if b.ErrorRate < 0 || b.ErrorRate > 1 {
+		return errors.New("errorRate: must be between 0 and 1")
+	}
+	if b.SlowRate < 0 || b.SlowRate > 1 {
+		return errors.New("slowRate: must be between 0 and 1")
+	}
+	for field, v := range map[string]string{"slowCall": b.SlowCall, "openFor": b.OpenFor} {
+		if v == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(v); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+	}
 	return nil
 }
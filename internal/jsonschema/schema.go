@@ -0,0 +1,271 @@
+// Package jsonschema validates JSON documents against a subset of JSON Schema draft 2020-12.
+//
+// Supported keywords:
+//
+//	type, enum, const                                   any instance
+//	minimum, maximum, exclusiveMinimum,
+//	exclusiveMaximum, multipleOf                        numbers
+//	minLength, maxLength, pattern, format               strings
+//	items, minItems, maxItems, uniqueItems              arrays
+//	properties, required, additionalProperties,
+//	minProperties, maxProperties                        objects
+//	allOf, anyOf, oneOf, not                            combinators
+//	$ref (local "#/..." pointers), $defs                references
+//	default                                             applied to missing object properties
+//
+// Annotation keywords (title, description, examples, ...) are accepted and ignored.
+// Any other keyword is rejected when the schema is compiled, so a constraint is
+// never silently unenforced.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Schema is a compiled JSON Schema
+type Schema struct {
+	root     interface{}
+	patterns map[string]*regexp.Regexp
+}
+
+// ValidationError describes a single constraint an instance violates
+type ValidationError struct {
+	Path    string `json:"path"` // JSON pointer into the instance ("" is the document root)
+	Message string `json:"message"`
+}
+
+// Error implements the error interface
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// annotations are keywords that carry no constraint
+var annotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "$anchor": true,
+	"title": true, "description": true, "examples": true,
+	"deprecated": true, "readOnly": true, "writeOnly": true,
+	"default": true, "$defs": true, "definitions": true,
+}
+
+// keywords are the supported constraint keywords
+var keywords = map[string]bool{
+	"type": true, "enum": true, "const": true,
+	"minimum": true, "maximum": true, "exclusiveMinimum": true, "exclusiveMaximum": true, "multipleOf": true,
+	"minLength": true, "maxLength": true, "pattern": true, "format": true,
+	"items": true, "minItems": true, "maxItems": true, "uniqueItems": true,
+	"properties": true, "required": true, "additionalProperties": true,
+	"minProperties": true, "maxProperties": true,
+	"allOf": true, "anyOf": true, "oneOf": true, "not": true,
+	"$ref": true,
+}
+
+// typeNames are the values allowed in "type"
+var typeNames = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true,
+	"number": true, "integer": true, "string": true,
+}
+
+// Compile checks a schema document and prepares it for validation
+func Compile(doc interface{}) (*Schema, error) {
+	s := &Schema{
+		root:     normalize(doc),
+		patterns: map[string]*regexp.Regexp{},
+	}
+	if err := s.check(s.root, ""); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// check verifies a (sub)schema at the given schema path
+func (s *Schema) check(schema interface{}, path string) error {
+	if _, ok := schema.(bool); ok {
+		return nil
+	}
+	m, ok := schema.(map[string]interface{})
+	if !ok {
+		return schemaError(path, "schema must be an object or a boolean")
+	}
+
+	for key, val := range m {
+		p := path + "/" + escape(key)
+		if annotations[key] {
+			if key == "$defs" || key == "definitions" {
+				defs, ok := val.(map[string]interface{})
+				if !ok {
+					return schemaError(p, "must be an object")
+				}
+				for name, def := range defs {
+					if err := s.check(def, p+"/"+escape(name)); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+		if !keywords[key] {
+			return schemaError(p, "unsupported keyword")
+		}
+
+		switch key {
+		case "type":
+			names, ok := stringList(val)
+			if !ok || len(names) == 0 {
+				return schemaError(p, "must be a type name or a list of type names")
+			}
+			for _, n := range names {
+				if !typeNames[n] {
+					return schemaError(p, fmt.Sprintf("unknown type %q", n))
+				}
+			}
+		case "enum":
+			if _, ok := val.([]interface{}); !ok {
+				return schemaError(p, "must be an array")
+			}
+		case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum":
+			if _, ok := val.(float64); !ok {
+				return schemaError(p, "must be a number")
+			}
+		case "multipleOf":
+			if f, ok := val.(float64); !ok || f <= 0 {
+				return schemaError(p, "must be a number greater than 0")
+			}
+		case "minLength", "maxLength", "minItems", "maxItems", "minProperties", "maxProperties":
+			if f, ok := val.(float64); !ok || f < 0 || f != float64(int(f)) {
+				return schemaError(p, "must be a non-negative integer")
+			}
+		case "pattern":
+			str, ok := val.(string)
+			if !ok {
+				return schemaError(p, "must be a string")
+			}
+			re, err := regexp.Compile(str)
+			if err != nil {
+				return schemaError(p, fmt.Sprintf("invalid pattern: %v", err))
+			}
+			s.patterns[str] = re
+		case "format":
+			if _, ok := val.(string); !ok {
+				return schemaError(p, "must be a string")
+			}
+		case "uniqueItems":
+			if _, ok := val.(bool); !ok {
+				return schemaError(p, "must be a boolean")
+			}
+		case "required":
+			if _, ok := stringList(val); !ok {
+				return schemaError(p, "must be an array of strings")
+			}
+		case "properties":
+			props, ok := val.(map[string]interface{})
+			if !ok {
+				return schemaError(p, "must be an object")
+			}
+			for name, prop := range props {
+				if err := s.check(prop, p+"/"+escape(name)); err != nil {
+					return err
+				}
+			}
+		case "items", "additionalProperties", "not":
+			if err := s.check(val, p); err != nil {
+				return err
+			}
+		case "allOf", "anyOf", "oneOf":
+			list, ok := val.([]interface{})
+			if !ok || len(list) == 0 {
+				return schemaError(p, "must be a non-empty array")
+			}
+			for i, sub := range list {
+				if err := s.check(sub, p+"/"+strconv.Itoa(i)); err != nil {
+					return err
+				}
+			}
+		case "$ref":
+			ref, ok := val.(string)
+			if !ok {
+				return schemaError(p, "must be a string")
+			}
+			if _, err := s.resolve(ref); err != nil {
+				return schemaError(p, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// resolve follows a local "#/..." reference from the document root
+func (s *Schema) resolve(ref string) (interface{}, error) {
+	if ref != "#" && !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("only local references (#/...) are supported, got %q", ref)
+	}
+	cur := s.root
+	for _, tok := range strings.Split(strings.TrimPrefix(ref, "#"), "/")[1:] {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unresolvable reference %q", ref)
+		}
+		cur, ok = m[unescape(tok)]
+		if !ok {
+			return nil, fmt.Errorf("unresolvable reference %q", ref)
+		}
+	}
+	return cur, nil
+}
+
+// schemaError reports a problem in the schema itself
+func schemaError(path, msg string) error {
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Errorf("invalid schema at %s: %s", path, msg)
+}
+
+// stringList accepts a string or an array of strings
+func stringList(v interface{}) ([]string, bool) {
+	switch val := v.(type) {
+	case string:
+		return []string{val}, true
+	case []interface{}:
+		out := make([]string, 0, len(val))
+		for _, item := range val {
+			str, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, str)
+		}
+		return out, true
+	default:
+		return nil, false
+	}
+}
+
+// normalize round-trips v through JSON so numbers are float64 and maps are map[string]interface{}
+func normalize(v interface{}) interface{} {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+// escape escapes a JSON pointer reference token (RFC 6901)
+func escape(tok string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(tok)
+}
+
+// unescape reverses escape
+func unescape(tok string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(tok)
+}
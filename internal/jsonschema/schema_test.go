@@ -0,0 +1,159 @@
+package jsonschema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type obj = map[string]interface{}
+type arr = []interface{}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   obj
+		instance interface{}
+		errs     []string // ValidationError.Error() of every violation, in order
+	}{
+		{"type string", obj{"type": "string"}, "x", nil},
+		{"type mismatch", obj{"type": "string"}, 3, []string{"expected string, got integer"}},
+		{"integer is a number", obj{"type": "number"}, 3, nil},
+		{"number is not an integer", obj{"type": "integer"}, 1.5, []string{"expected integer, got number"}},
+		{"type list", obj{"type": arr{"string", "null"}}, nil, nil},
+		{"type list mismatch", obj{"type": arr{"string", "null"}}, true, []string{"expected string or null, got boolean"}},
+
+		{"required present", obj{"type": "object", "required": arr{"repo"}}, obj{"repo": "r"}, nil},
+		{"required missing", obj{"type": "object", "required": arr{"repo", "a/b"}}, obj{}, []string{"/a~1b: is required", "/repo: is required"}},
+
+		{"enum", obj{"enum": arr{"low", "high", 3}}, 3, nil},
+		{"enum mismatch", obj{"enum": arr{"low", "high", 3}}, "mid", []string{`must be one of ["low", "high", 3]`}},
+		{"const mismatch", obj{"const": "x"}, "y", []string{`must be "x"`}},
+
+		{"minimum", obj{"minimum": 1}, 1, nil},
+		{"below minimum", obj{"minimum": 1}, 0.5, []string{"must be >= 1"}},
+		{"above maximum", obj{"maximum": 10}, 11, []string{"must be <= 10"}},
+		{"exclusive bounds", obj{"exclusiveMinimum": 0, "exclusiveMaximum": 1}, 1, []string{"must be < 1"}},
+		{"multipleOf", obj{"multipleOf": 0.1}, 0.3, nil},
+		{"not a multiple", obj{"multipleOf": 2}, 3, []string{"must be a multiple of 2"}},
+		{"string length", obj{"minLength": 2, "maxLength": 3}, "héé", nil},
+		{"too short", obj{"minLength": 2}, "a", []string{"must be at least 2 characters long"}},
+		{"pattern mismatch", obj{"pattern": "^[a-z]+$"}, "aB", []string{`must match pattern "^[a-z]+$"`}},
+		{"format uri", obj{"format": "uri"}, "https://example.com/x", nil},
+		{"format uri mismatch", obj{"format": "uri"}, "example.com/x", []string{"must be a valid uri"}},
+		{"format uuid mismatch", obj{"format": "uuid"}, "1234", []string{"must be a valid uuid"}},
+		{"unknown format", obj{"format": "hostname"}, "anything", nil},
+
+		{"items", obj{"items": obj{"type": "string"}}, arr{"a", "b"}, nil},
+		{"items mismatch", obj{"items": obj{"type": "string"}}, arr{"a", 2, true}, []string{"/1: expected string, got integer", "/2: expected string, got boolean"}},
+		{"item count", obj{"minItems": 1, "maxItems": 2}, arr{}, []string{"must contain at least 1 items"}},
+		{"unique items", obj{"uniqueItems": true}, arr{"a", "b", "a"}, []string{"items 0 and 2 are equal"}},
+
+		{"additionalProperties false", obj{"properties": obj{"a": true}, "additionalProperties": false}, obj{"a": 1, "b": 2}, []string{"/b: is not an allowed property"}},
+		{"additionalProperties schema", obj{"properties": obj{"a": true}, "additionalProperties": obj{"type": "string"}}, obj{"a": 1, "b": 2}, []string{"/b: expected string, got integer"}},
+		{"nested properties", obj{"properties": obj{"opts": obj{"properties": obj{"depth": obj{"type": "integer"}}}}}, obj{"opts": obj{"depth": "deep"}}, []string{"/opts/depth: expected integer, got string"}},
+		{"property count", obj{"maxProperties": 1}, obj{"a": 1, "b": 2}, []string{"must have at most 1 properties"}},
+
+		{"anyOf", obj{"anyOf": arr{obj{"type": "string"}, obj{"type": "integer"}}}, 2, nil},
+		{"anyOf mismatch", obj{"anyOf": arr{obj{"type": "string"}, obj{"type": "integer"}}}, 2.5, []string{"must match at least one of the anyOf schemas"}},
+		{"oneOf matching two", obj{"oneOf": arr{obj{"type": "number"}, obj{"type": "integer"}}}, 3, []string{"must match exactly one of the oneOf schemas (matched 2)"}},
+		{"allOf", obj{"allOf": arr{obj{"minimum": 1}, obj{"maximum": 2}}}, 3, []string{"must be <= 2"}},
+		{"not", obj{"not": obj{"type": "null"}}, nil, []string{"must not match the schema in not"}},
+		{"false schema", obj{"properties": obj{"a": false}}, obj{"a": 1}, []string{"/a: no value is allowed here"}},
+
+		{"$ref", obj{"$defs": obj{"level": obj{"enum": arr{"low", "high"}}}, "properties": obj{"level": obj{"$ref": "#/$defs/level"}}}, obj{"level": "mid"}, []string{`/level: must be one of ["low", "high"]`}},
+		{"recursive $ref", obj{"properties": obj{"child": obj{"$ref": "#"}}, "required": arr{"name"}}, obj{"name": "a", "child": obj{"child": obj{}}}, []string{"/child/child/name: is required", "/child/name: is required"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := Compile(tc.schema)
+			if err != nil {
+				t.Fatalf("Compile: %v", err)
+			}
+			_, errs := s.Validate(tc.instance)
+			var got []string
+			for _, e := range errs {
+				got = append(got, e.Error())
+			}
+			if !reflect.DeepEqual(got, tc.errs) {
+				t.Errorf("errors %q, want %q", got, tc.errs)
+			}
+		})
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	s, err := Compile(obj{
+		"type":     "object",
+		"required": arr{"repo", "branch"},
+		"properties": obj{
+			"repo":   obj{"type": "string"},
+			"branch": obj{"type": "string", "default": "main"},
+			"depth":  obj{"type": "integer", "minimum": 1, "default": 3},
+			"opts":   obj{"type": "object", "default": obj{}, "properties": obj{"verbose": obj{"default": true}}},
+			"level":  obj{"anyOf": arr{obj{"properties": obj{"probe": obj{"default": "no"}}}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := obj{"repo": "r", "depth": 5, "level": obj{}}
+	out, errs := s.Validate(input)
+	if len(errs) != 0 {
+		t.Fatalf("errors %v", errs)
+	}
+	want := obj{"repo": "r", "branch": "main", "depth": 5.0, "opts": obj{"verbose": true}, "level": obj{}}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("validated %v, want %v", out, want)
+	}
+	if _, ok := input["branch"]; ok {
+		t.Error("Validate filled defaults into the caller's instance")
+	}
+
+	// A default satisfies required; the copy is returned even when invalid
+	out, errs = s.Validate(obj{"depth": 0})
+	if len(errs) != 2 || errs[0].Error() != "/depth: must be >= 1" || errs[1].Error() != "/repo: is required" {
+		t.Errorf("errors %v", errs)
+	}
+	if out.(obj)["branch"] != "main" {
+		t.Errorf("invalid instance validated to %v, want the defaults filled in", out)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		schema interface{}
+		err    string
+	}{
+		{"string", "invalid schema at /: schema must be an object or a boolean"},
+		{obj{"if": true}, "invalid schema at /if: unsupported keyword"},
+		{obj{"type": "strin"}, `invalid schema at /type: unknown type "strin"`},
+		{obj{"type": arr{}}, "invalid schema at /type: must be a type name or a list of type names"},
+		{obj{"enum": "a"}, "invalid schema at /enum: must be an array"},
+		{obj{"minimum": "1"}, "invalid schema at /minimum: must be a number"},
+		{obj{"multipleOf": 0}, "invalid schema at /multipleOf: must be a number greater than 0"},
+		{obj{"minLength": 1.5}, "invalid schema at /minLength: must be a non-negative integer"},
+		{obj{"pattern": "("}, "invalid schema at /pattern: invalid pattern"},
+		{obj{"required": arr{1}}, "invalid schema at /required: must be an array of strings"},
+		{obj{"properties": obj{"a": obj{"type": 1}}}, "invalid schema at /properties/a/type"},
+		{obj{"items": 1}, "invalid schema at /items: schema must be an object or a boolean"},
+		{obj{"anyOf": arr{}}, "invalid schema at /anyOf: must be a non-empty array"},
+		{obj{"$ref": "#/$defs/nope"}, `invalid schema at /$ref: unresolvable reference "#/$defs/nope"`},
+		{obj{"$ref": "other.json"}, "invalid schema at /$ref: only local references"},
+		{obj{"$defs": obj{"a": obj{"maximum": true}}}, "invalid schema at /$defs/a/maximum: must be a number"},
+	}
+
+	for _, tc := range tests {
+		_, err := Compile(tc.schema)
+		if err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+			t.Errorf("Compile(%v) error = %v, want %q", tc.schema, err, tc.err)
+		}
+	}
+
+	// Annotations are accepted
+	if _, err := Compile(obj{"title": "t", "description": "d", "examples": arr{1}, "$schema": "x"}); err != nil {
+		t.Errorf("annotations: %v", err)
+	}
+}
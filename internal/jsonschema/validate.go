@@ -0,0 +1,355 @@
+package jsonschema
+
+import (
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxRefDepth guards against reference cycles such as {"$ref": "#"} with no constraint in between
+const maxRefDepth = 64
+
+// uuidPattern matches the textual form of a UUID
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Validate checks instance against the schema.
+// It returns a copy of the instance with property defaults filled in, and the
+// constraint violations sorted by path. The copy is returned even when invalid.
+func (s *Schema) Validate(instance interface{}) (interface{}, []ValidationError) {
+	doc := normalize(instance)
+	v := &validator{schema: s, defaults: true}
+	v.validate(s.root, doc, "", 0)
+
+	sort.SliceStable(v.errs, func(i, j int) bool { return v.errs[i].Path < v.errs[j].Path })
+	return doc, v.errs
+}
+
+// validator collects errors for one validation pass
+type validator struct {
+	schema   *Schema
+	defaults bool // apply defaults; off while probing anyOf/oneOf/not branches
+	errs     []ValidationError
+}
+
+// fail records a violation at path
+func (v *validator) fail(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// probe validates inst against schema without recording errors or applying defaults
+func (v *validator) probe(schema, inst interface{}, path string, depth int) bool {
+	sub := &validator{schema: v.schema}
+	sub.validate(schema, inst, path, depth)
+	return len(sub.errs) == 0
+}
+
+// validate checks inst against one (sub)schema
+func (v *validator) validate(schema, inst interface{}, path string, depth int) {
+	if b, ok := schema.(bool); ok {
+		if !b {
+			v.fail(path, "no value is allowed here")
+		}
+		return
+	}
+	m, ok := schema.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	if ref, ok := m["$ref"].(string); ok {
+		if depth >= maxRefDepth {
+			v.fail(path, "schema reference depth exceeded at %s", ref)
+			return
+		}
+		if target, err := v.schema.resolve(ref); err == nil {
+			v.validate(target, inst, path, depth+1)
+		}
+	}
+
+	if t, ok := m["type"]; ok {
+		names, _ := stringList(t)
+		if !matchesAnyType(names, inst) {
+			v.fail(path, "expected %s, got %s", strings.Join(names, " or "), typeOf(inst))
+			return
+		}
+	}
+	if enum, ok := m["enum"].([]interface{}); ok && !contains(enum, inst) {
+		v.fail(path, "must be one of %s", describeList(enum))
+	}
+	if c, ok := m["const"]; ok && !reflect.DeepEqual(c, inst) {
+		v.fail(path, "must be %s", describe(c))
+	}
+
+	switch val := inst.(type) {
+	case float64:
+		v.validateNumber(m, val, path)
+	case string:
+		v.validateString(m, val, path)
+	case []interface{}:
+		v.validateArray(m, val, path, depth)
+	case map[string]interface{}:
+		v.validateObject(m, val, path, depth)
+	}
+
+	if allOf, ok := m["allOf"].([]interface{}); ok {
+		for _, sub := range allOf {
+			v.validate(sub, inst, path, depth)
+		}
+	}
+	if anyOf, ok := m["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if v.probe(sub, inst, path, depth) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(path, "must match at least one of the anyOf schemas")
+		}
+	}
+	if oneOf, ok := m["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, sub := range oneOf {
+			if v.probe(sub, inst, path, depth) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			v.fail(path, "must match exactly one of the oneOf schemas (matched %d)", matches)
+		}
+	}
+	if not, ok := m["not"]; ok && v.probe(not, inst, path, depth) {
+		v.fail(path, "must not match the schema in not")
+	}
+}
+
+// validateNumber applies the numeric keywords
+func (v *validator) validateNumber(m map[string]interface{}, n float64, path string) {
+	if min, ok := m["minimum"].(float64); ok && n < min {
+		v.fail(path, "must be >= %s", formatNumber(min))
+	}
+	if max, ok := m["maximum"].(float64); ok && n > max {
+		v.fail(path, "must be <= %s", formatNumber(max))
+	}
+	if min, ok := m["exclusiveMinimum"].(float64); ok && n <= min {
+		v.fail(path, "must be > %s", formatNumber(min))
+	}
+	if max, ok := m["exclusiveMaximum"].(float64); ok && n >= max {
+		v.fail(path, "must be < %s", formatNumber(max))
+	}
+	if mul, ok := m["multipleOf"].(float64); ok {
+		q := n / mul
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			v.fail(path, "must be a multiple of %s", formatNumber(mul))
+		}
+	}
+}
+
+// validateString applies the string keywords
+func (v *validator) validateString(m map[string]interface{}, str string, path string) {
+	length := utf8.RuneCountInString(str)
+	if min, ok := m["minLength"].(float64); ok && length < int(min) {
+		v.fail(path, "must be at least %d characters long", int(min))
+	}
+	if max, ok := m["maxLength"].(float64); ok && length > int(max) {
+		v.fail(path, "must be at most %d characters long", int(max))
+	}
+	if pattern, ok := m["pattern"].(string); ok {
+		if re := v.schema.patterns[pattern]; re != nil && !re.MatchString(str) {
+			v.fail(path, "must match pattern %q", pattern)
+		}
+	}
+	if format, ok := m["format"].(string); ok && !matchesFormat(format, str) {
+		v.fail(path, "must be a valid %s", format)
+	}
+}
+
+// validateArray applies the array keywords
+func (v *validator) validateArray(m map[string]interface{}, arr []interface{}, path string, depth int) {
+	if min, ok := m["minItems"].(float64); ok && len(arr) < int(min) {
+		v.fail(path, "must contain at least %d items", int(min))
+	}
+	if max, ok := m["maxItems"].(float64); ok && len(arr) > int(max) {
+		v.fail(path, "must contain at most %d items", int(max))
+	}
+	if unique, _ := m["uniqueItems"].(bool); unique {
+	outer:
+		for i := range arr {
+			for j := 0; j < i; j++ {
+				if reflect.DeepEqual(arr[i], arr[j]) {
+					v.fail(path, "items %d and %d are equal", j, i)
+					break outer
+				}
+			}
+		}
+	}
+	if items, ok := m["items"]; ok {
+		for i, item := range arr {
+			v.validate(items, item, path+"/"+strconv.Itoa(i), depth)
+		}
+	}
+}
+
+// validateObject applies defaults and the object keywords
+func (v *validator) validateObject(m map[string]interface{}, obj map[string]interface{}, path string, depth int) {
+	props, _ := m["properties"].(map[string]interface{})
+
+	if v.defaults {
+		for name, prop := range props {
+			if _, present := obj[name]; present {
+				continue
+			}
+			if pm, ok := prop.(map[string]interface{}); ok {
+				if def, ok := pm["default"]; ok {
+					obj[name] = normalize(def)
+				}
+			}
+		}
+	}
+
+	required, _ := stringList(m["required"])
+	for _, name := range required {
+		if _, ok := obj[name]; !ok {
+			v.fail(path+"/"+escape(name), "is required")
+		}
+	}
+	if min, ok := m["minProperties"].(float64); ok && len(obj) < int(min) {
+		v.fail(path, "must have at least %d properties", int(min))
+	}
+	if max, ok := m["maxProperties"].(float64); ok && len(obj) > int(max) {
+		v.fail(path, "must have at most %d properties", int(max))
+	}
+
+	additional, hasAdditional := m["additionalProperties"]
+	for _, name := range sortedKeys(obj) {
+		p := path + "/" + escape(name)
+		if prop, ok := props[name]; ok {
+			v.validate(prop, obj[name], p, depth)
+			continue
+		}
+		if !hasAdditional {
+			continue
+		}
+		if b, ok := additional.(bool); ok && !b {
+			v.fail(p, "is not an allowed property")
+			continue
+		}
+		v.validate(additional, obj[name], p, depth)
+	}
+}
+
+// matchesAnyType reports whether inst is one of the named JSON types
+func matchesAnyType(names []string, inst interface{}) bool {
+	actual := typeOf(inst)
+	for _, n := range names {
+		if n == actual {
+			return true
+		}
+		if n == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON type name of a normalized value
+func typeOf(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if val == math.Trunc(val) && !math.IsInf(val, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// matchesFormat checks the formats this package knows; unknown formats always match
+func matchesFormat(format, s string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	case "email":
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != ""
+	case "uuid":
+		return uuidPattern.MatchString(s)
+	default:
+		return true
+	}
+}
+
+// contains reports whether list holds a value equal to v
+func contains(list []interface{}, v interface{}) bool {
+	for _, item := range list {
+		if reflect.DeepEqual(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// describe renders a value for an error message
+func describe(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return strconv.Quote(val)
+	case float64:
+		return formatNumber(val)
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// describeList renders enum values for an error message
+func describeList(list []interface{}) string {
+	parts := make([]string, len(list))
+	for i, item := range list {
+		parts[i] = describe(item)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// formatNumber renders a number without a trailing ".0"
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
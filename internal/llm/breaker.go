@@ -0,0 +1,177 @@
+package llm
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"agent-project-manager/internal/config"
+)
+
+// ErrCircuitOpen is returned for a provider whose circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"    // calls go through
+	BreakerOpen     = "open"      // calls are refused until the open period ends
+	BreakerHalfOpen = "half_open" // one probe call decides whether to close or reopen
+)
+
+// Breaker is a circuit breaker for one provider, keyed on error rate and latency
+type Breaker struct {
+	window    int
+	minCalls  int
+	errorRate float64
+	slowCall  time.Duration
+	slowRate  float64
+	openFor   time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    string
+	outcomes []outcome // ring of the last window calls
+	next     int
+	openedAt time.Time
+	probing  bool // a half-open probe is in flight
+}
+
+// outcome is one call as the breaker sees it
+type outcome struct {
+	failed bool
+	slow   bool
+}
+
+// NewBreaker creates a closed breaker, filling in defaults for unset settings
+func NewBreaker(cfg config.CircuitBreakerConfig) *Breaker {
+	b := &Breaker{
+		window:    cfg.Window,
+		minCalls:  cfg.MinCalls,
+		errorRate: cfg.ErrorRate,
+		slowRate:  cfg.SlowRate,
+		now:       time.Now,
+		state:     BreakerClosed,
+	}
+	if b.window <= 0 {
+		b.window = 20
+	}
+	if b.minCalls <= 0 {
+		b.minCalls = 5
+	}
+	if b.errorRate <= 0 {
+		b.errorRate = 0.5
+	}
+	if b.slowRate <= 0 {
+		b.slowRate = 0.8
+	}
+	b.slowCall, _ = time.ParseDuration(cfg.SlowCall)
+	if b.slowCall <= 0 {
+		b.slowCall = 2 * time.Minute
+	}
+	b.openFor, _ = time.ParseDuration(cfg.OpenFor)
+	if b.openFor <= 0 {
+		b.openFor = 30 * time.Second
+	}
+	return b
+}
+
+// Allow reports whether a call may go through. Once the open period is over it lets
+// a single probe through; the caller must report every allowed call with Record or Release.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.openFor {
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Record reports the outcome of an allowed call
+func (b *Breaker) Record(failed bool, latency time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	slow := latency >= b.slowCall
+	if b.state == BreakerHalfOpen {
+		b.probing = false
+		if failed || slow {
+			b.trip()
+		} else {
+			b.reset()
+		}
+		return
+	}
+
+	if len(b.outcomes) < b.window {
+		b.outcomes = append(b.outcomes, outcome{failed: failed, slow: slow})
+	} else {
+		b.outcomes[b.next] = outcome{failed: failed, slow: slow}
+		b.next = (b.next + 1) % b.window
+	}
+	if b.state == BreakerClosed && b.shouldTrip() {
+		b.trip()
+	}
+}
+
+// Release gives back an allowed call that ended without telling anything about the
+// provider's health, e.g. because the caller cancelled it
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// State returns the breaker's current state
+func (b *Breaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.openFor {
+		return BreakerHalfOpen // the next call is a probe
+	}
+	return b.state
+}
+
+// shouldTrip reports whether the recent calls cross a threshold
+func (b *Breaker) shouldTrip() bool {
+	if len(b.outcomes) < b.minCalls {
+		return false
+	}
+	failed, slow := 0, 0
+	for _, o := range b.outcomes {
+		if o.failed {
+			failed++
+		}
+		if o.slow {
+			slow++
+		}
+	}
+	n := float64(len(b.outcomes))
+	return float64(failed)/n >= b.errorRate || float64(slow)/n >= b.slowRate
+}
+
+// trip opens the breaker
+func (b *Breaker) trip() {
+	b.state = BreakerOpen
+	b.openedAt = b.now()
+}
+
+// reset closes the breaker and forgets past calls
+func (b *Breaker) reset() {
+	b.state = BreakerClosed
+	b.outcomes = nil
+	b.next = 0
+}
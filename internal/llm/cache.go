@@ -0,0 +1,150 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"agent-project-manager/internal/config"
+	"agent-project-manager/internal/logger"
+)
+
+// Cache modes of a call
+const (
+	CacheAuto  = ""      // use the cache for requests with temperature 0
+	CacheOff   = "off"   // never use the cache
+	CacheForce = "force" // use the cache whatever the temperature
+)
+
+// cacheKeyVersion is part of every cache key, so changing how keys are built invalidates old entries
+const cacheKeyVersion = 1
+
+// CacheStore persists cached responses
+type CacheStore interface {
+	// GetResponse returns the response stored under key; nil when there is none or it expired
+	GetResponse(ctx context.Context, key string) (*Response, error)
+	// PutResponse stores a response under key for ttl
+	PutResponse(ctx context.Context, key string, resp *Response, ttl time.Duration) error
+}
+
+// ResponseCache answers repeated requests from a CacheStore and reports hits, misses and
+// the tokens and cost they saved as metrics
+type ResponseCache struct {
+	store CacheStore
+	ttl   time.Duration
+
+	hits, misses atomic.Int64
+	lookups      metric.Int64Counter
+	savedTokens  metric.Int64Counter
+	savedCost    metric.Float64Counter
+}
+
+// NewResponseCache creates a response cache over store; nil when the cache is disabled
+func NewResponseCache(store CacheStore, cfg config.LLMCacheConfig) *ResponseCache {
+	if !cfg.Enabled || store == nil {
+		return nil
+	}
+	c := &ResponseCache{store: store}
+	c.ttl, _ = time.ParseDuration(cfg.TTL)
+	if c.ttl <= 0 {
+		c.ttl = 24 * time.Hour
+	}
+
+	meter := otel.Meter("agent-project-manager/llm")
+	c.lookups, _ = meter.Int64Counter("llm_cache_lookups",
+		metric.WithDescription("LLM response cache lookups by result (hit, miss or bypass)"))
+	c.savedTokens, _ = meter.Int64Counter("llm_cache_saved_tokens",
+		metric.WithDescription("Tokens not spent thanks to LLM response cache hits"))
+	c.savedCost, _ = meter.Float64Counter("llm_cache_saved_cost_usd",
+		metric.WithDescription("USD not spent thanks to LLM response cache hits"), metric.WithUnit("USD"))
+	meter.Float64ObservableGauge("llm_cache_hit_ratio",
+		metric.WithDescription("Share of LLM response cache lookups that were hits since agentd started"),
+		metric.WithFloat64Callback(func(_ context.Context, o metric.Float64Observer) error {
+			if total := c.hits.Load() + c.misses.Load(); total > 0 {
+				o.Observe(float64(c.hits.Load()) / float64(total))
+			}
+			return nil
+		}))
+	return c
+}
+
+// Usable reports whether a request of a call may be answered from or stored in the cache.
+// Sampled requests usually should not be: the caller asked for a different answer each time.
+func (c *ResponseCache) Usable(call *Call, req *Request) bool {
+	if c == nil || call.Cache == CacheOff {
+		return false
+	}
+	return call.Cache == CacheForce || (req.Temperature != nil && *req.Temperature == 0)
+}
+
+// Get looks up the response of a provider's model to a request.
+// A store error counts as a miss: the request is then simply sent.
+func (c *ResponseCache) Get(ctx context.Context, key, provider string) *Response {
+	resp, err := c.store.GetResponse(ctx, key)
+	if err != nil {
+		logger.Warnf("llm: response cache lookup failed: %v", err)
+	}
+	if resp == nil {
+		c.misses.Add(1)
+		c.lookups.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "miss"), attribute.String("provider", provider)))
+		return nil
+	}
+	c.hits.Add(1)
+	c.lookups.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "hit"), attribute.String("provider", provider)))
+	return resp
+}
+
+// Bypass counts a request that did not use the cache
+func (c *ResponseCache) Bypass(ctx context.Context, provider string) {
+	if c == nil {
+		return
+	}
+	c.lookups.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "bypass"), attribute.String("provider", provider)))
+}
+
+// Saved counts the tokens and cost a hit saved
+func (c *ResponseCache) Saved(ctx context.Context, provider string, tokens int, costUSD float64) {
+	attrs := metric.WithAttributes(attribute.String("provider", provider))
+	c.savedTokens.Add(ctx, int64(tokens), attrs)
+	c.savedCost.Add(ctx, costUSD, attrs)
+}
+
+// Put stores a response; failures are logged, the response is still good
+func (c *ResponseCache) Put(ctx context.Context, key string, resp *Response) {
+	if err := c.store.PutResponse(ctx, key, resp, c.ttl); err != nil {
+		logger.Warnf("llm: failed to store response in cache: %v", err)
+	}
+}
+
+// CacheKey hashes a request to a provider's model. Message text is normalized
+// (line endings, surrounding whitespace) so cosmetic differences still hit.
+func CacheKey(provider, model string, req *Request) string {
+	norm := *req
+	norm.Model = ""
+	norm.Messages = make([]Message, len(req.Messages))
+	for i, m := range req.Messages {
+		m.Role = strings.ToLower(m.Role)
+		m.Content = strings.TrimSpace(strings.ReplaceAll(m.Content, "\r\n", "\n"))
+		norm.Messages[i] = m
+	}
+	if len(norm.Stop) == 0 {
+		norm.Stop = nil
+	}
+
+	raw, _ := json.Marshal(map[string]interface{}{
+		"version":  cacheKeyVersion,
+		"provider": provider,
+		"model":    model,
+		"request":  norm,
+	})
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,128 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"agent-project-manager/internal/config"
+)
+
+// memoryCache is a CacheStore in a map
+type memoryCache map[string]*Response
+
+func (m memoryCache) GetResponse(ctx context.Context, key string) (*Response, error) {
+	if resp, ok := m[key]; ok {
+		cp := *resp
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (m memoryCache) PutResponse(ctx context.Context, key string, resp *Response, ttl time.Duration) error {
+	cp := *resp
+	m[key] = &cp
+	return nil
+}
+
+// usageProvider answers with token usage, so cache hits have something to save
+type usageProvider struct{ fakeProvider }
+
+func (p *usageProvider) Chat(ctx context.Context, req *Request) (*Response, error) {
+	resp, err := p.fakeProvider.Chat(ctx, req)
+	if resp != nil {
+		resp.Usage = Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120}
+	}
+	return resp, err
+}
+
+func (p *usageProvider) ChatStream(ctx context.Context, req *Request, onChunk func(Chunk) error) (*Response, error) {
+	resp, err := p.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, onChunk(Chunk{Content: resp.Content})
+}
+
+func TestGatewayCache(t *testing.T) {
+	p := &usageProvider{fakeProvider{name: "openai", model: "gpt-4o-mini"}}
+	store := memoryCache{}
+	reg := &Registry{providers: map[string]Provider{"openai": p}, defaultName: "openai"}
+	g, err := NewGateway(reg, config.LLMConfig{Provider: "openai"}, GatewayOptions{
+		Cache: NewResponseCache(store, config.LLMCacheConfig{Enabled: true}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zero, warm := 0.0, 0.7
+	ask := func(mode string, temperature *float64, content string) *Response {
+		t.Helper()
+		resp, err := g.Chat(context.Background(), &Call{Cache: mode}, &Request{
+			Messages:    []Message{{Role: RoleUser, Content: content}},
+			Temperature: temperature,
+		})
+		if err != nil {
+			t.Fatalf("Chat: %v", err)
+		}
+		return resp
+	}
+
+	if resp := ask(CacheAuto, &zero, "hello"); resp.Cached || p.calls != 1 {
+		t.Fatalf("first request: cached %v, %d calls", resp.Cached, p.calls)
+	}
+	// Whitespace and line endings do not change the key
+	resp := ask(CacheAuto, &zero, "hello\r\n")
+	if !resp.Cached || p.calls != 1 || resp.Content != "ok from openai" || resp.Usage.TotalTokens != 0 {
+		t.Errorf("repeated request: %+v, %d calls", resp, p.calls)
+	}
+
+	// Sampled requests bypass the cache unless forced
+	ask(CacheAuto, &warm, "hello")
+	ask(CacheAuto, nil, "hello")
+	if p.calls != 3 {
+		t.Errorf("sampled requests: %d calls, want 3", p.calls)
+	}
+	ask(CacheForce, &warm, "hello")
+	if resp := ask(CacheForce, &warm, "hello"); !resp.Cached || p.calls != 4 {
+		t.Errorf("forced request: cached %v, %d calls", resp.Cached, p.calls)
+	}
+	if resp := ask(CacheOff, &zero, "hello"); resp.Cached || p.calls != 5 {
+		t.Errorf("cache off: cached %v, %d calls", resp.Cached, p.calls)
+	}
+	if len(store) != 2 {
+		t.Errorf("%d responses stored, want 2", len(store))
+	}
+
+	// A stream answered from the cache arrives as one chunk
+	var chunks []Chunk
+	resp, err = g.ChatStream(context.Background(), &Call{}, &Request{
+		Messages:    []Message{{Role: RoleUser, Content: "hello"}},
+		Temperature: &zero,
+	}, func(c Chunk) error { chunks = append(chunks, c); return nil })
+	if err != nil || !resp.Cached || len(chunks) != 1 || chunks[0].Content != "ok from openai" {
+		t.Errorf("cached stream: %+v, %v, chunks %+v", resp, err, chunks)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	zero := 0.0
+	req := &Request{Messages: []Message{{Role: RoleUser, Content: "hi"}}, Temperature: &zero}
+	key := CacheKey("openai", "gpt-4o-mini", req)
+
+	for name, other := range map[string]string{
+		"provider":    CacheKey("vllm", "gpt-4o-mini", req),
+		"model":       CacheKey("openai", "gpt-4o", req),
+		"messages":    CacheKey("openai", "gpt-4o-mini", &Request{Messages: []Message{{Role: RoleUser, Content: "hey"}}, Temperature: &zero}),
+		"temperature": CacheKey("openai", "gpt-4o-mini", &Request{Messages: req.Messages}),
+		"maxTokens":   CacheKey("openai", "gpt-4o-mini", &Request{Messages: req.Messages, Temperature: &zero, MaxTokens: 10}),
+	} {
+		if other == key {
+			t.Errorf("changing the %s does not change the key", name)
+		}
+	}
+	same := &Request{Model: "ignored", Messages: []Message{{Role: "User", Content: " hi\n"}}, Temperature: &zero, Stop: []string{}}
+	if CacheKey("openai", "gpt-4o-mini", same) != key {
+		t.Error("normalized request has a different key")
+	}
+}
@@ -0,0 +1,272 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"agent-project-manager/internal/config"
+)
+
+// Cassette modes
+const (
+	CassetteRecord = config.CassetteRecord
+	CassetteReplay = config.CassetteReplay
+)
+
+// cassetteVersion is the version of the cassette file format
+const cassetteVersion = 1
+
+// ErrCassetteMiss means a replaying cassette holds no response to a request
+var ErrCassetteMiss = errors.New("request not in cassette")
+
+// Cassette records the requests sent to providers with their outcomes, or replays them
+// without calling any provider, so workflows can be tested offline and deterministically.
+//
+// Requests are matched by provider, model and normalized request, as for the response
+// cache. A request recorded several times is answered with its recordings in order, the
+// last one repeating once they are used up; a request that was never recorded fails with
+// ErrCassetteMiss instead of falling back to another provider. Provider errors are recorded
+// too, so a replay takes the same fallbacks as the recording.
+//
+// Recording into an existing file replaces the file's recordings of each request the
+// cassette records, so re-recording a workflow updates its answers; the recordings of
+// requests it does not repeat are kept.
+type Cassette struct {
+	path string
+	mode string
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	replayed     map[string]int  // by key: recordings used so far
+	recorded     map[string]bool // by key: requests this cassette has recorded
+}
+
+// Interaction is a recorded request and its outcome
+type Interaction struct {
+	Key      string          `json:"key"`
+	Provider string          `json:"provider"`
+	Model    string          `json:"model"`
+	Request  cassetteRequest `json:"request"` // for readers of the cassette; matching uses Key
+	Response *Response       `json:"response,omitempty"`
+	Error    *CassetteError  `json:"error,omitempty"`
+	Latency  int64           `json:"latencyMs"`
+}
+
+// cassetteRequest is the recorded form of a request
+type cassetteRequest struct {
+	Messages    []Message   `json:"messages"`
+	Temperature *float64    `json:"temperature,omitempty"`
+	MaxTokens   int         `json:"maxTokens,omitempty"`
+	Stop        []string    `json:"stop,omitempty"`
+	Format      *JSONFormat `json:"format,omitempty"`
+	Tools       []ToolSpec  `json:"tools,omitempty"`
+}
+
+// CassetteError is a recorded provider error
+type CassetteError struct {
+	Kind       string `json:"kind"` // the error class, e.g. "rate limited"
+	StatusCode int    `json:"statusCode,omitempty"`
+	Code       string `json:"code,omitempty"`
+	Message    string `json:"message"`
+}
+
+// cassetteFile is the content of a cassette file
+type cassetteFile struct {
+	Version      int            `json:"version"`
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// cassetteLocks serializes the writes to each cassette file: jobs recording with the same
+// cassette each open their own Cassette but add to the one file
+var cassetteLocks sync.Map // by path: *sync.Mutex
+
+// errorKinds are the error classes a cassette can hold
+var errorKinds = []error{ErrRateLimit, ErrContextLength, ErrModelNotFound, ErrAuth, ErrTransient, ErrInvalidRequest}
+
+// OpenCassette opens the cassette at path. Replaying needs the file; recording updates
+// the file when there is one, and creates it with the first recorded request otherwise.
+// Delete the file to drop the recordings of requests that are no longer made.
+func OpenCassette(path, mode string) (*Cassette, error) {
+	if mode != CassetteRecord && mode != CassetteReplay {
+		return nil, fmt.Errorf("unknown cassette mode %q (want %s or %s)", mode, CassetteRecord, CassetteReplay)
+	}
+	c := &Cassette{path: path, mode: mode, replayed: map[string]int{}, recorded: map[string]bool{}}
+	interactions, err := readCassette(path)
+	if err != nil && (mode == CassetteReplay || !errors.Is(err, fs.ErrNotExist)) {
+		return nil, err
+	}
+	c.interactions = interactions
+	return c, nil
+}
+
+// readCassette reads the interactions of a cassette file
+func readCassette(path string) ([]*Interaction, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var f cassetteFile
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	if f.Version != cassetteVersion {
+		return nil, fmt.Errorf("cassette %s has version %d; re-record it (want version %d)", path, f.Version, cassetteVersion)
+	}
+	return f.Interactions, nil
+}
+
+// Path returns the cassette file
+func (c *Cassette) Path() string {
+	return c.path
+}
+
+// Mode returns CassetteRecord or CassetteReplay
+func (c *Cassette) Mode() string {
+	return c.mode
+}
+
+// Replaying reports whether the cassette answers requests instead of the provider
+func (c *Cassette) Replaying() bool {
+	return c != nil && c.mode == CassetteReplay
+}
+
+// Replay returns the recorded outcome of a request to a provider's model: the response,
+// or the provider error. It fails with ErrCassetteMiss when the request was never recorded.
+func (c *Cassette) Replay(provider, model string, req *Request) (*Response, error) {
+	key := CacheKey(provider, model, req)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var matches []*Interaction
+	for _, in := range c.interactions {
+		if in.Key == key {
+			matches = append(matches, in)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, c.miss(provider, model, req)
+	}
+	n := c.replayed[key]
+	c.replayed[key] = n + 1
+	in := matches[min(n, len(matches)-1)]
+
+	if in.Error != nil {
+		return nil, in.Error.err(provider)
+	}
+	resp := *in.Response
+	resp.ToolCalls = append([]ToolCall(nil), in.Response.ToolCalls...)
+	return &resp, nil
+}
+
+// Record adds the outcome of a request to a provider's model to the cassette file. The
+// first recording of a request by this cassette replaces the ones the file held before,
+// later ones are added after it. Only responses and provider errors are recorded; other errors, such as a cancelled
+// request, say nothing about the provider.
+func (c *Cassette) Record(provider, model string, req *Request, resp *Response, err error, latency time.Duration) error {
+	in := &Interaction{
+		Key:      CacheKey(provider, model, req),
+		Provider: provider,
+		Model:    model,
+		Request: cassetteRequest{
+			Messages:    req.Messages,
+			Temperature: req.Temperature,
+			MaxTokens:   req.MaxTokens,
+			Stop:        req.Stop,
+			Format:      req.Format,
+			Tools:       req.Tools,
+		},
+		Latency: latency.Milliseconds(),
+	}
+	var pe *Error
+	switch {
+	case err == nil:
+		r := *resp
+		r.Provider, r.CostUSD, r.Cached = "", 0, false // set by the gateway on replay
+		in.Response = &r
+	case errors.As(err, &pe):
+		in.Error = &CassetteError{Kind: fmt.Sprint(pe.Kind), StatusCode: pe.StatusCode, Code: pe.Code, Message: pe.Message}
+		if in.Error.Message == "" && pe.Err != nil {
+			in.Error.Message = pe.Err.Error()
+		}
+	default:
+		return nil
+	}
+
+	lock, _ := cassetteLocks.LoadOrStore(filepath.Clean(c.path), &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Keep what other cassettes recorded to the file since this one read it
+	interactions, err := readCassette(c.path)
+	switch {
+	case err == nil:
+		c.interactions = interactions
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+	if !c.recorded[in.Key] {
+		// The file's older recordings of the request would be replayed before this one
+		c.recorded[in.Key] = true
+		kept := make([]*Interaction, 0, len(c.interactions))
+		for _, old := range c.interactions {
+			if old.Key != in.Key {
+				kept = append(kept, old)
+			}
+		}
+		c.interactions = kept
+	}
+	c.interactions = append(c.interactions, in)
+	return c.save()
+}
+
+// save writes the cassette, replacing the file atomically
+func (c *Cassette) save() error {
+	raw, err := json.MarshalIndent(cassetteFile{Version: cassetteVersion, Interactions: c.interactions}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, append(raw, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// miss describes a request the cassette does not hold
+func (c *Cassette) miss(provider, model string, req *Request) error {
+	last := ""
+	if len(req.Messages) > 0 {
+		last = strings.TrimSpace(req.Messages[len(req.Messages)-1].Content)
+		if len(last) > 80 {
+			last = last[:80] + "..."
+		}
+	}
+	return fmt.Errorf("%w %s: no recording of this request to %s/%s (%d messages, the last %q); re-record the cassette",
+		ErrCassetteMiss, c.path, provider, model, len(req.Messages), last)
+}
+
+// err rebuilds the recorded provider error
+func (e *CassetteError) err(provider string) *Error {
+	pe := &Error{Provider: provider, Kind: ErrTransient, StatusCode: e.StatusCode, Code: e.Code, Message: e.Message}
+	for _, kind := range errorKinds {
+		if kind.Error() == e.Kind {
+			pe.Kind = kind
+		}
+	}
+	return pe
+}
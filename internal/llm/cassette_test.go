@@ -0,0 +1,231 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"agent-project-manager/internal/config"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "review.json")
+	route := []Target{{Provider: "a"}, {Provider: "b"}}
+	ask := func(content string) *Request {
+		return &Request{Messages: []Message{{Role: RoleUser, Content: content}}}
+	}
+	gateway := func(tape *Cassette, providers ...*fakeProvider) (*Gateway, *[]*Attempt) {
+		t.Helper()
+		reg := &Registry{providers: map[string]Provider{}}
+		cfg := config.LLMConfig{Providers: map[string]config.ProviderConfig{}}
+		for _, p := range providers {
+			reg.providers[p.name] = p
+			cfg.Providers[p.name] = config.ProviderConfig{Type: "fake"}
+		}
+		var attempts []*Attempt
+		g, err := NewGateway(reg, cfg, GatewayOptions{
+			Record:   func(a *Attempt) { attempts = append(attempts, a) },
+			Cassette: func(*Call) (*Cassette, error) { return tape, nil },
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g, &attempts
+	}
+
+	// Record: a fails once, so the first request falls back to b
+	tape, err := OpenCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &fakeProvider{name: "a", model: "m", errs: []error{transient()}}
+	b := &fakeProvider{name: "b", model: "m"}
+	g, _ := gateway(tape, a, b)
+	for _, content := range []string{"hello", "again"} {
+		if _, err := g.Chat(context.Background(), &Call{Route: route}, ask(content)); err != nil {
+			t.Fatalf("record %q: %v", content, err)
+		}
+	}
+
+	// Replay: the providers are never called
+	tape, err = OpenCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a = &fakeProvider{name: "a", model: "m"}
+	b = &fakeProvider{name: "b", model: "m"}
+	g, attempts := gateway(tape, a, b)
+
+	resp, err := g.Chat(context.Background(), &Call{Route: route}, ask("hello"))
+	if err != nil || resp.Content != "ok from b" || resp.Provider != "b" {
+		t.Fatalf("replay hello = %+v, %v; want b's answer", resp, err)
+	}
+	if len(*attempts) != 2 || ErrorKind((*attempts)[0].Err) != "transient" || (*attempts)[1].Status != AttemptSucceeded {
+		t.Errorf("replay hello took attempts %+v; want the recorded fallback", *attempts)
+	}
+
+	// A request recorded once is answered every time it is repeated, streams included
+	var chunks string
+	for i := 0; i < 2; i++ {
+		resp, err = g.ChatStream(context.Background(), &Call{Route: route}, ask("again"), func(c Chunk) error {
+			chunks += c.Content
+			return nil
+		})
+		if err != nil || resp.Content != "ok from a" {
+			t.Fatalf("replay again = %+v, %v", resp, err)
+		}
+	}
+	if chunks != "ok from aok from a" {
+		t.Errorf("streamed %q", chunks)
+	}
+
+	// A request that was never recorded fails without falling back
+	*attempts = nil
+	_, err = g.Chat(context.Background(), &Call{Route: route}, ask("something else"))
+	if !errors.Is(err, ErrCassetteMiss) || len(*attempts) != 1 {
+		t.Errorf("unrecorded request: error = %v after %d attempts; want ErrCassetteMiss after 1", err, len(*attempts))
+	}
+	if a.calls != 0 || b.calls != 0 {
+		t.Errorf("providers called %d and %d times during replay", a.calls, b.calls)
+	}
+}
+
+func TestCassetteProviderConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.json")
+	gateway := func(mode string, p *fakeProvider) (*Gateway, error) {
+		reg := &Registry{providers: map[string]Provider{"a": p}, defaultName: "a"}
+		return NewGateway(reg, config.LLMConfig{
+			Provider: "a",
+			Providers: map[string]config.ProviderConfig{
+				"a": {Type: "fake", Cassette: config.CassetteConfig{Mode: mode, Path: path}},
+			},
+		}, GatewayOptions{})
+	}
+	req := &Request{Messages: []Message{{Role: RoleUser, Content: "hi"}}}
+
+	if _, err := gateway(CassetteReplay, &fakeProvider{name: "a"}); err == nil {
+		t.Error("replaying a missing cassette: want an error")
+	}
+
+	// Recorded errors replay too
+	g, err := gateway(CassetteRecord, &fakeProvider{name: "a", errs: []error{&Error{Provider: "a", Kind: ErrRateLimit, StatusCode: 429}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		g.Chat(context.Background(), &Call{}, req)
+	}
+
+	p := &fakeProvider{name: "a"}
+	if g, err = gateway(CassetteReplay, p); err != nil {
+		t.Fatal(err)
+	}
+	_, err = g.Chat(context.Background(), &Call{}, req)
+	var pe *Error
+	if !errors.As(err, &pe) || pe.Kind != ErrRateLimit || pe.StatusCode != 429 {
+		t.Errorf("first replay error = %v, want the recorded rate limit", err)
+	}
+	if resp, err := g.Chat(context.Background(), &Call{}, req); err != nil || resp.Content != "ok from a" {
+		t.Errorf("second replay = %+v, %v; want the recorded answer", resp, err)
+	}
+	if p.calls != 0 {
+		t.Errorf("provider called %d times during replay", p.calls)
+	}
+}
+
+func TestCassetteRecordKeepsRecordings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared.json")
+	ask := func(content string) *Request {
+		return &Request{Messages: []Message{{Role: RoleUser, Content: content}}}
+	}
+	record := func(tape *Cassette, content string) {
+		t.Helper()
+		if err := tape.Record("a", "m", ask(content), &Response{Content: "re: " + content}, nil, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Two jobs record with the same cassette at once, then one of them opens it again
+	first, err := OpenCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := OpenCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i, tape := range []*Cassette{first, second} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				record(tape, fmt.Sprintf("job %d request %d", i, j))
+			}
+		}()
+	}
+	wg.Wait()
+	reopened, err := OpenCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record(reopened, "after reopening")
+
+	tape, err := OpenCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 5; j++ {
+			content := fmt.Sprintf("job %d request %d", i, j)
+			if resp, err := tape.Replay("a", "m", ask(content)); err != nil || resp.Content != "re: "+content {
+				t.Errorf("replay %q = %+v, %v", content, resp, err)
+			}
+		}
+	}
+	if resp, err := tape.Replay("a", "m", ask("after reopening")); err != nil || resp.Content != "re: after reopening" {
+		t.Errorf("replay after reopening = %+v, %v", resp, err)
+	}
+}
+
+func TestCassetteReRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "review.json")
+	ask := func(content string) *Request {
+		return &Request{Messages: []Message{{Role: RoleUser, Content: content}}}
+	}
+	session := func(answers ...string) {
+		t.Helper()
+		tape, err := OpenCassette(path, CassetteRecord)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, answer := range answers {
+			content, reply, _ := strings.Cut(answer, "=")
+			if err := tape.Record("a", "m", ask(content), &Response{Content: reply}, nil, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	session("review=old 1", "review=old 2", "summary=old")
+	session("review=new 1", "review=new 2")
+
+	tape, err := OpenCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct{ content, want string }{
+		{"review", "new 1"},
+		{"review", "new 2"},
+		{"review", "new 2"},
+		{"summary", "old"}, // not recorded again, so kept
+	} {
+		if resp, err := tape.Replay("a", "m", ask(tt.content)); err != nil || resp.Content != tt.want {
+			t.Errorf("replay %q = %+v, %v; want %q", tt.content, resp, err, tt.want)
+		}
+	}
+}
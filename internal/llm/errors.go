@@ -0,0 +1,151 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error classes; match them with errors.Is
+var (
+	// ErrRateLimit means the provider throttled the request; retry after a while
+	ErrRateLimit = errors.New("rate limited")
+	// ErrContextLength means the prompt and completion do not fit the model's context window
+	ErrContextLength = errors.New("context length exceeded")
+	// ErrModelNotFound means the provider does not offer the model, or it is not installed locally
+	ErrModelNotFound = errors.New("model not found")
+	// ErrAuth means the credentials are missing, invalid or not allowed to use the model
+	ErrAuth = errors.New("authentication failed")
+	// ErrTransient means a network problem or server error; the same request may succeed later
+	ErrTransient = errors.New("transient error")
+	// ErrInvalidRequest means the provider rejected the request; retrying will not help
+	ErrInvalidRequest = errors.New("invalid request")
+)
+
+// Error is a failed provider call
+type Error struct {
+	Provider   string
+	Kind       error         // one of the error classes above
+	StatusCode int           // HTTP status; zero when no response was received
+	Code       string        // provider error code, e.g. "context_length_exceeded"
+	Message    string        // provider error message
+	RetryAfter time.Duration // how long the provider asked to wait; zero when unknown
+	Err        error         // underlying transport error, if any
+}
+
+// Error describes the failure
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" && e.Err != nil {
+		msg = e.Err.Error()
+	}
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s: %v (HTTP %d): %s", e.Provider, e.Kind, e.StatusCode, msg)
+	}
+	return fmt.Sprintf("%s: %v: %s", e.Provider, e.Kind, msg)
+}
+
+// Is matches the error class
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
+
+// Unwrap returns the underlying transport error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether a failed call may succeed if it is repeated
+func Retryable(err error) bool {
+	return errors.Is(err, ErrRateLimit) || errors.Is(err, ErrTransient)
+}
+
+// classifyStatus maps an HTTP error response to an error class.
+// Providers that report context overflows as plain 400s pass them in via code and message.
+func classifyStatus(status int, code, message string) error {
+	switch {
+	case isContextLengthError(code, message):
+		return ErrContextLength
+	case code == "model_not_found" || (status == http.StatusNotFound && strings.Contains(strings.ToLower(message), "model")):
+		return ErrModelNotFound
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrAuth
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimit
+	case status == http.StatusRequestTimeout || status == http.StatusConflict || status >= 500:
+		return ErrTransient
+	default:
+		return ErrInvalidRequest
+	}
+}
+
+// isContextLengthError recognizes the ways providers report an overlong prompt
+func isContextLengthError(code, message string) bool {
+	if code == "context_length_exceeded" {
+		return true
+	}
+	m := strings.ToLower(message)
+	return strings.Contains(m, "maximum context length") || strings.Contains(m, "context length exceeded") ||
+		strings.Contains(m, "context window")
+}
+
+// transportError wraps an error that happened before a response arrived.
+// Cancellation is returned as is; everything else is transient.
+func transportError(provider string, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return &Error{Provider: provider, Kind: ErrTransient, Err: err}
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(h http.Header) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// ErrorKind names the class of err for records and metrics, e.g. "rate_limit".
+// It returns "" for nil and "other" for errors that are not classified.
+func ErrorKind(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrRateLimit):
+		return "rate_limit"
+	case errors.Is(err, ErrContextLength):
+		return "context_length"
+	case errors.Is(err, ErrModelNotFound):
+		return "model_not_found"
+	case errors.Is(err, ErrAuth):
+		return "auth"
+	case errors.Is(err, ErrTransient):
+		return "transient"
+	case errors.Is(err, ErrInvalidRequest):
+		return "invalid_request"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, ErrUnknownProvider):
+		return "unknown_provider"
+	case errors.Is(err, ErrBudgetExceeded):
+		return "budget_exceeded"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "cancelled"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,358 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"agent-project-manager/internal/config"
+	"agent-project-manager/internal/logger"
+)
+
+// Attempt statuses
+const (
+	AttemptSucceeded = "succeeded"
+	AttemptFailed    = "failed"
+	AttemptSkipped   = "skipped" // the provider's circuit breaker was open
+	AttemptCached    = "cached"  // answered from the response cache without calling the provider
+)
+
+// DefaultRoute is the route of agents that have none of their own
+const DefaultRoute = "default"
+
+// Target is one provider of a route, optionally with a model other than its default
+type Target struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model,omitempty"`
+}
+
+// Call says who is asking, for routing and for the attempt records
+type Call struct {
+	JobID  string
+	StepID string
+	Agent  string   // selects the agent's route from llm.routes
+	Route  []Target // the step's own route; overrides the agent's
+	Cache  string   // CacheAuto, CacheOff or CacheForce
+}
+
+// Attempt is one try of a request against a single provider
+type Attempt struct {
+	RequestID string // the same for every attempt of one gateway request
+	Call      Call
+	Number    int // 1 for the first provider of the route
+	Provider  string
+	Model     string
+	Status    string
+	Err       error
+	Latency   time.Duration
+	Response  *Response // the answer, when Status is succeeded
+}
+
+// GatewayOptions configures a Gateway
+type GatewayOptions struct {
+	// Record is called for every attempt, including skipped ones; nil records nothing
+	Record func(a *Attempt)
+	// Admit is asked before a request is sent; an error, typically one wrapping
+	// ErrBudgetExceeded, fails the request without trying any provider. nil admits everything.
+	Admit func(call *Call) error
+	// Cache answers repeated requests without calling the provider; nil disables caching
+	Cache *ResponseCache
+	// Cassette returns the cassette a call records to or replays from, such as the one its
+	// job was submitted with; it takes precedence over the providers' cassettes. nil, or a
+	// nil cassette, leaves the call to them.
+	Cassette func(call *Call) (*Cassette, error)
+}
+
+// Gateway sends requests along a route of providers: when one fails with an error another
+// provider may not have (overload, outage, missing model, prompt too long), the next one
+// is tried. Every provider has a circuit breaker; providers whose breaker is open are skipped.
+// Providers with a cassette in record mode have their traffic recorded; in replay mode
+// they are never called and neither the cache nor the breaker is consulted.
+type Gateway struct {
+	registry  *Registry
+	routes    map[string][]Target
+	breakers  map[string]*Breaker
+	cassettes map[string]*Cassette // by provider
+	pricing   *Pricing
+	opts      GatewayOptions
+}
+
+// NewGateway creates a gateway over the registry's providers with the routes, breakers
+// and prices of cfg
+func NewGateway(registry *Registry, cfg config.LLMConfig, opts GatewayOptions) (*Gateway, error) {
+	g := &Gateway{
+		registry:  registry,
+		routes:    map[string][]Target{},
+		breakers:  map[string]*Breaker{},
+		cassettes: map[string]*Cassette{},
+		pricing:   NewPricing(cfg.Prices),
+		opts:      opts,
+	}
+	for agent, route := range cfg.Routes {
+		for _, t := range route {
+			if _, err := registry.Get(t.Provider); err != nil {
+				return nil, fmt.Errorf("route %s: %w", agent, err)
+			}
+			g.routes[agent] = append(g.routes[agent], Target{Provider: t.Provider, Model: t.Model})
+		}
+	}
+	byPath := map[string]*Cassette{}
+	for name, pc := range cfg.AllProviders() {
+		bc := cfg.CircuitBreaker
+		if pc.CircuitBreaker != nil {
+			bc = *pc.CircuitBreaker
+		}
+		g.breakers[name] = NewBreaker(bc)
+		if pc.Cassette.Mode != "" {
+			c, ok := byPath[pc.Cassette.Path] // providers may share a cassette
+			if !ok {
+				var err error
+				if c, err = OpenCassette(pc.Cassette.Path, pc.Cassette.Mode); err != nil {
+					return nil, fmt.Errorf("provider %s: %w", name, err)
+				}
+				byPath[pc.Cassette.Path] = c
+			} else if c.Mode() != pc.Cassette.Mode {
+				return nil, fmt.Errorf("provider %s: cassette %s is used in both modes", name, pc.Cassette.Path)
+			}
+			g.cassettes[name] = c
+			logger.Infof("llm: provider %s is in %s mode with cassette %s", name, c.Mode(), c.Path())
+		}
+	}
+	return g, nil
+}
+
+// Registry returns the providers the gateway routes to
+func (g *Gateway) Registry() *Registry {
+	return g.registry
+}
+
+// Breaker returns the circuit breaker of the named provider; nil for unknown providers
+func (g *Gateway) Breaker(provider string) *Breaker {
+	return g.breakers[provider]
+}
+
+// Pricing returns the price table the gateway computes costs with
+func (g *Gateway) Pricing() *Pricing {
+	return g.pricing
+}
+
+// Route resolves the providers a call is sent to, in order: the call's own route,
+// the agent's route, the default route, or else the default provider alone
+func (g *Gateway) Route(call *Call) []Target {
+	switch {
+	case len(call.Route) > 0:
+		return call.Route
+	case len(g.routes[call.Agent]) > 0:
+		return g.routes[call.Agent]
+	case len(g.routes[DefaultRoute]) > 0:
+		return g.routes[DefaultRoute]
+	default:
+		return []Target{{Provider: g.registry.Default()}}
+	}
+}
+
+// Chat sends a chat completion request along the call's route.
+// The response's Provider and Model tell which target answered, CostUSD what it cost.
+func (g *Gateway) Chat(ctx context.Context, call *Call, req *Request) (*Response, error) {
+	return g.send(ctx, call, req, func(p Provider, req *Request) (*Response, bool, error) {
+		resp, err := p.Chat(ctx, req)
+		return resp, false, err
+	}, nil)
+}
+
+// ChatStream streams a chat completion along the call's route. A provider that fails
+// before sending anything is replaced by the next one; once the first chunk has been
+// passed to onChunk the request stays with its provider. A cached response arrives as
+// a single chunk.
+func (g *Gateway) ChatStream(ctx context.Context, call *Call, req *Request, onChunk func(Chunk) error) (*Response, error) {
+	var callbackErr error
+	return g.send(ctx, call, req, func(p Provider, req *Request) (*Response, bool, error) {
+		started := false
+		resp, err := p.ChatStream(ctx, req, func(c Chunk) error {
+			started = true
+			if err := onChunk(c); err != nil {
+				callbackErr = err
+				return err
+			}
+			return nil
+		})
+		if callbackErr != nil {
+			return nil, true, callbackErr
+		}
+		return resp, started, err
+	}, func(resp *Response) error {
+		return onChunk(Chunk{Content: resp.Content, FinishReason: resp.FinishReason})
+	})
+}
+
+// send tries the route's targets in order, answering from the cache where it can.
+// do reports whether the request is committed to the provider, in which case its error
+// ends the request; replay, if set, hands a cached response to the caller.
+func (g *Gateway) send(ctx context.Context, call *Call, req *Request,
+	do func(p Provider, req *Request) (*Response, bool, error), replay func(resp *Response) error) (*Response, error) {
+	if call == nil {
+		call = &Call{}
+	}
+	if g.opts.Admit != nil {
+		if err := g.opts.Admit(call); err != nil {
+			return nil, err
+		}
+	}
+	var callTape *Cassette
+	if g.opts.Cassette != nil {
+		var err error
+		if callTape, err = g.opts.Cassette(call); err != nil {
+			return nil, err
+		}
+	}
+	route := g.Route(call)
+	requestID := uuid.New().String()
+	cache := g.opts.Cache
+	cacheable := cache.Usable(call, req)
+
+	var errs []error
+	for i, target := range route {
+		attempt := &Attempt{RequestID: requestID, Call: *call, Number: i + 1, Provider: target.Provider, Model: target.Model}
+
+		p, err := g.registry.Get(target.Provider)
+		if err != nil {
+			attempt.Status, attempt.Err = AttemptFailed, err
+			g.record(attempt)
+			errs = append(errs, err)
+			continue
+		}
+		attempt.Provider = p.Name()
+		if attempt.Model == "" {
+			attempt.Model = p.Model()
+		}
+
+		tape := callTape
+		if tape == nil {
+			tape = g.cassettes[p.Name()]
+		}
+
+		var cacheKey string
+		switch {
+		case tape != nil:
+			// Recordings and replays are of the provider's own answers
+		case cacheable:
+			cacheKey = CacheKey(p.Name(), attempt.Model, req)
+			if resp := cache.Get(ctx, cacheKey, p.Name()); resp != nil {
+				cache.Saved(ctx, p.Name(), resp.Usage.TotalTokens, g.cost(p.Name(), attempt.Model, resp))
+				resp.Provider, resp.Cached = p.Name(), true
+				resp.Usage, resp.CostUSD = Usage{}, 0
+				if replay != nil {
+					if err := replay(resp); err != nil {
+						return nil, err
+					}
+				}
+				attempt.Status, attempt.Response = AttemptCached, resp
+				g.record(attempt)
+				return resp, nil
+			}
+		default:
+			cache.Bypass(ctx, p.Name())
+		}
+
+		// Replays say nothing about the provider's health and skip its breaker
+		replaying := tape.Replaying()
+		breaker := g.breakers[p.Name()]
+		if !replaying {
+			if err := breaker.Allow(); err != nil {
+				attempt.Status, attempt.Err = AttemptSkipped, &Error{Provider: p.Name(), Kind: ErrCircuitOpen}
+				g.record(attempt)
+				errs = append(errs, attempt.Err)
+				continue
+			}
+		}
+
+		r := *req
+		r.Model = attempt.Model
+		start := time.Now()
+		var resp *Response
+		var committed bool
+		if replaying {
+			resp, err = tape.Replay(p.Name(), attempt.Model, req)
+			if err == nil && replay != nil {
+				if err := replay(resp); err != nil {
+					return nil, err
+				}
+			}
+			committed = errors.Is(err, ErrCassetteMiss) // a miss never falls back: the recording had no such request
+		} else {
+			resp, committed, err = do(p, &r)
+		}
+		attempt.Latency = time.Since(start)
+		if tape != nil && !replaying && ctx.Err() == nil {
+			if rerr := tape.Record(p.Name(), attempt.Model, req, resp, err, attempt.Latency); rerr != nil {
+				return nil, fmt.Errorf("failed to record %s: %w", tape.Path(), rerr)
+			}
+		}
+
+		if err != nil && ctx.Err() != nil {
+			if !replaying {
+				breaker.Release()
+			}
+			attempt.Status, attempt.Err = AttemptFailed, ctx.Err()
+			g.record(attempt)
+			return nil, ctx.Err()
+		}
+		if !replaying {
+			breaker.Record(err != nil && Retryable(err), attempt.Latency)
+		}
+		if err == nil {
+			resp.Provider = p.Name()
+			if resp.Model == "" {
+				resp.Model = attempt.Model
+			}
+			resp.CostUSD = g.cost(p.Name(), attempt.Model, resp)
+			if cacheKey != "" {
+				cache.Put(ctx, cacheKey, resp)
+			}
+			attempt.Status, attempt.Response = AttemptSucceeded, resp
+			g.record(attempt)
+			return resp, nil
+		}
+
+		attempt.Status, attempt.Err = AttemptFailed, err
+		g.record(attempt)
+		errs = append(errs, err)
+		if committed || !canFallBack(err) {
+			return nil, err
+		}
+		if i < len(route)-1 {
+			logger.Warnf("llm: %s/%s failed (%v), falling back to %s", p.Name(), attempt.Model, err, route[i+1].Provider)
+		}
+	}
+
+	if len(errs) == 1 {
+		return nil, errs[0]
+	}
+	return nil, fmt.Errorf("all %d providers of the route failed: %w", len(route), errors.Join(errs...))
+}
+
+// cost prices a response of a provider to a request for model
+func (g *Gateway) cost(provider, model string, resp *Response) float64 {
+	// Providers may answer with a dated snapshot of the requested model
+	priced := resp.Model
+	if _, ok := g.pricing.Price(provider, priced); !ok {
+		priced = model
+	}
+	return g.pricing.Cost(provider, priced, resp.Usage)
+}
+
+// canFallBack reports whether another provider may succeed where one failed.
+// A request the provider rejected as invalid would be rejected everywhere.
+func canFallBack(err error) bool {
+	return !errors.Is(err, ErrInvalidRequest)
+}
+
+// record passes an attempt to the recorder, if any
+func (g *Gateway) record(a *Attempt) {
+	if g.opts.Record != nil {
+		g.opts.Record(a)
+	}
+}
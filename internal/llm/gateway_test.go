@@ -0,0 +1,231 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"agent-project-manager/internal/config"
+)
+
+// fakeProvider answers from a script of errors; a nil error answers "ok"
+type fakeProvider struct {
+	name  string
+	model string
+	errs  []error
+	calls int
+}
+
+func (p *fakeProvider) Name() string  { return p.name }
+func (p *fakeProvider) Type() string  { return "fake" }
+func (p *fakeProvider) Model() string { return p.model }
+
+func (p *fakeProvider) Chat(ctx context.Context, req *Request) (*Response, error) {
+	p.calls++
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Response{Model: req.Model, Content: "ok from " + p.name}, nil
+}
+
+func (p *fakeProvider) ChatStream(ctx context.Context, req *Request, onChunk func(Chunk) error) (*Response, error) {
+	resp, err := p.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, onChunk(Chunk{Content: resp.Content})
+}
+
+func (p *fakeProvider) ListModels(ctx context.Context) ([]Model, error) {
+	return []Model{{ID: p.model}}, nil
+}
+
+func newTestGateway(t *testing.T, cfg config.LLMConfig, providers ...*fakeProvider) (*Gateway, *[]*Attempt) {
+	t.Helper()
+	reg := &Registry{providers: map[string]Provider{}, defaultName: cfg.Provider}
+	for _, p := range providers {
+		reg.providers[p.name] = p
+		if cfg.Providers == nil {
+			cfg.Providers = map[string]config.ProviderConfig{}
+		}
+		cfg.Providers[p.name] = config.ProviderConfig{Type: "fake"}
+	}
+	var attempts []*Attempt
+	g, err := NewGateway(reg, cfg, GatewayOptions{Record: func(a *Attempt) { attempts = append(attempts, a) }})
+	if err != nil {
+		t.Fatalf("NewGateway: %v", err)
+	}
+	return g, &attempts
+}
+
+func transient() error {
+	return &Error{Provider: "test", Kind: ErrTransient, Message: "upstream down"}
+}
+
+func TestGatewayFallback(t *testing.T) {
+	local := &fakeProvider{name: "ollama-local", model: "qwen2.5-coder:7b", errs: []error{transient()}}
+	cloud := &fakeProvider{name: "openai", model: "gpt-4o-mini"}
+	g, attempts := newTestGateway(t, config.LLMConfig{
+		Provider: "ollama-local",
+		Routes: map[string][]config.RouteTarget{
+			"review": {{Provider: "ollama-local"}, {Provider: "openai", Model: "gpt-4o"}},
+		},
+	}, local, cloud)
+
+	resp, err := g.Chat(context.Background(), &Call{JobID: "j1", StepID: "s1", Agent: "review"}, &Request{})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Provider != "openai" || resp.Model != "gpt-4o" {
+		t.Errorf("answered by %s/%s, want openai/gpt-4o", resp.Provider, resp.Model)
+	}
+
+	if len(*attempts) != 2 {
+		t.Fatalf("recorded %d attempts, want 2", len(*attempts))
+	}
+	first, second := (*attempts)[0], (*attempts)[1]
+	if first.Status != AttemptFailed || first.Provider != "ollama-local" || first.Model != "qwen2.5-coder:7b" ||
+		ErrorKind(first.Err) != "transient" || first.Number != 1 {
+		t.Errorf("first attempt = %+v", first)
+	}
+	if second.Status != AttemptSucceeded || second.Number != 2 || second.RequestID != first.RequestID ||
+		second.Call.JobID != "j1" || second.Call.StepID != "s1" {
+		t.Errorf("second attempt = %+v", second)
+	}
+
+	// Agents without a route go to the default provider alone
+	local.errs = []error{transient()}
+	if _, err := g.Chat(context.Background(), &Call{Agent: "codegen"}, &Request{}); !errors.Is(err, ErrTransient) {
+		t.Errorf("unrouted agent: error = %v, want ErrTransient", err)
+	}
+}
+
+func TestGatewayNoFallbackOnInvalidRequest(t *testing.T) {
+	a := &fakeProvider{name: "a", errs: []error{&Error{Provider: "a", Kind: ErrInvalidRequest}}}
+	b := &fakeProvider{name: "b"}
+	g, _ := newTestGateway(t, config.LLMConfig{}, a, b)
+
+	_, err := g.Chat(context.Background(), &Call{Route: []Target{{Provider: "a"}, {Provider: "b"}}}, &Request{})
+	if !errors.Is(err, ErrInvalidRequest) || b.calls != 0 {
+		t.Errorf("error = %v, b called %d times; want ErrInvalidRequest without fallback", err, b.calls)
+	}
+}
+
+func TestGatewayAllFail(t *testing.T) {
+	a := &fakeProvider{name: "a", errs: []error{transient()}}
+	b := &fakeProvider{name: "b", errs: []error{&Error{Provider: "b", Kind: ErrRateLimit}}}
+	g, _ := newTestGateway(t, config.LLMConfig{}, a, b)
+
+	_, err := g.Chat(context.Background(), &Call{Route: []Target{{Provider: "a"}, {Provider: "b"}}}, &Request{})
+	if !errors.Is(err, ErrTransient) || !errors.Is(err, ErrRateLimit) {
+		t.Errorf("error = %v, want both providers' errors", err)
+	}
+}
+
+func TestGatewayCircuitBreaker(t *testing.T) {
+	flaky := &fakeProvider{name: "flaky"}
+	backup := &fakeProvider{name: "backup"}
+	for i := 0; i < 3; i++ {
+		flaky.errs = append(flaky.errs, transient())
+	}
+	g, attempts := newTestGateway(t, config.LLMConfig{
+		Routes:         map[string][]config.RouteTarget{DefaultRoute: {{Provider: "flaky"}, {Provider: "backup"}}},
+		CircuitBreaker: config.CircuitBreakerConfig{MinCalls: 3, ErrorRate: 0.5, OpenFor: "1h"},
+	}, flaky, backup)
+
+	for i := 0; i < 5; i++ {
+		resp, err := g.Chat(context.Background(), &Call{}, &Request{})
+		if err != nil || resp.Provider != "backup" {
+			t.Fatalf("request %d: %v, %v", i, resp, err)
+		}
+	}
+	if flaky.calls != 3 {
+		t.Errorf("flaky called %d times, want 3 before its breaker opened", flaky.calls)
+	}
+	if state := g.Breaker("flaky").State(); state != BreakerOpen {
+		t.Errorf("breaker state = %s, want open", state)
+	}
+	last := (*attempts)[len(*attempts)-2]
+	if last.Status != AttemptSkipped || ErrorKind(last.Err) != "circuit_open" {
+		t.Errorf("attempt on open breaker = %+v", last)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewBreaker(config.CircuitBreakerConfig{MinCalls: 2, ErrorRate: 0.5, SlowCall: "10s", SlowRate: 0.5, OpenFor: "30s"})
+	b.now = func() time.Time { return now }
+
+	// Slow calls trip the breaker like failures
+	b.Record(false, 20*time.Second)
+	b.Record(false, 20*time.Second)
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Allow after slow calls = %v, want ErrCircuitOpen", err)
+	}
+
+	// After the open period one probe goes through; a failed probe reopens the breaker
+	now = now.Add(31 * time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe refused: %v", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("second call during probe = %v, want ErrCircuitOpen", err)
+	}
+	b.Record(true, time.Second)
+	if b.State() != BreakerOpen {
+		t.Errorf("state after failed probe = %s, want open", b.State())
+	}
+
+	// A successful probe closes it
+	now = now.Add(31 * time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe refused: %v", err)
+	}
+	b.Record(false, time.Second)
+	if b.State() != BreakerClosed {
+		t.Errorf("state after successful probe = %s, want closed", b.State())
+	}
+}
+
+func TestGatewayCostAndAdmit(t *testing.T) {
+	p := &fakeProvider{name: "openai", model: "gpt-4o-mini"}
+	g, attempts := newTestGateway(t, config.LLMConfig{
+		Provider: "openai",
+		Prices: map[string]config.ModelPrice{
+			"gpt-4o-mini":        {Input: 0.15, Output: 0.6},
+			"openai/gpt-4o-mini": {Input: 0.3, Output: 1.2}, // wins over the bare model
+		},
+	}, p)
+	usage := Usage{PromptTokens: 1_000_000, CompletionTokens: 500_000}
+	if got := g.Pricing().Cost("openai", "gpt-4o-mini", usage); got != 0.9 {
+		t.Errorf("Cost = %v, want 0.9", got)
+	}
+	if got := g.Pricing().Cost("openai", "local-model", usage); got != 0 {
+		t.Errorf("unlisted model costs %v, want 0", got)
+	}
+
+	var admitted []string
+	g.opts.Admit = func(call *Call) error {
+		admitted = append(admitted, call.JobID)
+		if call.JobID == "spent" {
+			return ErrBudgetExceeded
+		}
+		return nil
+	}
+	if _, err := g.Chat(context.Background(), &Call{JobID: "fresh"}, &Request{}); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	_, err := g.Chat(context.Background(), &Call{JobID: "spent"}, &Request{})
+	if !errors.Is(err, ErrBudgetExceeded) || ErrorKind(err) != "budget_exceeded" {
+		t.Errorf("error = %v, want ErrBudgetExceeded", err)
+	}
+	if p.calls != 1 || len(*attempts) != 1 || fmt.Sprint(admitted) != "[fresh spent]" {
+		t.Errorf("provider called %d times, %d attempts recorded, admitted %v", p.calls, len(*attempts), admitted)
+	}
+}
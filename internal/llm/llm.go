@@ -0,0 +1,158 @@
+// Package llm is the gateway to large language model providers.
+//
+// Provider is the interface every adapter implements: chat completion, streamed chat
+// completion and model listing. A Registry holds the providers configured by name. Errors returned by providers are classified (see Error),
+// so callers can decide whether to retry, shrink the prompt or give up:
+//
+//	resp, err := provider.Chat(ctx, &llm.Request{Messages: []llm.Message{
+//		{Role: llm.RoleUser, Content: "Write a haiku about Go"},
+//	}})
+//	if errors.Is(err, llm.ErrRateLimit) { ... }
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"agent-project-manager/internal/config"
+)
+
+// Message roles
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool" // the result of a tool call
+)
+
+// Message is one turn of a conversation
+type Message struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"toolCalls,omitempty"`  // the tools an assistant message calls
+	ToolCallID string     `json:"toolCallId,omitempty"` // the call a tool message answers
+	Name       string     `json:"name,omitempty"`       // the tool a tool message answers
+}
+
+// ToolSpec describes a tool the model may call
+type ToolSpec struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"` // a JSON Schema of the arguments object
+}
+
+// ToolCall is a call of a tool the model asked for
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // a JSON object
+}
+
+// Request is a chat completion request
+type Request struct {
+	Model       string // empty uses the provider's configured model
+	Messages    []Message
+	Temperature *float64 // nil uses the provider's default
+	MaxTokens   int      // zero leaves the limit to the provider
+	Stop        []string
+	Format      *JSONFormat // asks for a JSON reply; nil for free text
+	Tools       []ToolSpec  // tools the model may call; see Gateway.ChatTools
+}
+
+// JSONFormat asks for a reply that is a JSON document matching Schema. Providers use
+// their native JSON support as configured by their jsonMode; the reply still has to
+// be validated, see Gateway.ChatJSON.
+type JSONFormat struct {
+	Name   string                 // names the schema for APIs that want one
+	Schema map[string]interface{} // a JSON Schema
+}
+
+// Usage counts the tokens a request consumed
+type Usage struct {
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+	TotalTokens      int `json:"totalTokens"`
+}
+
+// Response is a completed chat completion
+type Response struct {
+	Provider     string  `json:"provider"` // the provider that answered; set by Gateway
+	Model        string  `json:"model"`    // the model that answered, as reported by the provider
+	Content      string  `json:"content"`
+	FinishReason string  `json:"finishReason"` // e.g. "stop" or "length"
+	Usage        Usage   `json:"usage"`
+	CostUSD      float64 `json:"costUsd"` // what the call cost by the configured price table; set by Gateway
+	Cached       bool    `json:"cached"`  // answered from the response cache; Usage and CostUSD are then zero
+
+	ToolCalls []ToolCall `json:"toolCalls,omitempty"` // tools the model wants called before it answers
+}
+
+// Chunk is a piece of a streamed chat completion
+type Chunk struct {
+	Content      string
+	FinishReason string // set on the last chunk
+}
+
+// Model is a model a provider offers
+type Model struct {
+	ID      string
+	OwnedBy string
+	Size    int64 // bytes on disk for local models; zero when unknown
+}
+
+// Provider is an LLM backend
+type Provider interface {
+	// Name is the name the provider is configured under, e.g. "ollama-local"
+	Name() string
+	// Type is the API the provider speaks: ProviderOpenAI or ProviderOllama
+	Type() string
+	// Model is the default model, used by requests that do not name one
+	Model() string
+	// Chat sends a chat completion request and waits for the whole answer
+	Chat(ctx context.Context, req *Request) (*Response, error)
+	// ChatStream sends a chat completion request and calls onChunk for every piece of
+	// the answer as it arrives. It returns the assembled response; an error returned
+	// by onChunk aborts the stream and is returned as is.
+	ChatStream(ctx context.Context, req *Request, onChunk func(Chunk) error) (*Response, error)
+	// ListModels lists the models the provider offers
+	ListModels(ctx context.Context) ([]Model, error)
+}
+
+// ModelChecker is implemented by providers that can look up a single model
+// more cheaply than listing them all
+type ModelChecker interface {
+	CheckModel(ctx context.Context, model string) error
+}
+
+// CheckModel returns an error matching ErrModelNotFound when p does not offer model.
+// An empty model checks the provider's default model.
+func CheckModel(ctx context.Context, p Provider, model string) error {
+	if model == "" {
+		model = p.Model()
+	}
+	if c, ok := p.(ModelChecker); ok {
+		return c.CheckModel(ctx, model)
+	}
+	models, err := p.ListModels(ctx)
+	if err != nil {
+		return err
+	}
+	for _, m := range models {
+		if m.ID == model {
+			return nil
+		}
+	}
+	return &Error{Provider: p.Name(), Kind: ErrModelNotFound, Message: fmt.Sprintf("model %q is not available", model)}
+}
+
+// NewProvider creates a provider of the configured type
+func NewProvider(name string, cfg config.ProviderConfig) (Provider, error) {
+	switch cfg.Type {
+	case ProviderOpenAI:
+		return NewOpenAI(name, cfg)
+	case ProviderOllama:
+		return NewOllama(name, cfg), nil
+	default:
+		return nil, fmt.Errorf("provider %s: unsupported type %q", name, cfg.Type)
+	}
+}
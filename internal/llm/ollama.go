@@ -0,0 +1,435 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"agent-project-manager/internal/config"
+)
+
+// ProviderOllama is the type of Ollama providers
+const ProviderOllama = config.ProviderTypeOllama
+
+// DefaultOllamaBaseURL is where a local Ollama listens by default
+const DefaultOllamaBaseURL = "http://127.0.0.1:11434"
+
+// Ollama talks to the native Ollama API (/api/chat, /api/tags, /api/pull)
+type Ollama struct {
+	name      string
+	baseURL   string
+	headers   map[string]string
+	model     string
+	keepAlive interface{} // a duration string or a number of seconds, as Ollama accepts both
+	options   map[string]interface{}
+	jsonMode  string
+	toolMode  string
+	client    *http.Client
+}
+
+// PullProgress reports the state of a model download
+type PullProgress struct {
+	Status    string // e.g. "pulling manifest", "downloading", "success"
+	Digest    string // the layer being downloaded
+	Total     int64  // layer size in bytes
+	Completed int64  // bytes downloaded so far
+}
+
+// NewOllama creates an Ollama provider from its config
+func NewOllama(name string, cfg config.ProviderConfig) *Ollama {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultOllamaBaseURL
+	}
+
+	options := map[string]interface{}{}
+	for k, v := range cfg.Options {
+		options[k] = v
+	}
+	if cfg.NumCtx > 0 {
+		options["num_ctx"] = cfg.NumCtx
+	}
+
+	var keepAlive interface{}
+	if cfg.KeepAlive != "" {
+		keepAlive = cfg.KeepAlive
+		if secs, err := strconv.Atoi(cfg.KeepAlive); err == nil {
+			keepAlive = secs
+		}
+	}
+
+	return &Ollama{
+		name:      name,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		headers:   cfg.Headers,
+		model:     cfg.Model,
+		keepAlive: keepAlive,
+		options:   options,
+		jsonMode:  cfg.JSONMode,
+		toolMode:  cfg.ToolMode,
+		client:    &http.Client{}, // loading a model on a Pi takes a while; requests are bounded by their context
+	}
+}
+
+// Name implements Provider
+func (p *Ollama) Name() string {
+	return p.name
+}
+
+// Model implements Provider
+func (p *Ollama) Model() string {
+	return p.model
+}
+
+// Type implements Provider
+func (p *Ollama) Type() string {
+	return ProviderOllama
+}
+
+// ollamaChatRequest is the body of POST /api/chat
+type ollamaChatRequest struct {
+	Model     string                 `json:"model"`
+	Messages  []ollamaMessage        `json:"messages"`
+	Stream    bool                   `json:"stream"`
+	KeepAlive interface{}            `json:"keep_alive,omitempty"`
+	Options   map[string]interface{} `json:"options,omitempty"`
+	Format    interface{}            `json:"format,omitempty"` // "json" or a JSON Schema
+	Tools     []ollamaTool           `json:"tools,omitempty"`
+}
+
+// ollamaMessage is a message as the API exchanges it
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"` // the tool a tool message answers
+}
+
+// ollamaToolCall is a function call of an assistant message; unlike OpenAI, Ollama
+// passes the arguments as an object and gives calls no ID
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// ollamaTool offers a function to the model, in the same shape as OpenAI's
+type ollamaTool = openAITool
+
+// ollamaChatResponse is a completion, or one line of a streamed completion
+type ollamaChatResponse struct {
+	Model           string        `json:"model"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// response converts the final line of a completion
+func (r *ollamaChatResponse) response(content string, calls []ollamaToolCall) *Response {
+	out := &Response{
+		Model:        r.Model,
+		Content:      content,
+		FinishReason: r.DoneReason,
+		Usage: Usage{
+			PromptTokens:     r.PromptEvalCount,
+			CompletionTokens: r.EvalCount,
+			TotalTokens:      r.PromptEvalCount + r.EvalCount,
+		},
+	}
+	for i, tc := range calls {
+		out.ToolCalls = append(out.ToolCalls, ToolCall{
+			ID:        fmt.Sprintf("call_%d", i+1),
+			Name:      tc.Function.Name,
+			Arguments: string(argumentsJSON(string(tc.Function.Arguments))),
+		})
+	}
+	return out
+}
+
+// Chat implements Provider
+func (p *Ollama) Chat(ctx context.Context, req *Request) (*Response, error) {
+	resp, err := p.post(ctx, "/api/chat", p.chatRequest(req, false))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body ollamaChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, transportError(p.name, fmt.Errorf("failed to decode response: %w", err))
+	}
+	if body.Error != "" {
+		return nil, p.streamError(body.Error)
+	}
+	out := body.response(body.Message.Content, body.Message.ToolCalls)
+	if promptTools(p.toolMode, req) {
+		out.Content, out.ToolCalls = parseToolCalls(out.Content)
+	}
+	return out, nil
+}
+
+// ChatStream implements Provider using Ollama's newline-delimited JSON stream
+func (p *Ollama) ChatStream(ctx context.Context, req *Request, onChunk func(Chunk) error) (*Response, error) {
+	resp, err := p.post(ctx, "/api/chat", p.chatRequest(req, true))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var content strings.Builder
+	var calls []ollamaToolCall
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var line ollamaChatResponse
+		if err := dec.Decode(&line); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF // the stream ended without a done line
+			}
+			return nil, transportError(p.name, err)
+		}
+		if line.Error != "" {
+			return nil, p.streamError(line.Error)
+		}
+
+		calls = append(calls, line.Message.ToolCalls...)
+		c := Chunk{Content: line.Message.Content}
+		if line.Done {
+			c.FinishReason = line.DoneReason
+			if c.FinishReason == "" {
+				c.FinishReason = "stop"
+			}
+		}
+		if c.Content != "" || c.FinishReason != "" {
+			content.WriteString(c.Content)
+			if err := onChunk(c); err != nil {
+				return nil, err
+			}
+		}
+		if line.Done {
+			out := line.response(content.String(), calls)
+			out.FinishReason = c.FinishReason
+			if promptTools(p.toolMode, req) {
+				out.Content, out.ToolCalls = parseToolCalls(out.Content)
+			}
+			return out, nil
+		}
+	}
+}
+
+// ListModels implements Provider: the models installed locally
+func (p *Ollama) ListModels(ctx context.Context) ([]Model, error) {
+	resp, err := p.do(ctx, http.MethodGet, "/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Models []struct {
+			Name string `json:"name"`
+			Size int64  `json:"size"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, transportError(p.name, fmt.Errorf("failed to decode response: %w", err))
+	}
+	models := make([]Model, len(body.Models))
+	for i, m := range body.Models {
+		models[i] = Model{ID: m.Name, OwnedBy: ProviderOllama, Size: m.Size}
+	}
+	return models, nil
+}
+
+// CheckModel implements ModelChecker. It returns an error matching
+// ErrModelNotFound, with the command to install the model, when it is not installed.
+func (p *Ollama) CheckModel(ctx context.Context, model string) error {
+	if model == "" {
+		model = p.model
+	}
+	resp, err := p.post(ctx, "/api/show", map[string]string{"model": model})
+	if err != nil {
+		var e *Error
+		if errors.As(err, &e) && e.Kind == ErrModelNotFound {
+			e.Message = fmt.Sprintf("model %q is not installed on the Ollama server at %s; run \"ollama pull %s\"",
+				model, p.baseURL, model)
+		}
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// PullModel downloads a model to the Ollama server, calling onProgress (if not nil)
+// as the download advances. It returns when the model is installed.
+func (p *Ollama) PullModel(ctx context.Context, model string, onProgress func(PullProgress) error) error {
+	resp, err := p.post(ctx, "/api/pull", map[string]interface{}{"model": model, "stream": true})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var line struct {
+			Status    string `json:"status"`
+			Digest    string `json:"digest"`
+			Total     int64  `json:"total"`
+			Completed int64  `json:"completed"`
+			Error     string `json:"error"`
+		}
+		if err := dec.Decode(&line); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF // the stream ended before "success"
+			}
+			return transportError(p.name, err)
+		}
+		if line.Error != "" {
+			e := p.streamError(line.Error)
+			if strings.Contains(line.Error, "file does not exist") {
+				e.Kind = ErrModelNotFound // the registry has no such model or tag
+			}
+			return e
+		}
+		if onProgress != nil {
+			progress := PullProgress{Status: line.Status, Digest: line.Digest, Total: line.Total, Completed: line.Completed}
+			if err := onProgress(progress); err != nil {
+				return err
+			}
+		}
+		if line.Status == "success" {
+			return nil
+		}
+	}
+}
+
+// chatRequest builds the API request body
+func (p *Ollama) chatRequest(req *Request, stream bool) *ollamaChatRequest {
+	model := req.Model
+	if model == "" {
+		model = p.model
+	}
+
+	options := map[string]interface{}{}
+	for k, v := range p.options {
+		options[k] = v
+	}
+	if req.Temperature != nil {
+		options["temperature"] = *req.Temperature
+	}
+	if req.MaxTokens > 0 {
+		options["num_predict"] = req.MaxTokens
+	}
+	if len(req.Stop) > 0 {
+		options["stop"] = req.Stop
+	}
+
+	body := &ollamaChatRequest{
+		Model:     model,
+		Stream:    stream,
+		KeepAlive: p.keepAlive,
+		Options:   options,
+	}
+
+	messages := req.Messages
+	if promptTools(p.toolMode, req) {
+		messages = withToolProtocol(messages, req.Tools)
+	} else {
+		for _, t := range req.Tools {
+			body.Tools = append(body.Tools, ollamaTool{
+				Type:     "function",
+				Function: openAIToolFunction{Name: t.Name, Description: t.Description, Parameters: t.Parameters},
+			})
+		}
+	}
+	if f := req.Format; f != nil {
+		switch p.jsonMode {
+		case config.JSONModeObject:
+			messages = withSchemaInstruction(messages, f)
+			body.Format = "json"
+		case config.JSONModePrompt:
+			messages = withSchemaInstruction(messages, f)
+		default:
+			body.Format = f.Schema
+		}
+	}
+	body.Messages = make([]ollamaMessage, len(messages))
+	for i, m := range messages {
+		body.Messages[i] = ollamaMessage{Role: m.Role, Content: m.Content}
+		if m.Role == RoleTool {
+			body.Messages[i].ToolName = m.Name
+		}
+		for _, tc := range m.ToolCalls {
+			var call ollamaToolCall
+			call.Function.Name = tc.Name
+			call.Function.Arguments = argumentsJSON(tc.Arguments)
+			body.Messages[i].ToolCalls = append(body.Messages[i].ToolCalls, call)
+		}
+	}
+	return body
+}
+
+// streamError classifies an error Ollama reported after it had answered 200,
+// such as a crashed model runner; these are server-side failures
+func (p *Ollama) streamError(message string) *Error {
+	return &Error{Provider: p.name, Kind: classifyStatus(http.StatusInternalServerError, "", message), Message: message}
+}
+
+// post sends a JSON request
+func (p *Ollama) post(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	return p.do(ctx, http.MethodPost, path, raw)
+}
+
+// do sends a request and turns error responses into classified errors.
+// The caller closes the body of a successful response.
+func (p *Ollama) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range p.headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, transportError(p.name, err)
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	e := &Error{Provider: p.name, StatusCode: resp.StatusCode, RetryAfter: retryAfter(resp.Header)}
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(raw, &apiErr) == nil && apiErr.Error != "" {
+		e.Message = apiErr.Error
+	} else {
+		e.Message = strings.TrimSpace(string(raw))
+	}
+	e.Kind = classifyStatus(resp.StatusCode, "", e.Message)
+	return nil, e
+}
@@ -0,0 +1,217 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"agent-project-manager/internal/config"
+)
+
+func newTestOllama(t *testing.T, handler http.HandlerFunc) *Ollama {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewOllama("ollama", config.ProviderConfig{
+		Type:      ProviderOllama,
+		BaseURL:   srv.URL,
+		Model:     "qwen2.5-coder:7b",
+		KeepAlive: "10m",
+		NumCtx:    8192,
+		Options:   map[string]interface{}{"num_thread": 4},
+	})
+}
+
+func TestOllamaChat(t *testing.T) {
+	p := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if body["model"] != "qwen2.5-coder:7b" || body["stream"] != false || body["keep_alive"] != "10m" {
+			t.Errorf("unexpected request body %v", body)
+		}
+		options, _ := body["options"].(map[string]interface{})
+		if options["num_ctx"] != 8192.0 || options["num_thread"] != 4.0 || options["temperature"] != 0.2 ||
+			options["num_predict"] != 100.0 {
+			t.Errorf("unexpected options %v", options)
+		}
+		fmt.Fprint(w, `{"model":"qwen2.5-coder:7b","message":{"role":"assistant","content":"hello"},
+			"done":true,"done_reason":"stop","prompt_eval_count":12,"eval_count":3}`)
+	})
+
+	temperature := 0.2
+	resp, err := p.Chat(context.Background(), &Request{
+		Messages:    []Message{{Role: RoleUser, Content: "hi"}},
+		Temperature: &temperature,
+		MaxTokens:   100,
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	want := &Response{Model: "qwen2.5-coder:7b", Content: "hello", FinishReason: "stop", Usage: Usage{12, 3, 15}}
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("Chat = %+v, want %+v", resp, want)
+	}
+}
+
+func TestOllamaChatStream(t *testing.T) {
+	p := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		var body ollamaChatRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		if !body.Stream {
+			t.Error("expected a streaming request")
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprintln(w, `{"model":"m","message":{"role":"assistant","content":"hel"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"m","message":{"role":"assistant","content":"lo"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"m","message":{"role":"assistant","content":""},"done":true,"done_reason":"length",`+
+			`"prompt_eval_count":5,"eval_count":2}`)
+	})
+
+	var chunks []Chunk
+	resp, err := p.ChatStream(context.Background(), &Request{}, func(c Chunk) error {
+		chunks = append(chunks, c)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	wantChunks := []Chunk{{Content: "hel"}, {Content: "lo"}, {FinishReason: "length"}}
+	if len(chunks) != len(wantChunks) {
+		t.Fatalf("chunks = %+v, want %+v", chunks, wantChunks)
+	}
+	for i := range chunks {
+		if chunks[i] != wantChunks[i] {
+			t.Errorf("chunk %d = %+v, want %+v", i, chunks[i], wantChunks[i])
+		}
+	}
+	want := &Response{Model: "m", Content: "hello", FinishReason: "length", Usage: Usage{5, 2, 7}}
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("ChatStream = %+v, want %+v", resp, want)
+	}
+}
+
+func TestOllamaChatStreamErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		kind error
+	}{
+		{"error line", `{"model":"m","message":{"content":"a"},"done":false}` + "\n" + `{"error":"model runner has unexpectedly stopped"}`, ErrTransient},
+		{"truncated", `{"model":"m","message":{"content":"a"},"done":false}`, ErrTransient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintln(w, tt.body)
+			})
+			_, err := p.ChatStream(context.Background(), &Request{}, func(Chunk) error { return nil })
+			if !errors.Is(err, tt.kind) {
+				t.Errorf("error = %v, want %v", err, tt.kind)
+			}
+		})
+	}
+}
+
+func TestOllamaModelNotInstalled(t *testing.T) {
+	p := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"model \"llama3:70b\" not found, try pulling it first"}`)
+	})
+
+	_, err := p.Chat(context.Background(), &Request{Model: "llama3:70b"})
+	if !errors.Is(err, ErrModelNotFound) || Retryable(err) {
+		t.Errorf("Chat error = %v, want ErrModelNotFound", err)
+	}
+
+	err = CheckModel(context.Background(), p, "llama3:70b")
+	if !errors.Is(err, ErrModelNotFound) {
+		t.Fatalf("CheckModel error = %v, want ErrModelNotFound", err)
+	}
+	if !strings.Contains(err.Error(), `ollama pull llama3:70b`) {
+		t.Errorf("CheckModel error %q does not say how to install the model", err)
+	}
+}
+
+func TestOllamaCheckModel(t *testing.T) {
+	p := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path != "/api/show" || body["model"] != "qwen2.5-coder:7b" {
+			t.Errorf("unexpected request %s %v", r.URL.Path, body)
+		}
+		fmt.Fprint(w, `{"details":{"family":"qwen2"}}`)
+	})
+
+	if err := p.CheckModel(context.Background(), ""); err != nil {
+		t.Errorf("CheckModel: %v", err)
+	}
+}
+
+func TestOllamaListModels(t *testing.T) {
+	p := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/tags" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		fmt.Fprint(w, `{"models":[{"name":"qwen2.5-coder:7b","size":4683087332},{"name":"nomic-embed-text:latest","size":274302450}]}`)
+	})
+
+	models, err := p.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	want := []Model{
+		{ID: "qwen2.5-coder:7b", OwnedBy: ProviderOllama, Size: 4683087332},
+		{ID: "nomic-embed-text:latest", OwnedBy: ProviderOllama, Size: 274302450},
+	}
+	if len(models) != len(want) || models[0] != want[0] || models[1] != want[1] {
+		t.Errorf("ListModels = %+v, want %+v", models, want)
+	}
+}
+
+func TestOllamaPullModel(t *testing.T) {
+	p := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/pull" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		switch body["model"] {
+		case "qwen2.5-coder:7b":
+			fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+			fmt.Fprintln(w, `{"status":"downloading","digest":"sha256:abc","total":100,"completed":40}`)
+			fmt.Fprintln(w, `{"status":"downloading","digest":"sha256:abc","total":100,"completed":100}`)
+			fmt.Fprintln(w, `{"status":"success"}`)
+		default:
+			fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+			fmt.Fprintln(w, `{"error":"pull model manifest: file does not exist"}`)
+		}
+	})
+
+	var progress []PullProgress
+	err := p.PullModel(context.Background(), "qwen2.5-coder:7b", func(pp PullProgress) error {
+		progress = append(progress, pp)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("PullModel: %v", err)
+	}
+	if len(progress) != 4 || progress[2].Completed != 100 || progress[3].Status != "success" {
+		t.Errorf("progress = %+v", progress)
+	}
+
+	err = p.PullModel(context.Background(), "no-such-model", nil)
+	if !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("PullModel error = %v, want ErrModelNotFound", err)
+	}
+}
@@ -0,0 +1,397 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"agent-project-manager/internal/config"
+)
+
+// ProviderOpenAI is the type of OpenAI-compatible providers
+const ProviderOpenAI = config.ProviderTypeOpenAI
+
+// DefaultOpenAIBaseURL is the OpenAI API used when the config does not name one
+const DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAI talks to the OpenAI Chat Completions API, or to any server that implements it
+// such as LM Studio, the llama.cpp server or vLLM
+type OpenAI struct {
+	name     string
+	baseURL  string
+	apiKey   string
+	headers  map[string]string
+	model    string
+	jsonMode string
+	toolMode string
+	client   *http.Client
+}
+
+// NewOpenAI creates an OpenAI-compatible provider from its config
+func NewOpenAI(name string, cfg config.ProviderConfig) (*OpenAI, error) {
+	apiKey, err := cfg.ResolveAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+	return &OpenAI{
+		name:     name,
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		apiKey:   apiKey,
+		headers:  cfg.Headers,
+		model:    cfg.Model,
+		jsonMode: cfg.JSONMode,
+		toolMode: cfg.ToolMode,
+		client:   &http.Client{}, // requests are bounded by their context; streams may run long
+	}, nil
+}
+
+// Name implements Provider
+func (p *OpenAI) Name() string {
+	return p.name
+}
+
+// Model implements Provider
+func (p *OpenAI) Model() string {
+	return p.model
+}
+
+// Type implements Provider
+func (p *OpenAI) Type() string {
+	return ProviderOpenAI
+}
+
+// openAIChatRequest is the body of POST /chat/completions
+type openAIChatRequest struct {
+	Model          string                `json:"model"`
+	Messages       []openAIMessage       `json:"messages"`
+	Temperature    *float64              `json:"temperature,omitempty"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	Stop           []string              `json:"stop,omitempty"`
+	Stream         bool                  `json:"stream,omitempty"`
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+	Tools          []openAITool          `json:"tools,omitempty"`
+}
+
+// openAIMessage is a message as the API exchanges it
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// openAIToolCall is a function call of an assistant message, or a piece of one in a stream
+type openAIToolCall struct {
+	Index    *int   `json:"index,omitempty"` // which call a stream delta belongs to
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// openAITool offers a function to the model
+type openAITool struct {
+	Type     string             `json:"type"` // function
+	Function openAIToolFunction `json:"function"`
+}
+
+type openAIToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// openAIResponseFormat asks for JSON output
+type openAIResponseFormat struct {
+	Type       string                `json:"type"` // json_schema or json_object
+	JSONSchema *openAIJSONSchemaSpec `json:"json_schema,omitempty"`
+}
+
+// openAIJSONSchemaSpec is the schema of a json_schema response format
+type openAIJSONSchemaSpec struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// openAIChatResponse is a completion, or one chunk of a streamed completion
+type openAIChatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		Delta        openAIMessage `json:"delta"`
+		FinishReason *string       `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+type openAIErrorResponse struct {
+	Error struct {
+		Message string      `json:"message"`
+		Type    string      `json:"type"`
+		Code    interface{} `json:"code"` // a string, a number or null depending on the error
+	} `json:"error"`
+}
+
+// Chat implements Provider
+func (p *OpenAI) Chat(ctx context.Context, req *Request) (*Response, error) {
+	resp, err := p.post(ctx, "/chat/completions", p.chatRequest(req, false))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, transportError(p.name, fmt.Errorf("failed to decode response: %w", err))
+	}
+	if len(body.Choices) == 0 {
+		return nil, &Error{Provider: p.name, Kind: ErrTransient, Message: "response has no choices"}
+	}
+
+	msg := body.Choices[0].Message
+	out := &Response{Model: body.Model, Content: msg.Content}
+	for _, tc := range msg.ToolCalls {
+		out.ToolCalls = append(out.ToolCalls, ToolCall{ID: tc.ID, Name: tc.Function.Name, Arguments: tc.Function.Arguments})
+	}
+	if fr := body.Choices[0].FinishReason; fr != nil {
+		out.FinishReason = *fr
+	}
+	if body.Usage != nil {
+		out.Usage = Usage(*body.Usage)
+	}
+	if promptTools(p.toolMode, req) {
+		out.Content, out.ToolCalls = parseToolCalls(out.Content)
+	}
+	return out, nil
+}
+
+// ChatStream implements Provider using server-sent events
+func (p *OpenAI) ChatStream(ctx context.Context, req *Request, onChunk func(Chunk) error) (*Response, error) {
+	resp, err := p.post(ctx, "/chat/completions", p.chatRequest(req, true))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	out := &Response{}
+	var content strings.Builder
+	var calls []ToolCall // assembled from the deltas, by index
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue // blank separators, comments and other SSE fields
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			out.Content, out.ToolCalls = content.String(), calls
+			if promptTools(p.toolMode, req) {
+				out.Content, out.ToolCalls = parseToolCalls(out.Content)
+			}
+			return out, nil
+		}
+
+		var chunk openAIChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, transportError(p.name, fmt.Errorf("failed to decode stream chunk: %w", err))
+		}
+		if chunk.Model != "" {
+			out.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			out.Usage = Usage(*chunk.Usage)
+		}
+		if len(chunk.Choices) == 0 {
+			continue // the usage chunk at the end of the stream has no choices
+		}
+		for _, tc := range chunk.Choices[0].Delta.ToolCalls {
+			i := len(calls)
+			if tc.Index != nil {
+				i = *tc.Index
+			}
+			for len(calls) <= i {
+				calls = append(calls, ToolCall{})
+			}
+			if tc.ID != "" {
+				calls[i].ID = tc.ID
+			}
+			calls[i].Name += tc.Function.Name
+			calls[i].Arguments += tc.Function.Arguments
+		}
+		c := Chunk{Content: chunk.Choices[0].Delta.Content}
+		if fr := chunk.Choices[0].FinishReason; fr != nil {
+			c.FinishReason = *fr
+			out.FinishReason = *fr
+		}
+		if c.Content == "" && c.FinishReason == "" {
+			continue
+		}
+		content.WriteString(c.Content)
+		if err := onChunk(c); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, transportError(p.name, err)
+	}
+	return nil, transportError(p.name, io.ErrUnexpectedEOF)
+}
+
+// ListModels implements Provider
+func (p *OpenAI) ListModels(ctx context.Context) ([]Model, error) {
+	resp, err := p.do(ctx, http.MethodGet, "/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Data []struct {
+			ID      string `json:"id"`
+			OwnedBy string `json:"owned_by"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, transportError(p.name, fmt.Errorf("failed to decode response: %w", err))
+	}
+	models := make([]Model, len(body.Data))
+	for i, m := range body.Data {
+		models[i] = Model{ID: m.ID, OwnedBy: m.OwnedBy}
+	}
+	return models, nil
+}
+
+// chatRequest builds the API request body
+func (p *OpenAI) chatRequest(req *Request, stream bool) *openAIChatRequest {
+	model := req.Model
+	if model == "" {
+		model = p.model
+	}
+	body := &openAIChatRequest{
+		Model:       model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		Stop:        req.Stop,
+		Stream:      stream,
+	}
+	if stream {
+		body.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+
+	messages := req.Messages
+	if promptTools(p.toolMode, req) {
+		messages = withToolProtocol(messages, req.Tools)
+	} else {
+		for _, t := range req.Tools {
+			body.Tools = append(body.Tools, openAITool{
+				Type:     "function",
+				Function: openAIToolFunction{Name: t.Name, Description: t.Description, Parameters: t.Parameters},
+			})
+		}
+	}
+	if f := req.Format; f != nil {
+		switch p.jsonMode {
+		case config.JSONModeObject:
+			messages = withSchemaInstruction(messages, f)
+			body.ResponseFormat = &openAIResponseFormat{Type: "json_object"}
+		case config.JSONModePrompt:
+			messages = withSchemaInstruction(messages, f)
+		default:
+			body.ResponseFormat = &openAIResponseFormat{
+				Type:       "json_schema",
+				JSONSchema: &openAIJSONSchemaSpec{Name: f.name(), Schema: f.Schema},
+			}
+		}
+	}
+	body.Messages = make([]openAIMessage, len(messages))
+	for i, m := range messages {
+		body.Messages[i] = openAIMessage{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		for _, tc := range m.ToolCalls {
+			call := openAIToolCall{ID: tc.ID, Type: "function"}
+			call.Function.Name = tc.Name
+			call.Function.Arguments = tc.Arguments
+			body.Messages[i].ToolCalls = append(body.Messages[i].ToolCalls, call)
+		}
+	}
+	return body
+}
+
+// post sends a JSON request
+func (p *OpenAI) post(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	return p.do(ctx, http.MethodPost, path, raw)
+}
+
+// do sends a request and turns error responses into classified errors.
+// The caller closes the body of a successful response.
+func (p *OpenAI) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range p.headers {
+		httpReq.Header.Set(k, v)
+	}
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, transportError(p.name, err)
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	e := &Error{Provider: p.name, StatusCode: resp.StatusCode, RetryAfter: retryAfter(resp.Header)}
+	var apiErr openAIErrorResponse
+	if json.Unmarshal(raw, &apiErr) == nil && apiErr.Error.Message != "" {
+		e.Message = apiErr.Error.Message
+		if code, ok := apiErr.Error.Code.(string); ok {
+			e.Code = code
+		} else {
+			e.Code = apiErr.Error.Type
+		}
+	} else {
+		e.Message = strings.TrimSpace(string(raw))
+	}
+	e.Kind = classifyStatus(resp.StatusCode, e.Code, e.Message)
+	return nil, e
+}
@@ -0,0 +1,251 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-project-manager/internal/config"
+)
+
+func newTestOpenAI(t *testing.T, handler http.HandlerFunc) *OpenAI {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	p, err := NewOpenAI("openai", config.ProviderConfig{Type: ProviderOpenAI, APIKey: "test-key", Model: "gpt-test", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewOpenAI: %v", err)
+	}
+	return p
+}
+
+func TestOpenAIChat(t *testing.T) {
+	p := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/chat/completions" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var body openAIChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if body.Model != "gpt-test" || body.Stream || len(body.Messages) != 1 || body.Messages[0].Content != "hi" {
+			t.Errorf("unexpected request body %+v", body)
+		}
+		fmt.Fprint(w, `{"model":"gpt-test-0613","choices":[{"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`)
+	})
+
+	resp, err := p.Chat(context.Background(), &Request{Messages: []Message{{Role: RoleUser, Content: "hi"}}})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	want := &Response{Model: "gpt-test-0613", Content: "hello", FinishReason: "stop", Usage: Usage{3, 1, 4}}
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("Chat = %+v, want %+v", resp, want)
+	}
+}
+
+func TestOpenAIChatStream(t *testing.T) {
+	p := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		var body openAIChatRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		if !body.Stream || body.StreamOptions == nil || !body.StreamOptions.IncludeUsage {
+			t.Errorf("expected a streaming request with usage, got %+v", body)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, line := range []string{
+			`: keep-alive`,
+			`data: {"model":"gpt-test","choices":[{"delta":{"role":"assistant","content":""}}]}`,
+			`data: {"model":"gpt-test","choices":[{"delta":{"content":"hel"}}]}`,
+			`data: {"model":"gpt-test","choices":[{"delta":{"content":"lo"}}]}`,
+			`data: {"model":"gpt-test","choices":[{"delta":{},"finish_reason":"stop"}]}`,
+			`data: {"model":"gpt-test","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`,
+			`data: [DONE]`,
+		} {
+			fmt.Fprintf(w, "%s\n\n", line)
+		}
+	})
+
+	var chunks []Chunk
+	resp, err := p.ChatStream(context.Background(), &Request{Messages: []Message{{Role: RoleUser, Content: "hi"}}},
+		func(c Chunk) error {
+			chunks = append(chunks, c)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	wantChunks := []Chunk{{Content: "hel"}, {Content: "lo"}, {FinishReason: "stop"}}
+	if len(chunks) != len(wantChunks) {
+		t.Fatalf("chunks = %+v, want %+v", chunks, wantChunks)
+	}
+	for i := range chunks {
+		if chunks[i] != wantChunks[i] {
+			t.Errorf("chunk %d = %+v, want %+v", i, chunks[i], wantChunks[i])
+		}
+	}
+	want := &Response{Model: "gpt-test", Content: "hello", FinishReason: "stop", Usage: Usage{3, 2, 5}}
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("ChatStream = %+v, want %+v", resp, want)
+	}
+}
+
+func TestOpenAIChatStreamAbort(t *testing.T) {
+	p := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"a\"}}]}\n\ndata: [DONE]\n\n")
+	})
+
+	stop := errors.New("stop")
+	_, err := p.ChatStream(context.Background(), &Request{}, func(Chunk) error { return stop })
+	if err != stop {
+		t.Errorf("ChatStream error = %v, want the callback's error", err)
+	}
+}
+
+func TestOpenAIChatStreamTruncated(t *testing.T) {
+	p := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"a\"}}]}\n\n")
+	})
+
+	_, err := p.ChatStream(context.Background(), &Request{}, func(Chunk) error { return nil })
+	if !errors.Is(err, ErrTransient) {
+		t.Errorf("ChatStream error = %v, want ErrTransient", err)
+	}
+}
+
+func TestOpenAIListModels(t *testing.T) {
+	p := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/models" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		fmt.Fprint(w, `{"object":"list","data":[{"id":"gpt-4o","owned_by":"openai"},{"id":"ft:custom","owned_by":"org"}]}`)
+	})
+
+	models, err := p.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	want := []Model{{ID: "gpt-4o", OwnedBy: "openai"}, {ID: "ft:custom", OwnedBy: "org"}}
+	if len(models) != len(want) || models[0] != want[0] || models[1] != want[1] {
+		t.Errorf("ListModels = %+v, want %+v", models, want)
+	}
+}
+
+func TestOpenAIErrorClassification(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     map[string]string
+		body       string
+		kind       error
+		code       string
+		retryAfter time.Duration
+		retryable  bool
+	}{
+		{
+			name:       "rate limit",
+			status:     http.StatusTooManyRequests,
+			header:     map[string]string{"Retry-After": "7"},
+			body:       `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`,
+			kind:       ErrRateLimit,
+			code:       "rate_limit_exceeded",
+			retryAfter: 7 * time.Second,
+			retryable:  true,
+		},
+		{
+			name:   "context length",
+			status: http.StatusBadRequest,
+			body: `{"error":{"message":"This model's maximum context length is 8192 tokens.",` +
+				`"type":"invalid_request_error","code":"context_length_exceeded"}}`,
+			kind: ErrContextLength,
+			code: "context_length_exceeded",
+		},
+		{
+			name:   "auth",
+			status: http.StatusUnauthorized,
+			body:   `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`,
+			kind:   ErrAuth,
+			code:   "invalid_api_key",
+		},
+		{
+			name:   "forbidden model",
+			status: http.StatusForbidden,
+			body:   `{"error":{"message":"You do not have access to this model","type":"invalid_request_error","code":null}}`,
+			kind:   ErrAuth,
+			code:   "invalid_request_error",
+		},
+		{
+			name:      "server error",
+			status:    http.StatusBadGateway,
+			body:      `<html>bad gateway</html>`,
+			kind:      ErrTransient,
+			retryable: true,
+		},
+		{
+			name:   "invalid request",
+			status: http.StatusBadRequest,
+			body:   `{"error":{"message":"Unknown parameter","type":"invalid_request_error","code":"unknown_parameter"}}`,
+			kind:   ErrInvalidRequest,
+			code:   "unknown_parameter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+
+			_, err := p.Chat(context.Background(), &Request{})
+			if !errors.Is(err, tt.kind) {
+				t.Fatalf("error = %v, want %v", err, tt.kind)
+			}
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("error %T is not *Error", err)
+			}
+			if e.StatusCode != tt.status || e.Code != tt.code || e.RetryAfter != tt.retryAfter {
+				t.Errorf("error = %+v", e)
+			}
+			if Retryable(err) != tt.retryable {
+				t.Errorf("Retryable = %v, want %v", Retryable(err), tt.retryable)
+			}
+			if strings.TrimSpace(e.Message) == "" {
+				t.Error("error message is empty")
+			}
+		})
+	}
+}
+
+func TestOpenAITransportErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := srv.URL
+	srv.Close()
+
+	p, _ := NewOpenAI("openai", config.ProviderConfig{Type: ProviderOpenAI, BaseURL: url})
+	_, err := p.Chat(context.Background(), &Request{})
+	if !errors.Is(err, ErrTransient) || !Retryable(err) {
+		t.Errorf("connection refused: error = %v, want a retryable ErrTransient", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = p.Chat(ctx, &Request{})
+	if !errors.Is(err, context.Canceled) || Retryable(err) {
+		t.Errorf("canceled: error = %v, want context.Canceled", err)
+	}
+}
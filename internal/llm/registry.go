@@ -0,0 +1,66 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"agent-project-manager/internal/config"
+)
+
+// ErrUnknownProvider is returned for a provider name that is not configured
+var ErrUnknownProvider = errors.New("unknown LLM provider")
+
+// Registry holds the configured providers by name
+type Registry struct {
+	providers   map[string]Provider
+	defaultName string
+}
+
+// NewRegistry creates every provider in cfg; see config.LLMConfig.AllProviders
+func NewRegistry(cfg config.LLMConfig) (*Registry, error) {
+	r := &Registry{providers: map[string]Provider{}, defaultName: cfg.Provider}
+	for name, pc := range cfg.AllProviders() {
+		p, err := NewProvider(name, pc)
+		if err != nil {
+			return nil, err
+		}
+		r.providers[name] = p
+	}
+	if r.defaultName != "" {
+		if _, ok := r.providers[r.defaultName]; !ok {
+			return nil, fmt.Errorf("default provider %q: %w", r.defaultName, ErrUnknownProvider)
+		}
+	}
+	return r, nil
+}
+
+// Get returns the named provider; an empty name returns the default provider
+func (r *Registry) Get(name string) (Provider, error) {
+	if name == "" {
+		name = r.defaultName
+		if name == "" {
+			return nil, fmt.Errorf("%w: no default provider is configured (llm.provider)", ErrUnknownProvider)
+		}
+	}
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownProvider, name)
+	}
+	return p, nil
+}
+
+// Default returns the name of the default provider; empty when none is configured
+func (r *Registry) Default() string {
+	return r.defaultName
+}
+
+// Names lists the configured providers in alphabetical order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
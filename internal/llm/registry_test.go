@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"agent-project-manager/internal/config"
+)
+
+func TestRegistry(t *testing.T) {
+	var gotAuth, gotHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotHeader = r.Header.Get("X-Gateway")
+		fmt.Fprint(w, `{"model":"local","choices":[{"message":{"content":"ok"},"finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte("file-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_VLLM_KEY", "env-key")
+
+	reg, err := NewRegistry(config.LLMConfig{
+		Provider: "lmstudio",
+		Ollama:   config.OllamaConfig{Model: "qwen2.5-coder:7b"},
+		Providers: map[string]config.ProviderConfig{
+			"lmstudio": {Type: ProviderOpenAI, BaseURL: srv.URL, Model: "local", Headers: map[string]string{"X-Gateway": "pi"}},
+			"vllm":     {Type: ProviderOpenAI, BaseURL: srv.URL, APIKeyEnv: "TEST_VLLM_KEY"},
+			"llamacpp": {Type: ProviderOpenAI, BaseURL: srv.URL, APIKeyFile: keyFile},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+
+	want := []string{"llamacpp", "lmstudio", "ollama", "openai", "vllm"}
+	if got := reg.Names(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Names = %v, want %v", got, want)
+	}
+
+	p, err := reg.Get("")
+	if err != nil || p.Name() != "lmstudio" || p.Type() != ProviderOpenAI || p.Model() != "local" {
+		t.Fatalf("default provider = %v, %v", p, err)
+	}
+	if _, err := p.Chat(context.Background(), &Request{}); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if gotAuth != "" || gotHeader != "pi" {
+		t.Errorf("lmstudio sent Authorization %q, X-Gateway %q", gotAuth, gotHeader)
+	}
+
+	for name, key := range map[string]string{"vllm": "env-key", "llamacpp": "file-key"} {
+		p, _ := reg.Get(name)
+		if _, err := p.Chat(context.Background(), &Request{}); err != nil {
+			t.Fatalf("%s Chat: %v", name, err)
+		}
+		if gotAuth != "Bearer "+key {
+			t.Errorf("%s sent Authorization %q", name, gotAuth)
+		}
+	}
+
+	if p, _ := reg.Get("ollama"); p == nil || p.Type() != ProviderOllama || p.Model() != "qwen2.5-coder:7b" {
+		t.Errorf("ollama block is not registered as provider %q", "ollama")
+	}
+	if _, err := reg.Get("nope"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("Get(nope) error = %v, want ErrUnknownProvider", err)
+	}
+}
+
+func TestRegistryErrors(t *testing.T) {
+	_, err := NewRegistry(config.LLMConfig{Provider: "missing"})
+	if !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("unknown default: error = %v, want ErrUnknownProvider", err)
+	}
+
+	_, err = NewRegistry(config.LLMConfig{Providers: map[string]config.ProviderConfig{
+		"bad": {Type: ProviderOpenAI, APIKeyFile: filepath.Join(t.TempDir(), "missing")},
+	}})
+	if err == nil {
+		t.Error("missing API key file: expected an error")
+	}
+}
@@ -0,0 +1,183 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"agent-project-manager/internal/jsonschema"
+)
+
+// DefaultMaxRepairs is how many repair prompts ChatJSON sends after invalid replies
+const DefaultMaxRepairs = 2
+
+// ErrInvalidOutput means the model did not produce JSON matching the schema, even after repairs
+var ErrInvalidOutput = errors.New("invalid model output")
+
+// OutputSpec describes the JSON output ChatJSON asks for
+type OutputSpec struct {
+	Name       string                 // names the schema for APIs that want one; default "output"
+	Schema     map[string]interface{} // a JSON Schema
+	MaxRepairs int                    // repair prompts after invalid replies; zero uses DefaultMaxRepairs, negative sends none
+}
+
+// JSONResponse is the answer of ChatJSON
+type JSONResponse struct {
+	*Response             // the last reply; Usage and CostUSD add up all replies
+	Value     interface{} // the reply decoded, with schema defaults filled in
+	Repairs   int         // repair prompts it took
+}
+
+// OutputError is a reply that still violated the schema when the repairs ran out
+type OutputError struct {
+	Attempts int                          // replies received
+	Errors   []jsonschema.ValidationError // what is wrong with the last reply
+	Content  string                       // the last reply
+}
+
+// Error lists the violations of the last reply
+func (e *OutputError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, v := range e.Errors {
+		msgs[i] = v.Error()
+	}
+	return fmt.Sprintf("%v: no valid reply in %d attempts: %s", ErrInvalidOutput, e.Attempts, strings.Join(msgs, "; "))
+}
+
+// Is matches ErrInvalidOutput
+func (e *OutputError) Is(target error) bool {
+	return target == ErrInvalidOutput
+}
+
+// ChatJSON asks for a JSON reply matching spec.Schema and validates it. An invalid reply is
+// answered with a repair prompt listing the violations, up to spec.MaxRepairs times.
+// The valid reply is decoded into out (a pointer, as for json.Unmarshal; nil skips decoding).
+// Invalid output ends with an *OutputError; provider errors are returned as they are.
+func (g *Gateway) ChatJSON(ctx context.Context, call *Call, req *Request, spec OutputSpec, out interface{}) (*JSONResponse, error) {
+	schema, err := jsonschema.Compile(spec.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output schema: %w", err)
+	}
+	maxRepairs := spec.MaxRepairs
+	if maxRepairs == 0 {
+		maxRepairs = DefaultMaxRepairs
+	}
+
+	r := *req
+	r.Format = &JSONFormat{Name: spec.Name, Schema: spec.Schema}
+	r.Messages = append([]Message{}, req.Messages...)
+
+	var usage Usage
+	var cost float64
+	for attempt := 0; ; attempt++ {
+		resp, err := g.Chat(ctx, call, &r)
+		if err != nil {
+			return nil, err
+		}
+		usage.PromptTokens += resp.Usage.PromptTokens
+		usage.CompletionTokens += resp.Usage.CompletionTokens
+		usage.TotalTokens += resp.Usage.TotalTokens
+		cost += resp.CostUSD
+
+		value, verrs := parseOutput(resp.Content, schema)
+		if len(verrs) == 0 {
+			resp.Usage, resp.CostUSD = usage, cost
+			if out != nil {
+				raw, _ := json.Marshal(value)
+				if err := json.Unmarshal(raw, out); err != nil {
+					return nil, fmt.Errorf("failed to decode model output: %w", err)
+				}
+			}
+			return &JSONResponse{Response: resp, Value: value, Repairs: attempt}, nil
+		}
+
+		if attempt >= maxRepairs {
+			return nil, &OutputError{Attempts: attempt + 1, Errors: verrs, Content: resp.Content}
+		}
+		r.Messages = append(r.Messages,
+			Message{Role: RoleAssistant, Content: resp.Content},
+			Message{Role: RoleUser, Content: repairPrompt(verrs)})
+	}
+}
+
+// parseOutput decodes a reply and checks it against the schema
+func parseOutput(content string, schema *jsonschema.Schema) (interface{}, []jsonschema.ValidationError) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(extractJSON(content)), &doc); err != nil {
+		return nil, []jsonschema.ValidationError{{Message: "reply is not valid JSON: " + err.Error()}}
+	}
+	return schema.Validate(doc)
+}
+
+// extractJSON cuts the JSON document out of a reply, dropping markdown code fences and
+// any prose around it, which models add even when asked not to
+func extractJSON(content string) string {
+	s := strings.TrimSpace(content)
+	if strings.HasPrefix(s, "```") {
+		s = strings.TrimPrefix(s[strings.IndexByte(s+"\n", '\n'):], "\n")
+		if end := strings.LastIndex(s, "```"); end >= 0 {
+			s = s[:end]
+		}
+		s = strings.TrimSpace(s)
+	}
+	if s == "" || s[0] == '{' || s[0] == '[' {
+		return s
+	}
+	start := strings.IndexAny(s, "{[")
+	end := strings.LastIndexAny(s, "}]")
+	if start < 0 || end < start {
+		return s
+	}
+	return s[start : end+1]
+}
+
+// repairPrompt asks the model to fix its previous reply
+func repairPrompt(errs []jsonschema.ValidationError) string {
+	var b strings.Builder
+	b.WriteString("Your reply does not match the required JSON Schema:\n")
+	for _, e := range errs {
+		path := e.Path
+		if path == "" {
+			path = "(root)"
+		}
+		fmt.Fprintf(&b, "- %s: %s\n", path, e.Message)
+	}
+	b.WriteString("Reply again with only the corrected JSON document, without explanations or code fences.")
+	return b.String()
+}
+
+// withSchemaInstruction tells the model about the schema in the system message,
+// for providers that cannot enforce it themselves
+func withSchemaInstruction(messages []Message, f *JSONFormat) []Message {
+	schema, _ := json.MarshalIndent(f.Schema, "", "  ")
+	instruction := "Reply with only a JSON document, without explanations or code fences, " +
+		"that matches this JSON Schema:\n" + string(schema)
+
+	out := make([]Message, 0, len(messages)+1)
+	if len(messages) > 0 && messages[0].Role == RoleSystem {
+		out = append(out, Message{Role: RoleSystem, Content: messages[0].Content + "\n\n" + instruction})
+		return append(out, messages[1:]...)
+	}
+	out = append(out, Message{Role: RoleSystem, Content: instruction})
+	return append(out, messages...)
+}
+
+// name is the schema name sent to APIs that want one; OpenAI accepts
+// up to 64 letters, digits, underscores and dashes
+func (f *JSONFormat) name() string {
+	name := strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, f.Name)
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	if name == "" {
+		return "output"
+	}
+	return name
+}
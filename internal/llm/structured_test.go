@@ -0,0 +1,186 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"agent-project-manager/internal/config"
+)
+
+// scriptedProvider answers with one reply after another and keeps the requests it got
+type scriptedProvider struct {
+	fakeProvider
+	replies  []string
+	requests []*Request
+}
+
+func (p *scriptedProvider) Chat(ctx context.Context, req *Request) (*Response, error) {
+	p.calls++
+	p.requests = append(p.requests, req)
+	reply := p.replies[0]
+	p.replies = p.replies[1:]
+	return &Response{Content: reply, Usage: Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}}, nil
+}
+
+var reviewSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []interface{}{"verdict"},
+	"properties": map[string]interface{}{
+		"verdict":  map[string]interface{}{"type": "string", "enum": []interface{}{"approve", "reject"}},
+		"comments": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "default": []interface{}{}},
+	},
+}
+
+func newScriptedGateway(t *testing.T, replies ...string) (*Gateway, *scriptedProvider) {
+	t.Helper()
+	p := &scriptedProvider{fakeProvider: fakeProvider{name: "openai", model: "gpt-4o-mini"}, replies: replies}
+	reg := &Registry{providers: map[string]Provider{"openai": p}, defaultName: "openai"}
+	g, err := NewGateway(reg, config.LLMConfig{
+		Provider: "openai",
+		Prices:   map[string]config.ModelPrice{"gpt-4o-mini": {Input: 1, Output: 2}},
+	}, GatewayOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g, p
+}
+
+func TestChatJSONRepair(t *testing.T) {
+	g, p := newScriptedGateway(t,
+		"Sure! Here is my review: {\"verdict\": \"maybe\"}",
+		"```json\n{\"verdict\": \"approve\"}\n```")
+
+	var out struct {
+		Verdict  string   `json:"verdict"`
+		Comments []string `json:"comments"`
+	}
+	req := &Request{Messages: []Message{{Role: RoleUser, Content: "review this"}}}
+	resp, err := g.ChatJSON(context.Background(), &Call{}, req, OutputSpec{Name: "review", Schema: reviewSchema}, &out)
+	if err != nil {
+		t.Fatalf("ChatJSON: %v", err)
+	}
+	if out.Verdict != "approve" || out.Comments == nil || resp.Repairs != 1 {
+		t.Errorf("out = %+v, repairs = %d", out, resp.Repairs)
+	}
+	if resp.Usage.TotalTokens != 30 || resp.CostUSD != 2*(10*1+5*2)/1e6 {
+		t.Errorf("usage = %+v, cost = %v; want both replies added up", resp.Usage, resp.CostUSD)
+	}
+
+	// The repair prompt quotes the invalid reply and the violation
+	if p.calls != 2 || p.requests[0].Format == nil || p.requests[0].Format.Name != "review" {
+		t.Fatalf("%d calls, first request %+v", p.calls, p.requests[0])
+	}
+	msgs := p.requests[1].Messages
+	if len(msgs) != 3 || msgs[1].Role != RoleAssistant || !strings.Contains(msgs[2].Content, "/verdict") {
+		t.Errorf("repair messages = %+v", msgs)
+	}
+	if len(req.Messages) != 1 {
+		t.Errorf("caller's request was modified: %+v", req.Messages)
+	}
+}
+
+func TestChatJSONInvalidOutput(t *testing.T) {
+	g, p := newScriptedGateway(t, "not json", "{}")
+
+	_, err := g.ChatJSON(context.Background(), &Call{}, &Request{}, OutputSpec{Schema: reviewSchema, MaxRepairs: 1}, nil)
+	var outErr *OutputError
+	if !errors.Is(err, ErrInvalidOutput) || !errors.As(err, &outErr) {
+		t.Fatalf("error = %v, want an OutputError", err)
+	}
+	if outErr.Attempts != 2 || outErr.Content != "{}" || len(outErr.Errors) != 1 || p.calls != 2 {
+		t.Errorf("OutputError = %+v after %d calls", outErr, p.calls)
+	}
+
+	// Without repairs the first invalid reply is final
+	g, p = newScriptedGateway(t, "not json")
+	if _, err := g.ChatJSON(context.Background(), &Call{}, &Request{}, OutputSpec{Schema: reviewSchema, MaxRepairs: -1}, nil); !errors.Is(err, ErrInvalidOutput) || p.calls != 1 {
+		t.Errorf("error = %v after %d calls, want ErrInvalidOutput after one", err, p.calls)
+	}
+
+	if _, err := g.ChatJSON(context.Background(), &Call{}, &Request{}, OutputSpec{Schema: map[string]interface{}{"type": 1}}, nil); err == nil {
+		t.Error("invalid schema accepted")
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	for in, want := range map[string]string{
+		`{"a": 1}`:                          `{"a": 1}`,
+		"```json\n{\"a\": 1}\n```":          `{"a": 1}`,
+		"```\n[1, 2]\n```\n":                `[1, 2]`,
+		"The answer is {\"a\": 1}. Thanks!": `{"a": 1}`,
+		"no json here":                      "no json here",
+	} {
+		if got := extractJSON(in); got != want {
+			t.Errorf("extractJSON(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestOpenAIJSONModes(t *testing.T) {
+	format := &JSONFormat{Name: "code review", Schema: reviewSchema}
+	for _, mode := range []string{"", config.JSONModeObject, config.JSONModePrompt} {
+		var body openAIChatRequest
+		p := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&body)
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"{}"}}]}`)
+		})
+		p.jsonMode = mode
+		req := &Request{Messages: []Message{{Role: RoleSystem, Content: "You review code."}, {Role: RoleUser, Content: "hi"}}, Format: format}
+		if _, err := p.Chat(context.Background(), req); err != nil {
+			t.Fatalf("%q: Chat: %v", mode, err)
+		}
+
+		instructed := len(body.Messages) == 2 && strings.HasPrefix(body.Messages[0].Content, "You review code.\n\n") &&
+			strings.Contains(body.Messages[0].Content, `"verdict"`)
+		switch mode {
+		case "":
+			rf := body.ResponseFormat
+			if rf == nil || rf.Type != "json_schema" || rf.JSONSchema.Name != "code_review" || rf.JSONSchema.Schema["type"] != "object" {
+				t.Errorf("schema mode: response_format = %+v", rf)
+			}
+			if len(body.Messages) != 2 || body.Messages[0].Content != "You review code." {
+				t.Errorf("schema mode: messages = %+v", body.Messages)
+			}
+		case config.JSONModeObject:
+			if body.ResponseFormat == nil || body.ResponseFormat.Type != "json_object" || !instructed {
+				t.Errorf("object mode: response_format = %+v, messages = %+v", body.ResponseFormat, body.Messages)
+			}
+		case config.JSONModePrompt:
+			if body.ResponseFormat != nil || !instructed {
+				t.Errorf("prompt mode: response_format = %+v, messages = %+v", body.ResponseFormat, body.Messages)
+			}
+		}
+	}
+}
+
+func TestOllamaJSONModes(t *testing.T) {
+	format := &JSONFormat{Schema: reviewSchema}
+	for mode, want := range map[string]string{"": "object", config.JSONModeObject: "json", config.JSONModePrompt: ""} {
+		var body map[string]interface{}
+		p := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&body)
+			fmt.Fprint(w, `{"message":{"role":"assistant","content":"{}"},"done":true}`)
+		})
+		p.jsonMode = mode
+		if _, err := p.Chat(context.Background(), &Request{Messages: []Message{{Role: RoleUser, Content: "hi"}}, Format: format}); err != nil {
+			t.Fatalf("%q: Chat: %v", mode, err)
+		}
+
+		var got string
+		switch f := body["format"].(type) {
+		case string:
+			got = f
+		case map[string]interface{}:
+			got, _ = f["type"].(string)
+		}
+		messages, _ := body["messages"].([]interface{})
+		if got != want || (mode != "") != (len(messages) == 2) {
+			t.Errorf("%q: format = %v, %d messages", mode, body["format"], len(messages))
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"agent-project-manager/internal/config"
+)
+
+// The text protocol offers tools to models without native function calling: the tools
+// are described in the system message, the model calls them by replying with a
+// <tool_calls> block and gets their results back in <tool_result> blocks.
+const (
+	toolCallsOpen  = "<tool_calls>"
+	toolCallsClose = "</tool_calls>"
+)
+
+// promptTools reports whether a provider with the tool mode offers the request's tools
+// through the text protocol
+func promptTools(mode string, req *Request) bool {
+	return mode == config.ToolModePrompt && len(req.Tools) > 0
+}
+
+// withToolProtocol describes the tools in the system message and rewrites tool calls
+// and their results as plain text, which every model understands
+func withToolProtocol(messages []Message, tools []ToolSpec) []Message {
+	var b strings.Builder
+	b.WriteString("You can call tools to gather what you need. To call tools, reply with only a block like\n")
+	b.WriteString(toolCallsOpen + "\n[{\"name\": \"tool_name\", \"arguments\": {\"argument\": \"value\"}}]\n" + toolCallsClose + "\n")
+	b.WriteString("and nothing after it. The results come back in <tool_result> blocks. ")
+	b.WriteString("When you have what you need, reply with your answer and no <tool_calls> block.\n\nTools:")
+	for _, t := range tools {
+		params, _ := json.Marshal(t.Parameters)
+		fmt.Fprintf(&b, "\n- %s: %s\n  arguments: %s", t.Name, t.Description, params)
+	}
+	instruction := b.String()
+
+	out := make([]Message, 0, len(messages)+1)
+	if len(messages) > 0 && messages[0].Role == RoleSystem {
+		out = append(out, Message{Role: RoleSystem, Content: messages[0].Content + "\n\n" + instruction})
+		messages = messages[1:]
+	} else {
+		out = append(out, Message{Role: RoleSystem, Content: instruction})
+	}
+	for _, m := range messages {
+		switch {
+		case m.Role == RoleAssistant && len(m.ToolCalls) > 0:
+			out = append(out, Message{Role: RoleAssistant, Content: formatToolCalls(m.Content, m.ToolCalls)})
+		case m.Role == RoleTool:
+			result := fmt.Sprintf("<tool_result name=%q>\n%s\n</tool_result>", m.Name, m.Content)
+			// Results of one turn's calls go back as a single message, as roles must alternate
+			if last := len(out) - 1; out[last].Role == RoleUser && strings.HasPrefix(out[last].Content, "<tool_result") {
+				out[last].Content += "\n" + result
+				continue
+			}
+			out = append(out, Message{Role: RoleUser, Content: result})
+		default:
+			out = append(out, Message{Role: m.Role, Content: m.Content})
+		}
+	}
+	return out
+}
+
+// textToolCall is a tool call in the text protocol
+type textToolCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// formatToolCalls writes an assistant message's tool calls as the block the model would have sent
+func formatToolCalls(content string, calls []ToolCall) string {
+	list := make([]textToolCall, len(calls))
+	for i, c := range calls {
+		list[i] = textToolCall{Name: c.Name, Arguments: argumentsJSON(c.Arguments)}
+	}
+	raw, _ := json.Marshal(list)
+	block := toolCallsOpen + "\n" + string(raw) + "\n" + toolCallsClose
+	if content = strings.TrimSpace(content); content != "" {
+		return content + "\n" + block
+	}
+	return block
+}
+
+// parseToolCalls takes the <tool_calls> block out of a reply. A block that is not a JSON
+// call or list of calls is left in the content, so the reply reads as an answer.
+func parseToolCalls(content string) (string, []ToolCall) {
+	start := strings.Index(content, toolCallsOpen)
+	if start < 0 {
+		return content, nil
+	}
+	body := content[start+len(toolCallsOpen):]
+	if end := strings.Index(body, toolCallsClose); end >= 0 {
+		body = body[:end]
+	}
+
+	var list []textToolCall
+	raw := []byte(extractJSON(body))
+	if err := json.Unmarshal(raw, &list); err != nil {
+		var single textToolCall
+		if err := json.Unmarshal(raw, &single); err != nil {
+			return content, nil
+		}
+		list = []textToolCall{single}
+	}
+
+	var calls []ToolCall
+	for _, c := range list {
+		if c.Name == "" {
+			continue
+		}
+		args := "{}"
+		if len(c.Arguments) > 0 && string(c.Arguments) != "null" {
+			args = string(c.Arguments)
+		}
+		calls = append(calls, ToolCall{ID: fmt.Sprintf("call_%d", len(calls)+1), Name: c.Name, Arguments: args})
+	}
+	if len(calls) == 0 {
+		return content, nil
+	}
+	return strings.TrimSpace(content[:start]), calls
+}
+
+// argumentsJSON returns tool call arguments as raw JSON; arguments that are not valid
+// JSON, which models do produce, become an empty object
+func argumentsJSON(arguments string) json.RawMessage {
+	if strings.TrimSpace(arguments) == "" || !json.Valid([]byte(arguments)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(arguments)
+}
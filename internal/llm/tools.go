@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Defaults of a ToolLoop
+const (
+	DefaultMaxIterations = 10
+	DefaultMaxToolTime   = 5 * time.Minute
+)
+
+// ErrToolLimit means the model was still calling tools when the loop's iterations or tool time ran out
+var ErrToolLimit = errors.New("tool limit exceeded")
+
+// ToolExecutor runs the tools a model may call
+type ToolExecutor interface {
+	// Tools describes the tools offered to the model
+	Tools() []ToolSpec
+	// CallTool runs a tool with its JSON arguments and returns the result the model sees
+	CallTool(ctx context.Context, name, arguments string) (string, error)
+}
+
+// ToolLoop bounds a conversation in which the model calls tools
+type ToolLoop struct {
+	MaxIterations int                  // model replies; zero uses DefaultMaxIterations
+	MaxToolTime   time.Duration        // total time the tool calls may take; zero uses DefaultMaxToolTime
+	OnToolCall    func(ToolCallRecord) // called after every tool call; nil records nothing
+}
+
+// ToolCallRecord is a tool call that was run
+type ToolCallRecord struct {
+	Iteration int // the model reply that asked for the call, from 1
+	ID        string
+	Name      string
+	Arguments string
+	Result    string // what the model got back
+	Error     string // why the tool failed; the model sees it as the result
+	Duration  time.Duration
+}
+
+// ToolResponse is the answer of ChatTools
+type ToolResponse struct {
+	*Response                   // the final reply; Usage and CostUSD add up all replies
+	Messages   []Message        // the conversation, with the tool calls and their results
+	Calls      []ToolCallRecord // the tool calls, in order
+	Iterations int              // model replies it took
+}
+
+// ChatTools lets the model call the executor's tools before it answers. Each reply that
+// asks for tools has them run in order and their results sent back, until a reply asks
+// for none. Tool errors, unknown tools included, are sent back as the result so the model
+// can correct itself. When the model is still calling tools after loop.MaxIterations
+// replies, or the calls take longer than loop.MaxToolTime, ChatTools fails with an error
+// matching ErrToolLimit.
+func (g *Gateway) ChatTools(ctx context.Context, call *Call, req *Request, tools ToolExecutor, loop ToolLoop) (*ToolResponse, error) {
+	maxIterations := loop.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = DefaultMaxIterations
+	}
+	maxToolTime := loop.MaxToolTime
+	if maxToolTime <= 0 {
+		maxToolTime = DefaultMaxToolTime
+	}
+
+	r := *req
+	r.Tools = tools.Tools()
+	r.Messages = append([]Message{}, req.Messages...)
+
+	out := &ToolResponse{}
+	var usage Usage
+	var cost float64
+	var toolTime time.Duration
+	for iteration := 1; ; iteration++ {
+		if iteration > maxIterations {
+			return nil, fmt.Errorf("%w: no answer after %d replies", ErrToolLimit, maxIterations)
+		}
+		resp, err := g.Chat(ctx, call, &r)
+		if err != nil {
+			return nil, err
+		}
+		usage.PromptTokens += resp.Usage.PromptTokens
+		usage.CompletionTokens += resp.Usage.CompletionTokens
+		usage.TotalTokens += resp.Usage.TotalTokens
+		cost += resp.CostUSD
+		out.Iterations = iteration
+
+		if len(resp.ToolCalls) == 0 {
+			resp.Usage, resp.CostUSD = usage, cost
+			out.Response = resp
+			out.Messages = append(r.Messages, Message{Role: RoleAssistant, Content: resp.Content})
+			return out, nil
+		}
+
+		for i := range resp.ToolCalls {
+			if resp.ToolCalls[i].ID == "" {
+				resp.ToolCalls[i].ID = fmt.Sprintf("call_%d_%d", iteration, i+1)
+			}
+		}
+		r.Messages = append(r.Messages, Message{Role: RoleAssistant, Content: resp.Content, ToolCalls: resp.ToolCalls})
+		for _, tc := range resp.ToolCalls {
+			if toolTime >= maxToolTime {
+				return nil, fmt.Errorf("%w: tool calls took longer than %v", ErrToolLimit, maxToolTime)
+			}
+			rec := runTool(ctx, tools, tc, maxToolTime-toolTime)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			rec.Iteration = iteration
+			toolTime += rec.Duration
+			out.Calls = append(out.Calls, rec)
+			if loop.OnToolCall != nil {
+				loop.OnToolCall(rec)
+			}
+			r.Messages = append(r.Messages, Message{Role: RoleTool, Content: rec.Result, ToolCallID: tc.ID, Name: tc.Name})
+		}
+	}
+}
+
+// runTool runs one tool call within the tool time left
+func runTool(ctx context.Context, tools ToolExecutor, tc ToolCall, left time.Duration) ToolCallRecord {
+	ctx, cancel := context.WithTimeout(ctx, left)
+	defer cancel()
+
+	rec := ToolCallRecord{ID: tc.ID, Name: tc.Name, Arguments: tc.Arguments}
+	start := time.Now()
+	result, err := tools.CallTool(ctx, tc.Name, tc.Arguments)
+	rec.Duration = time.Since(start)
+	if err != nil {
+		rec.Error = err.Error()
+		result = "error: " + err.Error()
+	}
+	rec.Result = result
+	return rec
+}
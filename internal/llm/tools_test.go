@@ -0,0 +1,284 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-project-manager/internal/config"
+)
+
+// toolProvider answers with one response after another and keeps the requests it got
+type toolProvider struct {
+	fakeProvider
+	replies  []*Response
+	requests []*Request
+}
+
+func (p *toolProvider) Chat(ctx context.Context, req *Request) (*Response, error) {
+	p.calls++
+	p.requests = append(p.requests, req)
+	reply := *p.replies[0]
+	if len(p.replies) > 1 {
+		p.replies = p.replies[1:]
+	}
+	reply.Usage = Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}
+	return &reply, nil
+}
+
+// fakeTools offers read_file, which returns the file name, and sleep
+type fakeTools struct {
+	sleep time.Duration
+}
+
+func (f *fakeTools) Tools() []ToolSpec {
+	return []ToolSpec{
+		{Name: "read_file", Description: "Read a file", Parameters: map[string]interface{}{"type": "object"}},
+		{Name: "sleep", Description: "Wait", Parameters: map[string]interface{}{"type": "object"}},
+	}
+}
+
+func (f *fakeTools) CallTool(ctx context.Context, name, arguments string) (string, error) {
+	switch name {
+	case "read_file":
+		var args struct {
+			Path string `json:"path"`
+		}
+		json.Unmarshal([]byte(arguments), &args)
+		return "contents of " + args.Path, nil
+	case "sleep":
+		select {
+		case <-time.After(f.sleep):
+			return "slept", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	default:
+		return "", fmt.Errorf("unknown tool %q", name)
+	}
+}
+
+func newToolGateway(t *testing.T, replies ...*Response) (*Gateway, *toolProvider) {
+	t.Helper()
+	p := &toolProvider{fakeProvider: fakeProvider{name: "openai", model: "gpt-4o-mini"}, replies: replies}
+	reg := &Registry{providers: map[string]Provider{"openai": p}, defaultName: "openai"}
+	g, err := NewGateway(reg, config.LLMConfig{Provider: "openai"}, GatewayOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g, p
+}
+
+func TestChatTools(t *testing.T) {
+	g, p := newToolGateway(t,
+		&Response{ToolCalls: []ToolCall{
+			{ID: "a", Name: "read_file", Arguments: `{"path": "main.go"}`},
+			{ID: "b", Name: "rm", Arguments: `{}`},
+		}},
+		&Response{Content: "main.go looks fine", FinishReason: "stop"})
+
+	var recorded []ToolCallRecord
+	req := &Request{Messages: []Message{{Role: RoleUser, Content: "review main.go"}}}
+	resp, err := g.ChatTools(context.Background(), &Call{}, req, &fakeTools{}, ToolLoop{
+		OnToolCall: func(rec ToolCallRecord) { recorded = append(recorded, rec) },
+	})
+	if err != nil {
+		t.Fatalf("ChatTools: %v", err)
+	}
+	if resp.Content != "main.go looks fine" || resp.Iterations != 2 || resp.Usage.TotalTokens != 30 {
+		t.Errorf("response = %+v, %d iterations", resp.Response, resp.Iterations)
+	}
+	if len(resp.Calls) != 2 || len(recorded) != 2 {
+		t.Fatalf("calls = %+v, recorded = %+v", resp.Calls, recorded)
+	}
+	if c := resp.Calls[0]; c.Iteration != 1 || c.Result != "contents of main.go" || c.Error != "" {
+		t.Errorf("first call = %+v", c)
+	}
+	if c := resp.Calls[1]; c.Error == "" || !strings.HasPrefix(c.Result, "error: ") {
+		t.Errorf("unknown tool call = %+v, want its error sent back", c)
+	}
+
+	// The tools are offered and the results sent back as tool messages answering the calls
+	if len(p.requests[0].Tools) != 2 || len(req.Messages) != 1 {
+		t.Errorf("first request tools = %+v, caller's messages = %+v", p.requests[0].Tools, req.Messages)
+	}
+	msgs := p.requests[1].Messages
+	if len(msgs) != 4 || len(msgs[1].ToolCalls) != 2 || msgs[2].Role != RoleTool || msgs[2].ToolCallID != "a" ||
+		msgs[2].Name != "read_file" || msgs[3].ToolCallID != "b" {
+		t.Errorf("second request messages = %+v", msgs)
+	}
+	if len(resp.Messages) != 5 || resp.Messages[4].Role != RoleAssistant {
+		t.Errorf("conversation = %+v", resp.Messages)
+	}
+}
+
+func TestChatToolsLimits(t *testing.T) {
+	loop := &Response{ToolCalls: []ToolCall{{Name: "read_file", Arguments: `{"path": "a"}`}}}
+	g, p := newToolGateway(t, loop)
+	_, err := g.ChatTools(context.Background(), &Call{}, &Request{}, &fakeTools{}, ToolLoop{MaxIterations: 3})
+	if !errors.Is(err, ErrToolLimit) || p.calls != 3 {
+		t.Errorf("error = %v after %d calls, want ErrToolLimit after 3", err, p.calls)
+	}
+	if id := p.requests[1].Messages[0].ToolCalls[0].ID; id != "call_1_1" {
+		t.Errorf("call without an ID got %q", id)
+	}
+
+	slow := &Response{ToolCalls: []ToolCall{{Name: "sleep"}, {Name: "sleep"}}}
+	g, p = newToolGateway(t, slow)
+	var recorded []ToolCallRecord
+	_, err = g.ChatTools(context.Background(), &Call{}, &Request{}, &fakeTools{sleep: time.Second}, ToolLoop{
+		MaxToolTime: 20 * time.Millisecond,
+		OnToolCall:  func(rec ToolCallRecord) { recorded = append(recorded, rec) },
+	})
+	if !errors.Is(err, ErrToolLimit) || p.calls != 1 || len(recorded) != 1 || recorded[0].Error == "" {
+		t.Errorf("error = %v after %d calls, recorded %+v; want the first sleep cut short", err, p.calls, recorded)
+	}
+}
+
+func TestToolProtocol(t *testing.T) {
+	tools := (&fakeTools{}).Tools()
+	msgs := withToolProtocol([]Message{
+		{Role: RoleSystem, Content: "You review code."},
+		{Role: RoleUser, Content: "review main.go"},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "1", Name: "read_file", Arguments: `{"path":"main.go"}`}, {ID: "2", Name: "sleep", Arguments: "not json"}}},
+		{Role: RoleTool, ToolCallID: "1", Name: "read_file", Content: "package main"},
+		{Role: RoleTool, ToolCallID: "2", Name: "sleep", Content: "slept"},
+	}, tools)
+
+	if len(msgs) != 4 || !strings.HasPrefix(msgs[0].Content, "You review code.\n\n") || !strings.Contains(msgs[0].Content, "- read_file: Read a file") {
+		t.Fatalf("messages = %+v", msgs)
+	}
+	if want := "<tool_calls>\n" + `[{"name":"read_file","arguments":{"path":"main.go"}},{"name":"sleep","arguments":{}}]` + "\n</tool_calls>"; msgs[2].Content != want {
+		t.Errorf("assistant message = %q, want %q", msgs[2].Content, want)
+	}
+	if msgs[3].Role != RoleUser || strings.Count(msgs[3].Content, "<tool_result") != 2 || !strings.Contains(msgs[3].Content, "package main") {
+		t.Errorf("results message = %+v", msgs[3])
+	}
+
+	content, calls := parseToolCalls("Let me look.\n<tool_calls>\n```json\n[{\"name\": \"read_file\", \"arguments\": {\"path\": \"a.go\"}}, {\"name\": \"list_dir\"}]\n```\n</tool_calls>")
+	if content != "Let me look." || len(calls) != 2 || calls[0].ID != "call_1" || calls[0].Arguments != `{"path": "a.go"}` || calls[1].Arguments != "{}" {
+		t.Errorf("parseToolCalls = %q, %+v", content, calls)
+	}
+	if _, calls := parseToolCalls(`<tool_calls>{"name": "list_dir", "arguments": {}}`); len(calls) != 1 {
+		t.Errorf("single unterminated call not parsed: %+v", calls)
+	}
+	for _, reply := range []string{"The answer is 42.", "<tool_calls>oops</tool_calls>"} {
+		if content, calls := parseToolCalls(reply); content != reply || calls != nil {
+			t.Errorf("parseToolCalls(%q) = %q, %+v", reply, content, calls)
+		}
+	}
+}
+
+func TestOpenAITools(t *testing.T) {
+	var body openAIChatRequest
+	p := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, `{"model":"gpt-test","choices":[{"message":{"role":"assistant","content":null,"tool_calls":[`+
+			`{"id":"call_x","type":"function","function":{"name":"read_file","arguments":"{\"path\":\"go.mod\"}"}}]},"finish_reason":"tool_calls"}]}`)
+	})
+	req := &Request{
+		Messages: []Message{
+			{Role: RoleUser, Content: "hi"},
+			{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_1", Name: "list_dir", Arguments: "{}"}}},
+			{Role: RoleTool, ToolCallID: "call_1", Name: "list_dir", Content: "go.mod"},
+		},
+		Tools: (&fakeTools{}).Tools(),
+	}
+	resp, err := p.Chat(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0] != (ToolCall{ID: "call_x", Name: "read_file", Arguments: `{"path":"go.mod"}`}) {
+		t.Errorf("tool calls = %+v", resp.ToolCalls)
+	}
+	if len(body.Tools) != 2 || body.Tools[0].Type != "function" || body.Tools[0].Function.Name != "read_file" {
+		t.Errorf("tools = %+v", body.Tools)
+	}
+	m := body.Messages
+	if len(m) != 3 || len(m[1].ToolCalls) != 1 || m[1].ToolCalls[0].Function.Name != "list_dir" || m[1].ToolCalls[0].Index != nil ||
+		m[2].Role != RoleTool || m[2].ToolCallID != "call_1" {
+		t.Errorf("messages = %+v", m)
+	}
+
+	// Streamed calls arrive in pieces, by index
+	p = newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		for _, line := range []string{
+			`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"read_file","arguments":""}}]}}]}`,
+			`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"path\":"}}]}}]}`,
+			`data: {"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_b","function":{"name":"list_dir","arguments":"{}"}}]}}]}`,
+			`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"a.go\"}"}}]}}]}`,
+			`data: {"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+			`data: [DONE]`,
+		} {
+			fmt.Fprintf(w, "%s\n\n", line)
+		}
+	})
+	resp, err = p.ChatStream(context.Background(), req, func(Chunk) error { return nil })
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	want := []ToolCall{{ID: "call_a", Name: "read_file", Arguments: `{"path":"a.go"}`}, {ID: "call_b", Name: "list_dir", Arguments: "{}"}}
+	if len(resp.ToolCalls) != 2 || resp.ToolCalls[0] != want[0] || resp.ToolCalls[1] != want[1] || resp.FinishReason != "tool_calls" {
+		t.Errorf("streamed tool calls = %+v", resp.ToolCalls)
+	}
+}
+
+func TestOllamaTools(t *testing.T) {
+	var body map[string]interface{}
+	p := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, `{"model":"qwen2.5-coder:7b","message":{"role":"assistant","content":"",`+
+			`"tool_calls":[{"function":{"name":"read_file","arguments":{"path":"go.mod"}}}]},"done":true,"done_reason":"stop"}`)
+	})
+	req := &Request{
+		Messages: []Message{
+			{Role: RoleUser, Content: "hi"},
+			{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_1", Name: "list_dir", Arguments: `{"path":"."}`}}},
+			{Role: RoleTool, ToolCallID: "call_1", Name: "list_dir", Content: "go.mod"},
+		},
+		Tools: (&fakeTools{}).Tools(),
+	}
+	resp, err := p.Chat(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0] != (ToolCall{ID: "call_1", Name: "read_file", Arguments: `{"path":"go.mod"}`}) {
+		t.Errorf("tool calls = %+v", resp.ToolCalls)
+	}
+
+	raw, _ := json.Marshal(body["messages"])
+	want := `[{"content":"hi","role":"user"},` +
+		`{"content":"","role":"assistant","tool_calls":[{"function":{"arguments":{"path":"."},"name":"list_dir"}}]},` +
+		`{"content":"go.mod","role":"tool","tool_name":"list_dir"}]`
+	if string(raw) != want {
+		t.Errorf("messages = %s, want %s", raw, want)
+	}
+	if tools, _ := body["tools"].([]interface{}); len(tools) != 2 {
+		t.Errorf("tools = %v", body["tools"])
+	}
+}
+
+func TestPromptToolMode(t *testing.T) {
+	var body map[string]interface{}
+	p := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, `{"message":{"role":"assistant","content":"<tool_calls>[{\"name\":\"read_file\",\"arguments\":{\"path\":\"a\"}}]</tool_calls>"},"done":true}`)
+	})
+	p.toolMode = config.ToolModePrompt
+	resp, err := p.Chat(context.Background(), &Request{Messages: []Message{{Role: RoleUser, Content: "hi"}}, Tools: (&fakeTools{}).Tools()})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Content != "" || len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "read_file" {
+		t.Errorf("response = %+v", resp)
+	}
+	messages, _ := body["messages"].([]interface{})
+	if body["tools"] != nil || len(messages) != 2 {
+		t.Errorf("prompt mode request: tools = %v, %d messages", body["tools"], len(messages))
+	}
+}
@@ -0,0 +1,42 @@
+package llm
+
+import (
+	"errors"
+
+	"agent-project-manager/internal/config"
+)
+
+// ErrBudgetExceeded is returned for requests of a job that has used up its token or cost budget
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Pricing computes what calls cost from the configured price table
+type Pricing struct {
+	prices map[string]config.ModelPrice
+}
+
+// NewPricing creates a pricing over a price table keyed by "provider/model" or model name
+func NewPricing(prices map[string]config.ModelPrice) *Pricing {
+	return &Pricing{prices: prices}
+}
+
+// Price looks up a provider's model; a "provider/model" entry wins over a bare model entry
+func (p *Pricing) Price(provider, model string) (config.ModelPrice, bool) {
+	if p == nil || model == "" {
+		return config.ModelPrice{}, false
+	}
+	if price, ok := p.prices[provider+"/"+model]; ok {
+		return price, true
+	}
+	price, ok := p.prices[model]
+	return price, ok
+}
+
+// Cost returns the USD cost of the usage of a call to a provider's model.
+// Unlisted models, such as local ones, cost nothing.
+func (p *Pricing) Cost(provider, model string, u Usage) float64 {
+	price, ok := p.Price(provider, model)
+	if !ok {
+		return 0
+	}
+	return (float64(u.PromptTokens)*price.Input + float64(u.CompletionTokens)*price.Output) / 1e6
+}
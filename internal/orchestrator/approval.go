@@ -0,0 +1,124 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"agent-project-manager/internal/logger"
+	"agent-project-manager/internal/state"
+)
+
+var (
+	// ErrStepNotFound is returned when a step does not exist or belongs to another job
+	ErrStepNotFound = errors.New("step not found")
+	// ErrStepNotWaiting is returned when settling a step that is no longer parked
+	ErrStepNotWaiting = errors.New("step is not waiting")
+	// ErrNotAwaitingApproval is returned when deciding on a step that is not waiting for approval
+	ErrNotAwaitingApproval = errors.New("step is not awaiting approval")
+)
+
+// TimeoutApprover is recorded as the approver when a timeout decides a step
+const TimeoutApprover = "system:timeout"
+
+// executeApproval parks the job until someone approves or rejects the step
+func (o *Orchestrator) executeApproval(ctx context.Context, sc *StepContext) (*StepResult, error) {
+	if sc.Def.Timeout != "" {
+		timeout, err := time.ParseDuration(sc.Def.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid approval timeout %q: %w", sc.Def.Timeout, err)
+		}
+		onTimeout := sc.Def.OnTimeout
+		if onTimeout == "" {
+			onTimeout = DecisionRejected
+		}
+		sc.Step.Input["deadline"] = time.Now().Add(timeout).UTC().Format(time.RFC3339)
+		sc.Step.Input["onTimeout"] = onTimeout
+	}
+	return &StepResult{Wait: StepStatusWaitingApproval}, nil
+}
+
+// DecideApproval records an approval decision for a parked step.
+// Approving requeues the job; rejecting fails it.
+func (o *Orchestrator) DecideApproval(jobID, stepID, decision, approver, comment string) (*state.Step, error) {
+	if decision != DecisionApproved && decision != DecisionRejected {
+		return nil, fmt.Errorf("invalid decision %q", decision)
+	}
+
+	now := time.Now()
+	step, err := o.claimStep(jobID, stepID, StepStatusWaitingApproval, func(step *state.Step) {
+		step.Output = state.JSONMap{
+			"decision":  decision,
+			"approver":  approver,
+			"comment":   comment,
+			"decidedAt": now.UTC().Format(time.RFC3339),
+		}
+		step.CompletedAt = &now
+		if decision == DecisionApproved {
+			step.Status = StepStatusSucceeded
+			return
+		}
+		step.Status = StepStatusFailed
+		step.Error = fmt.Sprintf("rejected by %s", approver)
+		if comment != "" {
+			step.Error += ": " + comment
+		}
+	})
+	if errors.Is(err, ErrStepNotWaiting) {
+		return nil, ErrNotAwaitingApproval
+	}
+	if err != nil {
+		return nil, err
+	}
+	job, err := o.repo.GetJob(jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load job %s: %w", jobID, err)
+	}
+
+	data := map[string]interface{}{
+		"step":     step.Name,
+		"decision": decision,
+		"approver": approver,
+		"comment":  comment,
+	}
+	if decision == DecisionApproved {
+		o.emit(jobID, stepID, EventStepApproved, comment, data)
+		if err := o.resumeJob(job); err != nil {
+			return nil, err
+		}
+		return step, nil
+	}
+
+	o.emit(jobID, stepID, EventStepRejected, comment, data)
+	o.finishJob(job, JobStatusFailed, errors.New(step.Error))
+	return step, nil
+}
+
+// expireApprovals applies the configured timeout decision to overdue approval steps
+func (o *Orchestrator) expireApprovals(now time.Time) {
+	steps, err := o.repo.ListStepsByStatus(StepStatusWaitingApproval)
+	if err != nil {
+		logger.Errorf("orchestrator: failed to list waiting approvals: %v", err)
+		return
+	}
+
+	for _, s := range steps {
+		deadline, ok := s.Input["deadline"].(string)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, deadline)
+		if err != nil || now.Before(t) {
+			continue
+		}
+		decision, _ := s.Input["onTimeout"].(string)
+		if decision == "" {
+			decision = DecisionRejected
+		}
+		_, err = o.DecideApproval(s.JobID, s.ID, decision, TimeoutApprover, "approval timed out")
+		if err != nil && !errors.Is(err, ErrNotAwaitingApproval) {
+			logger.Errorf("orchestrator: failed to apply approval timeout to step %s: %v", s.ID, err)
+		}
+	}
+}
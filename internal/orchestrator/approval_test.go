@@ -0,0 +1,125 @@
+package orchestrator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"agent-project-manager/internal/state"
+)
+
+func approvalWorkflow(gate map[string]interface{}) map[string]state.JSONMap {
+	gate["name"] = "gate"
+	gate["type"] = StepTypeApproval
+	return map[string]state.JSONMap{
+		"wf": stepDefs(gate, map[string]interface{}{"name": "after", "type": "echo"}),
+	}
+}
+
+func TestApprovalApprove(t *testing.T) {
+	o, repo := newTestOrchestrator(t, approvalWorkflow(map[string]interface{}{}))
+
+	job := submit(t, o, "wf", nil)
+	if job.Status != JobStatusWaitingApproval {
+		t.Fatalf("job %s: %s; want it waiting for approval", job.Status, job.Error)
+	}
+	gate := jobSteps(t, o, job.ID)["gate"]
+	if _, ok := jobSteps(t, o, job.ID)["after"]; ok {
+		t.Fatal("the step after the gate ran before the approval")
+	}
+
+	step, err := o.DecideApproval(job.ID, gate.ID, DecisionApproved, "alice", "ship it")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if step.Status != StepStatusSucceeded || step.Output["approver"] != "alice" || step.Output["comment"] != "ship it" {
+		t.Errorf("approved step = %s %v", step.Status, step.Output)
+	}
+	if _, err := o.DecideApproval(job.ID, gate.ID, DecisionApproved, "bob", ""); !errors.Is(err, ErrNotAwaitingApproval) {
+		t.Errorf("deciding twice: error = %v, want ErrNotAwaitingApproval", err)
+	}
+
+	drain(o)
+	if job = reload(t, o, job.ID); job.Status != JobStatusSucceeded {
+		t.Fatalf("job %s: %s after the approval", job.Status, job.Error)
+	}
+	if after := jobSteps(t, o, job.ID)["after"]; after == nil || after.Status != StepStatusSucceeded {
+		t.Errorf("step after the gate = %+v", after)
+	}
+	if !contains(repo.eventTypes(job.ID), EventStepApproved) {
+		t.Errorf("events %v lack %s", repo.eventTypes(job.ID), EventStepApproved)
+	}
+}
+
+func TestApprovalReject(t *testing.T) {
+	o, repo := newTestOrchestrator(t, approvalWorkflow(map[string]interface{}{}))
+
+	job := submit(t, o, "wf", nil)
+	gate := jobSteps(t, o, job.ID)["gate"]
+	if _, err := o.DecideApproval(job.ID, gate.ID, "maybe", "alice", ""); err == nil {
+		t.Error("an unknown decision: want an error")
+	}
+	step, err := o.DecideApproval(job.ID, gate.ID, DecisionRejected, "alice", "not now")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if step.Status != StepStatusFailed || step.Error != "rejected by alice: not now" {
+		t.Errorf("rejected step = %s %q", step.Status, step.Error)
+	}
+
+	drain(o)
+	job = reload(t, o, job.ID)
+	if job.Status != JobStatusFailed || job.Error != "rejected by alice: not now" {
+		t.Errorf("job = %s %q, want it failed by the rejection", job.Status, job.Error)
+	}
+	if _, ok := jobSteps(t, o, job.ID)["after"]; ok {
+		t.Error("the step after a rejected gate ran")
+	}
+	if !contains(repo.eventTypes(job.ID), EventStepRejected) {
+		t.Errorf("events %v lack %s", repo.eventTypes(job.ID), EventStepRejected)
+	}
+}
+
+func TestApprovalTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		onTimeout string
+		want      string
+	}{
+		{onTimeout: "", want: JobStatusFailed}, // rejected by default
+		{onTimeout: DecisionRejected, want: JobStatusFailed},
+		{onTimeout: DecisionApproved, want: JobStatusSucceeded},
+	} {
+		t.Run("onTimeout="+tc.onTimeout, func(t *testing.T) {
+			gate := map[string]interface{}{"timeout": "1h"}
+			if tc.onTimeout != "" {
+				gate["onTimeout"] = tc.onTimeout
+			}
+			o, _ := newTestOrchestrator(t, approvalWorkflow(gate))
+
+			job := submit(t, o, "wf", nil)
+			o.expireApprovals(time.Now().Add(30 * time.Minute))
+			drain(o)
+			if job = reload(t, o, job.ID); job.Status != JobStatusWaitingApproval {
+				t.Fatalf("job %s before the deadline", job.Status)
+			}
+
+			o.expireApprovals(time.Now().Add(2 * time.Hour))
+			drain(o)
+			if job = reload(t, o, job.ID); job.Status != tc.want {
+				t.Fatalf("job %s: %s after the deadline, want %s", job.Status, job.Error, tc.want)
+			}
+			if approver := jobSteps(t, o, job.ID)["gate"].Output["approver"]; approver != TimeoutApprover {
+				t.Errorf("approver = %v, want %s", approver, TimeoutApprover)
+			}
+		})
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
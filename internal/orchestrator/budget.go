@@ -0,0 +1,70 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+
+	"agent-project-manager/internal/state"
+)
+
+// Budget caps the LLM usage of a job (meta.budget). Once the job and the jobs its
+// sub-workflow steps started have used up either limit, further LLM requests fail;
+// the call that crosses a limit still completes, so a job may overshoot by one call.
+type Budget struct {
+	Tokens  int64   `json:"tokens,omitempty"`  // prompt plus completion tokens; zero means no limit
+	CostUSD float64 `json:"costUsd,omitempty"` // by the configured price table; zero means no limit
+}
+
+// Validate checks the limits
+func (b *Budget) Validate() error {
+	if b.Tokens < 0 || b.CostUSD < 0 {
+		return errors.New("budget limits must not be negative")
+	}
+	return nil
+}
+
+// Meta returns the budget as stored in a job's meta
+func (b *Budget) Meta() map[string]interface{} {
+	meta := map[string]interface{}{}
+	if b.Tokens > 0 {
+		meta["tokens"] = b.Tokens
+	}
+	if b.CostUSD > 0 {
+		meta["costUsd"] = b.CostUSD
+	}
+	return meta
+}
+
+// Exceeded describes the limit the usage has reached; empty while within budget
+func (b *Budget) Exceeded(u *state.LLMUsage) string {
+	switch {
+	case b.Tokens > 0 && u.TotalTokens >= b.Tokens:
+		return fmt.Sprintf("used %d of %d tokens", u.TotalTokens, b.Tokens)
+	case b.CostUSD > 0 && u.CostUSD >= b.CostUSD:
+		return fmt.Sprintf("used $%.4f of $%.4f", u.CostUSD, b.CostUSD)
+	default:
+		return ""
+	}
+}
+
+// JobBudget returns the budget a job was submitted with; nil when it has none
+func JobBudget(job *state.Job) *Budget {
+	m, ok := job.Meta["budget"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	b := &Budget{}
+	switch v := m["tokens"].(type) {
+	case float64: // decoded from the database
+		b.Tokens = int64(v)
+	case int64:
+		b.Tokens = v
+	}
+	if v, ok := m["costUsd"].(float64); ok {
+		b.CostUSD = v
+	}
+	if b.Tokens <= 0 && b.CostUSD <= 0 {
+		return nil
+	}
+	return b
+}
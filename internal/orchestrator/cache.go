@@ -0,0 +1,176 @@
+package orchestrator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"agent-project-manager/internal/logger"
+	"agent-project-manager/internal/state"
+)
+
+// stepCacheVersion is part of every cache key, so changing how keys are built invalidates old entries
+const stepCacheVersion = 1
+
+// cacheableStepType reports whether results of a step type may be memoized.
+// Approval, sub-workflow and signal steps depend on things outside their input.
+func cacheableStepType(stepType string) bool {
+	return !isWaitingStepType(stepType)
+}
+
+// CacheStep reports whether a step's result is memoized in the step cache
+func (d *Definition) CacheStep(sd StepDef) bool {
+	if !cacheableStepType(sd.Type) {
+		return false
+	}
+	if sd.Cache != nil {
+		return *sd.Cache
+	}
+	return d.Cache
+}
+
+// noCache reports whether the job opted out of the step cache (meta.noCache)
+func noCache(job *state.Job) bool {
+	v, _ := job.Meta["noCache"].(bool)
+	return v
+}
+
+// CacheKeyer is implemented by executors whose results depend on more than the step's
+// definition and input, e.g. on the latest version of a prompt the input names.
+// The material returned is hashed into the step's cache key.
+type CacheKeyer interface {
+	CacheKeyMaterial(repo state.Repository, sd StepDef, input map[string]interface{}) (interface{}, error)
+}
+
+// stepCacheKey hashes the step definition, its resolved input, the digests of any
+// artifacts the input references and the executor's own key material.
+// The step's name and dependencies are left out: they only matter through the input.
+func (o *Orchestrator) stepCacheKey(exec StepExecutor, sd StepDef, input map[string]interface{}) (string, error) {
+	fields := map[string]interface{}{
+		"version":   stepCacheVersion,
+		"input":     input,
+		"artifacts": o.artifactDigests("", input, map[string]string{}),
+	}
+	if keyer, ok := exec.(CacheKeyer); ok {
+		material, err := keyer.CacheKeyMaterial(o.repo, sd, input)
+		if err != nil {
+			return "", err
+		}
+		if material != nil {
+			fields["executor"] = material
+		}
+	}
+
+	sd.Name = ""
+	sd.DependsOn = nil
+	sd.Cache = nil
+	fields["step"] = sd
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode step cache key: %w", err)
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// artifactDigests maps the JSON pointer of every input value naming an artifact to its digest.
+// Artifacts without a recorded digest are identified by their ID instead.
+func (o *Orchestrator) artifactDigests(path string, v interface{}, out map[string]string) map[string]string {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			o.artifactDigests(path+"/"+escapePointer(k), item, out)
+		}
+	case state.JSONMap:
+		o.artifactDigests(path, map[string]interface{}(val), out)
+	case []interface{}:
+		for i, item := range val {
+			o.artifactDigests(path+"/"+strconv.Itoa(i), item, out)
+		}
+	case string:
+		if _, err := uuid.Parse(val); err != nil {
+			break
+		}
+		if a, err := o.repo.GetArtifact(val); err == nil {
+			if a.Digest != "" {
+				out[path] = a.Digest
+			} else {
+				out[path] = "artifact:" + a.ID
+			}
+		}
+	}
+	return out
+}
+
+// reuseCachedStep completes a step from a cache entry, attaching copies of the
+// cached artifacts to the job. It reports false when the entry cannot be used.
+func (o *Orchestrator) reuseCachedStep(job *state.Job, rec *state.Step, entry *state.StepCacheEntry) bool {
+	artifacts := make([]*state.Artifact, 0, len(entry.ArtifactIDs))
+	for _, id := range entry.ArtifactIDs {
+		a, err := o.repo.GetArtifact(id)
+		if err != nil {
+			logger.Infof("orchestrator: ignoring step cache entry %s: artifact %s is gone", entry.Key, id)
+			return false
+		}
+		artifacts = append(artifacts, a)
+	}
+
+	ids, err := o.attachArtifacts(job, artifacts)
+	if err != nil {
+		logger.Warnf("orchestrator: failed to attach cached artifacts to job %s: %v", job.ID, err)
+		return false
+	}
+
+	rec.Output = copyMap(entry.Output)
+	rec.ArtifactIDs = ids
+	rec.Cached = true
+	rec.CacheKey = entry.Key
+	if err := o.repo.RecordStepCacheHit(entry.Key); err != nil {
+		logger.Warnf("orchestrator: failed to record step cache hit %s: %v", entry.Key, err)
+	}
+	return true
+}
+
+// attachArtifacts makes artifacts of another job available to a job by recording copies
+// that point at the same content. It returns the IDs of the job's artifacts.
+func (o *Orchestrator) attachArtifacts(job *state.Job, artifacts []*state.Artifact) ([]string, error) {
+	ids := make([]string, 0, len(artifacts))
+	for _, a := range artifacts {
+		if a.JobID == job.ID {
+			ids = append(ids, a.ID)
+			continue
+		}
+		dup := &state.Artifact{
+			JobID:  job.ID,
+			Type:   a.Type,
+			Name:   a.Name,
+			Size:   a.Size,
+			Path:   a.Path,
+			Digest: a.Digest,
+		}
+		if err := o.repo.CreateArtifact(dup); err != nil {
+			return nil, fmt.Errorf("failed to attach artifact %s: %w", a.ID, err)
+		}
+		ids = append(ids, dup.ID)
+	}
+	return ids, nil
+}
+
+// saveStepCache stores a freshly computed step result under its cache key
+func (o *Orchestrator) saveStepCache(job *state.Job, sd StepDef, rec *state.Step, artifacts []string) {
+	entry := &state.StepCacheEntry{
+		Key:          rec.CacheKey,
+		StepType:     sd.Type,
+		Output:       rec.Output,
+		ArtifactIDs:  artifacts,
+		SourceJobID:  job.ID,
+		SourceStepID: rec.ID,
+	}
+	if err := o.repo.SaveStepCacheEntry(entry); err != nil {
+		logger.Warnf("orchestrator: failed to cache result of step %s: %v", rec.ID, err)
+	}
+}
@@ -0,0 +1,56 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+
+	"agent-project-manager/internal/state"
+)
+
+func TestStepCache(t *testing.T) {
+	o, repo := newTestOrchestrator(t, map[string]state.JSONMap{
+		"wf": {"cache": true, "steps": []interface{}{
+			map[string]interface{}{"name": "a", "type": "count", "input": map[string]interface{}{"x": "{{ .input.x }}", "doc": "{{ .input.doc }}"}},
+			map[string]interface{}{"name": "b", "type": "count", "cache": false},
+		}},
+	})
+	runs := map[string]int{}
+	o.Register("count", StepExecutorFunc(func(ctx context.Context, sc *StepContext) (*StepResult, error) {
+		runs[sc.Step.Name]++
+		out := &state.Artifact{JobID: sc.Job.ID, Name: "out.txt", Digest: "sha256:out"}
+		if err := sc.Repo.CreateArtifact(out); err != nil {
+			return nil, err
+		}
+		return &StepResult{Output: map[string]interface{}{"run": runs[sc.Step.Name]}, Artifacts: []string{out.ID}}, nil
+	}))
+	doc := &state.Artifact{Name: "doc.md", Digest: "sha256:d1"}
+	repo.CreateArtifact(doc)
+	input := state.JSONMap{"x": 1, "doc": doc.ID}
+
+	first := submit(t, o, "wf", input)
+	second := submit(t, o, "wf", input)
+	if runs["a"] != 1 || runs["b"] != 2 {
+		t.Fatalf("steps ran %v; want a once and the uncached b every time", runs)
+	}
+	cached := jobSteps(t, o, second.ID)["a"]
+	if !cached.Cached || cached.Output["run"] != 1 {
+		t.Errorf("second job's step a = cached %v, output %v; want the first run's output", cached.Cached, cached.Output)
+	}
+	if len(cached.ArtifactIDs) != 1 || cached.ArtifactIDs[0] == jobSteps(t, o, first.ID)["a"].ArtifactIDs[0] {
+		t.Errorf("cached step artifacts %v; want a copy attached to the second job", cached.ArtifactIDs)
+	}
+
+	// Opting out, or a changed input artifact, runs the step again
+	job := &state.Job{Workflow: "wf", WorkflowVersion: 1, Status: JobStatusQueued, Input: input, Meta: state.JSONMap{"noCache": true}}
+	repo.CreateJob(job)
+	o.Enqueue(job.ID)
+	drain(o)
+	if runs["a"] != 2 {
+		t.Errorf("step a ran %d times; want noCache to run it again", runs["a"])
+	}
+	repo.artifacts[doc.ID].Digest = "sha256:d2"
+	submit(t, o, "wf", input)
+	if runs["a"] != 3 {
+		t.Errorf("step a ran %d times; want a changed artifact digest to run it again", runs["a"])
+	}
+}
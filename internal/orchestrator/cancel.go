@@ -0,0 +1,104 @@
+package orchestrator
+
+import (
+	"errors"
+	"time"
+
+	"agent-project-manager/internal/logger"
+	"agent-project-manager/internal/state"
+)
+
+// cancelWait bounds how long cancelling a job waits for the step it interrupted to return
+const cancelWait = 30 * time.Second
+
+var (
+	// ErrJobNotFound is returned when a job does not exist
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobFinished is returned when cancelling a job that already reached a terminal status
+	// or is compensating its completed steps
+	ErrJobFinished = errors.New("job already finished")
+)
+
+// CancelJob cancels a job together with all of its unfinished child jobs.
+// A step the job is currently executing is interrupted, and the job is finished once
+// the step returned.
+func (o *Orchestrator) CancelJob(jobID string) error {
+	job, err := o.repo.GetJob(jobID)
+	if err != nil {
+		return ErrJobNotFound
+	}
+	if isFinishingJobStatus(job.Status) {
+		return ErrJobFinished
+	}
+	o.cancelJob(job, errors.New("cancelled"))
+	return nil
+}
+
+// cancelJob cancels a single job and recurses into its children.
+// A step that is running is interrupted and awaited before the job is finished, so
+// compensations never run alongside the step they may have to undo.
+func (o *Orchestrator) cancelJob(job *state.Job, cause error) {
+	o.runMu.Lock()
+	run := o.running[job.ID]
+	if run != nil {
+		run.cancelled = true
+	}
+	o.runMu.Unlock()
+	if run != nil {
+		run.cancel()
+		select {
+		case <-run.done:
+		case <-time.After(cancelWait):
+			logger.Warnf("orchestrator: a step of job %s did not stop within %v of being cancelled", job.ID, cancelWait)
+		}
+		// The worker updated the job while running it
+		current, err := o.repo.GetJob(job.ID)
+		if err != nil {
+			logger.Errorf("orchestrator: failed to reload cancelled job %s: %v", job.ID, err)
+		} else {
+			job = current
+		}
+	}
+
+	o.abortSteps(job.ID, cause)
+	if !isFinishingJobStatus(job.Status) {
+		o.finishJob(job, JobStatusCancelled, cause)
+	}
+
+	children, err := o.repo.ListChildJobs(job.ID)
+	if err != nil {
+		logger.Errorf("orchestrator: failed to list child jobs of %s: %v", job.ID, err)
+		return
+	}
+	for _, child := range children {
+		if !isFinishingJobStatus(child.Status) {
+			o.cancelJob(child, errors.New("parent job cancelled"))
+		}
+	}
+}
+
+// abortSteps fails the unfinished steps of a cancelled job
+func (o *Orchestrator) abortSteps(jobID string, cause error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	steps, err := o.repo.ListSteps(jobID)
+	if err != nil {
+		logger.Errorf("orchestrator: failed to list steps of %s: %v", jobID, err)
+		return
+	}
+	for _, s := range steps {
+		if s.Status != StepStatusPending && s.Status != StepStatusRunning && !isWaitingStepStatus(s.Status) {
+			continue
+		}
+		now := time.Now()
+		s.Status = StepStatusFailed
+		s.CompletedAt = &now
+		s.Error = cause.Error()
+		if err := o.repo.UpdateStep(s); err != nil {
+			logger.Errorf("orchestrator: failed to abort step %s: %v", s.ID, err)
+			continue
+		}
+		o.emit(jobID, s.ID, EventStepFailed, s.Error, map[string]interface{}{"step": s.Name})
+	}
+}
@@ -0,0 +1,51 @@
+package orchestrator
+
+import (
+	"fmt"
+	"regexp"
+
+	"agent-project-manager/internal/config"
+	"agent-project-manager/internal/state"
+)
+
+// cassetteName is what a cassette name may look like; it becomes a file name
+var cassetteName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Cassette has a job's LLM traffic recorded to, or replayed from, a cassette file
+// (meta.cassette). It applies to the jobs the job's sub-workflow steps start too,
+// and takes precedence over the cassettes of the providers.
+type Cassette struct {
+	Mode string `json:"mode"` // record or replay
+	Name string `json:"name"` // the file <llm.cassettes.dir>/<name>.json
+}
+
+// Validate checks the mode and the name
+func (c *Cassette) Validate() error {
+	if c.Mode != config.CassetteRecord && c.Mode != config.CassetteReplay {
+		return fmt.Errorf("mode must be %s or %s", config.CassetteRecord, config.CassetteReplay)
+	}
+	if !cassetteName.MatchString(c.Name) {
+		return fmt.Errorf("invalid name %q: use letters, digits, '.', '_' and '-'", c.Name)
+	}
+	return nil
+}
+
+// Meta returns the cassette as stored in a job's meta
+func (c *Cassette) Meta() map[string]interface{} {
+	return map[string]interface{}{"mode": c.Mode, "name": c.Name}
+}
+
+// JobCassette returns the cassette a job was submitted with; nil when it has none
+func JobCassette(job *state.Job) *Cassette {
+	m, ok := job.Meta["cassette"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	c := &Cassette{}
+	c.Mode, _ = m["mode"].(string)
+	c.Name, _ = m["name"].(string)
+	if c.Validate() != nil {
+		return nil
+	}
+	return c
+}
@@ -0,0 +1,176 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"agent-project-manager/internal/logger"
+	"agent-project-manager/internal/state"
+)
+
+// compensationPrefix names the step record of a compensation, e.g. "compensate:create-branch"
+const compensationPrefix = "compensate:"
+
+// startCompensation parks a failed or cancelled job in the compensating status when
+// any of its completed steps declare a compensation, and queues the job to run them.
+// It reports false when there is nothing to undo.
+func (o *Orchestrator) startCompensation(job *state.Job, outcome string, cause error) bool {
+	if _, started := job.Meta["compensation"]; started {
+		return false
+	}
+	def, err := o.loadDefinition(job)
+	if err != nil {
+		return false
+	}
+	records, err := o.stepRecords(job.ID)
+	if err != nil {
+		logger.Errorf("orchestrator: failed to list steps of %s: %v", job.ID, err)
+		return false
+	}
+	pending := compensationsFor(def, records)
+	if len(pending) == 0 {
+		return false
+	}
+
+	if job.Meta == nil {
+		job.Meta = state.JSONMap{}
+	}
+	reason := ""
+	if cause != nil {
+		reason = cause.Error()
+	}
+	job.Meta["compensation"] = map[string]interface{}{"outcome": outcome, "error": reason}
+	job.Status = JobStatusCompensating
+	job.Error = reason
+	if err := o.repo.UpdateJob(job); err != nil {
+		logger.Errorf("orchestrator: failed to start compensation of job %s: %v", job.ID, err)
+		return false
+	}
+
+	names := make([]string, len(pending))
+	for i, sd := range pending {
+		names[i] = sd.Name
+	}
+	o.emit(job.ID, "", EventJobCompensating, reason, map[string]interface{}{"outcome": outcome, "steps": names})
+
+	if err := o.Enqueue(job.ID); err != nil {
+		logger.Errorf("orchestrator: %v", err)
+	}
+	return true
+}
+
+// compensate runs the compensations of a job's completed steps in reverse order,
+// then settles the job with the outcome that triggered them.
+// A failed compensation does not stop the others; failures are reported on the job.
+func (o *Orchestrator) compensate(ctx context.Context, job *state.Job) {
+	info, _ := job.Meta["compensation"].(map[string]interface{})
+	outcome, _ := info["outcome"].(string)
+	if outcome == "" {
+		outcome = JobStatusFailed
+	}
+	reason, _ := info["error"].(string)
+
+	var failed []string
+	def, err := o.loadDefinition(job)
+	if err != nil {
+		failed = append(failed, err.Error())
+	}
+	records, err := o.stepRecords(job.ID)
+	if err != nil {
+		failed = append(failed, fmt.Sprintf("failed to list steps: %v", err))
+	}
+
+	if def != nil && records != nil {
+		for _, sd := range compensationsFor(def, records) {
+			if ctx.Err() != nil {
+				// Shutting down: Recover resumes the remaining compensations
+				return
+			}
+			if err := o.compensateStep(ctx, job, sd, records); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				failed = append(failed, err.Error())
+			}
+		}
+	}
+
+	o.emit(job.ID, "", EventJobCompensated, strings.Join(failed, "; "), map[string]interface{}{
+		"outcome": outcome,
+		"failed":  len(failed),
+	})
+
+	var cause error
+	if reason != "" {
+		cause = errors.New(reason)
+	}
+	if len(failed) > 0 {
+		msg := "compensation failed: " + strings.Join(failed, "; ")
+		if reason != "" {
+			msg = reason + "; " + msg
+		}
+		cause = errors.New(msg)
+	}
+	o.settleJob(job, outcome, cause)
+}
+
+// compensateStep runs the compensation of one completed step as its own step record.
+// The step is marked compensated once its compensation succeeds. A compensation that
+// already succeeded, before a crash kept the step from being marked, is not run again.
+func (o *Orchestrator) compensateStep(ctx context.Context, job *state.Job, sd StepDef, records map[string]*state.Step) error {
+	comp := StepDef{
+		Name:  compensationPrefix + sd.Name,
+		Type:  sd.Compensate.Type,
+		Input: sd.Compensate.Input,
+	}
+	if rec := records[comp.Name]; rec == nil || rec.Status != StepStatusSucceeded {
+		rec, _, err := o.runStep(ctx, job, comp, rec, templateData(job, records), false)
+		if rec != nil {
+			records[comp.Name] = rec
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	target := records[sd.Name]
+	target.Status = StepStatusCompensated
+	if err := o.repo.UpdateStep(target); err != nil {
+		return fmt.Errorf("failed to mark step %s compensated: %w", sd.Name, err)
+	}
+	return nil
+}
+
+// compensationsFor lists the succeeded steps with a compensation that has not
+// succeeded yet, latest step first
+func compensationsFor(def *Definition, records map[string]*state.Step) []StepDef {
+	order, err := def.Order()
+	if err != nil {
+		return nil
+	}
+	var out []StepDef
+	for i := len(order) - 1; i >= 0; i-- {
+		sd := order[i]
+		rec := records[sd.Name]
+		if sd.Compensate == nil || rec == nil || rec.Status != StepStatusSucceeded {
+			continue
+		}
+		out = append(out, sd)
+	}
+	return out
+}
+
+// stepRecords indexes a job's step records by name
+func (o *Orchestrator) stepRecords(jobID string) (map[string]*state.Step, error) {
+	steps, err := o.repo.ListSteps(jobID)
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[string]*state.Step, len(steps))
+	for _, s := range steps {
+		records[s.Name] = s
+	}
+	return records, nil
+}
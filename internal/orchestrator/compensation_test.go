@@ -0,0 +1,186 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"agent-project-manager/internal/state"
+)
+
+// compensatingOrchestrator records the steps the "undo" step type compensates;
+// the "boom" step type always fails
+func compensatingOrchestrator(t *testing.T, workflows map[string]state.JSONMap) (*Orchestrator, *fakeRepo, *[]string) {
+	t.Helper()
+	o, repo := newTestOrchestrator(t, workflows)
+	var undone []string
+	o.Register("undo", StepExecutorFunc(func(ctx context.Context, sc *StepContext) (*StepResult, error) {
+		undone = append(undone, sc.Step.Input["undo"].(string))
+		return &StepResult{}, nil
+	}))
+	o.Register("boom", StepExecutorFunc(func(ctx context.Context, sc *StepContext) (*StepResult, error) {
+		return nil, errors.New("kaboom")
+	}))
+	return o, repo, &undone
+}
+
+// undoable is a step compensated by undoing the value of its output
+func undoable(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"name": name, "type": "echo", "input": map[string]interface{}{"value": name},
+		"compensate": map[string]interface{}{"type": "undo", "input": map[string]interface{}{"undo": "{{ .steps." + name + ".output.value }}"}},
+	}
+}
+
+func TestCompensationOrder(t *testing.T) {
+	o, repo, undone := compensatingOrchestrator(t, map[string]state.JSONMap{
+		"wf": stepDefs(
+			undoable("branch"),
+			undoable("pr"),
+			map[string]interface{}{"name": "plain", "type": "echo"},
+			map[string]interface{}{"name": "boom", "type": "boom"},
+			undoable("never"),
+		),
+	})
+
+	job := submit(t, o, "wf", nil)
+	if job.Status != JobStatusFailed || job.Error != "step boom failed: kaboom" {
+		t.Errorf("job = %s %q; want it failed with the step's error once compensated", job.Status, job.Error)
+	}
+	if want := []string{"pr", "branch"}; !reflect.DeepEqual(*undone, want) {
+		t.Errorf("compensated %v, want %v: latest step first, only steps that succeeded", *undone, want)
+	}
+	steps := jobSteps(t, o, job.ID)
+	for _, name := range []string{"branch", "pr"} {
+		if steps[name].Status != StepStatusCompensated {
+			t.Errorf("step %s is %s, want compensated", name, steps[name].Status)
+		}
+		if comp := steps[compensationPrefix+name]; comp == nil || comp.Status != StepStatusSucceeded {
+			t.Errorf("compensation record of %s = %+v", name, comp)
+		}
+	}
+	if steps["plain"].Status != StepStatusSucceeded {
+		t.Errorf("step without a compensation is %s, want succeeded", steps["plain"].Status)
+	}
+	events := repo.eventTypes(job.ID)
+	if !contains(events, EventJobCompensating) || !contains(events, EventJobCompensated) {
+		t.Errorf("events %v lack the compensation events", events)
+	}
+}
+
+func TestCompensationOnCancel(t *testing.T) {
+	o, _, undone := compensatingOrchestrator(t, map[string]state.JSONMap{
+		"wf": stepDefs(
+			undoable("branch"),
+			map[string]interface{}{"name": "gate", "type": StepTypeApproval},
+		),
+	})
+
+	job := submit(t, o, "wf", nil)
+	if err := o.CancelJob(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if job = reload(t, o, job.ID); job.Status != JobStatusCompensating {
+		t.Fatalf("job %s after cancelling, want compensating", job.Status)
+	}
+	if err := o.CancelJob(job.ID); err != ErrJobFinished {
+		t.Errorf("cancelling a compensating job: error = %v, want ErrJobFinished", err)
+	}
+
+	drain(o)
+	if job = reload(t, o, job.ID); job.Status != JobStatusCancelled {
+		t.Errorf("job %s after compensating, want cancelled", job.Status)
+	}
+	if want := []string{"branch"}; !reflect.DeepEqual(*undone, want) {
+		t.Errorf("compensated %v, want %v", *undone, want)
+	}
+}
+
+func TestCompensationAfterInterruptedStep(t *testing.T) {
+	o, repo, undone := compensatingOrchestrator(t, map[string]state.JSONMap{
+		"wf": stepDefs(
+			undoable("branch"),
+			map[string]interface{}{"name": "slow", "type": "slow"},
+		),
+	})
+	started := make(chan struct{})
+	var returned atomic.Bool
+	o.Register("slow", StepExecutorFunc(func(ctx context.Context, sc *StepContext) (*StepResult, error) {
+		close(started)
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond) // still pushing the branch
+		returned.Store(true)
+		return nil, ctx.Err()
+	}))
+
+	job := &state.Job{Workflow: "wf", WorkflowVersion: 1, Status: JobStatusQueued}
+	repo.CreateJob(job)
+	o.Enqueue(job.ID)
+	worked := make(chan struct{})
+	go func() {
+		drain(o)
+		close(worked)
+	}()
+	<-started
+
+	if err := o.CancelJob(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !returned.Load() {
+		t.Error("CancelJob returned before the interrupted step did, so its compensation could run alongside it")
+	}
+	<-worked
+	drain(o)
+
+	if job = reload(t, o, job.ID); job.Status != JobStatusCancelled || job.Error != "cancelled" {
+		t.Errorf("job = %s %q, want cancelled", job.Status, job.Error)
+	}
+	if want := []string{"branch"}; !reflect.DeepEqual(*undone, want) {
+		t.Errorf("compensated %v, want %v", *undone, want)
+	}
+	if slow := jobSteps(t, o, job.ID)["slow"]; slow.Status != StepStatusFailed {
+		t.Errorf("interrupted step is %s, want failed", slow.Status)
+	}
+}
+
+func TestCompensationRecovered(t *testing.T) {
+	o, repo, undone := compensatingOrchestrator(t, map[string]state.JSONMap{
+		"wf": stepDefs(
+			undoable("branch"),
+			undoable("pr"),
+			map[string]interface{}{"name": "boom", "type": "boom"},
+		),
+	})
+
+	// A crash after the compensation of pr succeeded, before pr was marked compensated
+	job := &state.Job{
+		Workflow: "wf", WorkflowVersion: 1, Status: JobStatusCompensating,
+		Meta: state.JSONMap{"compensation": map[string]interface{}{"outcome": JobStatusFailed, "error": "step boom failed: kaboom"}},
+	}
+	repo.CreateJob(job)
+	repo.CreateStep(&state.Step{JobID: job.ID, Name: "branch", Status: StepStatusSucceeded, Output: state.JSONMap{"value": "branch"}})
+	repo.CreateStep(&state.Step{JobID: job.ID, Name: "pr", Status: StepStatusSucceeded, Output: state.JSONMap{"value": "pr"}})
+	repo.CreateStep(&state.Step{JobID: job.ID, Name: "boom", Status: StepStatusFailed})
+	repo.CreateStep(&state.Step{JobID: job.ID, Name: compensationPrefix + "pr", Status: StepStatusSucceeded})
+
+	if err := o.Recover(); err != nil {
+		t.Fatal(err)
+	}
+	drain(o)
+
+	if want := []string{"branch"}; !reflect.DeepEqual(*undone, want) {
+		t.Errorf("compensated %v, want %v: the compensation of pr already ran", *undone, want)
+	}
+	if job = reload(t, o, job.ID); job.Status != JobStatusFailed {
+		t.Errorf("job %s after recovering its compensation, want failed", job.Status)
+	}
+	steps := jobSteps(t, o, job.ID)
+	for _, name := range []string{"branch", "pr"} {
+		if steps[name].Status != StepStatusCompensated {
+			t.Errorf("step %s is %s, want compensated", name, steps[name].Status)
+		}
+	}
+}
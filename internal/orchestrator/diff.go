@@ -0,0 +1,155 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Diff operations
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// Change is a single difference between two workflow definitions.
+// Path is a JSON pointer into the definition, e.g. "/steps/2/timeout".
+type Change struct {
+	Path string
+	Op   string
+	From interface{}
+	To   interface{}
+}
+
+// DefinitionDiff describes how one workflow definition differs from another
+type DefinitionDiff struct {
+	StepsAdded   []string
+	StepsRemoved []string
+	StepsChanged []string
+	Changes      []Change
+}
+
+// DiffDefinitions compares two stored workflow definitions.
+// Steps are matched by name for the summary; Changes lists every structural difference.
+func DiffDefinitions(from, to map[string]interface{}) *DefinitionDiff {
+	d := &DefinitionDiff{
+		StepsAdded:   []string{},
+		StepsRemoved: []string{},
+		StepsChanged: []string{},
+		Changes:      []Change{},
+	}
+
+	fromSteps := stepsByName(from)
+	toSteps := stepsByName(to)
+	for _, name := range sortedKeys(toSteps) {
+		prev, ok := fromSteps[name]
+		switch {
+		case !ok:
+			d.StepsAdded = append(d.StepsAdded, name)
+		case !reflect.DeepEqual(prev, toSteps[name]):
+			d.StepsChanged = append(d.StepsChanged, name)
+		}
+	}
+	for _, name := range sortedKeys(fromSteps) {
+		if _, ok := toSteps[name]; !ok {
+			d.StepsRemoved = append(d.StepsRemoved, name)
+		}
+	}
+
+	diffValues("", normalize(from), normalize(to), &d.Changes)
+	return d
+}
+
+// stepsByName indexes a definition's steps by name, ignoring malformed definitions
+func stepsByName(schema map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	steps, _ := normalize(schema).(map[string]interface{})["steps"].([]interface{})
+	for _, s := range steps {
+		if m, ok := s.(map[string]interface{}); ok {
+			if name, ok := m["name"].(string); ok {
+				out[name] = m
+			}
+		}
+	}
+	return out
+}
+
+// diffValues appends the differences between a and b under path to changes
+func diffValues(path string, a, b interface{}, changes *[]Change) {
+	am, aIsMap := a.(map[string]interface{})
+	bm, bIsMap := b.(map[string]interface{})
+	if aIsMap && bIsMap {
+		for _, k := range sortedKeys(am) {
+			p := path + "/" + escapePointer(k)
+			if bv, ok := bm[k]; ok {
+				diffValues(p, am[k], bv, changes)
+			} else {
+				*changes = append(*changes, Change{Path: p, Op: DiffRemoved, From: am[k]})
+			}
+		}
+		for _, k := range sortedKeys(bm) {
+			if _, ok := am[k]; !ok {
+				*changes = append(*changes, Change{Path: path + "/" + escapePointer(k), Op: DiffAdded, To: bm[k]})
+			}
+		}
+		return
+	}
+
+	al, aIsList := a.([]interface{})
+	bl, bIsList := b.([]interface{})
+	if aIsList && bIsList {
+		for i := 0; i < len(al) || i < len(bl); i++ {
+			p := path + "/" + strconv.Itoa(i)
+			switch {
+			case i >= len(al):
+				*changes = append(*changes, Change{Path: p, Op: DiffAdded, To: bl[i]})
+			case i >= len(bl):
+				*changes = append(*changes, Change{Path: p, Op: DiffRemoved, From: al[i]})
+			default:
+				diffValues(p, al[i], bl[i], changes)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		if path == "" {
+			path = "/"
+		}
+		*changes = append(*changes, Change{Path: path, Op: DiffChanged, From: a, To: b})
+	}
+}
+
+// normalize round-trips v through JSON so values compare by their JSON form
+func normalize(v interface{}) interface{} {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return v
+	}
+	if out == nil {
+		return map[string]interface{}{}
+	}
+	return out
+}
+
+// escapePointer escapes a key for use in a JSON pointer (RFC 6901)
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
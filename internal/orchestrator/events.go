@@ -0,0 +1,84 @@
+package orchestrator
+
+import (
+	"agent-project-manager/internal/logger"
+	"agent-project-manager/internal/state"
+)
+
+// Event types recorded by the orchestrator
+const (
+	EventJobStarted       = "job.started"
+	EventJobResumed       = "job.resumed"
+	EventJobRecovered     = "job.recovered"
+	EventJobWaiting       = "job.waiting"
+	EventJobSucceeded     = "job.succeeded"
+	EventJobFailed        = "job.failed"
+	EventJobCancelled     = "job.cancelled"
+	EventJobCompensating  = "job.compensating"
+	EventJobCompensated   = "job.compensated"
+	EventJobRerun         = "job.rerun"
+	EventStepStarted      = "step.started"
+	EventStepWaiting      = "step.waiting"
+	EventStepSucceeded    = "step.succeeded"
+	EventStepCached       = "step.cached"
+	EventStepCopied       = "step.copied"
+	EventStepSkipped      = "step.skipped"
+	EventStepFailed       = "step.failed"
+	EventStepApproved     = "step.approved"
+	EventStepRejected     = "step.rejected"
+	EventStepChildStarted = "step.child_started"
+	EventSignalReceived   = "signal.received"
+	EventStepSignalled    = "step.signalled"
+	EventStepTimedOut     = "step.timed_out"
+	EventStepToolCall     = "step.tool_call" // a tool the model of an llm step called
+	EventArtifactCreated  = "artifact.created"
+	EventJobTriggered     = "job.triggered"
+)
+
+// EventHandler receives the events the orchestrator records.
+// Handlers run on the goroutine that emitted the event and must not block.
+type EventHandler func(event *state.Event)
+
+// Subscribe registers a handler for every event emitted from now on
+func (o *Orchestrator) Subscribe(h EventHandler) {
+	o.subMu.Lock()
+	defer o.subMu.Unlock()
+	o.subscribers = append(o.subscribers, h)
+}
+
+// emit records an event for a job (and optionally one of its steps).
+// Failures are logged rather than returned so bookkeeping never fails a job.
+func (o *Orchestrator) emit(jobID, stepID, eventType, message string, data map[string]interface{}) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	event := &state.Event{
+		JobID:   jobID,
+		StepID:  stepID,
+		Type:    eventType,
+		Message: message,
+		Data:    state.JSONMap(data),
+	}
+	if err := o.repo.CreateEvent(event); err != nil {
+		logger.Warnf("orchestrator: failed to record %s event for job %s: %v", eventType, jobID, err)
+	}
+
+	o.subMu.RLock()
+	handlers := o.subscribers
+	o.subMu.RUnlock()
+	for _, h := range handlers {
+		h(event)
+	}
+}
+
+// emitArtifacts announces the artifacts a step produced
+func (o *Orchestrator) emitArtifacts(job *state.Job, rec *state.Step, ids []string) {
+	for _, id := range ids {
+		data := map[string]interface{}{"step": rec.Name, "artifactId": id}
+		if a, err := o.repo.GetArtifact(id); err == nil {
+			data["type"] = a.Type
+			data["name"] = a.Name
+		}
+		o.emit(job.ID, rec.ID, EventArtifactCreated, "", data)
+	}
+}
@@ -0,0 +1,32 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"agent-project-manager/internal/state"
+)
+
+// The tests of package orchestrator_test run real step executors, which import this
+// package; these give them the in-memory repository and queue helpers.
+
+// NewTestOrchestrator creates an orchestrator over an in-memory repository holding workflows
+func NewTestOrchestrator(t *testing.T, workflows map[string]state.JSONMap, opts Options) (*Orchestrator, state.Repository) {
+	o, repo := newTestOrchestrator(t, workflows)
+	o.opts.CheckModel = opts.CheckModel
+	return o, repo
+}
+
+// Submit creates a job of the workflow's first version and works the queue until it is empty
+func Submit(t *testing.T, o *Orchestrator, workflow string, input state.JSONMap) *state.Job {
+	return submit(t, o, workflow, input)
+}
+
+// Drain works the queue until it is empty
+func Drain(o *Orchestrator) {
+	drain(o)
+}
+
+// JobSteps returns the step records of a job by name
+func JobSteps(t *testing.T, o *Orchestrator, jobID string) map[string]*state.Step {
+	return jobSteps(t, o, jobID)
+}
@@ -0,0 +1,322 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"agent-project-manager/internal/state"
+)
+
+// ErrWorkflowNotFound is returned when a workflow reference cannot be resolved
+var ErrWorkflowNotFound = errors.New("workflow not found")
+
+// Graph is the step DAG of a workflow, optionally annotated with the live state of a job
+type Graph struct {
+	Workflow string
+	Version  int    // zero for an unpublished draft
+	JobID    string // set for job graphs
+	Status   string // job status; empty for workflow graphs
+	Nodes    []GraphNode
+	Edges    []GraphEdge
+}
+
+// GraphNode is a step, or the compensation of a step.
+// Status and timing are only set on job graphs; a compensation that never ran has no status.
+type GraphNode struct {
+	ID           string // identifier safe to use in Mermaid and DOT, e.g. "s0"
+	Name         string
+	Type         string
+	If           string
+	Workflow     string // sub-workflow reference
+	Compensation bool   // the node undoes the step it is linked from
+	StepID       string
+	Status       string
+	StartedAt    *time.Time
+	CompletedAt  *time.Time
+	Duration     time.Duration // until now for steps that have not completed
+	Cached       bool
+	Error        string
+	ChildJobID   string
+}
+
+// GraphEdge links a step to a step that depends on it, or to its compensation
+type GraphEdge struct {
+	From         string
+	To           string
+	Compensation bool
+}
+
+// WorkflowGraph builds the graph of a workflow reference ("name" or "name@version").
+// Without a version it uses the latest published version, falling back to the draft.
+func (o *Orchestrator) WorkflowGraph(ref string) (*Graph, error) {
+	name, version, err := ParseWorkflowRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var schema state.JSONMap
+	wv, err := ResolveWorkflowVersion(o.repo, ref)
+	switch {
+	case err == nil:
+		name, version, schema = wv.Workflow, wv.Version, wv.Schema
+	case version > 0 || !errors.Is(err, ErrWorkflowNotFound):
+		return nil, err
+	default:
+		wf, err := o.repo.GetWorkflow(name)
+		if err != nil {
+			if errors.Is(err, state.ErrNotFound) {
+				return nil, fmt.Errorf("%w: %s", ErrWorkflowNotFound, ref)
+			}
+			return nil, err
+		}
+		schema = wf.Schema
+	}
+
+	def, err := ParseDefinition(schema)
+	if err != nil {
+		return nil, err
+	}
+	g := buildGraph(def)
+	g.Workflow, g.Version = name, version
+	return g, nil
+}
+
+// JobGraph builds the graph of the workflow version a job is pinned to,
+// annotated with each step's status and duration
+func (o *Orchestrator) JobGraph(jobID string) (*Graph, error) {
+	job, err := o.repo.GetJob(jobID)
+	if err != nil {
+		return nil, ErrJobNotFound
+	}
+	def, err := o.loadDefinition(job)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := o.repo.ListSteps(job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list steps: %w", err)
+	}
+
+	g := buildGraph(def)
+	g.Workflow, g.Version = job.Workflow, job.WorkflowVersion
+	g.JobID, g.Status = job.ID, job.Status
+	g.annotate(steps, time.Now())
+	return g, nil
+}
+
+// buildGraph turns a definition into nodes and edges.
+// Steps keep their declaration order; compensations follow them.
+func buildGraph(def *Definition) *Graph {
+	g := &Graph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	ids := map[string]string{}
+	for i, s := range def.Steps {
+		ids[s.Name] = fmt.Sprintf("s%d", i)
+		g.Nodes = append(g.Nodes, GraphNode{
+			ID:       ids[s.Name],
+			Name:     s.Name,
+			Type:     s.Type,
+			If:       s.If,
+			Workflow: s.Workflow,
+		})
+	}
+	for i, s := range def.Steps {
+		for _, dep := range def.Dependencies(i) {
+			if from, ok := ids[dep]; ok {
+				g.Edges = append(g.Edges, GraphEdge{From: from, To: ids[s.Name]})
+			}
+		}
+	}
+	for i, s := range def.Steps {
+		if s.Compensate == nil {
+			continue
+		}
+		id := fmt.Sprintf("c%d", i)
+		g.Nodes = append(g.Nodes, GraphNode{
+			ID:           id,
+			Name:         compensationPrefix + s.Name,
+			Type:         s.Compensate.Type,
+			Compensation: true,
+		})
+		g.Edges = append(g.Edges, GraphEdge{From: ids[s.Name], To: id, Compensation: true})
+	}
+	return g
+}
+
+// annotate copies the state of a job's step records onto the nodes
+func (g *Graph) annotate(steps []*state.Step, now time.Time) {
+	records := map[string]*state.Step{}
+	for _, s := range steps {
+		records[s.Name] = s
+	}
+	for i := range g.Nodes {
+		n := &g.Nodes[i]
+		rec := records[n.Name]
+		if rec == nil {
+			if !n.Compensation {
+				n.Status = StepStatusPending
+			}
+			continue
+		}
+		n.StepID = rec.ID
+		n.Status = rec.Status
+		n.StartedAt = rec.StartedAt
+		n.CompletedAt = rec.CompletedAt
+		n.Cached = rec.Cached
+		n.Error = rec.Error
+		if id, ok := rec.Output["childJobId"].(string); ok {
+			n.ChildJobID = id
+		}
+		if rec.StartedAt != nil {
+			end := now
+			if rec.CompletedAt != nil {
+				end = *rec.CompletedAt
+			}
+			n.Duration = end.Sub(*rec.StartedAt)
+		}
+	}
+}
+
+// label is the text shown in a node: name, type, condition and, for job graphs, status and duration
+func (n GraphNode) label() []string {
+	lines := []string{n.Name}
+	kind := n.Type
+	if n.Workflow != "" {
+		kind += ": " + n.Workflow
+	}
+	lines = append(lines, kind)
+	if n.If != "" {
+		lines = append(lines, "if "+n.If)
+	}
+	if n.Status != "" {
+		status := n.Status
+		if n.StartedAt != nil {
+			status += " (" + formatDuration(n.Duration) + ")"
+		}
+		if n.Cached {
+			status += " cached"
+		}
+		lines = append(lines, status)
+	}
+	return lines
+}
+
+// formatDuration rounds a duration for display
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+// statusStyles maps step statuses to a style class and fill and stroke colours
+var statusStyles = map[string][3]string{
+	StepStatusPending:         {"pending", "#ffffff", "#adb5bd"},
+	StepStatusRunning:         {"running", "#cce5ff", "#007bff"},
+	StepStatusWaitingApproval: {"waiting", "#fff3cd", "#ffc107"},
+	StepStatusWaitingChild:    {"waiting", "#fff3cd", "#ffc107"},
+	StepStatusWaitingSignal:   {"waiting", "#fff3cd", "#ffc107"},
+	StepStatusSucceeded:       {"succeeded", "#d4edda", "#28a745"},
+	StepStatusFailed:          {"failed", "#f8d7da", "#dc3545"},
+	StepStatusSkipped:         {"skipped", "#e2e3e5", "#6c757d"},
+	StepStatusCompensated:     {"compensated", "#e8dff5", "#6f42c1"},
+}
+
+// Mermaid renders the graph as a Mermaid flowchart
+func (g *Graph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+
+	for _, n := range g.Nodes {
+		label := `"` + mermaidEscape(strings.Join(n.label(), "\n")) + `"`
+		switch {
+		case n.Compensation:
+			fmt.Fprintf(&b, "    %s(%s)\n", n.ID, label)
+		case n.Type == StepTypeApproval:
+			fmt.Fprintf(&b, "    %s{{%s}}\n", n.ID, label)
+		case n.Type == StepTypeWorkflow:
+			fmt.Fprintf(&b, "    %s[[%s]]\n", n.ID, label)
+		case n.Type == StepTypeSignal:
+			fmt.Fprintf(&b, "    %s>%s]\n", n.ID, label)
+		default:
+			fmt.Fprintf(&b, "    %s[%s]\n", n.ID, label)
+		}
+	}
+	for _, e := range g.Edges {
+		arrow := "-->"
+		if e.Compensation {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "    %s %s %s\n", e.From, arrow, e.To)
+	}
+
+	classes := map[string][]string{}
+	for _, n := range g.Nodes {
+		if style, ok := statusStyles[n.Status]; ok {
+			classes[style[0]] = append(classes[style[0]], n.ID)
+		}
+	}
+	names := make([]string, 0, len(classes))
+	for name := range classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, style := range statusStyles {
+			if style[0] == name {
+				fmt.Fprintf(&b, "    classDef %s fill:%s,stroke:%s\n", name, style[1], style[2])
+				break
+			}
+		}
+		fmt.Fprintf(&b, "    class %s %s\n", strings.Join(classes[name], ","), name)
+	}
+	return b.String()
+}
+
+// mermaidEscape escapes text for a quoted Mermaid label
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "\n", "<br/>", "<", "#lt;", ">", "#gt;").Replace(s)
+}
+
+// DOT renders the graph in Graphviz DOT format
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(g.Workflow))
+	b.WriteString("    rankdir=TB;\n")
+	b.WriteString("    node [shape=box, style=\"rounded,filled\", fillcolor=\"#ffffff\", fontname=\"Helvetica\"];\n")
+
+	for _, n := range g.Nodes {
+		attrs := []string{"label=" + dotQuote(strings.Join(n.label(), "\n"))}
+		switch {
+		case n.Compensation:
+			attrs = append(attrs, `style="rounded,filled,dashed"`)
+		case n.Type == StepTypeApproval:
+			attrs = append(attrs, "shape=hexagon")
+		case n.Type == StepTypeWorkflow:
+			attrs = append(attrs, `peripheries=2`)
+		case n.Type == StepTypeSignal:
+			attrs = append(attrs, "shape=cds")
+		}
+		if style, ok := statusStyles[n.Status]; ok {
+			attrs = append(attrs, "fillcolor="+dotQuote(style[1]), "color="+dotQuote(style[2]))
+		}
+		fmt.Fprintf(&b, "    %s [%s];\n", n.ID, strings.Join(attrs, ", "))
+	}
+	for _, e := range g.Edges {
+		if e.Compensation {
+			fmt.Fprintf(&b, "    %s -> %s [style=dashed];\n", e.From, e.To)
+		} else {
+			fmt.Fprintf(&b, "    %s -> %s;\n", e.From, e.To)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotQuote quotes a DOT string
+func dotQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
@@ -0,0 +1,91 @@
+package orchestrator
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"agent-project-manager/internal/state"
+)
+
+func graphWorkflows() map[string]state.JSONMap {
+	return map[string]state.JSONMap{
+		"g": stepDefs(
+			map[string]interface{}{"name": "a", "type": "echo", "compensate": map[string]interface{}{"type": "echo"}},
+			map[string]interface{}{"name": "b", "type": "echo", "if": `eq .input.x "y"`},
+			map[string]interface{}{"name": "c", "type": StepTypeApproval, "dependsOn": []interface{}{"a", "b"}},
+		),
+	}
+}
+
+func TestWorkflowGraph(t *testing.T) {
+	o, _ := newTestOrchestrator(t, graphWorkflows())
+
+	g, err := o.WorkflowGraph("g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Workflow != "g" || g.Version != 1 || g.JobID != "" {
+		t.Errorf("graph of %s@%d, job %q", g.Workflow, g.Version, g.JobID)
+	}
+	var nodes []string
+	for _, n := range g.Nodes {
+		nodes = append(nodes, n.ID+":"+n.Name)
+	}
+	if want := []string{"s0:a", "s1:b", "s2:c", "c0:compensate:a"}; !reflect.DeepEqual(nodes, want) {
+		t.Errorf("nodes %v, want %v", nodes, want)
+	}
+	want := []GraphEdge{{From: "s0", To: "s1"}, {From: "s0", To: "s2"}, {From: "s1", To: "s2"}, {From: "s0", To: "c0", Compensation: true}}
+	if !reflect.DeepEqual(g.Edges, want) {
+		t.Errorf("edges %+v, want %+v", g.Edges, want)
+	}
+
+	mermaid := g.Mermaid()
+	for _, line := range []string{"flowchart TD", `s2{{"c<br/>approval"}}`, "s0 --> s2", "s0 -.-> c0", `s1["b<br/>echo<br/>if eq .input.x #quot;y#quot;"]`} {
+		if !strings.Contains(mermaid, line) {
+			t.Errorf("Mermaid lacks %q:\n%s", line, mermaid)
+		}
+	}
+	dot := g.DOT()
+	for _, line := range []string{`digraph "g" {`, `s2 [label="c\napproval", shape=hexagon];`, "s0 -> c0 [style=dashed];"} {
+		if !strings.Contains(dot, line) {
+			t.Errorf("DOT lacks %q:\n%s", line, dot)
+		}
+	}
+
+	if _, err := o.WorkflowGraph("missing"); !errors.Is(err, ErrWorkflowNotFound) {
+		t.Errorf("graph of a missing workflow: error = %v, want ErrWorkflowNotFound", err)
+	}
+	if _, err := o.WorkflowGraph("g@2"); !errors.Is(err, ErrWorkflowNotFound) {
+		t.Errorf("graph of a missing version: error = %v, want ErrWorkflowNotFound", err)
+	}
+}
+
+func TestJobGraph(t *testing.T) {
+	o, _ := newTestOrchestrator(t, graphWorkflows())
+
+	job := submit(t, o, "g", state.JSONMap{"x": "z"})
+	g, err := o.JobGraph(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.JobID != job.ID || g.Status != JobStatusWaitingApproval {
+		t.Errorf("graph of job %s %s", g.JobID, g.Status)
+	}
+	statuses := map[string]string{}
+	for _, n := range g.Nodes {
+		statuses[n.Name] = n.Status
+	}
+	want := map[string]string{"a": StepStatusSucceeded, "b": StepStatusSkipped, "c": StepStatusWaitingApproval, "compensate:a": ""}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("node statuses %v, want %v", statuses, want)
+	}
+	if mermaid := g.Mermaid(); !strings.Contains(mermaid, "class s2 waiting") {
+		t.Errorf("Mermaid does not style the waiting step:\n%s", mermaid)
+	}
+
+	if _, err := o.JobGraph("missing"); err != ErrJobNotFound {
+		t.Errorf("graph of a missing job: error = %v, want ErrJobNotFound", err)
+	}
+}
@@ -0,0 +1,56 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+
+	"agent-project-manager/internal/jsonschema"
+	"agent-project-manager/internal/state"
+)
+
+// ValidateInput checks a job's input against the inputSchema of a stored workflow definition.
+// It returns the input with schema defaults applied and any constraint violations.
+// The error is set when the definition itself cannot be used.
+func ValidateInput(schema state.JSONMap, input map[string]interface{}) (map[string]interface{}, []jsonschema.ValidationError, error) {
+	def, err := ParseDefinition(schema)
+	if err != nil {
+		return nil, nil, err
+	}
+	return def.ValidateInput(input)
+}
+
+// ValidateInput checks input against the definition's inputSchema.
+// A definition without an inputSchema accepts any input unchanged.
+func (d *Definition) ValidateInput(input map[string]interface{}) (map[string]interface{}, []jsonschema.ValidationError, error) {
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+	if d.InputSchema == nil {
+		return input, nil, nil
+	}
+
+	s, err := jsonschema.Compile(d.InputSchema)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid inputSchema: %w", err)
+	}
+	out, errs := s.Validate(input)
+	result, ok := out.(map[string]interface{})
+	if !ok {
+		result = input
+	}
+	return result, errs, nil
+}
+
+// InvalidInputError reports job input that violates the workflow's inputSchema
+type InvalidInputError struct {
+	Errors []jsonschema.ValidationError
+}
+
+// Error joins the violations into one message
+func (e *InvalidInputError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, v := range e.Errors {
+		msgs[i] = v.Error()
+	}
+	return "invalid input: " + strings.Join(msgs, "; ")
+}
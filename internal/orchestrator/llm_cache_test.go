@@ -0,0 +1,56 @@
+package orchestrator_test
+
+import (
+	"fmt"
+	"testing"
+
+	"agent-project-manager/internal/agents"
+	"agent-project-manager/internal/llm"
+	"agent-project-manager/internal/orchestrator"
+	"agent-project-manager/internal/state"
+)
+
+func TestStepCacheLatestPrompt(t *testing.T) {
+	gateway, requested := newTestGateway(t)
+	o, repo := orchestrator.NewTestOrchestrator(t, map[string]state.JSONMap{
+		"review": {
+			"cache": true,
+			"steps": []interface{}{map[string]interface{}{
+				"name": "review", "type": agents.StepTypeLLM, "model": "gpt-4o-mini",
+				"input": map[string]interface{}{"template": "review", "vars": map[string]interface{}{"patch": "+x"}},
+			}},
+		},
+	}, orchestrator.Options{})
+	o.Register(agents.StepTypeLLM, agents.NewLLMStep(gateway, nil, llm.ToolLoop{}))
+	publish := func(template string) {
+		t.Helper()
+		_, err := repo.PublishPrompt(&state.PromptVersion{
+			Prompt: "review", Template: template,
+			Variables: []state.PromptVariable{{Name: "patch", Required: true}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	publish("Review {{ .patch }}")
+	orchestrator.Submit(t, o, "review", nil)
+	job := orchestrator.Submit(t, o, "review", nil)
+	if step := orchestrator.JobSteps(t, o, job.ID)["review"]; !step.Cached {
+		t.Errorf("second job with the same prompt version was not answered from the cache")
+	}
+
+	// A new latest version must not be answered with output rendered from the old one
+	publish("Review {{ .patch }} carefully")
+	job = orchestrator.Submit(t, o, "review", nil)
+	step := orchestrator.JobSteps(t, o, job.ID)["review"]
+	if job.Status != orchestrator.JobStatusSucceeded || step.Cached {
+		t.Fatalf("job after a new prompt version: %s, cached %v", job.Status, step.Cached)
+	}
+	if prompts := fmt.Sprint(step.Output["prompts"]); prompts != "[review@2]" {
+		t.Errorf("rendered prompts %v, want review@2", step.Output["prompts"])
+	}
+	if n := len(requested()); n != 2 {
+		t.Errorf("%d requests sent, want one per prompt version", n)
+	}
+}
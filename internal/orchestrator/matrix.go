@@ -0,0 +1,399 @@
+package orchestrator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"agent-project-manager/internal/jsonschema"
+	"agent-project-manager/internal/logger"
+	"agent-project-manager/internal/state"
+)
+
+const (
+	// MaxMatrixJobs caps the number of jobs one matrix submission may expand into
+	MaxMatrixJobs = 100
+	// ArtifactTypeMarkdown is the artifact type of matrix reports
+	ArtifactTypeMarkdown = "markdown"
+)
+
+var (
+	// ErrMatrixNotFound is returned for an unknown matrix ID
+	ErrMatrixNotFound = errors.New("matrix not found")
+	// ErrInvalidMatrix is returned for a matrix without axes, with an empty axis or with too many jobs
+	ErrInvalidMatrix = errors.New("invalid matrix")
+)
+
+// MatrixRequest expands one workflow across parameter axes.
+// Every combination of axis values becomes a job whose input is Input with
+// each axis name set to that combination's value, e.g. {"model": "gpt-4o-mini", "temperature": 0.2}.
+// Steps pick the values up through templates, including in their route: model: "{{ .input.model }}".
+type MatrixRequest struct {
+	Workflow string // workflow reference, "name" or "name@version"
+	Input    map[string]interface{}
+	Axes     map[string][]interface{}
+	NoCache  bool
+	Budget   *Budget // applies to every job on its own
+}
+
+// MatrixReport compares the jobs of a matrix
+type MatrixReport struct {
+	Matrix   *state.Matrix
+	Axes     []string // axis names, in the order they vary
+	Scores   []string // names of the steps that reported a score
+	Rows     []MatrixRow
+	Finished bool // every job reached a terminal status
+}
+
+// MatrixRow is one job of a matrix: its parameters and how it did.
+// Tokens and cost add up the "usage" reported in step outputs;
+// scores are the numeric "score" outputs, by step name.
+type MatrixRow struct {
+	JobID            string
+	Params           map[string]interface{}
+	Status           string
+	Duration         time.Duration // until now for jobs that have not finished
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	CostUSD          float64
+	Scores           map[string]float64
+	Error            string
+}
+
+// SubmitMatrix creates a job for every combination of axis values and enqueues them.
+// All jobs are pinned to the same workflow version; the input of every job is validated
+// before any is created, and the matrix is stored and queued with its jobs in one
+// transaction, so a matrix is submitted completely or not at all.
+func (o *Orchestrator) SubmitMatrix(req MatrixRequest) (*state.Matrix, []*state.Job, error) {
+	cells, err := expandAxes(req.Axes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wv, err := ResolveWorkflowVersion(o.repo, req.Workflow)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve workflow %s: %w", req.Workflow, err)
+	}
+	def, err := ParseDefinition(wv.Schema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	inputs := make([]map[string]interface{}, len(cells))
+	var verrs []jsonschema.ValidationError
+	seen := map[string]bool{}
+	for i, params := range cells {
+		input := copyMap(req.Input)
+		for k, v := range params {
+			input[k] = v
+		}
+		validated, errs, err := def.ValidateInput(input)
+		if err != nil {
+			return nil, nil, err
+		}
+		// The same violation usually shows up in every combination; report it once
+		for _, e := range errs {
+			if key := e.Path + "\x00" + e.Message; !seen[key] {
+				seen[key] = true
+				verrs = append(verrs, e)
+			}
+		}
+		inputs[i] = validated
+	}
+	if len(verrs) > 0 {
+		return nil, nil, &InvalidInputError{Errors: verrs}
+	}
+
+	axes := state.JSONMap{}
+	for name, values := range req.Axes {
+		axes[name] = values
+	}
+	matrix := &state.Matrix{
+		Workflow:        wv.Workflow,
+		WorkflowVersion: wv.Version,
+		Input:           copyMap(req.Input),
+		Axes:            axes,
+	}
+	jobs := make([]*state.Job, 0, len(cells))
+	for i, params := range cells {
+		meta := state.JSONMap{"matrixParams": params}
+		if req.NoCache {
+			meta["noCache"] = true
+		}
+		if req.Budget != nil {
+			meta["budget"] = req.Budget.Meta()
+		}
+		job := &state.Job{
+			Workflow:        wv.Workflow,
+			WorkflowVersion: wv.Version,
+			Status:          JobStatusQueued,
+			Input:           state.JSONMap(inputs[i]),
+			Meta:            meta,
+		}
+		jobs = append(jobs, job)
+	}
+	if err := o.repo.CreateMatrixJobs(matrix, jobs, QueueStatePending); err != nil {
+		return nil, nil, err
+	}
+
+	logger.Infof("orchestrator: matrix %s expands %s@%d into %d jobs", matrix.ID, wv.Workflow, wv.Version, len(jobs))
+	return matrix, jobs, nil
+}
+
+// expandAxes returns every combination of axis values.
+// Axes vary in name order, the last one fastest.
+func expandAxes(axes map[string][]interface{}) ([]map[string]interface{}, error) {
+	if len(axes) == 0 {
+		return nil, fmt.Errorf("%w: at least one axis is required", ErrInvalidMatrix)
+	}
+	names := make([]string, 0, len(axes))
+	total := 1
+	for name, values := range axes {
+		if name == "" {
+			return nil, fmt.Errorf("%w: axis names must not be empty", ErrInvalidMatrix)
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("%w: axis %s has no values", ErrInvalidMatrix, name)
+		}
+		names = append(names, name)
+		total *= len(values)
+		if total > MaxMatrixJobs {
+			return nil, fmt.Errorf("%w: more than %d jobs", ErrInvalidMatrix, MaxMatrixJobs)
+		}
+	}
+	sort.Strings(names)
+
+	cells := []map[string]interface{}{{}}
+	for _, name := range names {
+		next := make([]map[string]interface{}, 0, len(cells)*len(axes[name]))
+		for _, cell := range cells {
+			for _, v := range axes[name] {
+				c := copyMap(cell)
+				c[name] = v
+				next = append(next, c)
+			}
+		}
+		cells = next
+	}
+	return cells, nil
+}
+
+// MatrixReport compares the jobs of a matrix
+func (o *Orchestrator) MatrixReport(id string) (*MatrixReport, error) {
+	matrix, err := o.repo.GetMatrix(id)
+	if err != nil {
+		return nil, ErrMatrixNotFound
+	}
+	jobs, err := o.repo.ListMatrixJobs(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list matrix jobs: %w", err)
+	}
+
+	report := &MatrixReport{Matrix: matrix, Axes: []string{}, Scores: []string{}, Rows: []MatrixRow{}, Finished: true}
+	for name := range matrix.Axes {
+		report.Axes = append(report.Axes, name)
+	}
+	sort.Strings(report.Axes)
+
+	scores := map[string]bool{}
+	now := time.Now()
+	for _, job := range jobs {
+		steps, err := o.repo.ListSteps(job.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list steps: %w", err)
+		}
+		row := matrixRow(job, steps, now)
+		for name := range row.Scores {
+			scores[name] = true
+		}
+		if !isTerminalJobStatus(job.Status) {
+			report.Finished = false
+		}
+		report.Rows = append(report.Rows, row)
+	}
+	for name := range scores {
+		report.Scores = append(report.Scores, name)
+	}
+	sort.Strings(report.Scores)
+	return report, nil
+}
+
+// matrixRow sums up one job of a matrix
+func matrixRow(job *state.Job, steps []*state.Step, now time.Time) MatrixRow {
+	row := MatrixRow{
+		JobID:  job.ID,
+		Params: map[string]interface{}{},
+		Status: job.Status,
+		Scores: map[string]float64{},
+		Error:  job.Error,
+	}
+	if params, ok := job.Meta["matrixParams"].(map[string]interface{}); ok {
+		row.Params = params
+	}
+	if job.StartedAt != nil {
+		end := now
+		if job.CompletedAt != nil {
+			end = *job.CompletedAt
+		}
+		row.Duration = end.Sub(*job.StartedAt)
+	}
+
+	for _, s := range steps {
+		// A cached step did not spend anything in this job
+		if usage, ok := s.Output["usage"].(map[string]interface{}); ok && !s.Cached {
+			row.PromptTokens += intValue(usage["promptTokens"])
+			row.CompletionTokens += intValue(usage["completionTokens"])
+			row.TotalTokens += intValue(usage["totalTokens"])
+			if cost, ok := usage["costUsd"].(float64); ok {
+				row.CostUSD += cost
+			}
+		}
+		if score, ok := s.Output["score"].(float64); ok {
+			row.Scores[s.Name] = score
+		}
+	}
+	if row.TotalTokens == 0 {
+		row.TotalTokens = row.PromptTokens + row.CompletionTokens
+	}
+	return row
+}
+
+// intValue reads a JSON number as an int
+func intValue(v interface{}) int {
+	switch n := v.(type) {
+	case float64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
+
+// Markdown renders the report as a markdown table, one row per job,
+// followed by the best job for every score
+func (r *MatrixReport) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Matrix %s\n\n", r.Matrix.ID)
+	status := "in progress"
+	if r.Finished {
+		status = "finished"
+	}
+	fmt.Fprintf(&b, "Workflow `%s@%d`, %d jobs, %s.\n\n", r.Matrix.Workflow, r.Matrix.WorkflowVersion, len(r.Rows), status)
+
+	header := append([]string{}, r.Axes...)
+	header = append(header, "status", "duration", "tokens", "cost (USD)")
+	header = append(header, r.Scores...)
+	b.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	b.WriteString("|" + strings.Repeat(" --- |", len(header)) + "\n")
+
+	for _, row := range r.Rows {
+		cells := make([]string, 0, len(header))
+		for _, axis := range r.Axes {
+			cells = append(cells, markdownCell(fmt.Sprint(row.Params[axis])))
+		}
+		cells = append(cells, row.Status, formatDuration(row.Duration),
+			fmt.Sprint(row.TotalTokens), fmt.Sprintf("%.4f", row.CostUSD))
+		for _, name := range r.Scores {
+			if score, ok := row.Scores[name]; ok {
+				cells = append(cells, fmt.Sprintf("%g", score))
+			} else {
+				cells = append(cells, "-")
+			}
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+
+	if len(r.Scores) > 0 {
+		b.WriteString("\n## Best\n\n")
+		for _, name := range r.Scores {
+			best := -1
+			for i, row := range r.Rows {
+				if score, ok := row.Scores[name]; ok && (best < 0 || score > r.Rows[best].Scores[name]) {
+					best = i
+				}
+			}
+			if best >= 0 {
+				fmt.Fprintf(&b, "- **%s**: %g (%s, job `%s`)\n", name, r.Rows[best].Scores[name],
+					r.paramsLabel(r.Rows[best]), r.Rows[best].JobID)
+			}
+		}
+	}
+	return b.String()
+}
+
+// paramsLabel lists a row's axis values, e.g. "model=gpt-4o-mini, temperature=0.2"
+func (r *MatrixReport) paramsLabel(row MatrixRow) string {
+	parts := make([]string, len(r.Axes))
+	for i, axis := range r.Axes {
+		parts[i] = fmt.Sprintf("%s=%v", axis, row.Params[axis])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// markdownCell escapes text for a markdown table cell
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// completeMatrix writes the report artifact of a matrix once its last job finished.
+// It runs after every matrix job settles; only the call that sees all jobs finished writes the report.
+func (o *Orchestrator) completeMatrix(id string) {
+	o.matrixMu.Lock()
+	defer o.matrixMu.Unlock()
+
+	report, err := o.MatrixReport(id)
+	if err != nil {
+		logger.Errorf("orchestrator: failed to build report of matrix %s: %v", id, err)
+		return
+	}
+	if !report.Finished || report.Matrix.CompletedAt != nil {
+		return
+	}
+
+	artifactID := ""
+	if o.opts.ArtifactDir == "" {
+		logger.Warnf("orchestrator: no artifact directory configured; matrix %s has no report artifact", id)
+	} else if a, err := o.writeMatrixReport(report); err != nil {
+		logger.Errorf("orchestrator: failed to write report of matrix %s: %v", id, err)
+	} else {
+		artifactID = a.ID
+	}
+	if err := o.repo.CompleteMatrix(id, artifactID); err != nil {
+		logger.Errorf("orchestrator: failed to complete matrix %s: %v", id, err)
+		return
+	}
+	logger.Infof("orchestrator: matrix %s finished", id)
+}
+
+// writeMatrixReport stores the markdown report under the artifact directory and records it
+func (o *Orchestrator) writeMatrixReport(report *MatrixReport) (*state.Artifact, error) {
+	content := []byte(report.Markdown())
+	dir := filepath.Join(o.opts.ArtifactDir, "matrix", report.Matrix.ID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "report.md")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(content)
+	artifact := &state.Artifact{
+		Type:   ArtifactTypeMarkdown,
+		Name:   "matrix-" + report.Matrix.ID + ".md",
+		Size:   int64(len(content)),
+		Path:   path,
+		Digest: "sha256:" + hex.EncodeToString(sum[:]),
+	}
+	if err := o.repo.CreateArtifact(artifact); err != nil {
+		return nil, fmt.Errorf("failed to record artifact: %w", err)
+	}
+	return artifact, nil
+}
@@ -0,0 +1,121 @@
+package orchestrator_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"agent-project-manager/internal/agents"
+	"agent-project-manager/internal/config"
+	"agent-project-manager/internal/llm"
+	"agent-project-manager/internal/orchestrator"
+	"agent-project-manager/internal/state"
+)
+
+// newTestGateway serves chat completions that echo the requested model and records the models asked for
+func newTestGateway(t *testing.T) (*llm.Gateway, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var models []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		mu.Lock()
+		models = append(models, body.Model)
+		mu.Unlock()
+		fmt.Fprintf(w, `{"model": %q, "choices": [{"message": {"role": "assistant", "content": "looks good"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}}`, body.Model)
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := config.LLMConfig{
+		Provider:  "openai",
+		Providers: map[string]config.ProviderConfig{"openai": {Type: llm.ProviderOpenAI, BaseURL: srv.URL, APIKey: "test-key", Model: "default"}},
+	}
+	registry, err := llm.NewRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gateway, err := llm.NewGateway(registry, cfg, llm.GatewayOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gateway, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		got := append([]string{}, models...)
+		sort.Strings(got)
+		return got
+	}
+}
+
+func TestMatrixModelAxis(t *testing.T) {
+	gateway, requested := newTestGateway(t)
+	var checked []string
+	o, _ := orchestrator.NewTestOrchestrator(t, map[string]state.JSONMap{
+		"compare": {
+			"inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{
+				"model": map[string]interface{}{"type": "string"},
+			}},
+			"steps": []interface{}{map[string]interface{}{
+				"name": "review", "type": agents.StepTypeLLM,
+				"provider": "openai", "model": "{{ .input.model }}",
+				"input": map[string]interface{}{"prompt": "Review this patch"},
+			}},
+		},
+	}, orchestrator.Options{CheckModel: func(ctx context.Context, provider, model string) error {
+		checked = append(checked, provider+"/"+model)
+		if model == "missing" {
+			return llm.ErrModelNotFound
+		}
+		return nil
+	}})
+	o.Register(agents.StepTypeLLM, agents.NewLLMStep(gateway, nil, llm.ToolLoop{}))
+
+	matrix, _, err := o.SubmitMatrix(orchestrator.MatrixRequest{Workflow: "compare", Axes: map[string][]interface{}{
+		"model": {"qwen2.5-coder:7b", "gpt-4o-mini", "missing"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	orchestrator.Drain(o)
+
+	report, err := o.MatrixReport(matrix.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range report.Rows {
+		model := row.Params["model"]
+		if model == "missing" {
+			if row.Status != orchestrator.JobStatusFailed || !strings.Contains(row.Error, "model is unavailable") {
+				t.Errorf("job with a missing model: %s, %q; want it failed by the model check", row.Status, row.Error)
+			}
+			continue
+		}
+		if row.Status != orchestrator.JobStatusSucceeded || row.TotalTokens != 15 {
+			t.Errorf("job with %v: %s, %d tokens (%s)", model, row.Status, row.TotalTokens, row.Error)
+			continue
+		}
+		if got := orchestrator.JobSteps(t, o, row.JobID)["review"].Output["model"]; got != model {
+			t.Errorf("job with %v was answered by model %v", model, got)
+		}
+	}
+
+	if got, want := strings.Join(requested(), ","), "gpt-4o-mini,qwen2.5-coder:7b"; got != want {
+		t.Errorf("models requested %s, want %s", got, want)
+	}
+	sort.Strings(checked)
+	if got, want := strings.Join(checked, ","), "openai/gpt-4o-mini,openai/missing,openai/qwen2.5-coder:7b"; got != want {
+		t.Errorf("models checked %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,147 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"agent-project-manager/internal/state"
+)
+
+func TestMatrix(t *testing.T) {
+	o, repo := newTestOrchestrator(t, map[string]state.JSONMap{
+		"m": {
+			"inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{
+				"model":       map[string]interface{}{"type": "string"},
+				"temperature": map[string]interface{}{"type": "number"},
+			}},
+			"steps": []interface{}{
+				map[string]interface{}{"name": "gen", "type": "gen", "input": map[string]interface{}{"model": "{{ .input.model }}"}},
+				map[string]interface{}{"name": "review", "type": "score"},
+			},
+		},
+	})
+	o.opts.ArtifactDir = t.TempDir()
+	o.Register("gen", StepExecutorFunc(func(ctx context.Context, sc *StepContext) (*StepResult, error) {
+		if sc.Step.Input["model"] == "broken" {
+			return nil, errors.New("model unavailable")
+		}
+		usage := map[string]interface{}{"promptTokens": 100.0, "completionTokens": 20.0, "costUsd": 0.002}
+		return &StepResult{Output: map[string]interface{}{"usage": usage}}, nil
+	}))
+	o.Register("score", StepExecutorFunc(func(ctx context.Context, sc *StepContext) (*StepResult, error) {
+		return &StepResult{Output: map[string]interface{}{"score": sc.Job.Input["temperature"].(float64) * 10}}, nil
+	}))
+
+	if _, _, err := o.SubmitMatrix(MatrixRequest{Workflow: "m"}); !errors.Is(err, ErrInvalidMatrix) {
+		t.Errorf("a matrix without axes: error = %v, want ErrInvalidMatrix", err)
+	}
+	if _, _, err := o.SubmitMatrix(MatrixRequest{Workflow: "m", Axes: map[string][]interface{}{"temperature": {0.2, "hot"}}}); err == nil {
+		t.Error("a matrix with an invalid value: want an error")
+	}
+	if len(repo.jobs) != 0 {
+		t.Fatalf("rejected matrices created %d jobs", len(repo.jobs))
+	}
+
+	matrix, jobs, err := o.SubmitMatrix(MatrixRequest{Workflow: "m", Axes: map[string][]interface{}{
+		"model":       {"qwen", "gpt", "broken"},
+		"temperature": {0.2, 0.7},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 6 || matrix.WorkflowVersion != 1 {
+		t.Fatalf("%d jobs of version %d, want 6 of version 1", len(jobs), matrix.WorkflowVersion)
+	}
+	drain(o)
+
+	report, err := o.MatrixReport(matrix.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Finished || len(report.Rows) != 6 || strings.Join(report.Axes, ",") != "model,temperature" || strings.Join(report.Scores, ",") != "review" {
+		t.Fatalf("report finished %v, %d rows, axes %v, scores %v", report.Finished, len(report.Rows), report.Axes, report.Scores)
+	}
+	for _, row := range report.Rows {
+		broken := row.Params["model"] == "broken"
+		if broken != (row.Status == JobStatusFailed) {
+			t.Errorf("job with %v is %s", row.Params, row.Status)
+		}
+		if !broken && (row.TotalTokens != 120 || row.CostUSD != 0.002 || row.Scores["review"] != row.Params["temperature"].(float64)*10) {
+			t.Errorf("row %v: %d tokens, $%g, scores %v", row.Params, row.TotalTokens, row.CostUSD, row.Scores)
+		}
+	}
+
+	md := report.Markdown()
+	for _, line := range []string{"| model | temperature | status | duration | tokens | cost (USD) | review |", "- **review**: 7 (model=qwen, temperature=0.7"} {
+		if !strings.Contains(md, line) {
+			t.Errorf("report lacks %q:\n%s", line, md)
+		}
+	}
+	completed := repo.matrices[matrix.ID]
+	if completed.CompletedAt == nil || completed.ReportArtifactID == "" {
+		t.Fatalf("matrix not completed with a report: %+v", completed)
+	}
+	written, err := os.ReadFile(repo.artifacts[completed.ReportArtifactID].Path)
+	if err != nil || string(written) != md {
+		t.Errorf("report artifact = %q, %v", written, err)
+	}
+
+	if _, err := o.MatrixReport("missing"); err != ErrMatrixNotFound {
+		t.Errorf("report of a missing matrix: error = %v, want ErrMatrixNotFound", err)
+	}
+}
+
+// unreachableVersions fails every lookup of a workflow's latest version, like a database that is down
+type unreachableVersions struct{ *fakeRepo }
+
+var errUnreachable = errors.New("connection refused")
+
+func (r unreachableVersions) GetLatestWorkflowVersion(name string) (*state.WorkflowVersion, error) {
+	return nil, errUnreachable
+}
+
+func TestMatrixWorkflowLookup(t *testing.T) {
+	o, repo := newTestOrchestrator(t, map[string]state.JSONMap{
+		"m": {"steps": []interface{}{map[string]interface{}{"name": "a", "type": "echo"}}},
+	})
+	axes := map[string][]interface{}{"x": {1, 2}}
+
+	for _, ref := range []string{"missing", "m@2", "@1", "m@latest"} {
+		if _, _, err := o.SubmitMatrix(MatrixRequest{Workflow: ref, Axes: axes}); !errors.Is(err, ErrWorkflowNotFound) {
+			t.Errorf("matrix of %q: error = %v, want ErrWorkflowNotFound", ref, err)
+		}
+	}
+
+	o.repo = unreachableVersions{repo}
+	_, _, err := o.SubmitMatrix(MatrixRequest{Workflow: "m", Axes: axes})
+	if !errors.Is(err, errUnreachable) || errors.Is(err, ErrWorkflowNotFound) {
+		t.Errorf("matrix with a failing lookup: error = %v, want the lookup error and not ErrWorkflowNotFound", err)
+	}
+	if _, err := o.WorkflowGraph("m"); !errors.Is(err, errUnreachable) {
+		t.Errorf("graph with a failing lookup: error = %v, want the lookup error", err)
+	}
+}
+
+// unstorableMatrices fails to store matrix submissions
+type unstorableMatrices struct{ *fakeRepo }
+
+func (r unstorableMatrices) CreateMatrixJobs(matrix *state.Matrix, jobs []*state.Job, queueState string) error {
+	return errUnreachable
+}
+
+func TestMatrixStoreFailure(t *testing.T) {
+	o, repo := newTestOrchestrator(t, map[string]state.JSONMap{
+		"m": {"steps": []interface{}{map[string]interface{}{"name": "a", "type": "echo"}}},
+	})
+	o.repo = unstorableMatrices{repo}
+
+	if _, _, err := o.SubmitMatrix(MatrixRequest{Workflow: "m", Axes: map[string][]interface{}{"x": {1, 2, 3}}}); !errors.Is(err, errUnreachable) {
+		t.Fatalf("error = %v, want the store's error", err)
+	}
+	if len(repo.matrices) != 0 || len(repo.jobs) != 0 || len(repo.queue) != 0 {
+		t.Errorf("a failed submission left %d matrices, %d jobs and %d queue items", len(repo.matrices), len(repo.jobs), len(repo.queue))
+	}
+}
@@ -0,0 +1,782 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"agent-project-manager/internal/logger"
+	"agent-project-manager/internal/state"
+)
+
+// Options configures the orchestrator runtime
+type Options struct {
+	Workers         int           // number of concurrent job workers (default: 1)
+	PollInterval    time.Duration // how often idle workers poll the queue (default: 1s)
+	SweepInterval   time.Duration // how often waiting steps are checked for timeouts (default: 30s)
+	ArtifactDir     string        // where generated artifacts such as matrix reports are written
+	MaxTriggerDepth int           // longest chain of jobs started by triggers (default: 5)
+
+	// Providers lists the configured LLM provider names; definitions whose steps name
+	// another provider are rejected. nil skips the check.
+	Providers []string
+
+	// CheckModel returns an error when a provider's model is not available, e.g. not
+	// installed on the local Ollama; an empty provider or model means the default.
+	// Jobs whose remaining steps use such a model fail before running any of them.
+	// nil skips the check.
+	CheckModel func(ctx context.Context, provider, model string) error
+}
+
+// Orchestrator leases queued jobs and drives them through their workflow steps
+type Orchestrator struct {
+	repo      state.Repository
+	opts      Options
+	executors map[string]StepExecutor
+
+	// mu serializes decisions on parked steps (API calls, the timeout sweeper, child jobs)
+	mu sync.Mutex
+
+	// matrixMu makes sure a finished matrix gets one report
+	matrixMu sync.Mutex
+
+	// subscribers receive every emitted event
+	subMu       sync.RWMutex
+	subscribers []EventHandler
+
+	// triggerEvents holds events waiting for the trigger engine
+	triggerMu     sync.Mutex
+	triggerEvents []*state.Event
+	triggerReady  chan struct{}
+
+	// running holds the jobs currently being processed
+	runMu   sync.Mutex
+	running map[string]*runningJob
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates an orchestrator with the built-in step types registered
+func New(repo state.Repository, opts Options) *Orchestrator {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.PollInterval == 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.SweepInterval == 0 {
+		opts.SweepInterval = 30 * time.Second
+	}
+	if opts.MaxTriggerDepth <= 0 {
+		opts.MaxTriggerDepth = 5
+	}
+
+	o := &Orchestrator{
+		repo:      repo,
+		opts:      opts,
+		executors: map[string]StepExecutor{},
+		running:   map[string]*runningJob{},
+
+		triggerReady: make(chan struct{}, 1),
+	}
+	o.Register(StepTypeApproval, StepExecutorFunc(o.executeApproval))
+	o.Register(StepTypeSignal, StepExecutorFunc(o.executeSignal))
+	o.Register(StepTypeWorkflow, StepExecutorFunc(o.executeWorkflow))
+	o.Subscribe(o.receiveTriggerEvent)
+	return o
+}
+
+// Register installs the executor for a step type, replacing any previous one
+func (o *Orchestrator) Register(stepType string, exec StepExecutor) {
+	o.executors[stepType] = exec
+}
+
+// Start recovers interrupted work, then launches the worker pool, the timeout sweeper
+// and the trigger engine
+func (o *Orchestrator) Start(ctx context.Context) {
+	ctx, o.cancel = context.WithCancel(ctx)
+
+	if err := o.Recover(); err != nil {
+		logger.Errorf("orchestrator: recovery failed: %v", err)
+	}
+
+	for i := 0; i < o.opts.Workers; i++ {
+		o.wg.Add(1)
+		go o.worker(ctx)
+	}
+
+	o.wg.Add(1)
+	go o.sweeper(ctx)
+
+	o.wg.Add(1)
+	go o.triggerLoop(ctx)
+
+	logger.Infof("orchestrator: started %d workers", o.opts.Workers)
+}
+
+// Stop signals all workers to stop and waits for them to return
+func (o *Orchestrator) Stop() {
+	if o.cancel != nil {
+		o.cancel()
+	}
+	o.wg.Wait()
+}
+
+// Enqueue places a job on the queue for the next free worker
+func (o *Orchestrator) Enqueue(jobID string) error {
+	item := &state.QueueItem{
+		JobID: jobID,
+		State: QueueStatePending,
+		Data:  state.JSONMap{},
+	}
+	if err := o.repo.CreateQueueItem(item); err != nil {
+		return fmt.Errorf("failed to enqueue job %s: %w", jobID, err)
+	}
+	return nil
+}
+
+// worker leases queue items one at a time until the context is cancelled
+func (o *Orchestrator) worker(ctx context.Context) {
+	defer o.wg.Done()
+
+	for ctx.Err() == nil {
+		item, err := o.repo.LeaseQueueItem()
+		if err != nil {
+			logger.Errorf("orchestrator: failed to lease queue item: %v", err)
+			sleep(ctx, o.opts.PollInterval)
+			continue
+		}
+		if item == nil {
+			sleep(ctx, o.opts.PollInterval)
+			continue
+		}
+		o.process(ctx, item)
+	}
+}
+
+// sweeper periodically applies timeouts to parked steps
+func (o *Orchestrator) sweeper(ctx context.Context) {
+	defer o.wg.Done()
+
+	ticker := time.NewTicker(o.opts.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			o.expireApprovals(now)
+			o.expireSignals(now)
+		}
+	}
+}
+
+// process runs the job behind a leased queue item and settles the item
+func (o *Orchestrator) process(ctx context.Context, item *state.QueueItem) {
+	job, err := o.repo.GetJob(item.JobID)
+	if err != nil {
+		logger.Errorf("orchestrator: failed to load job %s: %v", item.JobID, err)
+		o.settleQueueItem(item, QueueStateDead)
+		return
+	}
+	if isTerminalJobStatus(job.Status) {
+		o.settleQueueItem(item, QueueStateDone)
+		return
+	}
+	if job.Status == JobStatusCompensating {
+		o.compensate(ctx, job)
+		if ctx.Err() == nil {
+			o.settleQueueItem(item, QueueStateDone)
+		}
+		return
+	}
+
+	now := time.Now()
+	eventType := EventJobResumed
+	switch {
+	case job.StartedAt == nil:
+		job.StartedAt = &now
+		eventType = EventJobStarted
+	case job.Status == JobStatusRunning:
+		// Still marked running: the previous run was interrupted (crash or shutdown)
+		eventType = EventJobRecovered
+		logger.Infof("orchestrator: recovering job %s from checkpoint %v", job.ID, job.Meta["checkpoint"])
+	}
+	job.Status = JobStatusRunning
+	if err := o.repo.UpdateJob(job); err != nil {
+		logger.Errorf("orchestrator: failed to mark job %s running: %v", job.ID, err)
+		o.settleQueueItem(item, QueueStateDead)
+		return
+	}
+	o.emit(job.ID, "", eventType, "", map[string]interface{}{"checkpoint": job.Meta["checkpoint"]})
+	if eventType == EventJobStarted {
+		o.syncRun(job)
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	o.track(job.ID, cancel)
+	wait, err := o.runJob(jobCtx, job)
+	cancelled := o.untrack(job.ID)
+	cancel()
+
+	if ctx.Err() != nil {
+		// Shutting down: leave the job and lease as they are for Recover
+		logger.Warnf("orchestrator: job %s interrupted by shutdown", job.ID)
+		return
+	}
+	if cancelled {
+		// CancelJob interrupted the job and settles it now that its step returned
+		o.settleQueueItem(item, QueueStateDone)
+		return
+	}
+	if current, gerr := o.repo.GetJob(job.ID); gerr == nil && isFinishingJobStatus(current.Status) {
+		// CancelJob already settled the job or handed it to compensation
+		o.settleQueueItem(item, QueueStateDone)
+		return
+	}
+
+	switch {
+	case err != nil:
+		o.finishJob(job, JobStatusFailed, err)
+		o.settleQueueItem(item, QueueStateDead)
+	case wait != "":
+		job.Status = wait
+		if err := o.repo.UpdateJob(job); err != nil {
+			logger.Errorf("orchestrator: failed to park job %s: %v", job.ID, err)
+		}
+		o.emit(job.ID, "", EventJobWaiting, "", map[string]interface{}{"status": wait})
+		o.settleQueueItem(item, QueueStateDone)
+		switch wait {
+		case JobStatusWaitingChild:
+			// The child may have finished before the step was parked
+			o.checkChildren(job.ID)
+		case JobStatusWaitingSignal:
+			// The signal may have arrived before the step was parked
+			o.deliverSignals(job.ID)
+		}
+	default:
+		o.finishJob(job, JobStatusSucceeded, nil)
+		o.settleQueueItem(item, QueueStateDone)
+	}
+}
+
+// runJob executes the job's remaining steps in order.
+// Steps that already succeeded are skipped, so a job resumes where it left off.
+// It returns a non-empty wait status when a step parked the job.
+func (o *Orchestrator) runJob(ctx context.Context, job *state.Job) (string, error) {
+	def, err := o.loadDefinition(job)
+	if err != nil {
+		return "", err
+	}
+	order, err := def.Order()
+	if err != nil {
+		return "", err
+	}
+
+	existing, err := o.repo.ListSteps(job.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list steps: %w", err)
+	}
+	records := map[string]*state.Step{}
+	for _, s := range existing {
+		records[s.Name] = s
+	}
+	if err := o.checkModels(ctx, order, records, templateData(job, records)); err != nil {
+		return "", err
+	}
+
+	completed := 0
+	for _, sd := range order {
+		rec := records[sd.Name]
+		if rec != nil {
+			if rec.Status == StepStatusSucceeded || rec.Status == StepStatusSkipped {
+				completed++
+				continue
+			}
+			if isWaitingStepStatus(rec.Status) {
+				return rec.Status, nil
+			}
+		}
+
+		cache := def.CacheStep(sd) && !noCache(job)
+		rec, wait, err := o.runStep(ctx, job, sd, rec, templateData(job, records), cache)
+		if rec != nil {
+			records[sd.Name] = rec
+		}
+		if err != nil || wait != "" {
+			return wait, err
+		}
+		completed++
+		o.checkpoint(job, rec, completed)
+	}
+
+	return "", nil
+}
+
+// checkModels checks the LLM providers and models named by the steps that have yet to run,
+// so a job fails up front instead of after its first steps did their work.
+// A step with fallbacks only fails the check when none of its models is available.
+// Templates in a route are resolved against data; a route that depends on the output
+// of a step yet to run is not checked.
+func (o *Orchestrator) checkModels(ctx context.Context, order []StepDef, records map[string]*state.Step, data map[string]interface{}) error {
+	if o.opts.CheckModel == nil {
+		return nil
+	}
+	checked := map[ModelRef]error{}
+	check := func(m ModelRef) error {
+		if err, ok := checked[m]; ok {
+			return err
+		}
+		err := o.opts.CheckModel(ctx, m.Provider, m.Model)
+		checked[m] = err
+		return err
+	}
+
+	for _, sd := range order {
+		if rec := records[sd.Name]; rec != nil && (rec.Status == StepStatusSucceeded || rec.Status == StepStatusSkipped) {
+			continue
+		}
+		route, err := resolveRoute(sd, data)
+		if err != nil {
+			continue
+		}
+		models := route.Models()
+		if len(models) == 0 {
+			continue
+		}
+		var firstErr error
+		for _, m := range models {
+			err := check(m)
+			if err == nil {
+				firstErr = nil
+				break
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		if firstErr != nil {
+			return fmt.Errorf("step %s: model is unavailable: %w", sd.Name, firstErr)
+		}
+	}
+	return nil
+}
+
+// runStep executes a single step, creating its record on first run.
+// The step's input templates are resolved against data before it executes.
+// With cache set, a result memoized under the same cache key is reused instead.
+func (o *Orchestrator) runStep(ctx context.Context, job *state.Job, sd StepDef, rec *state.Step, data map[string]interface{}, cache bool) (*state.Step, string, error) {
+	if rec == nil {
+		rec = &state.Step{
+			JobID:  job.ID,
+			Name:   sd.Name,
+			Status: StepStatusPending,
+			Input:  copyMap(sd.Input),
+			Output: state.JSONMap{},
+		}
+		if err := o.repo.CreateStep(rec); err != nil {
+			return nil, "", fmt.Errorf("failed to create step %s: %w", sd.Name, err)
+		}
+	}
+
+	if sd.If != "" {
+		run, err := evalCondition(sd.If, data)
+		if err != nil {
+			o.failStep(rec, err)
+			return rec, "", fmt.Errorf("step %s failed: %w", sd.Name, err)
+		}
+		if !run {
+			return o.skipStep(job, sd, rec)
+		}
+	}
+
+	input, err := resolveInput(sd.Input, data)
+	if err == nil {
+		sd, err = resolveRoute(sd, data)
+	}
+	if err != nil {
+		o.failStep(rec, err)
+		return rec, "", fmt.Errorf("step %s failed: %w", sd.Name, err)
+	}
+	rec.Input = input
+
+	now := time.Now()
+	rec.Status = StepStatusRunning
+	rec.StartedAt = &now
+	rec.CompletedAt = nil
+	rec.Error = ""
+	rec.Cached = false
+	rec.CacheKey = ""
+	rec.ArtifactIDs = nil
+	if err := o.repo.UpdateStep(rec); err != nil {
+		return rec, "", fmt.Errorf("failed to start step %s: %w", sd.Name, err)
+	}
+	o.emit(job.ID, rec.ID, EventStepStarted, "", map[string]interface{}{"step": sd.Name, "type": sd.Type})
+
+	exec, ok := o.executors[sd.Type]
+	if !ok {
+		err := fmt.Errorf("unsupported step type %q", sd.Type)
+		o.failStep(rec, err)
+		return rec, "", fmt.Errorf("step %s failed: %w", sd.Name, err)
+	}
+
+	if cache {
+		key, err := o.stepCacheKey(exec, sd, input)
+		if err != nil {
+			logger.Warnf("orchestrator: step %s is not cached: %v", rec.ID, err)
+		} else {
+			rec.CacheKey = key
+			entry, err := o.repo.GetStepCacheEntry(key)
+			if err != nil {
+				logger.Warnf("orchestrator: failed to read step cache for step %s: %v", rec.ID, err)
+			}
+			if entry != nil && o.reuseCachedStep(job, rec, entry) {
+				o.emit(job.ID, rec.ID, EventStepCached, "", map[string]interface{}{
+					"step":         sd.Name,
+					"key":          key,
+					"sourceJobId":  entry.SourceJobID,
+					"sourceStepId": entry.SourceStepID,
+				})
+				return o.completeStep(job, sd, rec)
+			}
+		}
+	}
+
+	res, err := exec.Execute(ctx, &StepContext{Job: job, Step: rec, Def: sd, Repo: o.repo,
+		Emit: func(eventType, message string, data map[string]interface{}) {
+			o.emit(job.ID, rec.ID, eventType, message, data)
+		}})
+	if err != nil {
+		if ctx.Err() != nil {
+			return rec, "", ctx.Err()
+		}
+		o.failStep(rec, err)
+		return rec, "", fmt.Errorf("step %s failed: %w", sd.Name, err)
+	}
+	if res == nil {
+		res = &StepResult{}
+	}
+	if res.Output != nil {
+		rec.Output = state.JSONMap(res.Output)
+	}
+	rec.ArtifactIDs = res.Artifacts
+	o.emitArtifacts(job, rec, res.Artifacts)
+
+	if res.Wait != "" {
+		rec.Status = res.Wait
+		if err := o.repo.UpdateStep(rec); err != nil {
+			return rec, "", fmt.Errorf("failed to park step %s: %w", sd.Name, err)
+		}
+		o.emit(job.ID, rec.ID, EventStepWaiting, "", map[string]interface{}{"step": sd.Name, "status": res.Wait})
+		return rec, res.Wait, nil
+	}
+
+	if rec.CacheKey != "" {
+		o.saveStepCache(job, sd, rec, res.Artifacts)
+	}
+	return o.completeStep(job, sd, rec)
+}
+
+// skipStep marks a step whose condition is false as skipped
+func (o *Orchestrator) skipStep(job *state.Job, sd StepDef, rec *state.Step) (*state.Step, string, error) {
+	now := time.Now()
+	rec.Status = StepStatusSkipped
+	rec.CompletedAt = &now
+	rec.Error = ""
+	if err := o.repo.UpdateStep(rec); err != nil {
+		return rec, "", fmt.Errorf("failed to skip step %s: %w", sd.Name, err)
+	}
+	o.emit(job.ID, rec.ID, EventStepSkipped, "", map[string]interface{}{"step": sd.Name, "if": sd.If})
+	return rec, "", nil
+}
+
+// completeStep marks a step as succeeded
+func (o *Orchestrator) completeStep(job *state.Job, sd StepDef, rec *state.Step) (*state.Step, string, error) {
+	completed := time.Now()
+	rec.Status = StepStatusSucceeded
+	rec.CompletedAt = &completed
+	if err := o.repo.UpdateStep(rec); err != nil {
+		return rec, "", fmt.Errorf("failed to complete step %s: %w", sd.Name, err)
+	}
+	o.emit(job.ID, rec.ID, EventStepSucceeded, "", map[string]interface{}{"step": sd.Name, "cached": rec.Cached})
+	return rec, "", nil
+}
+
+// loadDefinition loads and validates the workflow definition for a job.
+// Jobs pinned to a published version always run that version's definition.
+func (o *Orchestrator) loadDefinition(job *state.Job) (*Definition, error) {
+	var schema state.JSONMap
+	if job.WorkflowVersion > 0 {
+		wv, err := o.repo.GetWorkflowVersion(job.Workflow, job.WorkflowVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load workflow %s@%d: %w", job.Workflow, job.WorkflowVersion, err)
+		}
+		schema = wv.Schema
+	} else {
+		wf, err := o.repo.GetWorkflow(job.Workflow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load workflow %s: %w", job.Workflow, err)
+		}
+		schema = wf.Schema
+	}
+
+	def, err := ParseDefinition(schema)
+	if err != nil {
+		return nil, err
+	}
+	if errs := def.Validate(); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid workflow %s: %s", job.Workflow, errs[0])
+	}
+	return def, nil
+}
+
+// workflowName matches names that can be used in workflow references ("name@version")
+var workflowName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// CheckDefinition fully validates a workflow definition before it is saved:
+// the structural checks of Validate plus registered step types and referenced sub-workflows
+func (o *Orchestrator) CheckDefinition(name string, schema state.JSONMap) []string {
+	var errs []string
+	if !workflowName.MatchString(name) {
+		errs = append(errs, fmt.Sprintf("invalid workflow name %q: use letters, digits, '.', '_' and '-'", name))
+	}
+
+	def, err := ParseDefinition(schema)
+	if err != nil {
+		return append(errs, err.Error())
+	}
+	errs = append(errs, def.Validate()...)
+
+	for i, s := range def.Steps {
+		if s.Type != "" {
+			if _, ok := o.executors[s.Type]; !ok {
+				errs = append(errs, fmt.Sprintf("steps[%d]: unknown step type %q", i, s.Type))
+			}
+		}
+		if c := s.Compensate; c != nil && c.Type != "" {
+			if _, ok := o.executors[c.Type]; !ok {
+				errs = append(errs, fmt.Sprintf("steps[%d]: unknown compensate step type %q", i, c.Type))
+			}
+		}
+		if o.opts.Providers != nil {
+			for _, m := range s.Models() {
+				if m.Provider != "" && !strings.Contains(m.Provider, "{{") && !slices.Contains(o.opts.Providers, m.Provider) {
+					errs = append(errs, fmt.Sprintf("steps[%d]: unknown LLM provider %q", i, m.Provider))
+				}
+			}
+		}
+		if s.Type != StepTypeWorkflow || s.Workflow == "" {
+			continue
+		}
+		ref, version, err := ParseWorkflowRef(s.Workflow)
+		if err != nil {
+			continue // reported by Validate
+		}
+		switch {
+		case ref == name:
+			errs = append(errs, fmt.Sprintf("steps[%d]: workflow cannot invoke itself", i))
+		case version > 0:
+			if _, err := ResolveWorkflowVersion(o.repo, s.Workflow); err != nil {
+				errs = append(errs, fmt.Sprintf("steps[%d]: unknown workflow version %q", i, s.Workflow))
+			}
+		default:
+			if _, err := o.repo.GetWorkflow(ref); err != nil {
+				errs = append(errs, fmt.Sprintf("steps[%d]: unknown workflow %q", i, ref))
+			}
+		}
+	}
+	return errs
+}
+
+// WorkflowLookup finds workflows and their published versions
+type WorkflowLookup interface {
+	GetWorkflow(name string) (*state.Workflow, error)
+	GetWorkflowVersion(name string, version int) (*state.WorkflowVersion, error)
+	GetLatestWorkflowVersion(name string) (*state.WorkflowVersion, error)
+}
+
+// ResolveWorkflowVersion finds the published version a workflow reference points to.
+// Deleted workflows resolve to nothing, so no new job runs them; their versions stay
+// readable for the jobs pinned to them. A malformed reference or one that does not
+// resolve fails with an error matching ErrWorkflowNotFound; lookup failures are returned as is.
+func ResolveWorkflowVersion(repo WorkflowLookup, ref string) (*state.WorkflowVersion, error) {
+	name, version, err := ParseWorkflowRef(ref)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWorkflowNotFound, err)
+	}
+	if _, err := repo.GetWorkflow(name); err != nil {
+		if errors.Is(err, state.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrWorkflowNotFound, name)
+		}
+		return nil, err
+	}
+
+	var wv *state.WorkflowVersion
+	if version > 0 {
+		wv, err = repo.GetWorkflowVersion(name, version)
+	} else {
+		wv, err = repo.GetLatestWorkflowVersion(name)
+	}
+	switch {
+	case errors.Is(err, state.ErrNotFound) && version > 0:
+		return nil, fmt.Errorf("%w: %s@%d", ErrWorkflowNotFound, name, version)
+	case errors.Is(err, state.ErrNotFound):
+		return nil, fmt.Errorf("%w: %s has no published version", ErrWorkflowNotFound, name)
+	case err != nil:
+		return nil, err
+	}
+	return wv, nil
+}
+
+// failStep marks a step as failed
+func (o *Orchestrator) failStep(rec *state.Step, cause error) {
+	now := time.Now()
+	rec.Status = StepStatusFailed
+	rec.CompletedAt = &now
+	rec.Error = cause.Error()
+	if err := o.repo.UpdateStep(rec); err != nil {
+		logger.Errorf("orchestrator: failed to mark step %s failed: %v", rec.ID, err)
+	}
+	o.emit(rec.JobID, rec.ID, EventStepFailed, rec.Error, map[string]interface{}{"step": rec.Name})
+}
+
+// finishJob moves a job into a terminal status
+// A failed or cancelled job with completed steps to undo is handed to compensation first;
+// it reaches the terminal status once the compensations have run.
+func (o *Orchestrator) finishJob(job *state.Job, status string, cause error) {
+	if status != JobStatusSucceeded && o.startCompensation(job, status, cause) {
+		return
+	}
+	o.settleJob(job, status, cause)
+}
+
+// settleJob records a job's terminal status and notifies its parent
+func (o *Orchestrator) settleJob(job *state.Job, status string, cause error) {
+	now := time.Now()
+	job.Status = status
+	job.CompletedAt = &now
+	job.Error = ""
+	if cause != nil {
+		job.Error = cause.Error()
+	}
+	if err := o.repo.UpdateJob(job); err != nil {
+		logger.Errorf("orchestrator: failed to finish job %s: %v", job.ID, err)
+	}
+
+	eventType := EventJobFailed
+	switch status {
+	case JobStatusSucceeded:
+		eventType = EventJobSucceeded
+	case JobStatusCancelled:
+		eventType = EventJobCancelled
+	}
+	o.emit(job.ID, "", eventType, job.Error, map[string]interface{}{"status": status})
+	o.syncRun(job)
+
+	if job.ParentJobID != "" {
+		o.notifyParent(job)
+	}
+	if job.MatrixID != "" {
+		o.completeMatrix(job.MatrixID)
+	}
+}
+
+// claimStep moves a parked step out of its waiting status exactly once.
+// update is applied and persisted while decisions on parked steps are serialized.
+func (o *Orchestrator) claimStep(jobID, stepID, waiting string, update func(step *state.Step)) (*state.Step, error) {
+	return o.tryClaimStep(jobID, stepID, waiting, func(step *state.Step) error {
+		update(step)
+		return nil
+	})
+}
+
+// tryClaimStep is claimStep with an update that may decline the claim.
+// When update returns an error the step is left as it is and the error is returned.
+func (o *Orchestrator) tryClaimStep(jobID, stepID, waiting string, update func(step *state.Step) error) (*state.Step, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	step, err := o.repo.GetStep(stepID)
+	if err != nil || step.JobID != jobID {
+		return nil, ErrStepNotFound
+	}
+	if step.Status != waiting {
+		return nil, ErrStepNotWaiting
+	}
+	if err := update(step); err != nil {
+		return nil, err
+	}
+	if err := o.repo.UpdateStep(step); err != nil {
+		return nil, fmt.Errorf("failed to update step %s: %w", stepID, err)
+	}
+	return step, nil
+}
+
+// resumeJob puts a parked job back on the queue
+func (o *Orchestrator) resumeJob(job *state.Job) error {
+	job.Status = JobStatusQueued
+	if err := o.repo.UpdateJob(job); err != nil {
+		return fmt.Errorf("failed to requeue job %s: %w", job.ID, err)
+	}
+	return o.Enqueue(job.ID)
+}
+
+// runningJob is a job a worker is running the steps of
+type runningJob struct {
+	cancel    context.CancelFunc
+	done      chan struct{} // closed when the worker stopped running the steps
+	cancelled bool          // CancelJob interrupted the job and finishes it
+}
+
+// track registers the cancel function of a job being processed
+func (o *Orchestrator) track(jobID string, cancel context.CancelFunc) {
+	o.runMu.Lock()
+	defer o.runMu.Unlock()
+	o.running[jobID] = &runningJob{cancel: cancel, done: make(chan struct{})}
+}
+
+// untrack removes a job registered with track and reports whether CancelJob interrupted it
+func (o *Orchestrator) untrack(jobID string) bool {
+	o.runMu.Lock()
+	defer o.runMu.Unlock()
+	run := o.running[jobID]
+	delete(o.running, jobID)
+	close(run.done)
+	return run.cancelled
+}
+
+// settleQueueItem releases a leased queue item
+func (o *Orchestrator) settleQueueItem(item *state.QueueItem, queueState string) {
+	now := time.Now()
+	item.State = queueState
+	item.CompletedAt = &now
+	if err := o.repo.UpdateQueueItem(item); err != nil {
+		logger.Errorf("orchestrator: failed to settle queue item %s: %v", item.ID, err)
+	}
+}
+
+// copyMap returns a shallow copy of m as a JSONMap
+func copyMap(m map[string]interface{}) state.JSONMap {
+	out := state.JSONMap{}
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// sleep waits for d or until ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
@@ -0,0 +1,618 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"agent-project-manager/internal/state"
+)
+
+// fakeRepo is an in-memory state.Repository holding what the orchestrator reads and writes.
+// Methods the orchestrator does not use are left to the embedded nil interface.
+type fakeRepo struct {
+	state.Repository
+
+	mu        sync.Mutex
+	seq       int
+	jobs      map[string]*state.Job
+	steps     map[string]*state.Step
+	stepOrder []string
+	workflows map[string]*state.Workflow
+	versions  map[string][]*state.WorkflowVersion
+	queue     []*state.QueueItem
+	events    []*state.Event
+	artifacts map[string]*state.Artifact
+	cache     map[string]*state.StepCacheEntry
+	signals   []*state.Signal
+	matrices  map[string]*state.Matrix
+	firings   []*state.TriggerFiring
+	prompts   map[string][]*state.PromptVersion
+	runs      []*state.Run
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{
+		jobs:      map[string]*state.Job{},
+		steps:     map[string]*state.Step{},
+		workflows: map[string]*state.Workflow{},
+		versions:  map[string][]*state.WorkflowVersion{},
+		artifacts: map[string]*state.Artifact{},
+		cache:     map[string]*state.StepCacheEntry{},
+		matrices:  map[string]*state.Matrix{},
+		prompts:   map[string][]*state.PromptVersion{},
+	}
+}
+
+func clone[T any](v *T) *T {
+	c := *v
+	return &c
+}
+
+// now returns increasing timestamps so records sort in creation order
+func (f *fakeRepo) now() time.Time {
+	f.seq++
+	return time.Unix(1700000000+int64(f.seq), 0)
+}
+
+func (f *fakeRepo) CreateJob(job *state.Job) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if job.ID == "" {
+		job.ID = state.NewUUID()
+	}
+	job.CreatedAt = f.now()
+	f.jobs[job.ID] = clone(job)
+	return nil
+}
+
+func (f *fakeRepo) GetJob(id string) (*state.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	job, ok := f.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("job not found: %s", id)
+	}
+	return clone(job), nil
+}
+
+func (f *fakeRepo) UpdateJob(job *state.Job) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.jobs[job.ID] = clone(job)
+	return nil
+}
+
+func (f *fakeRepo) UpdateJobMeta(id string, meta state.JSONMap) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.jobs[id].Meta = meta
+	return nil
+}
+
+func (f *fakeRepo) ListChildJobs(parentID string) ([]*state.Job, error) {
+	return f.listJobs(func(j *state.Job) bool { return j.ParentJobID == parentID }), nil
+}
+
+func (f *fakeRepo) ListMatrixJobs(matrixID string) ([]*state.Job, error) {
+	return f.listJobs(func(j *state.Job) bool { return j.MatrixID == matrixID }), nil
+}
+
+// listJobs returns the jobs that match, oldest first
+func (f *fakeRepo) listJobs(match func(*state.Job) bool) []*state.Job {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []*state.Job
+	for _, j := range f.jobs {
+		if match(j) {
+			out = append(out, clone(j))
+		}
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].CreatedAt.Before(out[b].CreatedAt) })
+	return out
+}
+
+func (f *fakeRepo) ListStrandedJobIDs() ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []string
+	for id, j := range f.jobs {
+		if j.Status != JobStatusQueued && j.Status != JobStatusRunning && j.Status != JobStatusCompensating {
+			continue
+		}
+		queued := false
+		for _, item := range f.queue {
+			if item.JobID == id && (item.State == "pending" || item.State == "leased") {
+				queued = true
+			}
+		}
+		if !queued {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeRepo) CreateStep(step *state.Step) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if step.ID == "" {
+		step.ID = state.NewUUID()
+	}
+	step.CreatedAt = f.now()
+	f.steps[step.ID] = clone(step)
+	f.stepOrder = append(f.stepOrder, step.ID)
+	return nil
+}
+
+func (f *fakeRepo) GetStep(id string) (*state.Step, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	step, ok := f.steps[id]
+	if !ok {
+		return nil, fmt.Errorf("step not found: %s", id)
+	}
+	return clone(step), nil
+}
+
+func (f *fakeRepo) UpdateStep(step *state.Step) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.steps[step.ID] = clone(step)
+	return nil
+}
+
+func (f *fakeRepo) ListSteps(jobID string) ([]*state.Step, error) {
+	return f.listSteps(func(s *state.Step) bool { return s.JobID == jobID }), nil
+}
+
+func (f *fakeRepo) ListStepsByStatus(status string) ([]*state.Step, error) {
+	return f.listSteps(func(s *state.Step) bool { return s.Status == status }), nil
+}
+
+// listSteps returns the steps that match in creation order
+func (f *fakeRepo) listSteps(match func(*state.Step) bool) []*state.Step {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []*state.Step
+	for _, id := range f.stepOrder {
+		if s := f.steps[id]; match(s) {
+			out = append(out, clone(s))
+		}
+	}
+	return out
+}
+
+// CreateWorkflow stores a workflow and publishes its definition as the next version
+func (f *fakeRepo) CreateWorkflow(workflow *state.Workflow) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.workflows[workflow.Name] = clone(workflow)
+	f.versions[workflow.Name] = append(f.versions[workflow.Name], &state.WorkflowVersion{
+		Workflow: workflow.Name,
+		Version:  len(f.versions[workflow.Name]) + 1,
+		Schema:   workflow.Schema,
+	})
+	return nil
+}
+
+func (f *fakeRepo) GetWorkflow(name string) (*state.Workflow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	workflow, ok := f.workflows[name]
+	if !ok {
+		return nil, fmt.Errorf("workflow %w: %s", state.ErrNotFound, name)
+	}
+	return clone(workflow), nil
+}
+
+func (f *fakeRepo) ListWorkflows() ([]*state.Workflow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []*state.Workflow
+	for _, w := range f.workflows {
+		out = append(out, clone(w))
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Name < out[b].Name })
+	return out, nil
+}
+
+func (f *fakeRepo) GetWorkflowVersion(name string, version int) (*state.WorkflowVersion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	versions := f.versions[name]
+	if version <= 0 || version > len(versions) {
+		return nil, fmt.Errorf("workflow version %w: %s@%d", state.ErrNotFound, name, version)
+	}
+	return clone(versions[version-1]), nil
+}
+
+func (f *fakeRepo) GetLatestWorkflowVersion(name string) (*state.WorkflowVersion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	versions := f.versions[name]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("published workflow version %w: %s", state.ErrNotFound, name)
+	}
+	return clone(versions[len(versions)-1]), nil
+}
+
+func (f *fakeRepo) CreateQueueItem(item *state.QueueItem) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if item.ID == "" {
+		item.ID = state.NewUUID()
+	}
+	item.CreatedAt = f.now()
+	f.queue = append(f.queue, clone(item))
+	return nil
+}
+
+func (f *fakeRepo) UpdateQueueItem(item *state.QueueItem) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, q := range f.queue {
+		if q.ID == item.ID {
+			f.queue[i] = clone(item)
+		}
+	}
+	return nil
+}
+
+func (f *fakeRepo) LeaseQueueItem() (*state.QueueItem, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, item := range f.queue {
+		if item.State == "pending" {
+			item.State = "leased"
+			return clone(item), nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeRepo) ReleaseLeasedQueueItems() (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	released := 0
+	for _, item := range f.queue {
+		if item.State == "leased" {
+			item.State = "pending"
+			released++
+		}
+	}
+	return released, nil
+}
+
+func (f *fakeRepo) CreateEvent(event *state.Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, clone(event))
+	return nil
+}
+
+func (f *fakeRepo) CreateArtifact(artifact *state.Artifact) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if artifact.ID == "" {
+		artifact.ID = state.NewUUID()
+	}
+	f.artifacts[artifact.ID] = clone(artifact)
+	return nil
+}
+
+func (f *fakeRepo) GetArtifact(id string) (*state.Artifact, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	artifact, ok := f.artifacts[id]
+	if !ok {
+		return nil, fmt.Errorf("artifact not found: %s", id)
+	}
+	return clone(artifact), nil
+}
+
+func (f *fakeRepo) GetStepCacheEntry(key string) (*state.StepCacheEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if entry, ok := f.cache[key]; ok {
+		return clone(entry), nil
+	}
+	return nil, nil
+}
+
+func (f *fakeRepo) SaveStepCacheEntry(entry *state.StepCacheEntry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cache[entry.Key] = clone(entry)
+	return nil
+}
+
+func (f *fakeRepo) RecordStepCacheHit(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cache[key].Hits++
+	return nil
+}
+
+func (f *fakeRepo) CreateRun(run *state.Run) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if run.ID == "" {
+		run.ID = state.NewUUID()
+	}
+	run.CreatedAt = f.now()
+	f.runs = append(f.runs, clone(run))
+	return nil
+}
+
+func (f *fakeRepo) GetRun(id string) (*state.Run, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, run := range f.runs {
+		if run.ID == id {
+			return clone(run), nil
+		}
+	}
+	return nil, fmt.Errorf("run not found: %s", id)
+}
+
+// ListRuns returns the newest runs of a job first, without paging
+func (f *fakeRepo) ListRuns(jobID string, limit int, cursor string) ([]*state.Run, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var runs []*state.Run
+	for i := len(f.runs) - 1; i >= 0; i-- {
+		if f.runs[i].JobID == jobID && (limit <= 0 || len(runs) < limit) {
+			runs = append(runs, clone(f.runs[i]))
+		}
+	}
+	return runs, "", nil
+}
+
+func (f *fakeRepo) UpdateRun(run *state.Run) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, r := range f.runs {
+		if r.ID == run.ID {
+			f.runs[i] = clone(run)
+			return nil
+		}
+	}
+	return fmt.Errorf("run not found: %s", run.ID)
+}
+
+func (f *fakeRepo) PublishPrompt(pv *state.PromptVersion) (*state.PromptVersion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c := clone(pv)
+	c.Version = len(f.prompts[pv.Prompt]) + 1
+	f.prompts[pv.Prompt] = append(f.prompts[pv.Prompt], c)
+	return clone(c), nil
+}
+
+func (f *fakeRepo) GetPromptVersion(name string, version int) (*state.PromptVersion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	versions := f.prompts[name]
+	if version <= 0 || version > len(versions) {
+		return nil, fmt.Errorf("prompt version not found: %s@%d", name, version)
+	}
+	return clone(versions[version-1]), nil
+}
+
+func (f *fakeRepo) GetLatestPromptVersion(name string) (*state.PromptVersion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	versions := f.prompts[name]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("prompt not found: %s", name)
+	}
+	return clone(versions[len(versions)-1]), nil
+}
+
+func (f *fakeRepo) CreateSignal(signal *state.Signal) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	signal.ID = state.NewUUID()
+	signal.CreatedAt = f.now()
+	f.signals = append(f.signals, clone(signal))
+	return nil
+}
+
+func (f *fakeRepo) ConsumeSignal(jobID, name, stepID string) (*state.Signal, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, s := range f.signals {
+		if s.JobID == jobID && s.Name == name && s.ConsumedAt == nil {
+			now := f.now()
+			s.ConsumedAt = &now
+			s.StepID = stepID
+			return clone(s), nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeRepo) CreateMatrix(matrix *state.Matrix) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	matrix.ID = state.NewUUID()
+	matrix.CreatedAt = f.now()
+	f.matrices[matrix.ID] = clone(matrix)
+	return nil
+}
+
+func (f *fakeRepo) CreateMatrixJobs(matrix *state.Matrix, jobs []*state.Job, queueState string) error {
+	if err := f.CreateMatrix(matrix); err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		job.MatrixID = matrix.ID
+		if err := f.CreateJob(job); err != nil {
+			return err
+		}
+		if err := f.CreateQueueItem(&state.QueueItem{JobID: job.ID, State: queueState, Data: state.JSONMap{}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeRepo) GetMatrix(id string) (*state.Matrix, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	matrix, ok := f.matrices[id]
+	if !ok {
+		return nil, fmt.Errorf("matrix not found: %s", id)
+	}
+	return clone(matrix), nil
+}
+
+func (f *fakeRepo) CompleteMatrix(id string, reportArtifactID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	f.matrices[id].ReportArtifactID = reportArtifactID
+	f.matrices[id].CompletedAt = &now
+	return nil
+}
+
+func (f *fakeRepo) CreateTriggerFiring(firing *state.TriggerFiring) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.firings = append(f.firings, clone(firing))
+	return nil
+}
+
+// eventTypes returns the types of a job's events in order
+func (f *fakeRepo) eventTypes(jobID string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []string
+	for _, e := range f.events {
+		if e.JobID == jobID {
+			out = append(out, e.Type)
+		}
+	}
+	return out
+}
+
+// stepDefs builds a workflow definition from its steps
+func stepDefs(steps ...map[string]interface{}) state.JSONMap {
+	list := make([]interface{}, len(steps))
+	for i, s := range steps {
+		list[i] = s
+	}
+	return state.JSONMap{"steps": list}
+}
+
+// echo succeeds with its input as output
+func echo(ctx context.Context, sc *StepContext) (*StepResult, error) {
+	return &StepResult{Output: map[string]interface{}(sc.Step.Input)}, nil
+}
+
+// newTestOrchestrator returns an orchestrator on an empty fake repository, with the
+// echo step type registered and the workflows created
+func newTestOrchestrator(t *testing.T, workflows map[string]state.JSONMap) (*Orchestrator, *fakeRepo) {
+	t.Helper()
+	repo := newFakeRepo()
+	names := make([]string, 0, len(workflows))
+	for name := range workflows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := repo.CreateWorkflow(&state.Workflow{Name: name, Schema: workflows[name]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	o := New(repo, Options{})
+	o.Register("echo", StepExecutorFunc(echo))
+	return o, repo
+}
+
+// submit creates a job of the workflow's first version and works the queue until it is empty
+func submit(t *testing.T, o *Orchestrator, workflow string, input state.JSONMap) *state.Job {
+	t.Helper()
+	job := &state.Job{Workflow: workflow, WorkflowVersion: 1, Status: JobStatusQueued, Input: input}
+	if err := o.repo.CreateJob(job); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Enqueue(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	drain(o)
+	return reload(t, o, job.ID)
+}
+
+// drain processes queued jobs and trigger events, as the workers and the trigger loop
+// would, until there are none left
+func drain(o *Orchestrator) {
+	for i := 0; ; i++ {
+		if i == 1000 {
+			panic("drain: the queue never empties")
+		}
+		item, _ := o.repo.LeaseQueueItem()
+		if item != nil {
+			o.process(context.Background(), item)
+			continue
+		}
+		o.triggerMu.Lock()
+		events := o.triggerEvents
+		o.triggerEvents = nil
+		o.triggerMu.Unlock()
+		if len(events) == 0 {
+			return
+		}
+		for _, e := range events {
+			o.handleTriggerEvent(e)
+		}
+	}
+}
+
+func reload(t *testing.T, o *Orchestrator, jobID string) *state.Job {
+	t.Helper()
+	job, err := o.repo.GetJob(jobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+// jobSteps returns a job's step records by name
+func jobSteps(t *testing.T, o *Orchestrator, jobID string) map[string]*state.Step {
+	t.Helper()
+	steps, err := o.repo.ListSteps(jobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[string]*state.Step{}
+	for _, s := range steps {
+		out[s.Name] = s
+	}
+	return out
+}
+
+func TestRunJob(t *testing.T) {
+	o, _ := newTestOrchestrator(t, map[string]state.JSONMap{
+		"wf": stepDefs(
+			map[string]interface{}{"name": "a", "type": "echo", "input": map[string]interface{}{"repo": "{{ .input.repo }}"}},
+			map[string]interface{}{"name": "b", "type": "echo", "input": map[string]interface{}{"from": "{{ .steps.a.output.repo }}"}},
+		),
+	})
+
+	job := submit(t, o, "wf", state.JSONMap{"repo": "r1"})
+	if job.Status != JobStatusSucceeded {
+		t.Fatalf("job %s: %s", job.Status, job.Error)
+	}
+	steps := jobSteps(t, o, job.ID)
+	if got := steps["b"].Output["from"]; got != "r1" {
+		t.Errorf("step b got %v from step a, want r1", got)
+	}
+	if cp, _ := job.Meta["checkpoint"].(map[string]interface{}); cp["step"] != "b" {
+		t.Errorf("checkpoint = %v, want step b", job.Meta["checkpoint"])
+	}
+}
@@ -0,0 +1,203 @@
+package orchestrator
+
+import (
+	"strconv"
+
+	"agent-project-manager/internal/jsonschema"
+)
+
+// Planned step outcomes
+const (
+	PlanRun     = "run"     // the step will run
+	PlanSkip    = "skip"    // its condition is false
+	PlanUnknown = "unknown" // its condition depends on outputs of earlier steps
+)
+
+// LLMCallEstimator is implemented by executors that can tell how many LLM calls a step makes.
+// Steps of other types count one call when they name an agent or a model.
+type LLMCallEstimator interface {
+	EstimateLLMCalls(sd StepDef, input map[string]interface{}) int
+}
+
+// Plan describes what a job would do without running or persisting anything
+type Plan struct {
+	Workflow          string
+	Version           int
+	Valid             bool
+	Errors            []string                     // problems with the definition
+	InputErrors       []jsonschema.ValidationError // violations of the inputSchema
+	Input             map[string]interface{}       // input with schema defaults applied
+	Steps             []StepPlan                   // in execution order
+	EstimatedLLMCalls int                          // upper bound: steps with an unknown outcome are counted
+}
+
+// StepPlan is one step of a plan.
+// Input holds the resolved input; values that depend on earlier step outputs keep
+// their template and are listed in Unresolved as JSON pointers.
+type StepPlan struct {
+	Name              string
+	Type              string
+	DependsOn         []string
+	If                string
+	Outcome           string
+	Input             map[string]interface{}
+	Unresolved        []string
+	Agent             string
+	Tool              string
+	Provider          string
+	Model             string
+	Workflow          string // sub-workflow reference
+	Child             *Plan  // plan of the sub-workflow, when it can be resolved
+	Cache             bool
+	Compensate        string // compensation step type
+	EstimatedLLMCalls int
+}
+
+// Plan resolves a workflow reference ("name" or "name@version") and plans a job with the given input
+func (o *Orchestrator) Plan(ref string, input map[string]interface{}) (*Plan, error) {
+	return o.plan(ref, input, 0)
+}
+
+// plan builds the plan of one workflow; depth counts enclosing sub-workflows
+func (o *Orchestrator) plan(ref string, input map[string]interface{}, depth int) (*Plan, error) {
+	wv, err := ResolveWorkflowVersion(o.repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	p := &Plan{
+		Workflow: wv.Workflow,
+		Version:  wv.Version,
+		Input:    input,
+		Steps:    []StepPlan{},
+	}
+
+	def, err := ParseDefinition(wv.Schema)
+	if err != nil {
+		p.Errors = append(p.Errors, err.Error())
+		return p, nil
+	}
+	if errs := def.Validate(); len(errs) > 0 {
+		p.Errors = append(p.Errors, errs...)
+		return p, nil
+	}
+	p.Input, p.InputErrors, err = def.ValidateInput(input)
+	if err != nil {
+		p.Errors = append(p.Errors, err.Error())
+		p.Input = input
+	}
+	p.Valid = len(p.Errors) == 0 && len(p.InputErrors) == 0
+
+	order, err := def.Order()
+	if err != nil {
+		p.Errors = append(p.Errors, err.Error())
+		p.Valid = false
+		return p, nil
+	}
+
+	// Only the job input is known before anything runs
+	data := map[string]interface{}{
+		"job":   map[string]interface{}{"workflow": wv.Workflow},
+		"input": p.Input,
+		"steps": map[string]interface{}{},
+	}
+	for _, sd := range order {
+		sp := o.planStep(def, sd, data, depth)
+		if sp.Outcome != PlanSkip {
+			p.EstimatedLLMCalls += sp.EstimatedLLMCalls
+		}
+		p.Steps = append(p.Steps, sp)
+	}
+	return p, nil
+}
+
+// planStep resolves what can be known about a single step up front
+func (o *Orchestrator) planStep(def *Definition, sd StepDef, data map[string]interface{}, depth int) StepPlan {
+	if route, err := resolveRoute(sd, data); err == nil {
+		sd = route
+	}
+	sp := StepPlan{
+		Name:       sd.Name,
+		Type:       sd.Type,
+		If:         sd.If,
+		Outcome:    PlanRun,
+		Unresolved: []string{},
+		Agent:      sd.Agent,
+		Tool:       sd.Tool,
+		Provider:   sd.Provider,
+		Model:      sd.Model,
+		Workflow:   sd.Workflow,
+		Cache:      def.CacheStep(sd),
+	}
+	for i, s := range def.Steps {
+		if s.Name == sd.Name {
+			sp.DependsOn = def.Dependencies(i)
+		}
+	}
+	if sd.Compensate != nil {
+		sp.Compensate = sd.Compensate.Type
+	}
+
+	if sd.If != "" {
+		run, err := evalCondition(sd.If, data)
+		switch {
+		case err != nil:
+			sp.Outcome = PlanUnknown
+		case !run:
+			sp.Outcome = PlanSkip
+		}
+	}
+
+	sp.Input = map[string]interface{}{}
+	if sd.Input != nil {
+		sp.Input = resolvePartial(sd.Input, data, "", &sp.Unresolved).(map[string]interface{})
+	}
+
+	switch {
+	case sd.Type == StepTypeWorkflow:
+		if len(sp.Unresolved) == 0 && depth < MaxWorkflowDepth {
+			if child, err := o.plan(sd.Workflow, sp.Input, depth+1); err == nil {
+				sp.Child = child
+				sp.EstimatedLLMCalls = child.EstimatedLLMCalls
+			}
+		}
+	default:
+		if est, ok := o.executors[sd.Type].(LLMCallEstimator); ok {
+			sp.EstimatedLLMCalls = est.EstimateLLMCalls(sd, sp.Input)
+		} else if sd.Agent != "" || sd.Provider != "" || sd.Model != "" {
+			sp.EstimatedLLMCalls = 1
+		}
+	}
+	return sp
+}
+
+// resolvePartial evaluates the templates in v that only depend on known data.
+// Strings that cannot be resolved yet are kept as written and their paths recorded.
+func resolvePartial(v interface{}, data map[string]interface{}, path string, unresolved *[]string) interface{} {
+	switch val := v.(type) {
+	case string:
+		resolved, err := resolveString(val, data)
+		if err != nil {
+			p := path
+			if p == "" {
+				p = "/"
+			}
+			*unresolved = append(*unresolved, p)
+			return val
+		}
+		return resolved
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for _, k := range sortedKeys(val) {
+			out[k] = resolvePartial(val[k], data, path+"/"+escapePointer(k), unresolved)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = resolvePartial(item, data, path+"/"+strconv.Itoa(i), unresolved)
+		}
+		return out
+	default:
+		return v
+	}
+}
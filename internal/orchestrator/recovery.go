@@ -0,0 +1,54 @@
+package orchestrator
+
+import (
+	"fmt"
+	"time"
+
+	"agent-project-manager/internal/logger"
+	"agent-project-manager/internal/state"
+)
+
+// Recover re-queues work left behind by a crash or an unclean shutdown.
+// Leased queue items go back to pending, and queued, running or compensating jobs without
+// a queue item are enqueued again. Resumed jobs skip the steps they already
+// completed, so only the step that was in flight runs again.
+// It assumes a single agentd works the queue, and must run before the workers start.
+func (o *Orchestrator) Recover() error {
+	released, err := o.repo.ReleaseLeasedQueueItems()
+	if err != nil {
+		return fmt.Errorf("failed to release queue leases: %w", err)
+	}
+
+	stranded, err := o.repo.ListStrandedJobIDs()
+	if err != nil {
+		return fmt.Errorf("failed to list stranded jobs: %w", err)
+	}
+	for _, id := range stranded {
+		if err := o.Enqueue(id); err != nil {
+			return err
+		}
+	}
+
+	if released > 0 || len(stranded) > 0 {
+		logger.Infof("orchestrator: released %d queue leases, re-queued %d stranded jobs", released, len(stranded))
+	}
+	return nil
+}
+
+// checkpoint records the last completed step in the job's meta.
+// Step records hold the outputs; the checkpoint marks how far the job got.
+// Only meta is written so a concurrent cancel is never overwritten.
+func (o *Orchestrator) checkpoint(job *state.Job, rec *state.Step, completed int) {
+	if job.Meta == nil {
+		job.Meta = state.JSONMap{}
+	}
+	job.Meta["checkpoint"] = map[string]interface{}{
+		"step":      rec.Name,
+		"stepId":    rec.ID,
+		"completed": completed,
+		"at":        time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := o.repo.UpdateJobMeta(job.ID, job.Meta); err != nil {
+		logger.Warnf("orchestrator: failed to checkpoint job %s after step %s: %v", job.ID, rec.Name, err)
+	}
+}
@@ -0,0 +1,73 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"agent-project-manager/internal/state"
+)
+
+func TestRecover(t *testing.T) {
+	o, repo := newTestOrchestrator(t, map[string]state.JSONMap{
+		"wf": stepDefs(
+			map[string]interface{}{"name": "a", "type": "count"},
+			map[string]interface{}{"name": "b", "type": "count"},
+			map[string]interface{}{"name": "c", "type": "count"},
+		),
+	})
+	runs := map[string]int{}
+	o.Register("count", StepExecutorFunc(func(ctx context.Context, sc *StepContext) (*StepResult, error) {
+		runs[sc.Step.Name]++
+		return &StepResult{}, nil
+	}))
+
+	// Two jobs a crash interrupted in step b: one still holds its queue lease,
+	// the other lost its queue item
+	crashed := func() *state.Job {
+		now := time.Now()
+		job := &state.Job{Workflow: "wf", WorkflowVersion: 1, Status: JobStatusRunning, StartedAt: &now}
+		repo.CreateJob(job)
+		repo.CreateStep(&state.Step{JobID: job.ID, Name: "a", Status: StepStatusSucceeded})
+		repo.CreateStep(&state.Step{JobID: job.ID, Name: "b", Status: StepStatusRunning})
+		return job
+	}
+	leased := crashed()
+	if err := o.Enqueue(leased.ID); err != nil {
+		t.Fatal(err)
+	}
+	if item, _ := repo.LeaseQueueItem(); item == nil || item.JobID != leased.ID {
+		t.Fatalf("leased %+v", item)
+	}
+	stranded := crashed()
+
+	if err := o.Recover(); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range repo.queue {
+		if item.State != "pending" {
+			t.Errorf("queue item of job %s is %s after recovery, want pending", item.JobID, item.State)
+		}
+	}
+	if len(repo.queue) != 2 {
+		t.Fatalf("%d queue items after recovery, want one per job", len(repo.queue))
+	}
+
+	drain(o)
+	for _, job := range []*state.Job{leased, stranded} {
+		if job = reload(t, o, job.ID); job.Status != JobStatusSucceeded {
+			t.Errorf("job %s: %s after recovery", job.Status, job.Error)
+		}
+	}
+	if runs["a"] != 0 || runs["b"] != 2 || runs["c"] != 2 {
+		t.Errorf("steps ran %v; want only the interrupted and remaining steps, once per job", runs)
+	}
+
+	// Nothing is left to recover
+	if err := o.Recover(); err != nil {
+		t.Fatal(err)
+	}
+	if item, _ := repo.LeaseQueueItem(); item != nil {
+		t.Errorf("recovering again queued job %s", item.JobID)
+	}
+}
@@ -0,0 +1,242 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+
+	"agent-project-manager/internal/logger"
+	"agent-project-manager/internal/state"
+)
+
+var (
+	// ErrJobNotFinished is returned when rerunning a job that is still in progress
+	ErrJobNotFinished = errors.New("job has not finished")
+	// ErrUnknownStep is returned when a rerun names a step the workflow does not declare
+	ErrUnknownStep = errors.New("unknown step")
+)
+
+// Rerun is a job created from another job by RerunJob, with the run recording it
+type Rerun struct {
+	Job    *state.Job
+	Run    *state.Run
+	Copied []string // names of the steps whose results were copied from the original job
+}
+
+// RerunJob creates a new job that repeats a finished job from one step onwards.
+// Steps before fromStep (in execution order) that succeeded or were skipped are copied,
+// outputs and artifacts included, and everything from fromStep on runs again.
+// input overrides top-level keys of the original input. The new job runs the same
+// workflow version and records the original in RerunOfJobID; a rerun of a child job
+// runs on its own, without a parent.
+//
+// The rerun is also recorded as a new run of the new job, whose status follows the job's.
+// Its params hold the lineage: "rerunOfJobId" and "fromStep", "rerunOf" with the run of
+// the original job when it has one, and the "input" overrides.
+func (o *Orchestrator) RerunJob(jobID, fromStep string, input map[string]interface{}) (*Rerun, error) {
+	orig, err := o.repo.GetJob(jobID)
+	if err != nil {
+		return nil, ErrJobNotFound
+	}
+	if !isTerminalJobStatus(orig.Status) {
+		return nil, ErrJobNotFinished
+	}
+
+	def, err := o.loadDefinition(orig)
+	if err != nil {
+		return nil, err
+	}
+	order, err := def.Order()
+	if err != nil {
+		return nil, err
+	}
+	from := -1
+	for i, sd := range order {
+		if sd.Name == fromStep {
+			from = i
+			break
+		}
+	}
+	if from < 0 {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownStep, fromStep)
+	}
+
+	merged := copyMap(orig.Input)
+	for k, v := range input {
+		merged[k] = v
+	}
+	validated, verrs, err := def.ValidateInput(merged)
+	if err != nil {
+		return nil, err
+	}
+	if len(verrs) > 0 {
+		return nil, &InvalidInputError{Errors: verrs}
+	}
+
+	existing, err := o.repo.ListSteps(orig.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list steps: %w", err)
+	}
+	records := map[string]*state.Step{}
+	for _, s := range existing {
+		records[s.Name] = s
+	}
+
+	meta := state.JSONMap{}
+	if noCache(orig) {
+		meta["noCache"] = true
+	}
+	if budget := JobBudget(orig); budget != nil {
+		meta["budget"] = budget.Meta()
+	}
+	if cassette := JobCassette(orig); cassette != nil {
+		meta["cassette"] = cassette.Meta()
+	}
+
+	params := state.JSONMap{"rerunOfJobId": orig.ID, "fromStep": fromStep}
+	if origRun, err := o.jobRun(orig); err != nil {
+		return nil, err
+	} else if origRun != nil {
+		params["rerunOf"] = origRun.ID
+	}
+	if len(input) > 0 {
+		params["input"] = input
+	}
+	run := &state.Run{ID: state.NewUUID(), Status: runStatus(JobStatusQueued), Params: params}
+	meta["runId"] = run.ID
+
+	job := &state.Job{
+		Workflow:        orig.Workflow,
+		WorkflowVersion: orig.WorkflowVersion,
+		Status:          JobStatusQueued,
+		Input:           state.JSONMap(validated),
+		Meta:            meta,
+		RerunOfJobID:    orig.ID,
+		RerunFromStep:   fromStep,
+	}
+	if err := o.repo.CreateJob(job); err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+	run.JobID = job.ID
+	if err := o.repo.CreateRun(run); err != nil {
+		err = fmt.Errorf("failed to create run: %w", err)
+		o.settleJob(job, JobStatusFailed, err)
+		return nil, err
+	}
+
+	rerun := &Rerun{Job: job, Run: run, Copied: []string{}}
+	for _, sd := range order[:from] {
+		rec := records[sd.Name]
+		if rec == nil || (rec.Status != StepStatusSucceeded && rec.Status != StepStatusSkipped) {
+			continue
+		}
+		if err := o.copyStep(job, rec); err != nil {
+			// Nothing ran yet, so there is nothing to compensate
+			o.settleJob(job, JobStatusFailed, err)
+			return nil, err
+		}
+		rerun.Copied = append(rerun.Copied, sd.Name)
+	}
+
+	o.emit(job.ID, "", EventJobRerun, "", map[string]interface{}{
+		"rerunOf":  orig.ID,
+		"fromStep": fromStep,
+		"copied":   rerun.Copied,
+		"runId":    run.ID,
+	})
+	logger.Infof("orchestrator: job %s reruns job %s from step %s", job.ID, orig.ID, fromStep)
+	if err := o.Enqueue(job.ID); err != nil {
+		return nil, err
+	}
+	return rerun, nil
+}
+
+// copyStep records a completed step of another job as a step of job, attaching its artifacts
+func (o *Orchestrator) copyStep(job *state.Job, src *state.Step) error {
+	artifacts := make([]*state.Artifact, 0, len(src.ArtifactIDs))
+	for _, id := range src.ArtifactIDs {
+		a, err := o.repo.GetArtifact(id)
+		if err != nil {
+			logger.Warnf("orchestrator: artifact %s of step %s is gone; not copying it", id, src.ID)
+			continue
+		}
+		artifacts = append(artifacts, a)
+	}
+	ids, err := o.attachArtifacts(job, artifacts)
+	if err != nil {
+		return err
+	}
+
+	rec := &state.Step{
+		JobID:       job.ID,
+		Name:        src.Name,
+		Status:      src.Status,
+		Input:       copyMap(src.Input),
+		Output:      copyMap(src.Output),
+		StartedAt:   src.StartedAt,
+		CompletedAt: src.CompletedAt,
+		Cached:      src.Cached,
+		CacheKey:    src.CacheKey,
+		ArtifactIDs: ids,
+	}
+	if err := o.repo.CreateStep(rec); err != nil {
+		return fmt.Errorf("failed to copy step %s: %w", src.Name, err)
+	}
+	o.emit(job.ID, rec.ID, EventStepCopied, "", map[string]interface{}{
+		"step":         src.Name,
+		"sourceJobId":  src.JobID,
+		"sourceStepId": src.ID,
+	})
+	return nil
+}
+
+// jobRun returns the run of a job: the one it was created with, or else its latest run.
+// It is nil when the job has none.
+func (o *Orchestrator) jobRun(job *state.Job) (*state.Run, error) {
+	if id, _ := job.Meta["runId"].(string); id != "" {
+		run, err := o.repo.GetRun(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load run %s of job %s: %w", id, job.ID, err)
+		}
+		return run, nil
+	}
+	runs, _, err := o.repo.ListRuns(job.ID, 1, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs of job %s: %w", job.ID, err)
+	}
+	if len(runs) == 0 {
+		return nil, nil
+	}
+	return runs[0], nil
+}
+
+// syncRun brings the run a job was created with up to date with the job's status
+func (o *Orchestrator) syncRun(job *state.Job) {
+	id, _ := job.Meta["runId"].(string)
+	if id == "" {
+		return
+	}
+	run, err := o.repo.GetRun(id)
+	if err != nil {
+		logger.Errorf("orchestrator: failed to load run %s of job %s: %v", id, job.ID, err)
+		return
+	}
+	run.Status = runStatus(job.Status)
+	run.StartedAt = job.StartedAt
+	run.CompletedAt = job.CompletedAt
+	run.Error = job.Error
+	if err := o.repo.UpdateRun(run); err != nil {
+		logger.Errorf("orchestrator: failed to update run %s of job %s: %v", id, job.ID, err)
+	}
+}
+
+// runStatus maps a job status onto the statuses of runs
+func runStatus(jobStatus string) string {
+	switch jobStatus {
+	case JobStatusQueued:
+		return "pending"
+	case JobStatusSucceeded, JobStatusFailed, JobStatusCancelled:
+		return jobStatus
+	default:
+		return "running"
+	}
+}
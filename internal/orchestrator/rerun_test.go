@@ -0,0 +1,141 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"agent-project-manager/internal/state"
+)
+
+func TestRerunFromStep(t *testing.T) {
+	o, repo := newTestOrchestrator(t, map[string]state.JSONMap{
+		"wf": {
+			"inputSchema": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"mode": map[string]interface{}{"type": "string"}},
+			},
+			"steps": []interface{}{
+				map[string]interface{}{"name": "codegen", "type": "gen"},
+				map[string]interface{}{"name": "review", "type": "review", "input": map[string]interface{}{
+					"code": "{{ .steps.codegen.output.code }}",
+					"mode": "{{ .input.mode }}",
+				}},
+			},
+		},
+	})
+	runs := map[string]int{}
+	o.Register("gen", StepExecutorFunc(func(ctx context.Context, sc *StepContext) (*StepResult, error) {
+		runs["codegen"]++
+		code := &state.Artifact{JobID: sc.Job.ID, Name: "code.zip"}
+		if err := sc.Repo.CreateArtifact(code); err != nil {
+			return nil, err
+		}
+		return &StepResult{Output: map[string]interface{}{"code": "package main"}, Artifacts: []string{code.ID}}, nil
+	}))
+	o.Register("review", StepExecutorFunc(func(ctx context.Context, sc *StepContext) (*StepResult, error) {
+		runs["review"]++
+		if sc.Step.Input["mode"] != "lenient" {
+			return nil, errors.New("review failed")
+		}
+		return &StepResult{Output: map[string]interface{}{"reviewed": sc.Step.Input["code"]}}, nil
+	}))
+
+	orig := &state.Job{
+		Workflow: "wf", WorkflowVersion: 1, Status: JobStatusQueued,
+		Input: state.JSONMap{"mode": "strict"},
+		Meta: state.JSONMap{
+			"noCache":  true,
+			"cassette": map[string]interface{}{"mode": "replay", "name": "review-smoke"},
+		},
+	}
+	repo.CreateJob(orig)
+	origRun := &state.Run{JobID: orig.ID, Status: "pending"}
+	repo.CreateRun(origRun)
+	o.Enqueue(orig.ID)
+	drain(o)
+	if orig = reload(t, o, orig.ID); orig.Status != JobStatusFailed {
+		t.Fatalf("original job %s, want failed", orig.Status)
+	}
+
+	if _, err := o.RerunJob(orig.ID, "nope", nil); !errors.Is(err, ErrUnknownStep) {
+		t.Errorf("rerun from an unknown step: error = %v, want ErrUnknownStep", err)
+	}
+	var inputErr *InvalidInputError
+	if _, err := o.RerunJob(orig.ID, "review", map[string]interface{}{"mode": 3}); !errors.As(err, &inputErr) {
+		t.Errorf("rerun with invalid input: error = %v, want an InvalidInputError", err)
+	}
+
+	rerun, err := o.RerunJob(orig.ID, "review", map[string]interface{}{"mode": "lenient"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"codegen"}; !reflect.DeepEqual(rerun.Copied, want) {
+		t.Errorf("copied %v, want %v", rerun.Copied, want)
+	}
+	drain(o)
+
+	job := reload(t, o, rerun.Job.ID)
+	if job.Status != JobStatusSucceeded {
+		t.Fatalf("rerun %s: %s", job.Status, job.Error)
+	}
+	if job.RerunOfJobID != orig.ID || job.RerunFromStep != "review" || job.WorkflowVersion != 1 {
+		t.Errorf("rerun of %q from %q at version %d", job.RerunOfJobID, job.RerunFromStep, job.WorkflowVersion)
+	}
+	if runs["codegen"] != 1 || runs["review"] != 2 {
+		t.Errorf("steps ran %v; want codegen copied and review run again", runs)
+	}
+	if job.Input["mode"] != "lenient" {
+		t.Errorf("rerun input %v, want the override", job.Input)
+	}
+	if !noCache(job) || JobCassette(job) == nil || JobCassette(job).Name != "review-smoke" {
+		t.Errorf("rerun meta %v; want noCache and the cassette of the original", job.Meta)
+	}
+
+	run, err := repo.GetRun(rerun.Run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.JobID != job.ID || run.Status != JobStatusSucceeded || run.StartedAt == nil || run.CompletedAt == nil {
+		t.Errorf("run %+v; want a succeeded run of the rerun job", run)
+	}
+	want := state.JSONMap{"rerunOf": origRun.ID, "rerunOfJobId": orig.ID, "fromStep": "review", "input": map[string]interface{}{"mode": "lenient"}}
+	if !reflect.DeepEqual(run.Params, want) {
+		t.Errorf("run params %v, want %v", run.Params, want)
+	}
+
+	steps := jobSteps(t, o, job.ID)
+	if got := steps["review"].Output["reviewed"]; got != "package main" {
+		t.Errorf("review got %v from the copied codegen step", got)
+	}
+	origSteps := jobSteps(t, o, orig.ID)
+	copied := steps["codegen"]
+	if len(copied.ArtifactIDs) != 1 || copied.ArtifactIDs[0] == origSteps["codegen"].ArtifactIDs[0] {
+		t.Errorf("copied step artifacts %v; want a copy attached to the rerun", copied.ArtifactIDs)
+	} else if a, _ := repo.GetArtifact(copied.ArtifactIDs[0]); a.JobID != job.ID {
+		t.Errorf("copied artifact belongs to job %s, want the rerun", a.JobID)
+	}
+
+	again, err := o.RerunJob(job.ID, "review", nil)
+	if err != nil {
+		t.Fatalf("rerunning a rerun: %v", err)
+	}
+	if again.Run.Params["rerunOf"] != run.ID || again.Run.Status != "pending" {
+		t.Errorf("rerun of a rerun has run %+v, want it linked to run %s", again.Run, run.ID)
+	}
+}
+
+func TestRerunUnfinishedJob(t *testing.T) {
+	o, _ := newTestOrchestrator(t, map[string]state.JSONMap{
+		"wf": stepDefs(map[string]interface{}{"name": "gate", "type": StepTypeApproval}),
+	})
+
+	job := submit(t, o, "wf", nil)
+	if _, err := o.RerunJob(job.ID, "gate", nil); err != ErrJobNotFinished {
+		t.Errorf("rerun of a waiting job: error = %v, want ErrJobNotFinished", err)
+	}
+	if _, err := o.RerunJob("missing", "gate", nil); err != ErrJobNotFound {
+		t.Errorf("rerun of a missing job: error = %v, want ErrJobNotFound", err)
+	}
+}
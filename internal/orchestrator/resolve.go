@@ -0,0 +1,199 @@
+package orchestrator
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"agent-project-manager/internal/state"
+)
+
+// pathExpr matches a template that is nothing but a single field path,
+// e.g. "{{ .steps.build.output.files }}". Such values keep their JSON type
+// instead of being rendered to a string.
+var pathExpr = regexp.MustCompile(`^\{\{\s*\.([A-Za-z0-9_\-]+(?:\.[A-Za-z0-9_\-]+)*)\s*\}\}$`)
+
+// templateData builds the data step input templates are evaluated against:
+//
+//	.job.id, .job.workflow      the running job
+//	.input.<key>                the job input
+//	.steps.<name>.output.<key>  outputs of steps that already ran
+//	.steps.<name>.status        their status
+func templateData(job *state.Job, records map[string]*state.Step) map[string]interface{} {
+	steps := map[string]interface{}{}
+	for name, rec := range records {
+		steps[name] = map[string]interface{}{
+			"status": rec.Status,
+			"output": map[string]interface{}(rec.Output),
+		}
+	}
+	return map[string]interface{}{
+		"job": map[string]interface{}{
+			"id":       job.ID,
+			"workflow": job.Workflow,
+		},
+		"input": map[string]interface{}(job.Input),
+		"steps": steps,
+	}
+}
+
+// resolveInput evaluates the templates in a step's input
+func resolveInput(input map[string]interface{}, data map[string]interface{}) (state.JSONMap, error) {
+	out := state.JSONMap{}
+	for k, v := range input {
+		resolved, err := resolveValue(v, data)
+		if err != nil {
+			return nil, fmt.Errorf("input %q: %w", k, err)
+		}
+		out[k] = resolved
+	}
+	return out, nil
+}
+
+// resolveRoute evaluates the templates in a step's provider, model and fallbacks, so the
+// job input can choose the model, e.g. model: "{{ .input.model }}" for a matrix axis
+func resolveRoute(sd StepDef, data map[string]interface{}) (StepDef, error) {
+	render := func(field, s string) (string, error) {
+		v, err := resolveString(s, data)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", field, err)
+		}
+		str, ok := v.(string)
+		if !ok {
+			return "", fmt.Errorf("%s must be a string, got %v", field, v)
+		}
+		return str, nil
+	}
+
+	var err error
+	if sd.Provider, err = render("provider", sd.Provider); err != nil {
+		return sd, err
+	}
+	if sd.Model, err = render("model", sd.Model); err != nil {
+		return sd, err
+	}
+	fallbacks := make([]ModelRef, len(sd.Fallbacks))
+	for i, m := range sd.Fallbacks {
+		if fallbacks[i].Provider, err = render(fmt.Sprintf("fallbacks[%d].provider", i), m.Provider); err != nil {
+			return sd, err
+		}
+		if fallbacks[i].Model, err = render(fmt.Sprintf("fallbacks[%d].model", i), m.Model); err != nil {
+			return sd, err
+		}
+	}
+	if len(fallbacks) > 0 {
+		sd.Fallbacks = fallbacks
+	}
+	return sd, nil
+}
+
+// resolveValue evaluates templates inside strings, maps and slices
+func resolveValue(v interface{}, data map[string]interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		return resolveString(val, data)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			resolved, err := resolveValue(item, data)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = resolved
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			resolved, err := resolveValue(item, data)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// resolveString renders a single template string
+func resolveString(s string, data map[string]interface{}) (interface{}, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+
+	if m := pathExpr.FindStringSubmatch(s); m != nil {
+		return lookupPath(data, strings.Split(m[1], "."))
+	}
+
+	tmpl, err := template.New("input").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %q: %w", s, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render %q: %w", s, err)
+	}
+	return buf.String(), nil
+}
+
+// lookupPath walks nested maps following path
+func lookupPath(data map[string]interface{}, path []string) (interface{}, error) {
+	var cur interface{} = data
+	for i, key := range path {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			if jm, isJSON := cur.(state.JSONMap); isJSON {
+				m, ok = map[string]interface{}(jm), true
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s is not an object", strings.Join(path[:i], "."))
+		}
+		cur, ok = m[key]
+		if !ok {
+			return nil, fmt.Errorf("%s is not defined", strings.Join(path[:i+1], "."))
+		}
+	}
+	return cur, nil
+}
+
+// conditionTemplate turns a step condition into a template.
+// Conditions may be written as a bare expression ("eq .input.mode \"full\"")
+// or as a complete template ("{{ if .input.deploy }}yes{{ end }}").
+func conditionTemplate(expr string) string {
+	if strings.Contains(expr, "{{") {
+		return expr
+	}
+	return "{{ " + expr + " }}"
+}
+
+// checkCondition reports whether a step condition parses
+func checkCondition(expr string) error {
+	if _, err := template.New("if").Parse(conditionTemplate(expr)); err != nil {
+		return fmt.Errorf("invalid condition %q: %w", expr, err)
+	}
+	return nil
+}
+
+// evalCondition evaluates a step condition against data.
+// The condition is false when it renders to "", "false", "0" or "<no value>".
+func evalCondition(expr string, data map[string]interface{}) (bool, error) {
+	tmpl, err := template.New("if").Option("missingkey=error").Parse(conditionTemplate(expr))
+	if err != nil {
+		return false, fmt.Errorf("invalid condition %q: %w", expr, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return false, fmt.Errorf("failed to evaluate condition %q: %w", expr, err)
+	}
+	switch strings.TrimSpace(buf.String()) {
+	case "", "false", "0", "<no value>":
+		return false, nil
+	default:
+		return true, nil
+	}
+}
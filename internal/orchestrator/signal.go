@@ -0,0 +1,213 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"agent-project-manager/internal/logger"
+	"agent-project-manager/internal/state"
+)
+
+// errNoSignal declines a claim on a signal step when no matching signal is buffered
+var errNoSignal = errors.New("no signal buffered")
+
+// signalName returns the name of the signal a step waits for
+func signalName(sd StepDef) string {
+	if sd.Signal != "" {
+		return sd.Signal
+	}
+	return sd.Name
+}
+
+// executeSignal completes the step with a buffered signal, or parks the job until one arrives
+func (o *Orchestrator) executeSignal(ctx context.Context, sc *StepContext) (*StepResult, error) {
+	name := signalName(sc.Def)
+	sc.Step.Input["signal"] = name
+	if sc.Def.Timeout != "" {
+		timeout, err := time.ParseDuration(sc.Def.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signal timeout %q: %w", sc.Def.Timeout, err)
+		}
+		onTimeout := sc.Def.OnTimeout
+		if onTimeout == "" {
+			onTimeout = SignalTimeoutFail
+		}
+		sc.Step.Input["deadline"] = time.Now().Add(timeout).UTC().Format(time.RFC3339)
+		sc.Step.Input["onTimeout"] = onTimeout
+	}
+
+	signal, err := o.repo.ConsumeSignal(sc.Job.ID, name, sc.Step.ID)
+	if err != nil {
+		return nil, err
+	}
+	if signal == nil {
+		return &StepResult{Wait: StepStatusWaitingSignal}, nil
+	}
+	o.emit(sc.Job.ID, sc.Step.ID, EventStepSignalled, "", map[string]interface{}{
+		"step":     sc.Step.Name,
+		"signal":   name,
+		"signalId": signal.ID,
+		"buffered": true,
+	})
+	return &StepResult{Output: signal.Payload}, nil
+}
+
+// SendSignal delivers a signal to a job. The payload becomes the output of the
+// wait_for_signal step waiting for it; if no step is waiting yet the signal is
+// buffered until one starts. The returned signal has StepID set when it was consumed.
+func (o *Orchestrator) SendSignal(jobID, name string, payload map[string]interface{}) (*state.Signal, error) {
+	job, err := o.repo.GetJob(jobID)
+	if err != nil {
+		return nil, ErrJobNotFound
+	}
+	if isFinishingJobStatus(job.Status) {
+		return nil, ErrJobFinished
+	}
+
+	signal := &state.Signal{
+		JobID:   jobID,
+		Name:    name,
+		Payload: copyMap(payload),
+	}
+	if err := o.repo.CreateSignal(signal); err != nil {
+		return nil, err
+	}
+	o.emit(jobID, "", EventSignalReceived, "", map[string]interface{}{"signal": name, "signalId": signal.ID})
+
+	for _, delivered := range o.deliverSignals(jobID) {
+		if delivered.ID == signal.ID {
+			return delivered, nil
+		}
+	}
+	return signal, nil
+}
+
+// deliverSignals completes the job's waiting signal steps that have a buffered signal
+// and returns the signals that were consumed
+func (o *Orchestrator) deliverSignals(jobID string) []*state.Signal {
+	steps, err := o.repo.ListSteps(jobID)
+	if err != nil {
+		logger.Errorf("orchestrator: failed to list steps of job %s: %v", jobID, err)
+		return nil
+	}
+
+	var delivered []*state.Signal
+	for _, s := range steps {
+		if s.Status != StepStatusWaitingSignal {
+			continue
+		}
+		signal, err := o.deliverSignal(jobID, s.ID)
+		if err != nil {
+			logger.Errorf("orchestrator: failed to deliver signal to step %s: %v", s.ID, err)
+			continue
+		}
+		if signal != nil {
+			delivered = append(delivered, signal)
+		}
+	}
+	return delivered
+}
+
+// deliverSignal completes a waiting signal step with the oldest matching buffered signal
+// and requeues the job. It returns the consumed signal, or nil when there is nothing to deliver.
+func (o *Orchestrator) deliverSignal(jobID, stepID string) (*state.Signal, error) {
+	var signal *state.Signal
+	step, err := o.tryClaimStep(jobID, stepID, StepStatusWaitingSignal, func(step *state.Step) error {
+		name, _ := step.Input["signal"].(string)
+		s, err := o.repo.ConsumeSignal(jobID, name, step.ID)
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			return errNoSignal
+		}
+		signal = s
+		now := time.Now()
+		step.Status = StepStatusSucceeded
+		step.Output = s.Payload
+		step.CompletedAt = &now
+		return nil
+	})
+	if errors.Is(err, errNoSignal) || errors.Is(err, ErrStepNotWaiting) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	o.emit(jobID, stepID, EventStepSignalled, "", map[string]interface{}{
+		"step":     step.Name,
+		"signal":   signal.Name,
+		"signalId": signal.ID,
+	})
+	job, err := o.repo.GetJob(jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load job %s: %w", jobID, err)
+	}
+	if err := o.resumeJob(job); err != nil {
+		return nil, err
+	}
+	return signal, nil
+}
+
+// expireSignals applies the configured timeout behaviour to overdue signal steps
+func (o *Orchestrator) expireSignals(now time.Time) {
+	steps, err := o.repo.ListStepsByStatus(StepStatusWaitingSignal)
+	if err != nil {
+		logger.Errorf("orchestrator: failed to list waiting signal steps: %v", err)
+		return
+	}
+
+	for _, s := range steps {
+		deadline, ok := s.Input["deadline"].(string)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, deadline)
+		if err != nil || now.Before(t) {
+			continue
+		}
+		if err := o.timeOutSignal(s.JobID, s.ID); err != nil {
+			logger.Errorf("orchestrator: failed to apply signal timeout to step %s: %v", s.ID, err)
+		}
+	}
+}
+
+// timeOutSignal settles a signal step whose deadline passed.
+// The step fails the job unless its onTimeout is "continue".
+func (o *Orchestrator) timeOutSignal(jobID, stepID string) error {
+	now := time.Now()
+	step, err := o.claimStep(jobID, stepID, StepStatusWaitingSignal, func(step *state.Step) {
+		step.CompletedAt = &now
+		if step.Input["onTimeout"] == SignalTimeoutContinue {
+			step.Status = StepStatusSucceeded
+			step.Output = state.JSONMap{"timedOut": true}
+			return
+		}
+		step.Status = StepStatusFailed
+		step.Error = fmt.Sprintf("timed out waiting for signal %q", step.Input["signal"])
+	})
+	if errors.Is(err, ErrStepNotWaiting) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	o.emit(jobID, stepID, EventStepTimedOut, step.Error, map[string]interface{}{
+		"step":   step.Name,
+		"signal": step.Input["signal"],
+		"status": step.Status,
+	})
+	job, err := o.repo.GetJob(jobID)
+	if err != nil {
+		return fmt.Errorf("failed to load job %s: %w", jobID, err)
+	}
+	if step.Status == StepStatusSucceeded {
+		return o.resumeJob(job)
+	}
+	o.finishJob(job, JobStatusFailed, errors.New(step.Error))
+	return nil
+}
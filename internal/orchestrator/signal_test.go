@@ -0,0 +1,112 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+
+	"agent-project-manager/internal/state"
+)
+
+func signalWorkflow(wait map[string]interface{}) map[string]state.JSONMap {
+	wait["name"] = "ci"
+	wait["type"] = StepTypeSignal
+	return map[string]state.JSONMap{
+		"wf": stepDefs(wait, map[string]interface{}{"name": "after", "type": "echo", "input": map[string]interface{}{"sha": "{{ .steps.ci.output.sha }}"}}),
+	}
+}
+
+func TestSignalDelivered(t *testing.T) {
+	o, repo := newTestOrchestrator(t, signalWorkflow(map[string]interface{}{"signal": "ci-passed"}))
+
+	job := submit(t, o, "wf", nil)
+	if job.Status != JobStatusWaitingSignal {
+		t.Fatalf("job %s: %s; want it waiting for the signal", job.Status, job.Error)
+	}
+	if signal, err := o.SendSignal(job.ID, "other", nil); err != nil || signal.StepID != "" {
+		t.Errorf("a signal no step waits for = %+v, %v; want it buffered", signal, err)
+	}
+	signal, err := o.SendSignal(job.ID, "ci-passed", map[string]interface{}{"sha": "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ci := jobSteps(t, o, job.ID)["ci"]
+	if signal.StepID != ci.ID || ci.Status != StepStatusSucceeded {
+		t.Errorf("signal consumed by step %q, step %s; want step ci to succeed with it", signal.StepID, ci.Status)
+	}
+
+	drain(o)
+	if job = reload(t, o, job.ID); job.Status != JobStatusSucceeded {
+		t.Fatalf("job %s: %s after the signal", job.Status, job.Error)
+	}
+	if sha := jobSteps(t, o, job.ID)["after"].Output["sha"]; sha != "abc" {
+		t.Errorf("step after the signal got sha %v, want the payload's abc", sha)
+	}
+	if !contains(repo.eventTypes(job.ID), EventStepSignalled) {
+		t.Errorf("events %v lack %s", repo.eventTypes(job.ID), EventStepSignalled)
+	}
+	if _, err := o.SendSignal(job.ID, "ci-passed", nil); err != ErrJobFinished {
+		t.Errorf("signalling a finished job: error = %v, want ErrJobFinished", err)
+	}
+}
+
+func TestSignalBuffered(t *testing.T) {
+	o, repo := newTestOrchestrator(t, signalWorkflow(map[string]interface{}{}))
+
+	// Sent before the job reaches the step; the step is named after the signal by default
+	job := &state.Job{Workflow: "wf", WorkflowVersion: 1, Status: JobStatusQueued}
+	repo.CreateJob(job)
+	if _, err := o.SendSignal(job.ID, "ci", map[string]interface{}{"sha": "def"}); err != nil {
+		t.Fatal(err)
+	}
+	o.Enqueue(job.ID)
+	drain(o)
+
+	if job = reload(t, o, job.ID); job.Status != JobStatusSucceeded {
+		t.Fatalf("job %s: %s", job.Status, job.Error)
+	}
+	if sha := jobSteps(t, o, job.ID)["after"].Output["sha"]; sha != "def" {
+		t.Errorf("step after the signal got sha %v, want the buffered payload's def", sha)
+	}
+}
+
+func TestSignalTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		onTimeout string
+		want      string
+	}{
+		{onTimeout: "", want: JobStatusFailed}, // fails by default
+		{onTimeout: SignalTimeoutFail, want: JobStatusFailed},
+		{onTimeout: SignalTimeoutContinue, want: JobStatusSucceeded},
+	} {
+		t.Run("onTimeout="+tc.onTimeout, func(t *testing.T) {
+			wait := map[string]interface{}{"timeout": "1h"}
+			if tc.onTimeout != "" {
+				wait["onTimeout"] = tc.onTimeout
+			}
+			wait["name"], wait["type"] = "ci", StepTypeSignal
+			o, _ := newTestOrchestrator(t, map[string]state.JSONMap{
+				"wf": stepDefs(wait, map[string]interface{}{"name": "after", "type": "echo"}),
+			})
+
+			job := submit(t, o, "wf", nil)
+			o.expireSignals(time.Now().Add(30 * time.Minute))
+			drain(o)
+			if job = reload(t, o, job.ID); job.Status != JobStatusWaitingSignal {
+				t.Fatalf("job %s before the deadline", job.Status)
+			}
+
+			o.expireSignals(time.Now().Add(2 * time.Hour))
+			drain(o)
+			if job = reload(t, o, job.ID); job.Status != tc.want {
+				t.Fatalf("job %s: %s after the deadline, want %s", job.Status, job.Error, tc.want)
+			}
+			ci := jobSteps(t, o, job.ID)["ci"]
+			if tc.want == JobStatusSucceeded && ci.Output["timedOut"] != true {
+				t.Errorf("continued step output %v, want timedOut", ci.Output)
+			}
+			if tc.want == JobStatusFailed && ci.Error != `timed out waiting for signal "ci"` {
+				t.Errorf("failed step error %q", ci.Error)
+			}
+		})
+	}
+}
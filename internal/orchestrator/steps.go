@@ -0,0 +1,108 @@
+package orchestrator
+
+import (
+	"context"
+
+	"agent-project-manager/internal/state"
+)
+
+// Job statuses
+const (
+	JobStatusQueued          = "queued"
+	JobStatusRunning         = "running"
+	JobStatusWaitingApproval = "waiting_approval"
+	JobStatusWaitingChild    = "waiting_child"
+	JobStatusWaitingSignal   = "waiting_signal"
+	JobStatusCompensating    = "compensating"
+	JobStatusSucceeded       = "succeeded"
+	JobStatusFailed          = "failed"
+	JobStatusCancelled       = "cancelled"
+)
+
+// Step statuses
+const (
+	StepStatusPending         = "pending"
+	StepStatusRunning         = "running"
+	StepStatusWaitingApproval = "waiting_approval"
+	StepStatusWaitingChild    = "waiting_child"
+	StepStatusWaitingSignal   = "waiting_signal"
+	StepStatusSucceeded       = "succeeded"
+	StepStatusFailed          = "failed"
+	StepStatusSkipped         = "skipped"
+	StepStatusCompensated     = "compensated"
+)
+
+// Queue item states
+const (
+	QueueStatePending = "pending"
+	QueueStateLeased  = "leased"
+	QueueStateDone    = "done"
+	QueueStateDead    = "dead"
+)
+
+// StepExecutor runs one type of workflow step
+type StepExecutor interface {
+	Execute(ctx context.Context, sc *StepContext) (*StepResult, error)
+}
+
+// StepExecutorFunc adapts a function to the StepExecutor interface
+type StepExecutorFunc func(ctx context.Context, sc *StepContext) (*StepResult, error)
+
+// Execute calls f(ctx, sc)
+func (f StepExecutorFunc) Execute(ctx context.Context, sc *StepContext) (*StepResult, error) {
+	return f(ctx, sc)
+}
+
+// StepContext carries everything an executor needs to run a step
+type StepContext struct {
+	Job  *state.Job
+	Step *state.Step
+	Def  StepDef
+	Repo state.Repository
+	// Emit records an event of the step, such as the progress of a long running executor
+	Emit func(eventType, message string, data map[string]interface{})
+}
+
+// StepResult is the outcome of a step execution.
+// A result with Wait set parks the job: both the step and the job take that
+// status and the worker is released until something external completes the step.
+type StepResult struct {
+	Output    map[string]interface{}
+	Wait      string
+	Artifacts []string // IDs of artifacts the step produced; reused on step cache hits
+}
+
+// isWaitingStepStatus reports whether a step is parked waiting on something external
+func isWaitingStepStatus(status string) bool {
+	switch status {
+	case StepStatusWaitingApproval, StepStatusWaitingChild, StepStatusWaitingSignal:
+		return true
+	default:
+		return false
+	}
+}
+
+// isWaitingStepType reports whether steps of a type park the job until something external happens
+func isWaitingStepType(stepType string) bool {
+	switch stepType {
+	case StepTypeApproval, StepTypeWorkflow, StepTypeSignal:
+		return true
+	default:
+		return false
+	}
+}
+
+// isTerminalJobStatus reports whether a job can no longer make progress
+func isTerminalJobStatus(status string) bool {
+	switch status {
+	case JobStatusSucceeded, JobStatusFailed, JobStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// isFinishingJobStatus reports whether a job is terminal or only undoing its completed steps
+func isFinishingJobStatus(status string) bool {
+	return isTerminalJobStatus(status) || status == JobStatusCompensating
+}
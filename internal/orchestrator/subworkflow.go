@@ -0,0 +1,201 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"agent-project-manager/internal/logger"
+	"agent-project-manager/internal/state"
+)
+
+// MaxWorkflowDepth bounds how deeply sub-workflows may nest
+const MaxWorkflowDepth = 8
+
+// executeWorkflow starts the step's workflow as a child job and parks the
+// parent until the child finishes
+func (o *Orchestrator) executeWorkflow(ctx context.Context, sc *StepContext) (*StepResult, error) {
+	// A previous attempt may already have started the child (e.g. before a restart)
+	children, err := o.repo.ListChildJobs(sc.Job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list child jobs: %w", err)
+	}
+	for _, child := range children {
+		if child.ParentStepID != sc.Step.ID {
+			continue
+		}
+		if child.Status == JobStatusSucceeded {
+			return o.childResult(child)
+		}
+		if !isTerminalJobStatus(child.Status) {
+			return &StepResult{
+				Output: map[string]interface{}{"childJobId": child.ID},
+				Wait:   StepStatusWaitingChild,
+			}, nil
+		}
+		// Failed or cancelled children are replaced when the step is retried
+	}
+
+	depth := jobDepth(sc.Job) + 1
+	if depth > MaxWorkflowDepth {
+		return nil, fmt.Errorf("sub-workflow nesting exceeds %d levels", MaxWorkflowDepth)
+	}
+	wv, err := ResolveWorkflowVersion(o.repo, sc.Def.Workflow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve workflow %s: %w", sc.Def.Workflow, err)
+	}
+	input, verrs, err := ValidateInput(wv.Schema, copyMap(sc.Step.Input))
+	if err != nil {
+		return nil, fmt.Errorf("workflow %s@%d: %w", wv.Workflow, wv.Version, err)
+	}
+	if len(verrs) > 0 {
+		msgs := make([]string, len(verrs))
+		for i, e := range verrs {
+			msgs[i] = e.Error()
+		}
+		return nil, fmt.Errorf("invalid input for workflow %s@%d: %s", wv.Workflow, wv.Version, strings.Join(msgs, "; "))
+	}
+
+	child := &state.Job{
+		Workflow:        wv.Workflow,
+		WorkflowVersion: wv.Version,
+		Status:          JobStatusQueued,
+		Input:           state.JSONMap(input),
+		Meta:            childMeta(sc.Job, depth),
+		ParentJobID:     sc.Job.ID,
+		ParentStepID:    sc.Step.ID,
+	}
+	if err := o.repo.CreateJob(child); err != nil {
+		return nil, fmt.Errorf("failed to create child job: %w", err)
+	}
+	if err := o.Enqueue(child.ID); err != nil {
+		return nil, err
+	}
+	o.emit(sc.Job.ID, sc.Step.ID, EventStepChildStarted, "", map[string]interface{}{
+		"step":       sc.Step.Name,
+		"childJobId": child.ID,
+		"workflow":   child.Workflow,
+		"version":    child.WorkflowVersion,
+	})
+
+	return &StepResult{
+		Output: map[string]interface{}{"childJobId": child.ID},
+		Wait:   StepStatusWaitingChild,
+	}, nil
+}
+
+// childMeta builds the meta of a child job, carrying over the parent's job options and
+// the trigger depth that led to it
+func childMeta(parent *state.Job, depth int) state.JSONMap {
+	meta := state.JSONMap{"depth": depth}
+	if noCache(parent) {
+		meta["noCache"] = true
+	}
+	if d := triggerDepth(parent); d > 0 {
+		meta["triggerDepth"] = d
+	}
+	return meta
+}
+
+// notifyParent settles the parent step waiting on a finished child job.
+// A succeeded child resumes the parent; any other outcome fails it.
+func (o *Orchestrator) notifyParent(child *state.Job) {
+	parent, err := o.repo.GetJob(child.ParentJobID)
+	if err != nil {
+		logger.Errorf("orchestrator: failed to load parent job %s: %v", child.ParentJobID, err)
+		return
+	}
+	if isFinishingJobStatus(parent.Status) {
+		return
+	}
+
+	var output map[string]interface{}
+	if child.Status == JobStatusSucceeded {
+		res, err := o.childResult(child)
+		if err != nil {
+			logger.Errorf("orchestrator: failed to collect output of child job %s: %v", child.ID, err)
+			return
+		}
+		output = res.Output
+	}
+
+	now := time.Now()
+	step, err := o.claimStep(parent.ID, child.ParentStepID, StepStatusWaitingChild, func(step *state.Step) {
+		step.CompletedAt = &now
+		if output != nil {
+			step.Status = StepStatusSucceeded
+			step.Output = state.JSONMap(output)
+			return
+		}
+		step.Status = StepStatusFailed
+		step.Error = fmt.Sprintf("child job %s %s", child.ID, child.Status)
+		if child.Error != "" {
+			step.Error += ": " + child.Error
+		}
+	})
+	if err != nil {
+		// Not parked yet (process re-checks after parking) or already settled
+		if !errors.Is(err, ErrStepNotWaiting) {
+			logger.Errorf("orchestrator: failed to settle step %s: %v", child.ParentStepID, err)
+		}
+		return
+	}
+
+	data := map[string]interface{}{"step": step.Name, "childJobId": child.ID}
+	if step.Status == StepStatusSucceeded {
+		o.emit(parent.ID, step.ID, EventStepSucceeded, "", data)
+		if err := o.resumeJob(parent); err != nil {
+			logger.Errorf("orchestrator: failed to resume parent job %s: %v", parent.ID, err)
+		}
+		return
+	}
+	o.emit(parent.ID, step.ID, EventStepFailed, step.Error, data)
+	o.finishJob(parent, JobStatusFailed, errors.New(step.Error))
+}
+
+// checkChildren settles steps of a job whose child already finished
+func (o *Orchestrator) checkChildren(jobID string) {
+	children, err := o.repo.ListChildJobs(jobID)
+	if err != nil {
+		logger.Errorf("orchestrator: failed to list child jobs of %s: %v", jobID, err)
+		return
+	}
+	for _, child := range children {
+		if isTerminalJobStatus(child.Status) {
+			o.notifyParent(child)
+		}
+	}
+}
+
+// childResult builds a parent step's output from its finished child job
+func (o *Orchestrator) childResult(child *state.Job) (*StepResult, error) {
+	steps, err := o.repo.ListSteps(child.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list steps of child job %s: %w", child.ID, err)
+	}
+	outputs := map[string]interface{}{}
+	for _, s := range steps {
+		if s.Status == StepStatusSucceeded {
+			outputs[s.Name] = map[string]interface{}(s.Output)
+		}
+	}
+	return &StepResult{Output: map[string]interface{}{
+		"childJobId": child.ID,
+		"status":     child.Status,
+		"output":     outputs,
+	}}, nil
+}
+
+// jobDepth returns how deeply a job is nested below its root job
+func jobDepth(job *state.Job) int {
+	switch d := job.Meta["depth"].(type) {
+	case float64:
+		return int(d)
+	case int:
+		return d
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,87 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"agent-project-manager/internal/state"
+)
+
+func subWorkflows() map[string]state.JSONMap {
+	return map[string]state.JSONMap{
+		"lint": stepDefs(
+			map[string]interface{}{"name": "echo", "type": "echo", "input": map[string]interface{}{"repo": "{{ .input.repo }}"}},
+		),
+		"gated": stepDefs(
+			map[string]interface{}{"name": "gate", "type": StepTypeApproval},
+		),
+		"parent": stepDefs(
+			map[string]interface{}{"name": "checks", "type": StepTypeWorkflow, "workflow": "lint", "input": map[string]interface{}{"repo": "{{ .input.repo }}"}},
+			map[string]interface{}{"name": "after", "type": "echo", "input": map[string]interface{}{"repo": "{{ .steps.checks.output.output.echo.repo }}"}},
+		),
+		"parent-gated": stepDefs(
+			map[string]interface{}{"name": "checks", "type": StepTypeWorkflow, "workflow": "gated"},
+		),
+	}
+}
+
+func TestSubWorkflow(t *testing.T) {
+	o, _ := newTestOrchestrator(t, subWorkflows())
+
+	job := submit(t, o, "parent", state.JSONMap{"repo": "r1"})
+	if job.Status != JobStatusSucceeded {
+		t.Fatalf("parent %s: %s", job.Status, job.Error)
+	}
+	children, err := o.repo.ListChildJobs(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(children) != 1 || children[0].Workflow != "lint" || children[0].Status != JobStatusSucceeded {
+		t.Fatalf("children = %+v, want one succeeded lint job", children)
+	}
+	if got := jobSteps(t, o, job.ID)["after"].Output["repo"]; got != "r1" {
+		t.Errorf("step after the sub-workflow got %v from the child's output, want r1", got)
+	}
+}
+
+func TestSubWorkflowChildFails(t *testing.T) {
+	o, _ := newTestOrchestrator(t, subWorkflows())
+
+	job := submit(t, o, "parent-gated", nil)
+	if job.Status != JobStatusWaitingChild {
+		t.Fatalf("parent %s: %s; want it waiting for its child", job.Status, job.Error)
+	}
+	children, _ := o.repo.ListChildJobs(job.ID)
+	gate := jobSteps(t, o, children[0].ID)["gate"]
+	if _, err := o.DecideApproval(children[0].ID, gate.ID, DecisionRejected, "bob", "no"); err != nil {
+		t.Fatal(err)
+	}
+	drain(o)
+
+	if job = reload(t, o, job.ID); job.Status != JobStatusFailed {
+		t.Errorf("parent %s after its child was rejected, want failed", job.Status)
+	}
+	if step := jobSteps(t, o, job.ID)["checks"]; step.Status != StepStatusFailed {
+		t.Errorf("sub-workflow step %s, want failed", step.Status)
+	}
+}
+
+func TestSubWorkflowCancel(t *testing.T) {
+	o, _ := newTestOrchestrator(t, subWorkflows())
+
+	job := submit(t, o, "parent-gated", nil)
+	if err := o.CancelJob(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	drain(o)
+
+	children, _ := o.repo.ListChildJobs(job.ID)
+	if children[0].Status != JobStatusCancelled {
+		t.Errorf("child %s after cancelling the parent, want cancelled", children[0].Status)
+	}
+	if job = reload(t, o, job.ID); job.Status != JobStatusCancelled {
+		t.Errorf("parent %s, want cancelled", job.Status)
+	}
+	if err := o.CancelJob(job.ID); err != ErrJobFinished {
+		t.Errorf("cancelling twice: error = %v, want ErrJobFinished", err)
+	}
+}
@@ -0,0 +1,415 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"agent-project-manager/internal/logger"
+	"agent-project-manager/internal/state"
+)
+
+// Events a trigger can listen to
+const (
+	TriggerOnJobSucceeded = EventJobSucceeded
+	TriggerOnJobFailed    = EventJobFailed
+	TriggerOnJobCancelled = EventJobCancelled
+	TriggerOnArtifact     = EventArtifactCreated
+	TriggerOnWebhook      = "webhook"
+)
+
+// Trigger firing statuses
+const (
+	FiringStarted = "started" // a job was created
+	FiringSkipped = "skipped" // the maximum trigger depth was reached
+	FiringFailed  = "failed"  // the input mapping or the job could not be created
+)
+
+var (
+	// ErrNoWebhookTrigger is returned for a webhook no published workflow listens to
+	ErrNoWebhookTrigger = errors.New("no trigger listens to this webhook")
+
+	errTriggerConditionFalse = errors.New("trigger condition is false")
+	errTriggerTooDeep        = errors.New("maximum trigger depth reached")
+)
+
+// TriggerDef starts a job of the workflow when a matching event happens, e.g.
+//
+//	{"name": "review-diffs", "on": "artifact.created", "artifactType": "diff",
+//	 "if": "eq .job.workflow \"feature-dev\"",
+//	 "input": {"repo": "{{ .input.repo }}", "artifactId": "{{ .artifact.id }}"}}
+//
+// The condition and the input mapping are templates evaluated against:
+//
+//	.event.type, .event.id            the event
+//	.job.id, .job.workflow            the job the event is about (job and artifact events)
+//	.job.status, .job.error
+//	.input.<key>                      that job's input
+//	.steps.<name>.output.<key>        outputs of its steps
+//	.artifact.id, .artifact.type      the artifact (artifact events)
+//	.artifact.name, .artifact.digest
+//	.payload.<key>                    the request body (webhooks)
+//
+// Only triggers of a workflow's latest published version are active.
+type TriggerDef struct {
+	Name         string                 `json:"name,omitempty"`         // identifies the trigger in the audit trail; defaults to triggers[i]
+	On           string                 `json:"on"`                     // job.succeeded | job.failed | job.cancelled | artifact.created | webhook
+	Workflow     string                 `json:"workflow,omitempty"`     // job and artifact events: only jobs of this workflow
+	ArtifactType string                 `json:"artifactType,omitempty"` // artifact events: only artifacts of this type
+	Webhook      string                 `json:"webhook,omitempty"`      // webhook name, received at POST /v1/webhooks/{name}
+	If           string                 `json:"if,omitempty"`           // condition; the trigger does not fire when it is false
+	Input        map[string]interface{} `json:"input,omitempty"`        // input of the started job
+}
+
+// validate checks a trigger declared at position i
+func (t TriggerDef) validate(i int) []string {
+	var errs []string
+	switch t.On {
+	case TriggerOnJobSucceeded, TriggerOnJobFailed, TriggerOnJobCancelled, TriggerOnArtifact:
+		if t.Webhook != "" {
+			errs = append(errs, fmt.Sprintf("triggers[%d]: webhook is only allowed for webhook triggers", i))
+		}
+	case TriggerOnWebhook:
+		if t.Webhook == "" {
+			errs = append(errs, fmt.Sprintf("triggers[%d]: webhook is required for webhook triggers", i))
+		}
+		if t.Workflow != "" {
+			errs = append(errs, fmt.Sprintf("triggers[%d]: workflow is not allowed for webhook triggers", i))
+		}
+	case "":
+		errs = append(errs, fmt.Sprintf("triggers[%d]: on is required", i))
+	default:
+		errs = append(errs, fmt.Sprintf("triggers[%d]: unknown event %q", i, t.On))
+	}
+	if t.ArtifactType != "" && t.On != TriggerOnArtifact {
+		errs = append(errs, fmt.Sprintf("triggers[%d]: artifactType is only allowed for %s triggers", i, TriggerOnArtifact))
+	}
+	if t.If != "" {
+		if err := checkCondition(t.If); err != nil {
+			errs = append(errs, fmt.Sprintf("triggers[%d]: %v", i, err))
+		}
+	}
+	return errs
+}
+
+// triggerRule is an active trigger of a published workflow version
+type triggerRule struct {
+	name    string
+	version *state.WorkflowVersion
+	def     *Definition
+	trigger TriggerDef
+}
+
+// triggerRules returns the active triggers listening to an event type
+func (o *Orchestrator) triggerRules(on string) ([]triggerRule, error) {
+	workflows, err := o.repo.ListWorkflows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflows: %w", err)
+	}
+	var rules []triggerRule
+	for _, wf := range workflows {
+		wv, err := o.repo.GetLatestWorkflowVersion(wf.Name)
+		if err != nil {
+			continue // never published
+		}
+		def, err := ParseDefinition(wv.Schema)
+		if err != nil {
+			logger.Warnf("orchestrator: ignoring triggers of %s@%d: %v", wv.Workflow, wv.Version, err)
+			continue
+		}
+		for i, t := range def.Triggers {
+			if t.On != on {
+				continue
+			}
+			name := t.Name
+			if name == "" {
+				name = fmt.Sprintf("triggers[%d]", i)
+			}
+			rules = append(rules, triggerRule{name: name, version: wv, def: def, trigger: t})
+		}
+	}
+	return rules, nil
+}
+
+// triggerEvent is an event a trigger may fire on
+type triggerEvent struct {
+	Type     string
+	EventID  string
+	Job      *state.Job // the job the event is about; nil for webhooks
+	Artifact *state.Artifact
+	Webhook  string
+	Payload  map[string]interface{}
+}
+
+// depth is the length of the trigger chain that led to the event's job
+func (ev *triggerEvent) depth() int {
+	if ev.Job == nil {
+		return 0
+	}
+	return triggerDepth(ev.Job)
+}
+
+// triggerDepth is the length of the trigger chain that led to a job. Child jobs of
+// workflow steps carry the depth of their parent, so a chain through a sub-workflow
+// is bounded too.
+func triggerDepth(job *state.Job) int {
+	if meta, ok := job.Meta["trigger"].(map[string]interface{}); ok {
+		return intValue(meta["depth"])
+	}
+	return intValue(job.Meta["triggerDepth"])
+}
+
+// matches reports whether a trigger listens to the event, before its condition is evaluated
+func (ev *triggerEvent) matches(t TriggerDef) bool {
+	switch t.On {
+	case TriggerOnWebhook:
+		return t.Webhook == ev.Webhook
+	case TriggerOnArtifact:
+		if t.ArtifactType != "" && (ev.Artifact == nil || ev.Artifact.Type != t.ArtifactType) {
+			return false
+		}
+	}
+	return t.Workflow == "" || (ev.Job != nil && ev.Job.Workflow == t.Workflow)
+}
+
+// triggerData builds the data trigger templates are evaluated against; see TriggerDef
+func (o *Orchestrator) triggerData(ev *triggerEvent) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	if ev.Job != nil {
+		steps, err := o.repo.ListSteps(ev.Job.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list steps: %w", err)
+		}
+		records := map[string]*state.Step{}
+		for _, s := range steps {
+			records[s.Name] = s
+		}
+		data = templateData(ev.Job, records)
+		job := data["job"].(map[string]interface{})
+		job["status"] = ev.Job.Status
+		job["error"] = ev.Job.Error
+	}
+	data["event"] = map[string]interface{}{"type": ev.Type, "id": ev.EventID}
+	if ev.Artifact != nil {
+		data["artifact"] = map[string]interface{}{
+			"id":     ev.Artifact.ID,
+			"type":   ev.Artifact.Type,
+			"name":   ev.Artifact.Name,
+			"digest": ev.Artifact.Digest,
+		}
+	}
+	if ev.Webhook != "" {
+		data["payload"] = ev.Payload
+	}
+	return data, nil
+}
+
+// receiveTriggerEvent queues the events triggers listen to for the trigger loop
+func (o *Orchestrator) receiveTriggerEvent(event *state.Event) {
+	switch event.Type {
+	case TriggerOnJobSucceeded, TriggerOnJobFailed, TriggerOnJobCancelled, TriggerOnArtifact:
+	default:
+		return
+	}
+	o.triggerMu.Lock()
+	o.triggerEvents = append(o.triggerEvents, event)
+	o.triggerMu.Unlock()
+	select {
+	case o.triggerReady <- struct{}{}:
+	default:
+	}
+}
+
+// triggerLoop fires triggers for queued events until the context is cancelled.
+// Events still queued when the orchestrator stops are dropped.
+func (o *Orchestrator) triggerLoop(ctx context.Context) {
+	defer o.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-o.triggerReady:
+		}
+		for {
+			o.triggerMu.Lock()
+			events := o.triggerEvents
+			o.triggerEvents = nil
+			o.triggerMu.Unlock()
+			if len(events) == 0 {
+				break
+			}
+			for _, event := range events {
+				if ctx.Err() != nil {
+					return
+				}
+				o.handleTriggerEvent(event)
+			}
+		}
+	}
+}
+
+// handleTriggerEvent fires every trigger that matches a job or artifact event
+func (o *Orchestrator) handleTriggerEvent(event *state.Event) {
+	rules, err := o.triggerRules(event.Type)
+	if err != nil {
+		logger.Errorf("orchestrator: failed to load triggers for %s event %s: %v", event.Type, event.ID, err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+
+	ev := &triggerEvent{Type: event.Type, EventID: event.ID}
+	if ev.Job, err = o.repo.GetJob(event.JobID); err != nil {
+		logger.Errorf("orchestrator: failed to load job %s for %s event %s: %v", event.JobID, event.Type, event.ID, err)
+		return
+	}
+	if event.Type == TriggerOnArtifact {
+		id, _ := event.Data["artifactId"].(string)
+		if ev.Artifact, err = o.repo.GetArtifact(id); err != nil {
+			logger.Errorf("orchestrator: failed to load artifact %s for event %s: %v", id, event.ID, err)
+			return
+		}
+	}
+
+	for _, rule := range rules {
+		if ev.matches(rule.trigger) {
+			o.fireTrigger(rule, ev)
+		}
+	}
+}
+
+// Webhook fires the triggers listening to a named webhook.
+// It returns the firings, including those that did not start a job.
+func (o *Orchestrator) Webhook(name string, payload map[string]interface{}) ([]*state.TriggerFiring, error) {
+	rules, err := o.triggerRules(TriggerOnWebhook)
+	if err != nil {
+		return nil, err
+	}
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	ev := &triggerEvent{Type: TriggerOnWebhook, Webhook: name, Payload: payload}
+
+	firings := []*state.TriggerFiring{}
+	listened := false
+	for _, rule := range rules {
+		if !ev.matches(rule.trigger) {
+			continue
+		}
+		listened = true
+		if firing := o.fireTrigger(rule, ev); firing != nil {
+			firings = append(firings, firing)
+		}
+	}
+	if !listened {
+		return nil, ErrNoWebhookTrigger
+	}
+	return firings, nil
+}
+
+// fireTrigger starts a job for a trigger that matched an event and records the firing.
+// It returns nil when the trigger's condition is false; such non-firings are not recorded.
+func (o *Orchestrator) fireTrigger(rule triggerRule, ev *triggerEvent) *state.TriggerFiring {
+	firing := &state.TriggerFiring{
+		ID:              state.NewUUID(),
+		Workflow:        rule.version.Workflow,
+		WorkflowVersion: rule.version.Version,
+		TriggerName:     rule.name,
+		EventType:       ev.Type,
+		EventID:         ev.EventID,
+		Webhook:         ev.Webhook,
+		Depth:           ev.depth() + 1,
+	}
+	if ev.Job != nil {
+		firing.SourceJobID = ev.Job.ID
+	}
+	if ev.Artifact != nil {
+		firing.ArtifactID = ev.Artifact.ID
+	}
+
+	job, err := o.startTriggeredJob(rule, ev, firing)
+	switch {
+	case errors.Is(err, errTriggerConditionFalse):
+		return nil
+	case errors.Is(err, errTriggerTooDeep):
+		firing.Status = FiringSkipped
+		firing.Error = err.Error()
+		logger.Warnf("orchestrator: trigger %s of %s not fired for %s: %v", rule.name, firing.Workflow, ev.Type, err)
+	case err != nil:
+		firing.Status = FiringFailed
+		firing.Error = err.Error()
+		logger.Errorf("orchestrator: trigger %s of %s failed for %s: %v", rule.name, firing.Workflow, ev.Type, err)
+	default:
+		firing.Status = FiringStarted
+		firing.JobID = job.ID
+		logger.Infof("orchestrator: trigger %s started job %s of %s@%d", rule.name, job.ID, firing.Workflow, firing.WorkflowVersion)
+	}
+
+	if err := o.repo.CreateTriggerFiring(firing); err != nil {
+		logger.Errorf("orchestrator: failed to record firing of trigger %s: %v", rule.name, err)
+	}
+	return firing
+}
+
+// startTriggeredJob evaluates a trigger's condition and input mapping and creates the job
+func (o *Orchestrator) startTriggeredJob(rule triggerRule, ev *triggerEvent, firing *state.TriggerFiring) (*state.Job, error) {
+	data, err := o.triggerData(ev)
+	if err != nil {
+		return nil, err
+	}
+	if rule.trigger.If != "" {
+		ok, err := evalCondition(rule.trigger.If, data)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errTriggerConditionFalse
+		}
+	}
+	if firing.Depth > o.opts.MaxTriggerDepth {
+		return nil, fmt.Errorf("%w (%d)", errTriggerTooDeep, o.opts.MaxTriggerDepth)
+	}
+
+	input, err := resolveInput(rule.trigger.Input, data)
+	if err != nil {
+		return nil, err
+	}
+	validated, verrs, err := rule.def.ValidateInput(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(verrs) > 0 {
+		return nil, &InvalidInputError{Errors: verrs}
+	}
+
+	job := &state.Job{
+		Workflow:        rule.version.Workflow,
+		WorkflowVersion: rule.version.Version,
+		Status:          JobStatusQueued,
+		Input:           state.JSONMap(validated),
+		Meta: state.JSONMap{"trigger": map[string]interface{}{
+			"firingId":    firing.ID,
+			"name":        rule.name,
+			"event":       ev.Type,
+			"sourceJobId": firing.SourceJobID,
+			"depth":       firing.Depth,
+		}},
+	}
+	if err := o.repo.CreateJob(job); err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+	o.emit(job.ID, "", EventJobTriggered, "", map[string]interface{}{
+		"firingId":    firing.ID,
+		"trigger":     rule.name,
+		"event":       ev.Type,
+		"eventId":     ev.EventID,
+		"sourceJobId": firing.SourceJobID,
+		"webhook":     ev.Webhook,
+		"depth":       firing.Depth,
+	})
+	if err := o.Enqueue(job.ID); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
@@ -0,0 +1,168 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"agent-project-manager/internal/state"
+)
+
+func triggerWorkflows() map[string]state.JSONMap {
+	return map[string]state.JSONMap{
+		"codegen": stepDefs(map[string]interface{}{"name": "gen", "type": "gen"}),
+		"review": {
+			"inputSchema": map[string]interface{}{
+				"type": "object", "required": []interface{}{"from"},
+				"properties": map[string]interface{}{"from": map[string]interface{}{"type": "string"}},
+			},
+			"steps": []interface{}{map[string]interface{}{"name": "echo", "type": "echo", "input": map[string]interface{}{"code": "{{ .input.code }}"}}},
+			"triggers": []interface{}{map[string]interface{}{
+				"name": "after-codegen", "on": TriggerOnJobSucceeded, "workflow": "codegen",
+				"input": map[string]interface{}{"from": "{{ .job.id }}", "code": "{{ .steps.gen.output.code }}"},
+			}},
+		},
+		"diffs": {
+			"steps": []interface{}{map[string]interface{}{"name": "echo", "type": "echo"}},
+			"triggers": []interface{}{
+				map[string]interface{}{"on": TriggerOnArtifact, "artifactType": "diff", "input": map[string]interface{}{"artifact": "{{ .artifact.id }}"}},
+				map[string]interface{}{"on": TriggerOnArtifact, "artifactType": "zip"},
+			},
+		},
+		"loop": {
+			"steps": []interface{}{map[string]interface{}{"name": "echo", "type": "echo"}},
+			"triggers": []interface{}{
+				map[string]interface{}{"name": "again", "on": TriggerOnJobSucceeded, "workflow": "loop"},
+				map[string]interface{}{"name": "kick", "on": TriggerOnWebhook, "webhook": "kick", "if": `eq .payload.go "yes"`},
+			},
+		},
+	}
+}
+
+func triggerOrchestrator(t *testing.T) (*Orchestrator, *fakeRepo) {
+	t.Helper()
+	o, repo := newTestOrchestrator(t, triggerWorkflows())
+	o.opts.MaxTriggerDepth = 3
+	o.Register("gen", StepExecutorFunc(func(ctx context.Context, sc *StepContext) (*StepResult, error) {
+		diff := &state.Artifact{JobID: sc.Job.ID, Name: "change.diff", Type: "diff"}
+		if err := sc.Repo.CreateArtifact(diff); err != nil {
+			return nil, err
+		}
+		return &StepResult{Output: map[string]interface{}{"code": "package main"}, Artifacts: []string{diff.ID}}, nil
+	}))
+	return o, repo
+}
+
+func TestTriggerOnEvents(t *testing.T) {
+	o, repo := triggerOrchestrator(t)
+
+	source := submit(t, o, "codegen", nil)
+	started := map[string]*state.TriggerFiring{}
+	for _, f := range repo.firings {
+		if f.Status != FiringStarted || f.SourceJobID != source.ID || f.Depth != 1 {
+			t.Errorf("firing %+v", f)
+		}
+		started[f.Workflow] = f
+	}
+	if len(repo.firings) != 2 || started["review"] == nil || started["diffs"] == nil {
+		t.Fatalf("%d firings %v; want review after the job and diffs after its diff artifact", len(repo.firings), started)
+	}
+	if started["review"].TriggerName != "after-codegen" || started["diffs"].TriggerName != "triggers[0]" {
+		t.Errorf("fired triggers %q and %q", started["review"].TriggerName, started["diffs"].TriggerName)
+	}
+
+	review := reload(t, o, started["review"].JobID)
+	if review.Status != JobStatusSucceeded || review.Input["from"] != source.ID || review.Input["code"] != "package main" {
+		t.Errorf("triggered review job %s with input %v", review.Status, review.Input)
+	}
+	if trigger, _ := review.Meta["trigger"].(map[string]interface{}); trigger["sourceJobId"] != source.ID {
+		t.Errorf("triggered job meta %v", review.Meta)
+	}
+	diffs := reload(t, o, started["diffs"].JobID)
+	if diffs.Input["artifact"] != started["diffs"].ArtifactID {
+		t.Errorf("diffs job input %v, want the artifact %s", diffs.Input, started["diffs"].ArtifactID)
+	}
+}
+
+func TestTriggerWebhookAndDepth(t *testing.T) {
+	o, repo := triggerOrchestrator(t)
+
+	if _, err := o.Webhook("nobody", nil); !errors.Is(err, ErrNoWebhookTrigger) {
+		t.Errorf("unknown webhook: error = %v, want ErrNoWebhookTrigger", err)
+	}
+	if firings, err := o.Webhook("kick", map[string]interface{}{"go": "no"}); err != nil || len(firings) != 0 {
+		t.Errorf("webhook with a false condition = %v, %v; want no firing", firings, err)
+	}
+	firings, err := o.Webhook("kick", map[string]interface{}{"go": "yes"})
+	if err != nil || len(firings) != 1 || firings[0].Status != FiringStarted {
+		t.Fatalf("webhook = %v, %v; want one started job", firings, err)
+	}
+	drain(o)
+
+	// The loop workflow triggers itself until the chain is MaxTriggerDepth jobs long
+	var depths []int
+	for _, f := range repo.firings {
+		depths = append(depths, f.Depth)
+		if f.Depth > o.opts.MaxTriggerDepth && (f.Status != FiringSkipped || f.JobID != "") {
+			t.Errorf("firing beyond the maximum depth: %+v", f)
+		}
+	}
+	if len(repo.firings) != 4 || repo.firings[3].Status != FiringSkipped {
+		t.Errorf("firings at depths %v; want the chain to stop after depth %d", depths, o.opts.MaxTriggerDepth)
+	}
+}
+
+func TestTriggerThroughSubWorkflow(t *testing.T) {
+	// outer runs inner as a step and is triggered by inner finishing, so every child
+	// starts outer again; the chain must still stop at MaxTriggerDepth
+	o, repo := newTestOrchestrator(t, map[string]state.JSONMap{
+		"inner": stepDefs(map[string]interface{}{"name": "echo", "type": "echo"}),
+		"outer": {
+			"steps": []interface{}{map[string]interface{}{"name": "run", "type": StepTypeWorkflow, "workflow": "inner"}},
+			"triggers": []interface{}{
+				map[string]interface{}{"name": "again", "on": TriggerOnJobSucceeded, "workflow": "inner"},
+				map[string]interface{}{"name": "kick", "on": TriggerOnWebhook, "webhook": "kick"},
+			},
+		},
+	})
+	o.opts.MaxTriggerDepth = 3
+
+	if _, err := o.Webhook("kick", nil); err != nil {
+		t.Fatal(err)
+	}
+	drain(o)
+
+	var depths []int
+	for _, f := range repo.firings {
+		depths = append(depths, f.Depth)
+	}
+	if len(repo.firings) != 4 || repo.firings[3].Depth != 4 || repo.firings[3].Status != FiringSkipped {
+		t.Errorf("firings at depths %v; want the chain to stop after depth %d", depths, o.opts.MaxTriggerDepth)
+	}
+}
+
+func TestTriggerValidation(t *testing.T) {
+	def, err := ParseDefinition(state.JSONMap{
+		"steps": []interface{}{map[string]interface{}{"name": "echo", "type": "echo"}},
+		"triggers": []interface{}{
+			map[string]interface{}{"on": TriggerOnWebhook},
+			map[string]interface{}{"on": "job.started"},
+			map[string]interface{}{"on": TriggerOnJobFailed, "artifactType": "diff"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{
+		"triggers[0]: webhook is required for webhook triggers":                   true,
+		`triggers[1]: unknown event "job.started"`:                                true,
+		"triggers[2]: artifactType is only allowed for artifact.created triggers": true,
+	}
+	errs := def.Validate()
+	for _, e := range errs {
+		delete(want, e)
+	}
+	if len(want) > 0 {
+		t.Errorf("validation errors %v lack %v", errs, want)
+	}
+}
@@ -0,0 +1,295 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"agent-project-manager/internal/jsonschema"
+	"agent-project-manager/internal/state"
+)
+
+// Step types understood by the orchestrator
+const (
+	StepTypeApproval = "approval"
+	StepTypeWorkflow = "workflow"
+	StepTypeSignal   = "wait_for_signal"
+)
+
+// Approval timeout decisions
+const (
+	DecisionApproved = "approved"
+	DecisionRejected = "rejected"
+)
+
+// Signal timeout behaviours
+const (
+	SignalTimeoutFail     = "fail"     // fail the step and the job
+	SignalTimeoutContinue = "continue" // succeed with {"timedOut": true} as output
+)
+
+// Definition is a parsed workflow definition.
+// It is stored in the workflow's schema column, for example:
+//
+//	{
+//	  "inputSchema": {
+//	    "type": "object",
+//	    "required": ["repo"],
+//	    "properties": {"repo": {"type": "string"}, "branch": {"type": "string", "default": "main"}}
+//	  },
+//	  "steps": [
+//	    {"name": "codegen", "type": "llm", "input": {"prompt": "Implement the change in {{ .input.repo }}"}},
+//	    {"name": "checks", "type": "workflow", "workflow": "lint-test-review",
+//	     "input": {"repo": "{{ .input.repo }}"}},
+//	    {"name": "ci", "type": "wait_for_signal", "signal": "ci-passed", "timeout": "2h"},
+//	    {"name": "signoff", "type": "approval", "timeout": "24h", "onTimeout": "rejected",
+//	     "if": "ne .input.branch \"main\""}
+//	  ],
+//	  "triggers": [
+//	    {"on": "job.succeeded", "workflow": "feature-dev", "input": {"repo": "{{ .input.repo }}"}}
+//	  ]
+//	}
+//
+// String values in a step's input may use Go templates; see templateData.
+// triggers start jobs of the workflow when events happen; see TriggerDef.
+// inputSchema is an optional JSON Schema for the job input; see the jsonschema package.
+// cache: true memoizes every step's result; a step may override it with its own cache flag.
+type Definition struct {
+	InputSchema map[string]interface{} `json:"inputSchema,omitempty"`
+	Cache       bool                   `json:"cache,omitempty"`
+	Steps       []StepDef              `json:"steps"`
+	Triggers    []TriggerDef           `json:"triggers,omitempty"`
+}
+
+// StepDef describes a single step of a workflow
+type StepDef struct {
+	Name      string                 `json:"name"`
+	Type      string                 `json:"type"`
+	DependsOn []string               `json:"dependsOn,omitempty"`
+	If        string                 `json:"if,omitempty"` // condition; the step is skipped when it is false
+	Input     map[string]interface{} `json:"input,omitempty"`
+	Cache     *bool                  `json:"cache,omitempty"` // overrides the workflow's cache setting
+
+	// What the step runs; informational for most step types and reported by plans
+	Agent    string `json:"agent,omitempty"`
+	Tool     string `json:"tool,omitempty"`
+	Provider string `json:"provider,omitempty"` // named LLM provider from llm.providers; empty uses the default
+	Model    string `json:"model,omitempty"`    // LLM model, e.g. "gpt-4o-mini"; checked with the provider before the job runs
+
+	// Fallbacks are tried in order when the step's provider fails or its circuit breaker
+	// is open. Without provider, model or fallbacks the agent's route from llm.routes applies.
+	// Provider, model and fallbacks may use templates like the input, e.g. "{{ .input.model }}".
+	Fallbacks []ModelRef `json:"fallbacks,omitempty"`
+
+	// Compensate undoes the step's effects if the job later fails or is cancelled
+	Compensate *Compensation `json:"compensate,omitempty"`
+
+	// Approval and signal steps
+	Timeout   string `json:"timeout,omitempty"`   // e.g. "24h"; empty waits forever
+	OnTimeout string `json:"onTimeout,omitempty"` // approval: approved | rejected (default); signal: fail (default) | continue
+
+	// Signal steps
+	Signal string `json:"signal,omitempty"` // name of the signal to wait for; defaults to the step name
+
+	// Sub-workflow steps
+	Workflow string `json:"workflow,omitempty"` // "name" or "name@version"; Input becomes the child's input
+}
+
+// ModelRef names an LLM provider and model; empty fields mean the defaults
+type ModelRef struct {
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+}
+
+// Models returns the step's LLM route: its provider and model followed by its fallbacks.
+// It is empty when the step names none of them.
+func (s StepDef) Models() []ModelRef {
+	if s.Provider == "" && s.Model == "" && len(s.Fallbacks) == 0 {
+		return nil
+	}
+	return append([]ModelRef{{Provider: s.Provider, Model: s.Model}}, s.Fallbacks...)
+}
+
+// Compensation is the action that undoes a completed step, e.g. deleting the branch it created.
+// Its input may use the same templates as step input, including the step's own output.
+type Compensation struct {
+	Type  string                 `json:"type"`
+	Input map[string]interface{} `json:"input,omitempty"`
+}
+
+// ParseWorkflowRef splits a workflow reference of the form "name" or "name@version".
+// A zero version means the latest published version.
+func ParseWorkflowRef(ref string) (string, int, error) {
+	name, v, pinned := strings.Cut(ref, "@")
+	if name == "" {
+		return "", 0, fmt.Errorf("invalid workflow reference %q", ref)
+	}
+	if !pinned {
+		return name, 0, nil
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil || version <= 0 {
+		return "", 0, fmt.Errorf("invalid workflow version in %q", ref)
+	}
+	return name, version, nil
+}
+
+// ParseDefinition decodes a workflow definition from its stored schema
+func ParseDefinition(schema state.JSONMap) (*Definition, error) {
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode workflow definition: %w", err)
+	}
+	def := &Definition{}
+	if err := json.Unmarshal(raw, def); err != nil {
+		return nil, fmt.Errorf("invalid workflow definition: %w", err)
+	}
+	return def, nil
+}
+
+// Validate checks the definition for structural errors
+func (d *Definition) Validate() []string {
+	var errs []string
+	if len(d.Steps) == 0 {
+		errs = append(errs, "workflow must declare at least one step")
+	}
+	if d.InputSchema != nil {
+		if _, err := jsonschema.Compile(d.InputSchema); err != nil {
+			errs = append(errs, "inputSchema: "+err.Error())
+		}
+	}
+
+	seen := map[string]bool{}
+	for i, s := range d.Steps {
+		if s.Name == "" {
+			errs = append(errs, fmt.Sprintf("steps[%d]: name is required", i))
+			continue
+		}
+		if strings.HasPrefix(s.Name, compensationPrefix) {
+			errs = append(errs, fmt.Sprintf("steps[%d]: step names starting with %q are reserved", i, compensationPrefix))
+		}
+		if seen[s.Name] {
+			errs = append(errs, fmt.Sprintf("steps[%d]: duplicate step name %q", i, s.Name))
+		}
+		seen[s.Name] = true
+	}
+
+	for i, s := range d.Steps {
+		if s.Type == "" {
+			errs = append(errs, fmt.Sprintf("steps[%d]: type is required", i))
+		}
+		for _, dep := range s.DependsOn {
+			if !seen[dep] {
+				errs = append(errs, fmt.Sprintf("steps[%d]: unknown dependency %q", i, dep))
+			}
+		}
+		if s.If != "" {
+			if err := checkCondition(s.If); err != nil {
+				errs = append(errs, fmt.Sprintf("steps[%d]: %v", i, err))
+			}
+		}
+		if s.Cache != nil && *s.Cache && !cacheableStepType(s.Type) {
+			errs = append(errs, fmt.Sprintf("steps[%d]: %s steps cannot be cached", i, s.Type))
+		}
+		if c := s.Compensate; c != nil {
+			if c.Type == "" {
+				errs = append(errs, fmt.Sprintf("steps[%d]: compensate.type is required", i))
+			} else if isWaitingStepType(c.Type) {
+				errs = append(errs, fmt.Sprintf("steps[%d]: %s steps cannot be used to compensate", i, c.Type))
+			}
+		}
+		if s.Type == StepTypeWorkflow {
+			if s.Workflow == "" {
+				errs = append(errs, fmt.Sprintf("steps[%d]: workflow is required for workflow steps", i))
+			} else if _, _, err := ParseWorkflowRef(s.Workflow); err != nil {
+				errs = append(errs, fmt.Sprintf("steps[%d]: %v", i, err))
+			}
+		}
+		if s.Type == StepTypeApproval || s.Type == StepTypeSignal {
+			if s.Timeout != "" {
+				if _, err := time.ParseDuration(s.Timeout); err != nil {
+					errs = append(errs, fmt.Sprintf("steps[%d]: invalid timeout %q", i, s.Timeout))
+				}
+			}
+		}
+		if s.Type == StepTypeApproval {
+			if s.OnTimeout != "" && s.OnTimeout != DecisionApproved && s.OnTimeout != DecisionRejected {
+				errs = append(errs, fmt.Sprintf("steps[%d]: onTimeout must be %q or %q", i, DecisionApproved, DecisionRejected))
+			}
+		}
+		if s.Type == StepTypeSignal {
+			if s.OnTimeout != "" && s.OnTimeout != SignalTimeoutFail && s.OnTimeout != SignalTimeoutContinue {
+				errs = append(errs, fmt.Sprintf("steps[%d]: onTimeout must be %q or %q", i, SignalTimeoutFail, SignalTimeoutContinue))
+			}
+		}
+	}
+
+	for i, t := range d.Triggers {
+		errs = append(errs, t.validate(i)...)
+	}
+
+	if len(errs) == 0 {
+		if _, err := d.Order(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	return errs
+}
+
+// Step returns the step definition with the given name
+func (d *Definition) Step(name string) (StepDef, bool) {
+	for _, s := range d.Steps {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return StepDef{}, false
+}
+
+// Dependencies returns the steps a step waits for.
+// A step without an explicit dependsOn list follows the step declared before it.
+func (d *Definition) Dependencies(i int) []string {
+	s := d.Steps[i]
+	if s.DependsOn != nil {
+		return s.DependsOn
+	}
+	if i == 0 {
+		return nil
+	}
+	return []string{d.Steps[i-1].Name}
+}
+
+// Order returns the steps in execution order, keeping declaration order
+// between steps that do not depend on each other
+func (d *Definition) Order() ([]StepDef, error) {
+	done := map[string]bool{}
+	ordered := make([]StepDef, 0, len(d.Steps))
+
+	for len(ordered) < len(d.Steps) {
+		progressed := false
+		for i, s := range d.Steps {
+			if done[s.Name] {
+				continue
+			}
+			ready := true
+			for _, dep := range d.Dependencies(i) {
+				if !done[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				done[s.Name] = true
+				ordered = append(ordered, s)
+				progressed = true
+			}
+		}
+		if !progressed {
+			return nil, fmt.Errorf("workflow steps contain a dependency cycle")
+		}
+	}
+
+	return ordered, nil
+}
@@ -0,0 +1,228 @@
+// Package promptdir keeps the prompt registry in sync with prompt templates stored as
+// YAML files in a directory, typically checked into git next to the workflows using them.
+//
+// Each *.yaml or *.yml file holds one prompt:
+//
+//	name: review               # defaults to the file name without extension
+//	description: Review a patch
+//	variables:
+//	  - name: patch
+//	    required: true
+//	  - name: language
+//	    default: Go
+//	template: |
+//	  Review this {{ .language }} patch:
+//	  {{ .patch }}
+//
+// The directory is scanned periodically; a file whose content hash changed is validated and
+// published as the prompt's next version when its description, variables or template differ
+// from the latest one. Invalid files are logged and skipped, so the last good version stays
+// current. Published versions are immutable; removing a file leaves them in place.
+package promptdir
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"agent-project-manager/internal/logger"
+	"agent-project-manager/internal/prompts"
+	"agent-project-manager/internal/state"
+)
+
+// Options configures a Registry
+type Options struct {
+	Dir          string        // directory holding the prompt files
+	ScanInterval time.Duration // how often to rescan; default 10s
+}
+
+// Registry syncs a directory of prompt files into the database
+type Registry struct {
+	repo  state.Repository
+	opts  Options
+	mu    sync.Mutex
+	files map[string]fileState // by path
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// fileState remembers the last content of a file that was processed
+type fileState struct {
+	hash   string
+	prompt string // empty when the file was invalid
+}
+
+// promptFile is the content of a prompt file
+type promptFile struct {
+	Name        string                 `yaml:"name"`
+	Description string                 `yaml:"description"`
+	Variables   []state.PromptVariable `yaml:"variables"`
+	Template    string                 `yaml:"template"`
+}
+
+// New creates a registry; call Sync or Start to load the directory
+func New(repo state.Repository, opts Options) *Registry {
+	if opts.ScanInterval <= 0 {
+		opts.ScanInterval = 10 * time.Second
+	}
+	return &Registry{
+		repo:  repo,
+		opts:  opts,
+		files: map[string]fileState{},
+	}
+}
+
+// Start syncs the directory once and then keeps rescanning it until Stop is called
+func (r *Registry) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.Sync()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.opts.ScanInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.Sync()
+			}
+		}
+	}()
+	logger.Infof("promptdir: watching %s every %v", r.opts.Dir, r.opts.ScanInterval)
+}
+
+// Stop stops rescanning and waits for a scan in progress to finish
+func (r *Registry) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+}
+
+// Sync processes every file whose content changed since the last scan.
+// Problems are logged per file; one bad file never blocks the others.
+func (r *Registry) Sync() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	paths, err := r.list()
+	if err != nil {
+		logger.Errorf("promptdir: failed to scan %s: %v", r.opts.Dir, err)
+		return
+	}
+
+	// Names claimed by files that are unchanged, so a changed file cannot take them over
+	owners := map[string]string{}
+	for _, path := range paths {
+		if fs, ok := r.files[path]; ok && fs.prompt != "" {
+			owners[fs.prompt] = path
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, path := range paths {
+		seen[path] = true
+		content, err := os.ReadFile(path)
+		if err != nil {
+			logger.Errorf("promptdir: failed to read %s: %v", path, err)
+			continue
+		}
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])
+		if r.files[path].hash == hash {
+			continue
+		}
+
+		name, err := r.apply(path, content, owners)
+		if err != nil {
+			// Remember the hash so the error is logged once per change, not on every scan
+			logger.Errorf("promptdir: %s: %v; keeping the last good version", path, err)
+			r.files[path] = fileState{hash: hash}
+			continue
+		}
+		owners[name] = path
+		r.files[path] = fileState{hash: hash, prompt: name}
+	}
+
+	for path, fs := range r.files {
+		if !seen[path] {
+			if fs.prompt != "" {
+				logger.Warnf("promptdir: %s was removed; the versions of prompt %s stay registered", path, fs.prompt)
+			}
+			delete(r.files, path)
+		}
+	}
+}
+
+// list returns the prompt files in the directory in a stable order
+func (r *Registry) list() ([]string, error) {
+	entries, err := os.ReadDir(r.opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		paths = append(paths, filepath.Join(r.opts.Dir, e.Name()))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// apply validates one file and publishes it as a new version when it changed.
+// It returns the prompt name.
+func (r *Registry) apply(path string, content []byte, owners map[string]string) (string, error) {
+	pv, err := parseFile(path, content)
+	if err != nil {
+		return "", err
+	}
+	if owner, ok := owners[pv.Prompt]; ok && owner != path {
+		return "", fmt.Errorf("prompt %s is already defined in %s", pv.Prompt, owner)
+	}
+	if err := prompts.Check(pv); err != nil {
+		return "", fmt.Errorf("invalid prompt %s: %w", pv.Prompt, err)
+	}
+
+	// Publishing an unchanged prompt returns the latest version
+	published, err := r.repo.PublishPrompt(pv)
+	if err != nil {
+		return "", fmt.Errorf("failed to publish prompt %s: %w", pv.Prompt, err)
+	}
+	logger.Infof("promptdir: %s: prompt %s is at version %d", path, pv.Prompt, published.Version)
+	return pv.Prompt, nil
+}
+
+// parseFile decodes a prompt file into the version it describes
+func parseFile(path string, content []byte) (*state.PromptVersion, error) {
+	var f promptFile
+	if err := yaml.Unmarshal(content, &f); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	name := f.Name
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return &state.PromptVersion{
+		Prompt:      name,
+		Description: f.Description,
+		Template:    f.Template,
+		Variables:   f.Variables,
+		Source:      path,
+	}, nil
+}
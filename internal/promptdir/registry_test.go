@@ -0,0 +1,138 @@
+package promptdir
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"agent-project-manager/internal/prompts"
+	"agent-project-manager/internal/state"
+)
+
+// fakeRepo keeps published prompt versions in memory
+type fakeRepo struct {
+	state.Repository
+	versions  map[string][]*state.PromptVersion
+	publishes int
+}
+
+// PublishPrompt adds a version only when the prompt changed, as the database does
+func (f *fakeRepo) PublishPrompt(pv *state.PromptVersion) (*state.PromptVersion, error) {
+	f.publishes++
+	versions := f.versions[pv.Prompt]
+	if n := len(versions); n > 0 {
+		last := versions[n-1]
+		if last.Description == pv.Description && last.Template == pv.Template && reflect.DeepEqual(last.Variables, pv.Variables) {
+			return last, nil
+		}
+	}
+	c := *pv
+	c.Version = len(versions) + 1
+	f.versions[pv.Prompt] = append(versions, &c)
+	return &c, nil
+}
+
+func newTestRegistry(t *testing.T) (*Registry, *fakeRepo, string) {
+	t.Helper()
+	dir := t.TempDir()
+	repo := &fakeRepo{versions: map[string][]*state.PromptVersion{}}
+	return New(repo, Options{Dir: dir}), repo, dir
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSync(t *testing.T) {
+	r, repo, dir := newTestRegistry(t)
+	writeFile(t, dir, "review.yaml", "variables:\n  - name: patch\n    required: true\ntemplate: Review {{ .patch }}\n")
+	writeFile(t, dir, "README.md", "not a prompt")
+
+	r.Sync()
+	versions := repo.versions["review"]
+	if len(versions) != 1 || versions[0].Source != filepath.Join(dir, "review.yaml") {
+		t.Fatalf("versions %v after the first sync, want one from review.yaml", versions)
+	}
+
+	// Unchanged files are not published again
+	r.Sync()
+	if repo.publishes != 1 {
+		t.Errorf("%d publishes after syncing an unchanged directory, want 1", repo.publishes)
+	}
+
+	// An invalid change keeps the last good version and is not retried until the file changes
+	writeFile(t, dir, "review.yaml", "template: Review {{ .patch\n")
+	r.Sync()
+	r.Sync()
+	if len(repo.versions["review"]) != 1 || repo.publishes != 1 {
+		t.Errorf("an invalid file was published: %d versions, %d publishes", len(repo.versions["review"]), repo.publishes)
+	}
+
+	// A second file cannot take over the name of another
+	writeFile(t, dir, "review.yaml", "variables:\n  - name: patch\n    required: true\ntemplate: Review {{ .patch }} carefully\n")
+	writeFile(t, dir, "review.yml", "name: review\ntemplate: hijacked\n")
+	r.Sync()
+	versions = repo.versions["review"]
+	if len(versions) != 2 || versions[1].Template != "Review {{ .patch }} carefully" {
+		t.Fatalf("versions %v after a valid change, want a second one from review.yaml", versions)
+	}
+	if r.files[filepath.Join(dir, "review.yml")].prompt != "" {
+		t.Error("a second file claimed the prompt of review.yaml")
+	}
+
+	// Removing the file leaves the published versions in place
+	os.Remove(filepath.Join(dir, "review.yaml"))
+	r.Sync()
+	if len(repo.versions["review"]) != 2 || len(r.files) != 1 {
+		t.Errorf("after removing the file: %d versions, tracked files %v", len(repo.versions["review"]), r.files)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	pv, err := parseFile("/dir/review.yaml", []byte("description: d\ntemplate: t\n"))
+	if err != nil || pv.Prompt != "review" || pv.Description != "d" || pv.Source != "/dir/review.yaml" {
+		t.Errorf("parseFile = %+v, %v; want prompt review from the file name", pv, err)
+	}
+	if pv, err := parseFile("/dir/review.yaml", []byte("name: other\ntemplate: t\n")); err != nil || pv.Prompt != "other" {
+		t.Errorf("parseFile = %+v, %v; want prompt other", pv, err)
+	}
+	if _, err := parseFile("/dir/review.yaml", []byte("template: [\n")); err == nil || !strings.HasPrefix(err.Error(), "invalid YAML") {
+		t.Errorf("parseFile of broken YAML: error = %v", err)
+	}
+}
+
+// The prompts shipped in configs/prompts must load and render
+func TestConfigPrompts(t *testing.T) {
+	paths, err := filepath.Glob("../../configs/prompts/*.yaml")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no prompt files: %v", err)
+	}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pv, err := parseFile(path, content)
+		if err == nil {
+			err = prompts.Check(pv)
+		}
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		vars := map[string]interface{}{}
+		for _, v := range pv.Variables {
+			if v.Required {
+				vars[v.Name] = "x"
+			}
+		}
+		if _, err := prompts.Render(pv, vars); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+}
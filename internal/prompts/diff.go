@@ -0,0 +1,180 @@
+package prompts
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"agent-project-manager/internal/state"
+)
+
+// diffContext is how many unchanged lines a unified diff shows around each change
+const diffContext = 3
+
+// VersionDiff describes how one prompt version differs from another
+type VersionDiff struct {
+	DescriptionChanged bool
+	VariablesAdded     []string
+	VariablesRemoved   []string
+	VariablesChanged   []string // declared in both with a different description, default or required flag
+	Template           string   // unified diff of the template; empty when it is unchanged
+}
+
+// Diff compares two versions of a prompt
+func Diff(from, to *state.PromptVersion) *VersionDiff {
+	d := &VersionDiff{
+		DescriptionChanged: from.Description != to.Description,
+		VariablesAdded:     []string{},
+		VariablesRemoved:   []string{},
+		VariablesChanged:   []string{},
+	}
+
+	fromVars := map[string]state.PromptVariable{}
+	for _, v := range from.Variables {
+		fromVars[v.Name] = v
+	}
+	toVars := map[string]bool{}
+	for _, v := range to.Variables {
+		toVars[v.Name] = true
+		prev, ok := fromVars[v.Name]
+		switch {
+		case !ok:
+			d.VariablesAdded = append(d.VariablesAdded, v.Name)
+		case !sameVariable(prev, v):
+			d.VariablesChanged = append(d.VariablesChanged, v.Name)
+		}
+	}
+	for _, v := range from.Variables {
+		if !toVars[v.Name] {
+			d.VariablesRemoved = append(d.VariablesRemoved, v.Name)
+		}
+	}
+
+	if from.Template != to.Template {
+		d.Template = UnifiedDiff(
+			fmt.Sprintf("%s@%d", from.Prompt, from.Version),
+			fmt.Sprintf("%s@%d", to.Prompt, to.Version),
+			from.Template, to.Template)
+	}
+	return d
+}
+
+// sameVariable compares two declarations; defaults are compared as JSON
+func sameVariable(a, b state.PromptVariable) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
+
+// lineOp is one line of a line diff
+type lineOp struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+// UnifiedDiff returns a unified diff of two texts, line by line
+func UnifiedDiff(fromName, toName, from, to string) string {
+	ops := diffLines(splitLines(from), splitLines(to))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// A hunk runs from this change to the last change that follows within
+		// at most 2*diffContext unchanged lines, with diffContext lines around it
+		last := i
+		for j := i + 1; j < len(ops) && j-last <= 2*diffContext+1; j++ {
+			if ops[j].kind != ' ' {
+				last = j
+			}
+		}
+		lo, hi := max(i-diffContext, 0), min(last+1+diffContext, len(ops))
+
+		fromStart, toStart := lineNumbers(ops[:lo])
+		fromCount, toCount := lineNumbers(ops[lo:hi])
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(fromStart, fromCount), hunkRange(toStart, toCount))
+		for _, op := range ops[lo:hi] {
+			b.WriteByte(op.kind)
+			b.WriteString(op.text)
+			b.WriteByte('\n')
+		}
+		i = last + 1
+	}
+	return b.String()
+}
+
+// lineNumbers returns how many lines of each side ops cover
+func lineNumbers(ops []lineOp) (int, int) {
+	from, to := 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			from++
+		}
+		if op.kind != '-' {
+			to++
+		}
+	}
+	return from, to
+}
+
+// hunkRange formats the start,count of a hunk header; start is 1-based, or the line
+// before the hunk when it is empty
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+// splitLines splits a text into lines without their line breaks
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines computes a shortest line diff from the longest common subsequence.
+// Prompts are short, so the quadratic table is fine.
+func diffLines(a, b []string) []lineOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]lineOp, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, lineOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, lineOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, lineOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, lineOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, lineOp{'+', b[j]})
+	}
+	return ops
+}
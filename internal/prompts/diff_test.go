@@ -0,0 +1,73 @@
+package prompts
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"agent-project-manager/internal/state"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	var from []string
+	for i := 1; i <= 16; i++ {
+		from = append(from, "l"+strings.Repeat("x", i))
+	}
+	to := append([]string{}, from...)
+	to[1] = "changed"
+	to = append(to, "new")
+
+	want := `--- a
++++ b
+@@ -1,5 +1,5 @@
+ lx
+-lxx
++changed
+ lxxx
+ lxxxx
+ lxxxxx
+@@ -14,3 +14,4 @@
+ lxxxxxxxxxxxxxx
+ lxxxxxxxxxxxxxxx
+ lxxxxxxxxxxxxxxxx
++new
+`
+	if got := UnifiedDiff("a", "b", strings.Join(from, "\n")+"\n", strings.Join(to, "\n")+"\n"); got != want {
+		t.Errorf("diff:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got, want := UnifiedDiff("a", "b", "", "x\n"), "--- a\n+++ b\n@@ -0,0 +1,1 @@\n+x\n"; got != want {
+		t.Errorf("diff from empty = %q, want %q", got, want)
+	}
+	if got, want := UnifiedDiff("a", "b", "same\n", "same\n"), "--- a\n+++ b\n"; got != want {
+		t.Errorf("diff of equal texts = %q, want %q", got, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	from := reviewPrompt()
+	to := reviewPrompt()
+	to.Version = 3
+	to.Description = "Review a patch"
+	to.Variables = []state.PromptVariable{
+		{Name: "patch", Required: true},
+		{Name: "lang", Default: "Rust"},
+		{Name: "focus"},
+	}
+	to.Template = "Review {{ .patch }} in {{ .lang }}, focusing on {{ .focus }}"
+
+	d := Diff(from, to)
+	if !d.DescriptionChanged {
+		t.Error("description change not reported")
+	}
+	if !reflect.DeepEqual(d.VariablesAdded, []string{"focus"}) || !reflect.DeepEqual(d.VariablesRemoved, []string{"extra"}) || !reflect.DeepEqual(d.VariablesChanged, []string{"lang"}) {
+		t.Errorf("variables added %v, removed %v, changed %v", d.VariablesAdded, d.VariablesRemoved, d.VariablesChanged)
+	}
+	if !strings.HasPrefix(d.Template, "--- review@2\n+++ review@3\n@@ -1,1 +1,1 @@\n-Review") {
+		t.Errorf("template diff:\n%s", d.Template)
+	}
+
+	if d := Diff(from, reviewPrompt()); d.DescriptionChanged || len(d.VariablesAdded)+len(d.VariablesRemoved)+len(d.VariablesChanged) != 0 || d.Template != "" {
+		t.Errorf("diff of equal versions: %+v", d)
+	}
+}
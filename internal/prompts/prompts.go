@@ -0,0 +1,160 @@
+// Package prompts renders versioned prompt templates from the prompt registry.
+//
+// A prompt is a text/template body with declared variables, published as immutable
+// numbered versions (see state.PromptRepository). Steps refer to a prompt as
+// "name@version", or as "name" for its latest version:
+//
+//	template: review@3
+//	vars:
+//	  patch: "{{ .steps.diff.output.patch }}"
+//
+// Only declared variables may be passed; required ones must be, optional ones fall back
+// to their default. The template sees them as {{ .name }}.
+package prompts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"agent-project-manager/internal/state"
+)
+
+// namePattern is what prompt names may look like
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.\-]*$`)
+
+// variablePattern is what variable names may look like: identifiers, so templates can say {{ .name }}
+var variablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Ref names a prompt version; Version 0 means the latest
+type Ref struct {
+	Name    string
+	Version int
+}
+
+// ParseRef parses "name@version", "name@latest" or "name"
+func ParseRef(s string) (Ref, error) {
+	name, version, pinned := strings.Cut(strings.TrimSpace(s), "@")
+	if !namePattern.MatchString(name) {
+		return Ref{}, fmt.Errorf("invalid prompt reference %q: want name@version", s)
+	}
+	ref := Ref{Name: name}
+	if !pinned || version == "latest" {
+		return ref, nil
+	}
+	n, err := strconv.Atoi(version)
+	if err != nil || n <= 0 {
+		return Ref{}, fmt.Errorf("invalid prompt reference %q: version must be a positive number or \"latest\"", s)
+	}
+	ref.Version = n
+	return ref, nil
+}
+
+// String formats the reference as "name@version", or the bare name for the latest version
+func (r Ref) String() string {
+	if r.Version == 0 {
+		return r.Name
+	}
+	return fmt.Sprintf("%s@%d", r.Name, r.Version)
+}
+
+// Store looks up prompt versions; both state.Repository and the API's prompt repository are one
+type Store interface {
+	GetPromptVersion(name string, version int) (*state.PromptVersion, error)
+	GetLatestPromptVersion(name string) (*state.PromptVersion, error)
+}
+
+// Resolve looks up the version a reference names
+func Resolve(store Store, ref Ref) (*state.PromptVersion, error) {
+	if ref.Version == 0 {
+		return store.GetLatestPromptVersion(ref.Name)
+	}
+	return store.GetPromptVersion(ref.Name, ref.Version)
+}
+
+// Check validates a prompt before it is published: its name, its declared variables
+// and that the template parses
+func Check(pv *state.PromptVersion) error {
+	if !namePattern.MatchString(pv.Prompt) {
+		return fmt.Errorf("invalid prompt name %q: use letters, digits, '.', '_' and '-'", pv.Prompt)
+	}
+	if strings.TrimSpace(pv.Template) == "" {
+		return errors.New("template is required")
+	}
+	seen := map[string]bool{}
+	for i, v := range pv.Variables {
+		if !variablePattern.MatchString(v.Name) {
+			return fmt.Errorf("variables[%d]: invalid name %q: use letters, digits and '_'", i, v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("variables[%d]: %s is declared twice", i, v.Name)
+		}
+		seen[v.Name] = true
+		if v.Required && v.Default != nil {
+			return fmt.Errorf("variables[%d]: required variable %s cannot have a default", i, v.Name)
+		}
+	}
+	if _, err := parse(pv); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Render executes a prompt version with vars. Variables that are not declared, required
+// variables that are missing and template references to unknown variables are errors.
+func Render(pv *state.PromptVersion, vars map[string]interface{}) (string, error) {
+	declared := map[string]bool{}
+	data := map[string]interface{}{}
+	var missing []string
+	for _, v := range pv.Variables {
+		declared[v.Name] = true
+		value, ok := vars[v.Name]
+		switch {
+		case ok:
+			data[v.Name] = value
+		case v.Required:
+			missing = append(missing, v.Name)
+		case v.Default != nil:
+			data[v.Name] = v.Default
+		default:
+			data[v.Name] = ""
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("prompt %s@%d: missing required variables: %s", pv.Prompt, pv.Version, strings.Join(missing, ", "))
+	}
+	var unknown []string
+	for name := range vars {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return "", fmt.Errorf("prompt %s@%d: undeclared variables: %s", pv.Prompt, pv.Version, strings.Join(unknown, ", "))
+	}
+
+	tmpl, err := parse(pv)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("prompt %s@%d: %w", pv.Prompt, pv.Version, err)
+	}
+	return buf.String(), nil
+}
+
+// parse compiles the template of a prompt version
+func parse(pv *state.PromptVersion) (*template.Template, error) {
+	tmpl, err := template.New(pv.Prompt).Option("missingkey=error").Parse(pv.Template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
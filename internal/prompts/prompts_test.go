@@ -0,0 +1,114 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+
+	"agent-project-manager/internal/state"
+)
+
+func reviewPrompt() *state.PromptVersion {
+	return &state.PromptVersion{
+		Prompt:   "review",
+		Version:  2,
+		Template: "Review {{ .patch }} in {{ .lang }}{{ if .extra }} {{ .extra }}{{ end }}",
+		Variables: []state.PromptVariable{
+			{Name: "patch", Required: true},
+			{Name: "lang", Default: "Go"},
+			{Name: "extra"},
+		},
+	}
+}
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Ref
+		err  string
+	}{
+		{in: "review@3", want: Ref{Name: "review", Version: 3}},
+		{in: "review", want: Ref{Name: "review"}},
+		{in: " review@latest ", want: Ref{Name: "review"}},
+		{in: "review@0", err: `invalid prompt reference "review@0": version must be a positive number or "latest"`},
+		{in: "review@next", err: `invalid prompt reference "review@next": version must be a positive number or "latest"`},
+		{in: "@1", err: `invalid prompt reference "@1": want name@version`},
+		{in: "a b", err: `invalid prompt reference "a b": want name@version`},
+	}
+	for _, tc := range tests {
+		ref, err := ParseRef(tc.in)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("ParseRef(%q) error = %v, want %q", tc.in, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || ref != tc.want {
+			t.Errorf("ParseRef(%q) = %+v, %v; want %+v", tc.in, ref, err, tc.want)
+		}
+	}
+
+	if s := (Ref{Name: "review", Version: 3}).String(); s != "review@3" {
+		t.Errorf("String() = %q, want review@3", s)
+	}
+	if s := (Ref{Name: "review"}).String(); s != "review" {
+		t.Errorf("String() = %q, want review", s)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := Check(reviewPrompt()); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(pv *state.PromptVersion)
+		err    string
+	}{
+		{"name", func(pv *state.PromptVersion) { pv.Prompt = "a b" }, `invalid prompt name "a b"`},
+		{"empty template", func(pv *state.PromptVersion) { pv.Template = " \n" }, "template is required"},
+		{"variable name", func(pv *state.PromptVersion) { pv.Variables[1].Name = "the-lang" }, `variables[1]: invalid name "the-lang"`},
+		{"duplicate", func(pv *state.PromptVersion) { pv.Variables[2].Name = "patch" }, "variables[2]: patch is declared twice"},
+		{"required default", func(pv *state.PromptVersion) { pv.Variables[0].Default = "x" }, "variables[0]: required variable patch cannot have a default"},
+		{"template syntax", func(pv *state.PromptVersion) { pv.Template = "{{ .patch " }, "invalid template: "},
+	}
+	for _, tc := range tests {
+		pv := reviewPrompt()
+		tc.modify(pv)
+		if err := Check(pv); err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+			t.Errorf("%s: error = %v, want %q", tc.name, err, tc.err)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		vars     map[string]interface{}
+		want     string
+		err      string
+	}{
+		{name: "defaults", vars: map[string]interface{}{"patch": "P"}, want: "Review P in Go"},
+		{name: "all given", vars: map[string]interface{}{"patch": "P", "lang": "Rust", "extra": "carefully"}, want: "Review P in Rust carefully"},
+		{name: "missing required", vars: map[string]interface{}{}, err: "prompt review@2: missing required variables: patch"},
+		{name: "undeclared", vars: map[string]interface{}{"patch": "P", "zzz": 1, "aaa": 2}, err: "prompt review@2: undeclared variables: aaa, zzz"},
+		{name: "unknown in template", template: "{{ .nope }}", vars: map[string]interface{}{"patch": "P"}, err: `prompt review@2: template: review:1:3: executing "review" at <.nope>: map has no entry for key "nope"`},
+	}
+	for _, tc := range tests {
+		pv := reviewPrompt()
+		if tc.template != "" {
+			pv.Template = tc.template
+		}
+		got, err := Render(pv, tc.vars)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("%s: error = %v, want %q", tc.name, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%s: Render = %q, %v; want %q", tc.name, got, err, tc.want)
+		}
+	}
+}
@@ -28,27 +28,29 @@ func (r *ArtifactRepository) CreateArtifact(artifact *state.Artifact) error {
 	}
 	artifact.CreatedAt = time.Now()
 
-	query := `INSERT INTO artifacts (id, job_id, run_id, type, name, size, path, created_at)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
-	_, err := r.db.Exec(query, artifact.ID, artifact.JobID, artifact.RunID,
-		artifact.Type, artifact.Name, artifact.Size, artifact.Path, artifact.CreatedAt)
+	query := `INSERT INTO artifacts (id, job_id, run_id, type, name, size, path, digest, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := r.db.Exec(query, artifact.ID, state.NullIfEmpty(artifact.JobID), state.NullIfEmpty(artifact.RunID),
+		artifact.Type, artifact.Name, artifact.Size, artifact.Path, state.NullIfEmpty(artifact.Digest), artifact.CreatedAt)
 	return err
 }
 
 // GetArtifact retrieves an artifact by ID
 func (r *ArtifactRepository) GetArtifact(id string) (*state.Artifact, error) {
 	artifact := &state.Artifact{}
+	var jobID, runID, digest sql.NullString
 
-	query := `SELECT id, job_id, run_id, type, name, size, path, created_at FROM artifacts WHERE id = $1`
+	query := `SELECT id, job_id, run_id, type, name, size, path, digest, created_at FROM artifacts WHERE id = $1`
 	err := r.db.QueryRow(query, id).Scan(
-		&artifact.ID, &artifact.JobID, &artifact.RunID, &artifact.Type,
-		&artifact.Name, &artifact.Size, &artifact.Path, &artifact.CreatedAt)
+		&artifact.ID, &jobID, &runID, &artifact.Type,
+		&artifact.Name, &artifact.Size, &artifact.Path, &digest, &artifact.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("artifact not found: %s", id)
 		}
 		return nil, err
 	}
+	artifact.JobID, artifact.RunID, artifact.Digest = jobID.String, runID.String, digest.String
 
 	return artifact, nil
 }
@@ -59,7 +61,7 @@ func (r *ArtifactRepository) ListArtifacts(jobID string, runID string, limit int
 		limit = 50
 	}
 
-	query := `SELECT id, job_id, run_id, type, name, size, path, created_at FROM artifacts WHERE 1=1`
+	query := `SELECT id, job_id, run_id, type, name, size, path, digest, created_at FROM artifacts WHERE 1=1`
 	args := []interface{}{}
 	argPos := 1
 
@@ -91,12 +93,14 @@ func (r *ArtifactRepository) ListArtifacts(jobID string, runID string, limit int
 	artifacts := []*state.Artifact{}
 	for rows.Next() {
 		artifact := &state.Artifact{}
+		var jobID, runID, digest sql.NullString
 
-		err := rows.Scan(&artifact.ID, &artifact.JobID, &artifact.RunID, &artifact.Type,
-			&artifact.Name, &artifact.Size, &artifact.Path, &artifact.CreatedAt)
+		err := rows.Scan(&artifact.ID, &jobID, &runID, &artifact.Type,
+			&artifact.Name, &artifact.Size, &artifact.Path, &digest, &artifact.CreatedAt)
 		if err != nil {
 			return nil, "", err
 		}
+		artifact.JobID, artifact.RunID, artifact.Digest = jobID.String, runID.String, digest.String
 
 		artifacts = append(artifacts, artifact)
 	}
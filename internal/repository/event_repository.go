@@ -32,7 +32,7 @@ func (r *EventRepository) CreateEvent(event *state.Event) error {
 
 	query := `INSERT INTO events (id, job_id, step_id, type, message, data, created_at)
 	          VALUES ($1, $2, $3, $4, $5, $6, $7)`
-	_, err := r.db.Exec(query, event.ID, event.JobID, event.StepID, event.Type,
+	_, err := r.db.Exec(query, event.ID, event.JobID, state.NullIfEmpty(event.StepID), event.Type,
 		event.Message, string(dataJSON), event.CreatedAt)
 	return err
 }
@@ -71,12 +71,15 @@ func (r *EventRepository) ListEvents(jobID string, stepID string, limit int) ([]
 	for rows.Next() {
 		event := &state.Event{}
 		var dataJSON string
+		var stepID, message sql.NullString
 
-		err := rows.Scan(&event.ID, &event.JobID, &event.StepID, &event.Type,
-			&event.Message, &dataJSON, &event.CreatedAt)
+		err := rows.Scan(&event.ID, &event.JobID, &stepID, &event.Type,
+			&message, &dataJSON, &event.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
+		event.StepID = stepID.String
+		event.Message = message.String
 
 		json.Unmarshal([]byte(dataJSON), &event.Data)
 		events = append(events, event)
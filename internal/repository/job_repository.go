@@ -12,10 +12,13 @@ import (
 // IJobRepository defines database operations for Jobs
 type IJobRepository interface {
 	CreateJob(job *state.Job) error
+	EnqueueJob(job *state.Job) error
 	GetJob(id string) (*state.Job, error)
 	ListJobs(limit int, cursor string, status string, workflow string) ([]*state.Job, string, error)
 	UpdateJob(job *state.Job) error
 	DeleteJob(id string) error
+	ListChildJobs(parentID string) ([]*state.Job, error)
+	CountJobsByWorkflow(workflow string) (total int, active int, err error)
 }
 
 // JobRepository implements IJobRepository
@@ -25,6 +28,48 @@ type JobRepository struct {
 
 // CreateJob creates a new job in the database
 func (r *JobRepository) CreateJob(job *state.Job) error {
+	query, args := insertJob(job)
+	_, err := r.db.Exec(query, args...)
+	return err
+}
+
+// EnqueueJob creates a new job together with its pending queue item, in one transaction,
+// so a job is never left without an item for the workers to pick up. The job's workflow
+// is share-locked meanwhile, so it cannot be deleted under the new job.
+func (r *JobRepository) EnqueueJob(job *state.Job) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var name string
+	err = tx.QueryRow(`SELECT name FROM workflows WHERE name = $1 AND deleted_at IS NULL FOR SHARE`, job.Workflow).Scan(&name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("workflow %w: %s", state.ErrNotFound, job.Workflow)
+		}
+		return err
+	}
+
+	query, args := insertJob(job)
+	if _, err := tx.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+	now := time.Now()
+	_, err = tx.Exec(`INSERT INTO queue_items (id, job_id, state, data, created_at, updated_at)
+	          VALUES ($1, $2, 'pending', '{}', $3, $4)`, state.NewUUID(), job.ID, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit job: %w", err)
+	}
+	return nil
+}
+
+// insertJob returns the statement creating a job, filling in its ID and timestamps
+func insertJob(job *state.Job) (string, []interface{}) {
 	if job.ID == "" {
 		job.ID = state.NewUUID()
 	}
@@ -35,11 +80,13 @@ func (r *JobRepository) CreateJob(job *state.Job) error {
 	inputJSON, _ := json.Marshal(job.Input)
 	metaJSON, _ := json.Marshal(job.Meta)
 
-	query := `INSERT INTO jobs (id, workflow, status, input, meta, created_at, updated_at, started_at, completed_at, error)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
-	_, err := r.db.Exec(query, job.ID, job.Workflow, job.Status, string(inputJSON), string(metaJSON),
-		job.CreatedAt, job.UpdatedAt, job.StartedAt, job.CompletedAt, job.Error)
-	return err
+	query := `INSERT INTO jobs (id, workflow, status, input, meta, created_at, updated_at, started_at, completed_at, error,
+	          parent_job_id, parent_step_id, workflow_version, rerun_of_job_id, rerun_from_step, matrix_id)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`
+	return query, []interface{}{job.ID, job.Workflow, job.Status, string(inputJSON), string(metaJSON),
+		job.CreatedAt, job.UpdatedAt, job.StartedAt, job.CompletedAt, job.Error,
+		state.NullIfEmpty(job.ParentJobID), state.NullIfEmpty(job.ParentStepID), state.NullIfZero(job.WorkflowVersion),
+		state.NullIfEmpty(job.RerunOfJobID), state.NullIfEmpty(job.RerunFromStep), state.NullIfEmpty(job.MatrixID)}
 }
 
 // GetJob retrieves a job by ID from the database
@@ -47,12 +94,16 @@ func (r *JobRepository) GetJob(id string) (*state.Job, error) {
 	job := &state.Job{}
 	var inputJSON, metaJSON string
 	var startedAt, completedAt sql.NullTime
+	var parentJobID, parentStepID, rerunOfJobID, rerunFromStep, matrixID sql.NullString
+	var workflowVersion sql.NullInt64
 
-	query := `SELECT id, workflow, status, input, meta, created_at, updated_at, started_at, completed_at, error
+	query := `SELECT id, workflow, status, input, meta, created_at, updated_at, started_at, completed_at, error,
+	          parent_job_id, parent_step_id, workflow_version, rerun_of_job_id, rerun_from_step, matrix_id
 	          FROM jobs WHERE id = $1`
 	err := r.db.QueryRow(query, id).Scan(
 		&job.ID, &job.Workflow, &job.Status, &inputJSON, &metaJSON,
-		&job.CreatedAt, &job.UpdatedAt, &startedAt, &completedAt, &job.Error)
+		&job.CreatedAt, &job.UpdatedAt, &startedAt, &completedAt, &job.Error,
+		&parentJobID, &parentStepID, &workflowVersion, &rerunOfJobID, &rerunFromStep, &matrixID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("job not found: %s", id)
@@ -62,6 +113,12 @@ func (r *JobRepository) GetJob(id string) (*state.Job, error) {
 
 	json.Unmarshal([]byte(inputJSON), &job.Input)
 	json.Unmarshal([]byte(metaJSON), &job.Meta)
+	job.ParentJobID = parentJobID.String
+	job.ParentStepID = parentStepID.String
+	job.RerunOfJobID = rerunOfJobID.String
+	job.RerunFromStep = rerunFromStep.String
+	job.MatrixID = matrixID.String
+	job.WorkflowVersion = int(workflowVersion.Int64)
 	if startedAt.Valid {
 		job.StartedAt = &startedAt.Time
 	}
@@ -74,7 +131,8 @@ func (r *JobRepository) GetJob(id string) (*state.Job, error) {
 
 // ListJobs lists jobs from the database with pagination and filtering
 func (r *JobRepository) ListJobs(limit int, cursor string, status string, workflow string) ([]*state.Job, string, error) {
-	query := `SELECT id, workflow, status, input, meta, created_at, updated_at, started_at, completed_at, error
+	query := `SELECT id, workflow, status, input, meta, created_at, updated_at, started_at, completed_at, error,
+	          parent_job_id, parent_step_id, workflow_version, rerun_of_job_id, rerun_from_step, matrix_id
 	          FROM jobs WHERE 1=1`
 	args := []interface{}{}
 	argPos := 1
@@ -109,15 +167,24 @@ func (r *JobRepository) ListJobs(limit int, cursor string, status string, workfl
 		job := &state.Job{}
 		var inputJSON, metaJSON string
 		var startedAt, completedAt sql.NullTime
+		var parentJobID, parentStepID, rerunOfJobID, rerunFromStep, matrixID sql.NullString
+		var workflowVersion sql.NullInt64
 
 		err := rows.Scan(&job.ID, &job.Workflow, &job.Status, &inputJSON, &metaJSON,
-			&job.CreatedAt, &job.UpdatedAt, &startedAt, &completedAt, &job.Error)
+			&job.CreatedAt, &job.UpdatedAt, &startedAt, &completedAt, &job.Error,
+			&parentJobID, &parentStepID, &workflowVersion, &rerunOfJobID, &rerunFromStep, &matrixID)
 		if err != nil {
 			return nil, "", err
 		}
 
 		json.Unmarshal([]byte(inputJSON), &job.Input)
 		json.Unmarshal([]byte(metaJSON), &job.Meta)
+		job.ParentJobID = parentJobID.String
+		job.ParentStepID = parentStepID.String
+		job.RerunOfJobID = rerunOfJobID.String
+		job.RerunFromStep = rerunFromStep.String
+		job.MatrixID = matrixID.String
+		job.WorkflowVersion = int(workflowVersion.Int64)
 		if startedAt.Valid {
 			job.StartedAt = &startedAt.Time
 		}
@@ -145,12 +212,72 @@ func (r *JobRepository) UpdateJob(job *state.Job) error {
 	metaJSON, _ := json.Marshal(job.Meta)
 
 	query := `UPDATE jobs SET workflow = $1, status = $2, input = $3, meta = $4, updated_at = $5, 
-	          started_at = $6, completed_at = $7, error = $8 WHERE id = $9`
+	          started_at = $6, completed_at = $7, error = $8, parent_job_id = $9, parent_step_id = $10,
+	          workflow_version = $11 WHERE id = $12`
 	_, err := r.db.Exec(query, job.Workflow, job.Status, string(inputJSON), string(metaJSON),
-		job.UpdatedAt, job.StartedAt, job.CompletedAt, job.Error, job.ID)
+		job.UpdatedAt, job.StartedAt, job.CompletedAt, job.Error,
+		state.NullIfEmpty(job.ParentJobID), state.NullIfEmpty(job.ParentStepID), state.NullIfZero(job.WorkflowVersion), job.ID)
 	return err
 }
 
+// ListChildJobs lists the jobs started by steps of the given parent job
+func (r *JobRepository) ListChildJobs(parentID string) ([]*state.Job, error) {
+	query := `SELECT id, workflow, status, input, meta, created_at, updated_at, started_at, completed_at, error,
+	          parent_job_id, parent_step_id, workflow_version, rerun_of_job_id, rerun_from_step, matrix_id
+	          FROM jobs WHERE parent_job_id = $1 ORDER BY created_at ASC`
+	rows, err := r.db.Query(query, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*state.Job{}
+	for rows.Next() {
+		job := &state.Job{}
+		var inputJSON, metaJSON string
+		var startedAt, completedAt sql.NullTime
+		var parentJobID, parentStepID, rerunOfJobID, rerunFromStep, matrixID sql.NullString
+		var workflowVersion sql.NullInt64
+
+		err := rows.Scan(&job.ID, &job.Workflow, &job.Status, &inputJSON, &metaJSON,
+			&job.CreatedAt, &job.UpdatedAt, &startedAt, &completedAt, &job.Error,
+			&parentJobID, &parentStepID, &workflowVersion, &rerunOfJobID, &rerunFromStep, &matrixID)
+		if err != nil {
+			return nil, err
+		}
+
+		json.Unmarshal([]byte(inputJSON), &job.Input)
+		json.Unmarshal([]byte(metaJSON), &job.Meta)
+		job.ParentJobID = parentJobID.String
+		job.ParentStepID = parentStepID.String
+		job.RerunOfJobID = rerunOfJobID.String
+		job.RerunFromStep = rerunFromStep.String
+		job.MatrixID = matrixID.String
+		job.WorkflowVersion = int(workflowVersion.Int64)
+		if startedAt.Valid {
+			job.StartedAt = &startedAt.Time
+		}
+		if completedAt.Valid {
+			job.CompletedAt = &completedAt.Time
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// CountJobsByWorkflow counts the jobs referencing a workflow, and how many of them are unfinished
+func (r *JobRepository) CountJobsByWorkflow(workflow string) (int, int, error) {
+	var total, active int
+	query := `SELECT COUNT(*), COUNT(*) FILTER (WHERE status NOT IN ('succeeded', 'failed', 'cancelled'))
+	          FROM jobs WHERE workflow = $1`
+	if err := r.db.QueryRow(query, workflow).Scan(&total, &active); err != nil {
+		return 0, 0, err
+	}
+	return total, active, nil
+}
+
 // DeleteJob deletes a job by ID
 func (r *JobRepository) DeleteJob(id string) error {
 	_, err := r.db.Exec("DELETE FROM jobs WHERE id = $1", id)
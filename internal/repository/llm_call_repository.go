@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"agent-project-manager/internal/state"
+)
+
+// llmUsageGroupColumns are the SQL expressions of the grouping fields
+var llmUsageGroupColumns = map[string][]string{
+	state.LLMUsageByJob:      {"COALESCE(c.job_id, '')"},
+	state.LLMUsageByStep:     {"COALESCE(c.step_id, '')", "COALESCE(s.name, '')"},
+	state.LLMUsageByWorkflow: {"COALESCE(j.workflow, '')"},
+	state.LLMUsageByAgent:    {"COALESCE(c.agent, '')"},
+	state.LLMUsageByProvider: {"c.provider"},
+	state.LLMUsageByModel:    {"COALESCE(c.model, '')"},
+	state.LLMUsageByDay:      {"to_char(c.created_at, 'YYYY-MM-DD')"},
+}
+
+// ILLMCallRepository defines database operations for LLM call records
+type ILLMCallRepository interface {
+	CreateLLMCall(call *state.LLMCall) error
+	ListLLMCalls(filter state.LLMCallFilter, limit int) ([]*state.LLMCall, error)
+	AggregateLLMUsage(filter state.LLMUsageFilter, groupBy []string) ([]*state.LLMUsageGroup, error)
+}
+
+// LLMCallRepository implements ILLMCallRepository
+type LLMCallRepository struct {
+	db *sql.DB
+}
+
+// CreateLLMCall records an LLM call attempt
+func (r *LLMCallRepository) CreateLLMCall(call *state.LLMCall) error {
+	if call.ID == "" {
+		call.ID = state.NewUUID()
+	}
+	call.CreatedAt = time.Now()
+
+	query := `INSERT INTO llm_calls (id, request_id, job_id, step_id, agent, attempt, provider, model,
+	          status, error_kind, error, latency_ms, created_at,
+	          prompt_tokens, completion_tokens, total_tokens, cost_usd)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)`
+	_, err := r.db.Exec(query, call.ID, call.RequestID, state.NullIfEmpty(call.JobID), state.NullIfEmpty(call.StepID),
+		state.NullIfEmpty(call.Agent), call.Attempt, call.Provider, state.NullIfEmpty(call.Model), call.Status,
+		state.NullIfEmpty(call.ErrorKind), state.NullIfEmpty(call.Error), call.LatencyMs, call.CreatedAt,
+		call.PromptTokens, call.CompletionTokens, call.TotalTokens, call.CostUSD)
+	if err != nil {
+		return fmt.Errorf("failed to create LLM call: %w", err)
+	}
+	return nil
+}
+
+// ListLLMCalls lists LLM call attempts in the order they were made
+func (r *LLMCallRepository) ListLLMCalls(filter state.LLMCallFilter, limit int) ([]*state.LLMCall, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	query := `SELECT id, request_id, job_id, step_id, agent, attempt, provider, model, status,
+	          error_kind, error, latency_ms, created_at,
+	          prompt_tokens, completion_tokens, total_tokens, cost_usd
+	          FROM llm_calls WHERE 1=1`
+	args := []interface{}{}
+	argPos := 1
+
+	if filter.JobID != "" {
+		query += fmt.Sprintf(" AND job_id = $%d", argPos)
+		args = append(args, filter.JobID)
+		argPos++
+	}
+	if filter.StepID != "" {
+		query += fmt.Sprintf(" AND step_id = $%d", argPos)
+		args = append(args, filter.StepID)
+		argPos++
+	}
+
+	query += fmt.Sprintf(" ORDER BY created_at, attempt LIMIT $%d", argPos)
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	calls := []*state.LLMCall{}
+	for rows.Next() {
+		call, err := scanLLMCall(rows)
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, call)
+	}
+
+	return calls, nil
+}
+
+// AggregateLLMUsage adds up the LLM calls matching the filter, one group per combination
+// of the groupBy fields (state.LLMUsageByJob, ...). Without groupBy it returns a single group
+// with the totals, all zero when no call matched.
+func (r *LLMCallRepository) AggregateLLMUsage(filter state.LLMUsageFilter, groupBy []string) ([]*state.LLMUsageGroup, error) {
+	var columns []string
+	for _, field := range groupBy {
+		cols, ok := llmUsageGroupColumns[field]
+		if !ok {
+			return nil, fmt.Errorf("unknown LLM usage grouping %q", field)
+		}
+		columns = append(columns, cols...)
+	}
+
+	query := ""
+	args := []interface{}{}
+	argPos := 1
+	if filter.JobTree != "" {
+		query = `WITH RECURSIVE tree(id) AS (
+		           SELECT id FROM jobs WHERE id = $1
+		           UNION ALL SELECT j.id FROM jobs j JOIN tree t ON j.parent_job_id = t.id) `
+		args = append(args, filter.JobTree)
+		argPos++
+	}
+
+	selected := append(append([]string{}, columns...),
+		"COUNT(*) FILTER (WHERE c.status NOT IN ('skipped', 'cached'))",
+		"COALESCE(SUM(c.prompt_tokens), 0)", "COALESCE(SUM(c.completion_tokens), 0)",
+		"COALESCE(SUM(c.total_tokens), 0)", "COALESCE(SUM(c.cost_usd), 0)", "COALESCE(SUM(c.latency_ms), 0)")
+	query += `SELECT ` + strings.Join(selected, ", ") + `
+	          FROM llm_calls c
+	          LEFT JOIN jobs j ON j.id = c.job_id
+	          LEFT JOIN steps s ON s.id = c.step_id
+	          WHERE 1=1`
+
+	if filter.JobTree != "" {
+		query += " AND c.job_id IN (SELECT id FROM tree)"
+	}
+	if filter.Workflow != "" {
+		query += fmt.Sprintf(" AND j.workflow = $%d", argPos)
+		args = append(args, filter.Workflow)
+		argPos++
+	}
+	if filter.Provider != "" {
+		query += fmt.Sprintf(" AND c.provider = $%d", argPos)
+		args = append(args, filter.Provider)
+		argPos++
+	}
+	if filter.Since != nil {
+		query += fmt.Sprintf(" AND c.created_at >= $%d", argPos)
+		args = append(args, *filter.Since)
+		argPos++
+	}
+	if filter.Until != nil {
+		query += fmt.Sprintf(" AND c.created_at < $%d", argPos)
+		args = append(args, *filter.Until)
+		argPos++
+	}
+	if len(columns) > 0 {
+		query += " GROUP BY " + strings.Join(columns, ", ") + " ORDER BY " + strings.Join(columns, ", ")
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate LLM usage: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []*state.LLMUsageGroup{}
+	for rows.Next() {
+		g := &state.LLMUsageGroup{}
+		var dest []interface{}
+		for _, field := range groupBy {
+			switch field {
+			case state.LLMUsageByJob:
+				dest = append(dest, &g.JobID)
+			case state.LLMUsageByStep:
+				dest = append(dest, &g.StepID, &g.StepName)
+			case state.LLMUsageByWorkflow:
+				dest = append(dest, &g.Workflow)
+			case state.LLMUsageByAgent:
+				dest = append(dest, &g.Agent)
+			case state.LLMUsageByProvider:
+				dest = append(dest, &g.Provider)
+			case state.LLMUsageByModel:
+				dest = append(dest, &g.Model)
+			case state.LLMUsageByDay:
+				dest = append(dest, &g.Day)
+			}
+		}
+		dest = append(dest, &g.Calls, &g.PromptTokens, &g.CompletionTokens, &g.TotalTokens, &g.CostUSD, &g.LatencyMs)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+
+	return groups, rows.Err()
+}
+
+// scanLLMCall reads one row of the LLM call listing
+func scanLLMCall(rows *sql.Rows) (*state.LLMCall, error) {
+	call := &state.LLMCall{}
+	var jobID, stepID, agent, model, errorKind, errMsg sql.NullString
+
+	err := rows.Scan(&call.ID, &call.RequestID, &jobID, &stepID, &agent, &call.Attempt, &call.Provider,
+		&model, &call.Status, &errorKind, &errMsg, &call.LatencyMs, &call.CreatedAt,
+		&call.PromptTokens, &call.CompletionTokens, &call.TotalTokens, &call.CostUSD)
+	if err != nil {
+		return nil, err
+	}
+	call.JobID = jobID.String
+	call.StepID = stepID.String
+	call.Agent = agent.String
+	call.Model = model.String
+	call.ErrorKind = errorKind.String
+	call.Error = errMsg.String
+	return call, nil
+}
+
+// NewLLMCallRepository creates a new LLMCallRepository
+func NewLLMCallRepository(db *sql.DB) ILLMCallRepository {
+	return &LLMCallRepository{db: db}
+}
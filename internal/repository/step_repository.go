@@ -34,12 +34,17 @@ func (r *StepRepository) CreateStep(step *state.Step) error {
 
 	inputJSON, _ := json.Marshal(step.Input)
 	outputJSON, _ := json.Marshal(step.Output)
+	if step.ArtifactIDs == nil {
+		step.ArtifactIDs = []string{}
+	}
+	artifactsJSON, _ := json.Marshal(step.ArtifactIDs)
 
-	query := `INSERT INTO steps (id, job_id, name, status, input, output, created_at, updated_at, started_at, completed_at, error)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	query := `INSERT INTO steps (id, job_id, name, status, input, output, created_at, updated_at, started_at, completed_at, error,
+	          cached, cache_key, artifact_ids)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
 	_, err := r.db.Exec(query, step.ID, step.JobID, step.Name, step.Status,
 		string(inputJSON), string(outputJSON), step.CreatedAt, step.UpdatedAt,
-		step.StartedAt, step.CompletedAt, step.Error)
+		step.StartedAt, step.CompletedAt, step.Error, step.Cached, state.NullIfEmpty(step.CacheKey), string(artifactsJSON))
 	return err
 }
 
@@ -48,12 +53,16 @@ func (r *StepRepository) GetStep(id string) (*state.Step, error) {
 	step := &state.Step{}
 	var inputJSON, outputJSON string
 	var startedAt, completedAt sql.NullTime
+	var cacheKey sql.NullString
+	var artifactsJSON string
 
-	query := `SELECT id, job_id, name, status, input, output, created_at, updated_at, started_at, completed_at, error
+	query := `SELECT id, job_id, name, status, input, output, created_at, updated_at, started_at, completed_at, error,
+	          cached, cache_key, artifact_ids
 	          FROM steps WHERE id = $1`
 	err := r.db.QueryRow(query, id).Scan(
 		&step.ID, &step.JobID, &step.Name, &step.Status, &inputJSON, &outputJSON,
-		&step.CreatedAt, &step.UpdatedAt, &startedAt, &completedAt, &step.Error)
+		&step.CreatedAt, &step.UpdatedAt, &startedAt, &completedAt, &step.Error,
+		&step.Cached, &cacheKey, &artifactsJSON)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("step not found: %s", id)
@@ -69,13 +78,16 @@ func (r *StepRepository) GetStep(id string) (*state.Step, error) {
 	if completedAt.Valid {
 		step.CompletedAt = &completedAt.Time
 	}
+	step.CacheKey = cacheKey.String
+	json.Unmarshal([]byte(artifactsJSON), &step.ArtifactIDs)
 
 	return step, nil
 }
 
 // ListSteps lists steps for a job
 func (r *StepRepository) ListSteps(jobID string) ([]*state.Step, error) {
-	query := `SELECT id, job_id, name, status, input, output, created_at, updated_at, started_at, completed_at, error
+	query := `SELECT id, job_id, name, status, input, output, created_at, updated_at, started_at, completed_at, error,
+	          cached, cache_key, artifact_ids
 	          FROM steps WHERE job_id = $1 ORDER BY created_at ASC`
 	rows, err := r.db.Query(query, jobID)
 	if err != nil {
@@ -88,10 +100,13 @@ func (r *StepRepository) ListSteps(jobID string) ([]*state.Step, error) {
 		step := &state.Step{}
 		var inputJSON, outputJSON string
 		var startedAt, completedAt sql.NullTime
+		var cacheKey sql.NullString
+		var artifactsJSON string
 
 		err := rows.Scan(
 			&step.ID, &step.JobID, &step.Name, &step.Status, &inputJSON, &outputJSON,
-			&step.CreatedAt, &step.UpdatedAt, &startedAt, &completedAt, &step.Error)
+			&step.CreatedAt, &step.UpdatedAt, &startedAt, &completedAt, &step.Error,
+			&step.Cached, &cacheKey, &artifactsJSON)
 		if err != nil {
 			return nil, err
 		}
@@ -104,6 +119,8 @@ func (r *StepRepository) ListSteps(jobID string) ([]*state.Step, error) {
 		if completedAt.Valid {
 			step.CompletedAt = &completedAt.Time
 		}
+		step.CacheKey = cacheKey.String
+		json.Unmarshal([]byte(artifactsJSON), &step.ArtifactIDs)
 
 		steps = append(steps, step)
 	}
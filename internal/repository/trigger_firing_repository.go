@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"agent-project-manager/internal/state"
+)
+
+// ITriggerFiringRepository defines database operations for the trigger audit trail
+type ITriggerFiringRepository interface {
+	CreateTriggerFiring(firing *state.TriggerFiring) error
+	ListTriggerFirings(filter state.TriggerFiringFilter, limit int) ([]*state.TriggerFiring, error)
+}
+
+// TriggerFiringRepository implements ITriggerFiringRepository
+type TriggerFiringRepository struct {
+	db *sql.DB
+}
+
+// CreateTriggerFiring records a trigger firing
+func (r *TriggerFiringRepository) CreateTriggerFiring(firing *state.TriggerFiring) error {
+	if firing.ID == "" {
+		firing.ID = state.NewUUID()
+	}
+	firing.CreatedAt = time.Now()
+
+	query := `INSERT INTO trigger_firings (id, workflow, workflow_version, trigger_name, event_type, event_id,
+	          source_job_id, artifact_id, webhook, job_id, depth, status, error, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+	_, err := r.db.Exec(query, firing.ID, firing.Workflow, state.NullIfZero(firing.WorkflowVersion), firing.TriggerName,
+		firing.EventType, state.NullIfEmpty(firing.EventID), state.NullIfEmpty(firing.SourceJobID), state.NullIfEmpty(firing.ArtifactID),
+		state.NullIfEmpty(firing.Webhook), state.NullIfEmpty(firing.JobID), firing.Depth, firing.Status,
+		state.NullIfEmpty(firing.Error), firing.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create trigger firing: %w", err)
+	}
+	return nil
+}
+
+// ListTriggerFirings lists trigger firings, newest first
+func (r *TriggerFiringRepository) ListTriggerFirings(filter state.TriggerFiringFilter, limit int) ([]*state.TriggerFiring, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	query := `SELECT id, workflow, workflow_version, trigger_name, event_type, event_id, source_job_id,
+	          artifact_id, webhook, job_id, depth, status, error, created_at
+	          FROM trigger_firings WHERE 1=1`
+	args := []interface{}{}
+	argPos := 1
+
+	if filter.Workflow != "" {
+		query += fmt.Sprintf(" AND workflow = $%d", argPos)
+		args = append(args, filter.Workflow)
+		argPos++
+	}
+	if filter.SourceJobID != "" {
+		query += fmt.Sprintf(" AND source_job_id = $%d", argPos)
+		args = append(args, filter.SourceJobID)
+		argPos++
+	}
+	if filter.JobID != "" {
+		query += fmt.Sprintf(" AND job_id = $%d", argPos)
+		args = append(args, filter.JobID)
+		argPos++
+	}
+
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d", argPos)
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	firings := []*state.TriggerFiring{}
+	for rows.Next() {
+		firing := &state.TriggerFiring{}
+		var workflowVersion sql.NullInt64
+		var eventID, sourceJobID, artifactID, webhook, jobID, errMsg sql.NullString
+
+		err := rows.Scan(&firing.ID, &firing.Workflow, &workflowVersion, &firing.TriggerName, &firing.EventType,
+			&eventID, &sourceJobID, &artifactID, &webhook, &jobID, &firing.Depth, &firing.Status,
+			&errMsg, &firing.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		firing.WorkflowVersion = int(workflowVersion.Int64)
+		firing.EventID = eventID.String
+		firing.SourceJobID = sourceJobID.String
+		firing.ArtifactID = artifactID.String
+		firing.Webhook = webhook.String
+		firing.JobID = jobID.String
+		firing.Error = errMsg.String
+		firings = append(firings, firing)
+	}
+
+	return firings, nil
+}
+
+// NewTriggerFiringRepository creates a new TriggerFiringRepository
+func NewTriggerFiringRepository(db *sql.DB) ITriggerFiringRepository {
+	return &TriggerFiringRepository{db: db}
+}
@@ -16,6 +16,8 @@ type IWorkflowRepository interface {
 	ListWorkflows() ([]*state.Workflow, error)
 	UpdateWorkflow(workflow *state.Workflow) error
 	DeleteWorkflow(name string) error
+	SoftDeleteWorkflow(name string) error
+	DeleteUnusedWorkflow(name string) (softDeleted bool, active int, err error)
 }
 
 // WorkflowRepository implements IWorkflowRepository
@@ -25,17 +27,42 @@ type WorkflowRepository struct {
 
 // CreateWorkflow creates a new workflow
 func (r *WorkflowRepository) CreateWorkflow(workflow *state.Workflow) error {
+	return createWorkflow(r.db, workflow)
+}
+
+// execer is implemented by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// createWorkflow inserts a workflow through ex
+func createWorkflow(ex execer, workflow *state.Workflow) error {
 	now := time.Now()
 	workflow.CreatedAt = now
 	workflow.UpdatedAt = now
 
 	schemaJSON, _ := json.Marshal(workflow.Schema)
 
+	// A soft-deleted workflow with the same name is revived; its published versions are kept
 	query := `INSERT INTO workflows (name, description, schema, version, created_at, updated_at)
-	          VALUES ($1, $2, $3, $4, $5, $6)`
-	_, err := r.db.Exec(query, workflow.Name, workflow.Description, string(schemaJSON),
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description, schema = EXCLUDED.schema,
+	          updated_at = EXCLUDED.updated_at, deleted_at = NULL
+	          WHERE workflows.deleted_at IS NOT NULL`
+	res, err := ex.Exec(query, workflow.Name, workflow.Description, string(schemaJSON),
 		workflow.Version, workflow.CreatedAt, workflow.UpdatedAt)
-	return err
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("workflow %w: %s", state.ErrAlreadyExists, workflow.Name)
+	}
+	return nil
 }
 
 // GetWorkflow retrieves a workflow by name
@@ -44,13 +71,13 @@ func (r *WorkflowRepository) GetWorkflow(name string) (*state.Workflow, error) {
 	var schemaJSON string
 
 	query := `SELECT name, description, schema, version, created_at, updated_at
-	          FROM workflows WHERE name = $1`
+	          FROM workflows WHERE name = $1 AND deleted_at IS NULL`
 	err := r.db.QueryRow(query, name).Scan(
 		&workflow.Name, &workflow.Description, &schemaJSON,
 		&workflow.Version, &workflow.CreatedAt, &workflow.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("workflow not found: %s", name)
+			return nil, fmt.Errorf("workflow %w: %s", state.ErrNotFound, name)
 		}
 		return nil, err
 	}
@@ -61,7 +88,7 @@ func (r *WorkflowRepository) GetWorkflow(name string) (*state.Workflow, error) {
 
 // ListWorkflows lists all workflows
 func (r *WorkflowRepository) ListWorkflows() ([]*state.Workflow, error) {
-	rows, err := r.db.Query(`SELECT name, description, schema, version, created_at, updated_at FROM workflows ORDER BY name`)
+	rows, err := r.db.Query(`SELECT name, description, schema, version, created_at, updated_at FROM workflows WHERE deleted_at IS NULL ORDER BY name`)
 	if err != nil {
 		return nil, err
 	}
@@ -90,7 +117,8 @@ func (r *WorkflowRepository) UpdateWorkflow(workflow *state.Workflow) error {
 	workflow.UpdatedAt = time.Now()
 	schemaJSON, _ := json.Marshal(workflow.Schema)
 
-	query := `UPDATE workflows SET description = $1, schema = $2, version = $3, updated_at = $4 WHERE name = $5`
+	query := `UPDATE workflows SET description = $1, schema = $2, version = $3, updated_at = $4
+	          WHERE name = $5 AND deleted_at IS NULL`
 	_, err := r.db.Exec(query, workflow.Description, string(schemaJSON), workflow.Version, workflow.UpdatedAt, workflow.Name)
 	return err
 }
@@ -101,6 +129,57 @@ func (r *WorkflowRepository) DeleteWorkflow(name string) error {
 	return err
 }
 
+// SoftDeleteWorkflow hides a workflow while keeping its row and published versions
+func (r *WorkflowRepository) SoftDeleteWorkflow(name string) error {
+	_, err := r.db.Exec("UPDATE workflows SET deleted_at = $1 WHERE name = $2 AND deleted_at IS NULL", time.Now(), name)
+	return err
+}
+
+// DeleteUnusedWorkflow deletes a workflow unless unfinished jobs use it, counting its jobs
+// and deleting in one transaction. A workflow that finished jobs still reference is
+// soft-deleted so their history stays intact. When active jobs use the workflow nothing
+// is deleted and their number is returned.
+func (r *WorkflowRepository) DeleteUnusedWorkflow(name string) (bool, int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the workflow row; job creation takes a share lock on it, so no job of the
+	// workflow can be created until the deletion is done
+	var locked string
+	err = tx.QueryRow(`SELECT name FROM workflows WHERE name = $1 AND deleted_at IS NULL FOR UPDATE`, name).Scan(&locked)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, 0, fmt.Errorf("workflow %w: %s", state.ErrNotFound, name)
+		}
+		return false, 0, err
+	}
+
+	var total, active int
+	query := `SELECT COUNT(*), COUNT(*) FILTER (WHERE status NOT IN ('succeeded', 'failed', 'cancelled'))
+	          FROM jobs WHERE workflow = $1`
+	if err := tx.QueryRow(query, name).Scan(&total, &active); err != nil {
+		return false, 0, fmt.Errorf("failed to count jobs: %w", err)
+	}
+	if active > 0 {
+		return false, active, nil
+	}
+	if total > 0 {
+		_, err = tx.Exec("UPDATE workflows SET deleted_at = $1 WHERE name = $2", time.Now(), name)
+	} else {
+		_, err = tx.Exec("DELETE FROM workflows WHERE name = $1", name)
+	}
+	if err != nil {
+		return false, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, 0, fmt.Errorf("failed to commit workflow deletion: %w", err)
+	}
+	return total > 0, 0, nil
+}
+
 // NewWorkflowRepository creates a new WorkflowRepository
 func NewWorkflowRepository(db *sql.DB) IWorkflowRepository {
 	return &WorkflowRepository{db: db}
@@ -21,27 +21,29 @@ func (r *postgresRepository) CreateArtifact(artifact *Artifact) error {
 	}
 	artifact.CreatedAt = time.Now()
 
-	query := `INSERT INTO artifacts (id, job_id, run_id, type, name, size, path, created_at)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
-	_, err := r.db.Exec(query, artifact.ID, artifact.JobID, artifact.RunID,
-		artifact.Type, artifact.Name, artifact.Size, artifact.Path, artifact.CreatedAt)
+	query := `INSERT INTO artifacts (id, job_id, run_id, type, name, size, path, digest, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := r.db.Exec(query, artifact.ID, NullIfEmpty(artifact.JobID), NullIfEmpty(artifact.RunID),
+		artifact.Type, artifact.Name, artifact.Size, artifact.Path, NullIfEmpty(artifact.Digest), artifact.CreatedAt)
 	return err
 }
 
 // GetArtifact retrieves an artifact by ID
 func (r *postgresRepository) GetArtifact(id string) (*Artifact, error) {
 	artifact := &Artifact{}
+	var jobID, runID, digest sql.NullString
 
-	query := `SELECT id, job_id, run_id, type, name, size, path, created_at FROM artifacts WHERE id = $1`
+	query := `SELECT id, job_id, run_id, type, name, size, path, digest, created_at FROM artifacts WHERE id = $1`
 	err := r.db.QueryRow(query, id).Scan(
-		&artifact.ID, &artifact.JobID, &artifact.RunID, &artifact.Type,
-		&artifact.Name, &artifact.Size, &artifact.Path, &artifact.CreatedAt)
+		&artifact.ID, &jobID, &runID, &artifact.Type,
+		&artifact.Name, &artifact.Size, &artifact.Path, &digest, &artifact.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("artifact not found: %s", id)
 		}
 		return nil, err
 	}
+	artifact.JobID, artifact.RunID, artifact.Digest = jobID.String, runID.String, digest.String
 
 	return artifact, nil
 }
@@ -52,7 +54,7 @@ func (r *postgresRepository) ListArtifacts(jobID string, runID string, limit int
 		limit = 50
 	}
 
-	query := `SELECT id, job_id, run_id, type, name, size, path, created_at FROM artifacts WHERE 1=1`
+	query := `SELECT id, job_id, run_id, type, name, size, path, digest, created_at FROM artifacts WHERE 1=1`
 	args := []interface{}{}
 	argPos := 1
 
@@ -84,12 +86,14 @@ func (r *postgresRepository) ListArtifacts(jobID string, runID string, limit int
 	artifacts := []*Artifact{}
 	for rows.Next() {
 		artifact := &Artifact{}
+		var jobID, runID, digest sql.NullString
 
-		err := rows.Scan(&artifact.ID, &artifact.JobID, &artifact.RunID, &artifact.Type,
-			&artifact.Name, &artifact.Size, &artifact.Path, &artifact.CreatedAt)
+		err := rows.Scan(&artifact.ID, &jobID, &runID, &artifact.Type,
+			&artifact.Name, &artifact.Size, &artifact.Path, &digest, &artifact.CreatedAt)
 		if err != nil {
 			return nil, "", err
 		}
+		artifact.JobID, artifact.RunID, artifact.Digest = jobID.String, runID.String, digest.String
 
 		artifacts = append(artifacts, artifact)
 	}
@@ -13,11 +13,20 @@ type JobRepository interface {
 	GetJob(id string) (*Job, error)
 	ListJobs(limit int, cursor string, status string, workflow string) ([]*Job, string, error)
 	UpdateJob(job *Job) error
+	UpdateJobMeta(id string, meta JSONMap) error
 	DeleteJob(id string) error
+	ListChildJobs(parentID string) ([]*Job, error)
+	CountJobsByWorkflow(workflow string) (total int, active int, err error)
+	ListStrandedJobIDs() ([]string, error)
 }
 
 // CreateJob creates a new job in the database
 func (r *postgresRepository) CreateJob(job *Job) error {
+	return insertJob(r.db, job)
+}
+
+// insertJob inserts a job through ex
+func insertJob(ex execer, job *Job) error {
 	if job.ID == "" {
 		job.ID = NewUUID()
 	}
@@ -28,10 +37,13 @@ func (r *postgresRepository) CreateJob(job *Job) error {
 	inputJSON, _ := json.Marshal(job.Input)
 	metaJSON, _ := json.Marshal(job.Meta)
 
-	query := `INSERT INTO jobs (id, workflow, status, input, meta, created_at, updated_at, started_at, completed_at, error)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
-	_, err := r.db.Exec(query, job.ID, job.Workflow, job.Status, string(inputJSON), string(metaJSON),
-		job.CreatedAt, job.UpdatedAt, job.StartedAt, job.CompletedAt, job.Error)
+	query := `INSERT INTO jobs (id, workflow, status, input, meta, created_at, updated_at, started_at, completed_at, error,
+	          parent_job_id, parent_step_id, workflow_version, rerun_of_job_id, rerun_from_step, matrix_id)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`
+	_, err := ex.Exec(query, job.ID, job.Workflow, job.Status, string(inputJSON), string(metaJSON),
+		job.CreatedAt, job.UpdatedAt, job.StartedAt, job.CompletedAt, job.Error,
+		NullIfEmpty(job.ParentJobID), NullIfEmpty(job.ParentStepID), NullIfZero(job.WorkflowVersion),
+		NullIfEmpty(job.RerunOfJobID), NullIfEmpty(job.RerunFromStep), NullIfEmpty(job.MatrixID))
 	return err
 }
 
@@ -40,12 +52,16 @@ func (r *postgresRepository) GetJob(id string) (*Job, error) {
 	job := &Job{}
 	var inputJSON, metaJSON string
 	var startedAt, completedAt sql.NullTime
+	var parentJobID, parentStepID, rerunOfJobID, rerunFromStep, matrixID sql.NullString
+	var workflowVersion sql.NullInt64
 
-	query := `SELECT id, workflow, status, input, meta, created_at, updated_at, started_at, completed_at, error
+	query := `SELECT id, workflow, status, input, meta, created_at, updated_at, started_at, completed_at, error,
+	          parent_job_id, parent_step_id, workflow_version, rerun_of_job_id, rerun_from_step, matrix_id
 	          FROM jobs WHERE id = $1`
 	err := r.db.QueryRow(query, id).Scan(
 		&job.ID, &job.Workflow, &job.Status, &inputJSON, &metaJSON,
-		&job.CreatedAt, &job.UpdatedAt, &startedAt, &completedAt, &job.Error)
+		&job.CreatedAt, &job.UpdatedAt, &startedAt, &completedAt, &job.Error,
+		&parentJobID, &parentStepID, &workflowVersion, &rerunOfJobID, &rerunFromStep, &matrixID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("job not found: %s", id)
@@ -55,6 +71,12 @@ func (r *postgresRepository) GetJob(id string) (*Job, error) {
 
 	json.Unmarshal([]byte(inputJSON), &job.Input)
 	json.Unmarshal([]byte(metaJSON), &job.Meta)
+	job.ParentJobID = parentJobID.String
+	job.ParentStepID = parentStepID.String
+	job.RerunOfJobID = rerunOfJobID.String
+	job.RerunFromStep = rerunFromStep.String
+	job.MatrixID = matrixID.String
+	job.WorkflowVersion = int(workflowVersion.Int64)
 	if startedAt.Valid {
 		job.StartedAt = &startedAt.Time
 	}
@@ -71,7 +93,8 @@ func (r *postgresRepository) ListJobs(limit int, cursor string, status string, w
 		limit = 50
 	}
 
-	query := `SELECT id, workflow, status, input, meta, created_at, updated_at, started_at, completed_at, error
+	query := `SELECT id, workflow, status, input, meta, created_at, updated_at, started_at, completed_at, error,
+	          parent_job_id, parent_step_id, workflow_version, rerun_of_job_id, rerun_from_step, matrix_id
 	          FROM jobs WHERE 1=1`
 	args := []interface{}{}
 	argPos := 1
@@ -106,15 +129,24 @@ func (r *postgresRepository) ListJobs(limit int, cursor string, status string, w
 		job := &Job{}
 		var inputJSON, metaJSON string
 		var startedAt, completedAt sql.NullTime
+		var parentJobID, parentStepID, rerunOfJobID, rerunFromStep, matrixID sql.NullString
+		var workflowVersion sql.NullInt64
 
 		err := rows.Scan(&job.ID, &job.Workflow, &job.Status, &inputJSON, &metaJSON,
-			&job.CreatedAt, &job.UpdatedAt, &startedAt, &completedAt, &job.Error)
+			&job.CreatedAt, &job.UpdatedAt, &startedAt, &completedAt, &job.Error,
+			&parentJobID, &parentStepID, &workflowVersion, &rerunOfJobID, &rerunFromStep, &matrixID)
 		if err != nil {
 			return nil, "", err
 		}
 
 		json.Unmarshal([]byte(inputJSON), &job.Input)
 		json.Unmarshal([]byte(metaJSON), &job.Meta)
+		job.ParentJobID = parentJobID.String
+		job.ParentStepID = parentStepID.String
+		job.RerunOfJobID = rerunOfJobID.String
+		job.RerunFromStep = rerunFromStep.String
+		job.MatrixID = matrixID.String
+		job.WorkflowVersion = int(workflowVersion.Int64)
 		if startedAt.Valid {
 			job.StartedAt = &startedAt.Time
 		}
@@ -141,12 +173,104 @@ func (r *postgresRepository) UpdateJob(job *Job) error {
 	metaJSON, _ := json.Marshal(job.Meta)
 
 	query := `UPDATE jobs SET workflow = $1, status = $2, input = $3, meta = $4, updated_at = $5, 
-	          started_at = $6, completed_at = $7, error = $8 WHERE id = $9`
+	          started_at = $6, completed_at = $7, error = $8, parent_job_id = $9, parent_step_id = $10,
+	          workflow_version = $11 WHERE id = $12`
 	_, err := r.db.Exec(query, job.Workflow, job.Status, string(inputJSON), string(metaJSON),
-		job.UpdatedAt, job.StartedAt, job.CompletedAt, job.Error, job.ID)
+		job.UpdatedAt, job.StartedAt, job.CompletedAt, job.Error,
+		NullIfEmpty(job.ParentJobID), NullIfEmpty(job.ParentStepID), NullIfZero(job.WorkflowVersion), job.ID)
+	return err
+}
+
+// UpdateJobMeta replaces only a job's meta, leaving its status untouched
+func (r *postgresRepository) UpdateJobMeta(id string, meta JSONMap) error {
+	metaJSON, _ := json.Marshal(meta)
+	_, err := r.db.Exec(`UPDATE jobs SET meta = $1, updated_at = $2 WHERE id = $3`,
+		string(metaJSON), time.Now(), id)
 	return err
 }
 
+// ListChildJobs lists the jobs started by steps of the given parent job
+func (r *postgresRepository) ListChildJobs(parentID string) ([]*Job, error) {
+	query := `SELECT id, workflow, status, input, meta, created_at, updated_at, started_at, completed_at, error,
+	          parent_job_id, parent_step_id, workflow_version, rerun_of_job_id, rerun_from_step, matrix_id
+	          FROM jobs WHERE parent_job_id = $1 ORDER BY created_at ASC`
+	rows, err := r.db.Query(query, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*Job{}
+	for rows.Next() {
+		job := &Job{}
+		var inputJSON, metaJSON string
+		var startedAt, completedAt sql.NullTime
+		var parentJobID, parentStepID, rerunOfJobID, rerunFromStep, matrixID sql.NullString
+		var workflowVersion sql.NullInt64
+
+		err := rows.Scan(&job.ID, &job.Workflow, &job.Status, &inputJSON, &metaJSON,
+			&job.CreatedAt, &job.UpdatedAt, &startedAt, &completedAt, &job.Error,
+			&parentJobID, &parentStepID, &workflowVersion, &rerunOfJobID, &rerunFromStep, &matrixID)
+		if err != nil {
+			return nil, err
+		}
+
+		json.Unmarshal([]byte(inputJSON), &job.Input)
+		json.Unmarshal([]byte(metaJSON), &job.Meta)
+		job.ParentJobID = parentJobID.String
+		job.ParentStepID = parentStepID.String
+		job.RerunOfJobID = rerunOfJobID.String
+		job.RerunFromStep = rerunFromStep.String
+		job.MatrixID = matrixID.String
+		job.WorkflowVersion = int(workflowVersion.Int64)
+		if startedAt.Valid {
+			job.StartedAt = &startedAt.Time
+		}
+		if completedAt.Valid {
+			job.CompletedAt = &completedAt.Time
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// CountJobsByWorkflow counts the jobs referencing a workflow, and how many of them are unfinished
+func (r *postgresRepository) CountJobsByWorkflow(workflow string) (int, int, error) {
+	var total, active int
+	query := `SELECT COUNT(*), COUNT(*) FILTER (WHERE status NOT IN ('succeeded', 'failed', 'cancelled'))
+	          FROM jobs WHERE workflow = $1`
+	if err := r.db.QueryRow(query, workflow).Scan(&total, &active); err != nil {
+		return 0, 0, err
+	}
+	return total, active, nil
+}
+
+// ListStrandedJobIDs lists queued, running or compensating jobs that have no pending or leased queue item
+func (r *postgresRepository) ListStrandedJobIDs() ([]string, error) {
+	query := `SELECT j.id FROM jobs j
+	          WHERE j.status IN ('queued', 'running', 'compensating')
+	            AND NOT EXISTS (SELECT 1 FROM queue_items q
+	                            WHERE q.job_id = j.id AND q.state IN ('pending', 'leased'))
+	          ORDER BY j.created_at ASC`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 // DeleteJob deletes a job by ID from the database
 func (r *postgresRepository) DeleteJob(id string) error {
 	_, err := r.db.Exec("DELETE FROM jobs WHERE id = $1", id)
@@ -0,0 +1,89 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// LLMCacheRepository defines database operations for the LLM response cache
+type LLMCacheRepository interface {
+	GetLLMCacheEntry(key string) (*LLMCacheEntry, error)
+	SaveLLMCacheEntry(entry *LLMCacheEntry) error
+	RecordLLMCacheHit(key string) error
+	PruneLLMCache(maxBytes int64) (int64, error)
+}
+
+// GetLLMCacheEntry retrieves a cached LLM response by key.
+// It returns nil when there is no entry or it has expired.
+func (r *postgresRepository) GetLLMCacheEntry(key string) (*LLMCacheEntry, error) {
+	entry := &LLMCacheEntry{}
+	var responseJSON string
+	var model sql.NullString
+
+	query := `SELECT key, provider, model, response, size_bytes, hits, created_at, expires_at, last_used_at
+	          FROM llm_cache WHERE key = $1 AND expires_at > $2`
+	err := r.db.QueryRow(query, key, time.Now()).Scan(
+		&entry.Key, &entry.Provider, &model, &responseJSON, &entry.SizeBytes, &entry.Hits,
+		&entry.CreatedAt, &entry.ExpiresAt, &entry.LastUsedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	json.Unmarshal([]byte(responseJSON), &entry.Response)
+	entry.Model = model.String
+	return entry, nil
+}
+
+// SaveLLMCacheEntry stores an LLM response, replacing any entry with the same key
+func (r *postgresRepository) SaveLLMCacheEntry(entry *LLMCacheEntry) error {
+	responseJSON, _ := json.Marshal(entry.Response)
+	entry.SizeBytes = len(responseJSON)
+	entry.CreatedAt = time.Now()
+	entry.LastUsedAt = entry.CreatedAt
+
+	query := `INSERT INTO llm_cache (key, provider, model, response, size_bytes, hits, created_at, expires_at, last_used_at)
+	          VALUES ($1, $2, $3, $4, $5, 0, $6, $7, $6)
+	          ON CONFLICT (key) DO UPDATE SET provider = EXCLUDED.provider, model = EXCLUDED.model,
+	              response = EXCLUDED.response, size_bytes = EXCLUDED.size_bytes, hits = 0,
+	              created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at,
+	              last_used_at = EXCLUDED.last_used_at`
+	_, err := r.db.Exec(query, entry.Key, entry.Provider, NullIfEmpty(entry.Model), string(responseJSON),
+		entry.SizeBytes, entry.CreatedAt, entry.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save LLM cache entry: %w", err)
+	}
+	return nil
+}
+
+// RecordLLMCacheHit counts a reuse of a cached LLM response
+func (r *postgresRepository) RecordLLMCacheHit(key string) error {
+	_, err := r.db.Exec(`UPDATE llm_cache SET hits = hits + 1, last_used_at = $1 WHERE key = $2`, time.Now(), key)
+	return err
+}
+
+// PruneLLMCache deletes expired entries, then the least recently used entries until the
+// rest fit in maxBytes. It returns the number of entries deleted.
+func (r *postgresRepository) PruneLLMCache(maxBytes int64) (int64, error) {
+	res, err := r.db.Exec(`DELETE FROM llm_cache WHERE expires_at <= $1`, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired LLM cache entries: %w", err)
+	}
+	expired, _ := res.RowsAffected()
+
+	query := `DELETE FROM llm_cache WHERE key IN (
+	              SELECT key FROM (
+	                  SELECT key, SUM(size_bytes) OVER (ORDER BY last_used_at DESC, key) AS kept
+	                  FROM llm_cache) ranked
+	              WHERE kept > $1)`
+	res, err = r.db.Exec(query, maxBytes)
+	if err != nil {
+		return expired, fmt.Errorf("failed to evict LLM cache entries: %w", err)
+	}
+	evicted, _ := res.RowsAffected()
+	return expired + evicted, nil
+}
@@ -0,0 +1,152 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MatrixRepository defines database operations for matrix submissions
+type MatrixRepository interface {
+	CreateMatrix(matrix *Matrix) error
+	CreateMatrixJobs(matrix *Matrix, jobs []*Job, queueState string) error
+	GetMatrix(id string) (*Matrix, error)
+	CompleteMatrix(id string, reportArtifactID string) error
+	ListMatrixJobs(matrixID string) ([]*Job, error)
+}
+
+// CreateMatrix creates a new matrix submission
+func (r *postgresRepository) CreateMatrix(matrix *Matrix) error {
+	return insertMatrix(r.db, matrix)
+}
+
+// CreateMatrixJobs creates a matrix submission and its jobs, each with a queue item in
+// queueState, in one transaction: if any insert fails nothing is created or queued.
+func (r *postgresRepository) CreateMatrixJobs(matrix *Matrix, jobs []*Job, queueState string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertMatrix(tx, matrix); err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		job.MatrixID = matrix.ID
+		if err := insertJob(tx, job); err != nil {
+			return fmt.Errorf("failed to create job: %w", err)
+		}
+		item := &QueueItem{JobID: job.ID, State: queueState, Data: JSONMap{}}
+		if err := insertQueueItem(tx, item); err != nil {
+			return fmt.Errorf("failed to enqueue job %s: %w", job.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit matrix: %w", err)
+	}
+	return nil
+}
+
+// insertMatrix inserts a matrix submission through ex
+func insertMatrix(ex execer, matrix *Matrix) error {
+	if matrix.ID == "" {
+		matrix.ID = NewUUID()
+	}
+	matrix.CreatedAt = time.Now()
+	inputJSON, _ := json.Marshal(matrix.Input)
+	axesJSON, _ := json.Marshal(matrix.Axes)
+
+	query := `INSERT INTO matrices (id, workflow, workflow_version, input, axes, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := ex.Exec(query, matrix.ID, matrix.Workflow, NullIfZero(matrix.WorkflowVersion),
+		string(inputJSON), string(axesJSON), matrix.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create matrix: %w", err)
+	}
+	return nil
+}
+
+// GetMatrix retrieves a matrix submission by ID
+func (r *postgresRepository) GetMatrix(id string) (*Matrix, error) {
+	matrix := &Matrix{}
+	var inputJSON, axesJSON string
+	var workflowVersion sql.NullInt64
+	var reportArtifactID sql.NullString
+	var completedAt sql.NullTime
+
+	query := `SELECT id, workflow, workflow_version, input, axes, report_artifact_id, created_at, completed_at
+	          FROM matrices WHERE id = $1`
+	err := r.db.QueryRow(query, id).Scan(&matrix.ID, &matrix.Workflow, &workflowVersion,
+		&inputJSON, &axesJSON, &reportArtifactID, &matrix.CreatedAt, &completedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("matrix not found: %s", id)
+		}
+		return nil, err
+	}
+
+	json.Unmarshal([]byte(inputJSON), &matrix.Input)
+	json.Unmarshal([]byte(axesJSON), &matrix.Axes)
+	matrix.WorkflowVersion = int(workflowVersion.Int64)
+	matrix.ReportArtifactID = reportArtifactID.String
+	if completedAt.Valid {
+		matrix.CompletedAt = &completedAt.Time
+	}
+	return matrix, nil
+}
+
+// CompleteMatrix marks a matrix as completed and records its report artifact
+func (r *postgresRepository) CompleteMatrix(id string, reportArtifactID string) error {
+	_, err := r.db.Exec(`UPDATE matrices SET report_artifact_id = $1, completed_at = $2 WHERE id = $3`,
+		NullIfEmpty(reportArtifactID), time.Now(), id)
+	return err
+}
+
+// ListMatrixJobs lists the jobs expanded from a matrix submission, in submission order
+func (r *postgresRepository) ListMatrixJobs(matrixID string) ([]*Job, error) {
+	query := `SELECT id, workflow, status, input, meta, created_at, updated_at, started_at, completed_at, error,
+	          parent_job_id, parent_step_id, workflow_version, rerun_of_job_id, rerun_from_step, matrix_id
+	          FROM jobs WHERE matrix_id = $1 ORDER BY created_at ASC, id ASC`
+	rows, err := r.db.Query(query, matrixID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*Job{}
+	for rows.Next() {
+		job := &Job{}
+		var inputJSON, metaJSON string
+		var startedAt, completedAt sql.NullTime
+		var parentJobID, parentStepID, rerunOfJobID, rerunFromStep, matrixID sql.NullString
+		var workflowVersion sql.NullInt64
+
+		err := rows.Scan(&job.ID, &job.Workflow, &job.Status, &inputJSON, &metaJSON,
+			&job.CreatedAt, &job.UpdatedAt, &startedAt, &completedAt, &job.Error,
+			&parentJobID, &parentStepID, &workflowVersion, &rerunOfJobID, &rerunFromStep, &matrixID)
+		if err != nil {
+			return nil, err
+		}
+
+		json.Unmarshal([]byte(inputJSON), &job.Input)
+		json.Unmarshal([]byte(metaJSON), &job.Meta)
+		job.ParentJobID = parentJobID.String
+		job.ParentStepID = parentStepID.String
+		job.RerunOfJobID = rerunOfJobID.String
+		job.RerunFromStep = rerunFromStep.String
+		job.MatrixID = matrixID.String
+		job.WorkflowVersion = int(workflowVersion.Int64)
+		if startedAt.Valid {
+			job.StartedAt = &startedAt.Time
+		}
+		if completedAt.Valid {
+			job.CompletedAt = &completedAt.Time
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
@@ -23,8 +23,7 @@ func RunMigrations(store Store, migrationsPath string) error {
 		return fmt.Errorf("no migration files found in %s", migrationsPath)
 	}
 
-	// Run the initial migration (for now we only have 0001_init.sql)
-	// The store.Migrate method will read and execute it
+	// The store.Migrate method executes the files in order
 	if err := store.Migrate(migrationsPath); err != nil {
 		return fmt.Errorf("failed to execute migrations: %w", err)
 	}
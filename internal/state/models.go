@@ -12,16 +12,22 @@ import (
 
 // Job represents a job in the database
 type Job struct {
-	ID          string    `db:"id"`
-	Workflow    string    `db:"workflow"`
-	Status      string    `db:"status"`
-	Input       JSONMap   `db:"input"`
-	Meta        JSONMap   `db:"meta"`
-	CreatedAt   time.Time `db:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at"`
-	StartedAt   *time.Time `db:"started_at"`
-	CompletedAt *time.Time `db:"completed_at"`
-	Error       string    `db:"error"`
+	ID              string     `db:"id"`
+	Workflow        string     `db:"workflow"`
+	Status          string     `db:"status"`
+	Input           JSONMap    `db:"input"`
+	Meta            JSONMap    `db:"meta"`
+	CreatedAt       time.Time  `db:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at"`
+	StartedAt       *time.Time `db:"started_at"`
+	CompletedAt     *time.Time `db:"completed_at"`
+	Error           string     `db:"error"`
+	ParentJobID     string     `db:"parent_job_id"`    // set for jobs started by a sub-workflow step
+	ParentStepID    string     `db:"parent_step_id"`   // the parent step waiting on this job
+	WorkflowVersion int        `db:"workflow_version"` // published workflow version the job is pinned to
+	RerunOfJobID    string     `db:"rerun_of_job_id"`  // set for jobs created by rerunning another job
+	RerunFromStep   string     `db:"rerun_from_step"`  // the step the rerun started from
+	MatrixID        string     `db:"matrix_id"`        // set for jobs expanded from a matrix submission
 }
 
 // Run represents a run in the database
@@ -47,6 +53,43 @@ type Workflow struct {
 	UpdatedAt   time.Time `db:"updated_at"`
 }
 
+// WorkflowVersion is an immutable, published snapshot of a workflow definition
+type WorkflowVersion struct {
+	Workflow    string    `db:"workflow"`
+	Version     int       `db:"version"`
+	Description string    `db:"description"`
+	Schema      JSONMap   `db:"schema"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// Prompt is a named prompt template; its text lives in immutable versions
+type Prompt struct {
+	Name          string    `db:"name"`
+	Description   string    `db:"description"`
+	LatestVersion int       `db:"latest_version"`
+	CreatedAt     time.Time `db:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at"`
+}
+
+// PromptVersion is an immutable published version of a prompt template
+type PromptVersion struct {
+	Prompt      string           `db:"prompt"`
+	Version     int              `db:"version"`
+	Description string           `db:"description"`
+	Template    string           `db:"template"`  // text/template body
+	Variables   []PromptVariable `db:"variables"` // variables the template may use
+	Source      string           `db:"source"`    // file the version was loaded from; empty when published through the API
+	CreatedAt   time.Time        `db:"created_at"`
+}
+
+// PromptVariable declares a variable of a prompt template
+type PromptVariable struct {
+	Name        string      `json:"name" yaml:"name"`
+	Description string      `json:"description,omitempty" yaml:"description"`
+	Required    bool        `json:"required,omitempty" yaml:"required"`
+	Default     interface{} `json:"default,omitempty" yaml:"default"` // used when an optional variable is not given
+}
+
 // Step represents a workflow step in the database
 type Step struct {
 	ID          string    `db:"id"`
@@ -60,6 +103,124 @@ type Step struct {
 	StartedAt   *time.Time `db:"started_at"`
 	CompletedAt *time.Time `db:"completed_at"`
 	Error       string    `db:"error"`
+	Cached      bool      `db:"cached"`    // output was reused from the step cache
+	CacheKey    string    `db:"cache_key"` // content hash the output is cached under
+	ArtifactIDs []string  `db:"artifact_ids"` // artifacts the step produced
+}
+
+// Matrix is a submission that expands one workflow across parameter axes into sibling jobs
+type Matrix struct {
+	ID               string     `db:"id"`
+	Workflow         string     `db:"workflow"`
+	WorkflowVersion  int        `db:"workflow_version"`
+	Input            JSONMap    `db:"input"` // input shared by every job
+	Axes             JSONMap    `db:"axes"`  // axis name -> list of values
+	ReportArtifactID string     `db:"report_artifact_id"` // markdown report, written once every job finished
+	CreatedAt        time.Time  `db:"created_at"`
+	CompletedAt      *time.Time `db:"completed_at"`
+}
+
+// TriggerFiring records a trigger rule matching an event: the job it started,
+// or why no job was started
+type TriggerFiring struct {
+	ID              string    `db:"id"`
+	Workflow        string    `db:"workflow"` // the workflow the trigger belongs to
+	WorkflowVersion int       `db:"workflow_version"`
+	TriggerName     string    `db:"trigger_name"`
+	EventType       string    `db:"event_type"`
+	EventID         string    `db:"event_id"`      // empty for webhooks
+	SourceJobID     string    `db:"source_job_id"` // the job the event is about
+	ArtifactID      string    `db:"artifact_id"`
+	Webhook         string    `db:"webhook"`
+	JobID           string    `db:"job_id"` // the started job; empty unless Status is started
+	Depth           int       `db:"depth"`  // length of the trigger chain that led to the job
+	Status          string    `db:"status"` // started, skipped or failed
+	Error           string    `db:"error"`
+	CreatedAt       time.Time `db:"created_at"`
+}
+
+// LLMCall records one attempt of a request to an LLM provider.
+// A request that fell back to other providers has one record per attempt, all with its RequestID.
+type LLMCall struct {
+	ID        string    `db:"id"`
+	RequestID string    `db:"request_id"`
+	JobID     string    `db:"job_id"`
+	StepID    string    `db:"step_id"`
+	Agent     string    `db:"agent"`
+	Attempt   int       `db:"attempt"` // 1 for the first provider of the route
+	Provider  string    `db:"provider"`
+	Model     string    `db:"model"`
+	Status    string    `db:"status"`     // succeeded, failed, skipped (circuit breaker open) or cached
+	ErrorKind string    `db:"error_kind"` // e.g. rate_limit or transient; see llm.ErrorKind
+	Error     string    `db:"error"`
+	LatencyMs int64     `db:"latency_ms"`
+	CreatedAt time.Time `db:"created_at"`
+
+	PromptTokens     int     `db:"prompt_tokens"`
+	CompletionTokens int     `db:"completion_tokens"`
+	TotalTokens      int     `db:"total_tokens"`
+	CostUSD          float64 `db:"cost_usd"` // by the price table in effect when the call was made
+}
+
+// LLMUsage adds up LLM calls
+type LLMUsage struct {
+	Calls            int64 // attempts sent to a provider; skipped and cached attempts do not count
+	PromptTokens     int64
+	CompletionTokens int64
+	TotalTokens      int64
+	CostUSD          float64
+	LatencyMs        int64
+}
+
+// LLMUsageGroup is the usage of one group of LLM calls; only the grouping fields are set
+type LLMUsageGroup struct {
+	JobID    string
+	StepID   string
+	StepName string
+	Workflow string
+	Agent    string
+	Provider string
+	Model    string
+	Day      string // YYYY-MM-DD
+	LLMUsage
+}
+
+// Signal is an external event sent to a job.
+// It stays buffered until a wait_for_signal step of the job consumes it.
+type Signal struct {
+	ID         string     `db:"id"`
+	JobID      string     `db:"job_id"`
+	Name       string     `db:"name"`
+	Payload    JSONMap    `db:"payload"`
+	StepID     string     `db:"step_id"` // the step that consumed the signal
+	CreatedAt  time.Time  `db:"created_at"`
+	ConsumedAt *time.Time `db:"consumed_at"`
+}
+
+// StepCacheEntry is a memoized step result, keyed by a hash of the step's definition and input
+type StepCacheEntry struct {
+	Key          string     `db:"key"`
+	StepType     string     `db:"step_type"`
+	Output       JSONMap    `db:"output"`
+	ArtifactIDs  []string   `db:"artifact_ids"`
+	SourceJobID  string     `db:"source_job_id"`
+	SourceStepID string     `db:"source_step_id"`
+	Hits         int        `db:"hits"`
+	CreatedAt    time.Time  `db:"created_at"`
+	LastHitAt    *time.Time `db:"last_hit_at"`
+}
+
+// LLMCacheEntry is a cached LLM response
+type LLMCacheEntry struct {
+	Key        string    `db:"key"`
+	Provider   string    `db:"provider"`
+	Model      string    `db:"model"`
+	Response   JSONMap   `db:"response"`
+	SizeBytes  int       `db:"size_bytes"`
+	Hits       int       `db:"hits"`
+	CreatedAt  time.Time `db:"created_at"`
+	ExpiresAt  time.Time `db:"expires_at"`
+	LastUsedAt time.Time `db:"last_used_at"` // eviction beyond the size cap goes by this
 }
 
 // Event represents an event in the database
@@ -82,6 +243,7 @@ type Artifact struct {
 	Name      string    `db:"name"`
 	Size      int64     `db:"size"`
 	Path      string    `db:"path"`
+	Digest    string    `db:"digest"` // content hash, e.g. "sha256:<hex>"; empty when unknown
 	CreatedAt time.Time `db:"created_at"`
 }
 
@@ -138,3 +300,19 @@ func NewUUID() string {
 	return uuid.New().String()
 }
 
+// NullIfEmpty maps an empty string to SQL NULL, for nullable reference columns
+func NullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// NullIfZero maps zero to SQL NULL, for nullable integer columns
+func NullIfZero(n int) interface{} {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
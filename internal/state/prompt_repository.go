@@ -0,0 +1,168 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PromptRepository defines database operations for prompt templates and their versions
+type PromptRepository interface {
+	PublishPrompt(pv *PromptVersion) (*PromptVersion, error)
+	ListPrompts() ([]*Prompt, error)
+	GetPromptVersion(name string, version int) (*PromptVersion, error)
+	GetLatestPromptVersion(name string) (*PromptVersion, error)
+	ListPromptVersions(name string) ([]*PromptVersion, error)
+}
+
+// PublishPrompt stores a prompt template as the next version of its prompt, creating the
+// prompt if needed. Publishing the same description, template and variables as the latest
+// version returns that version instead of creating a new one.
+func (r *postgresRepository) PublishPrompt(pv *PromptVersion) (*PromptVersion, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the prompt row so concurrent publishes get distinct version numbers
+	now := time.Now()
+	_, err = tx.Exec(`INSERT INTO prompts (name, description, latest_version, created_at, updated_at)
+	                  VALUES ($1, $2, 0, $3, $3) ON CONFLICT (name) DO NOTHING`, pv.Prompt, NullIfEmpty(pv.Description), now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create prompt: %w", err)
+	}
+	var latestVersion int
+	if err := tx.QueryRow(`SELECT latest_version FROM prompts WHERE name = $1 FOR UPDATE`, pv.Prompt).Scan(&latestVersion); err != nil {
+		return nil, err
+	}
+
+	if pv.Variables == nil {
+		pv.Variables = []PromptVariable{}
+	}
+	variablesJSON, _ := json.Marshal(pv.Variables)
+	if latestVersion > 0 {
+		latest, err := scanPromptVersion(tx.QueryRow(`SELECT prompt, version, description, template, variables, source, created_at
+		          FROM prompt_versions WHERE prompt = $1 AND version = $2`, pv.Prompt, latestVersion))
+		if err != nil {
+			return nil, err
+		}
+		latestJSON, _ := json.Marshal(latest.Variables)
+		if latest.Description == pv.Description && latest.Template == pv.Template && string(latestJSON) == string(variablesJSON) {
+			return latest, nil
+		}
+	}
+
+	version := *pv
+	version.Version = latestVersion + 1
+	version.CreatedAt = now
+	query := `INSERT INTO prompt_versions (prompt, version, description, template, variables, source, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err = tx.Exec(query, version.Prompt, version.Version, NullIfEmpty(version.Description), version.Template,
+		string(variablesJSON), NullIfEmpty(version.Source), version.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`UPDATE prompts SET description = $1, latest_version = $2, updated_at = $3 WHERE name = $4`,
+		NullIfEmpty(version.Description), version.Version, now, version.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit prompt version: %w", err)
+	}
+	return &version, nil
+}
+
+// ListPrompts lists all prompts by name
+func (r *postgresRepository) ListPrompts() ([]*Prompt, error) {
+	rows, err := r.db.Query(`SELECT name, description, latest_version, created_at, updated_at FROM prompts ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prompts := []*Prompt{}
+	for rows.Next() {
+		p := &Prompt{}
+		var description sql.NullString
+		if err := rows.Scan(&p.Name, &description, &p.LatestVersion, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		p.Description = description.String
+		prompts = append(prompts, p)
+	}
+	return prompts, nil
+}
+
+// GetPromptVersion retrieves a published version of a prompt
+func (r *postgresRepository) GetPromptVersion(name string, version int) (*PromptVersion, error) {
+	query := `SELECT prompt, version, description, template, variables, source, created_at
+	          FROM prompt_versions WHERE prompt = $1 AND version = $2`
+	pv, err := scanPromptVersion(r.db.QueryRow(query, name, version))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("prompt version not found: %s@%d", name, version)
+		}
+		return nil, err
+	}
+	return pv, nil
+}
+
+// GetLatestPromptVersion retrieves the most recently published version of a prompt
+func (r *postgresRepository) GetLatestPromptVersion(name string) (*PromptVersion, error) {
+	query := `SELECT prompt, version, description, template, variables, source, created_at
+	          FROM prompt_versions WHERE prompt = $1 ORDER BY version DESC LIMIT 1`
+	pv, err := scanPromptVersion(r.db.QueryRow(query, name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("prompt not found: %s", name)
+		}
+		return nil, err
+	}
+	return pv, nil
+}
+
+// ListPromptVersions lists the published versions of a prompt, oldest first
+func (r *postgresRepository) ListPromptVersions(name string) ([]*PromptVersion, error) {
+	query := `SELECT prompt, version, description, template, variables, source, created_at
+	          FROM prompt_versions WHERE prompt = $1 ORDER BY version ASC`
+	rows, err := r.db.Query(query, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []*PromptVersion{}
+	for rows.Next() {
+		pv, err := scanPromptVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, pv)
+	}
+	return versions, nil
+}
+
+// scanPromptVersion scans a prompt_versions row
+func scanPromptVersion(row interface {
+	Scan(dest ...interface{}) error
+}) (*PromptVersion, error) {
+	pv := &PromptVersion{}
+	var description, source sql.NullString
+	var variablesJSON string
+
+	err := row.Scan(&pv.Prompt, &pv.Version, &description, &pv.Template, &variablesJSON, &source, &pv.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	pv.Description = description.String
+	pv.Source = source.String
+	json.Unmarshal([]byte(variablesJSON), &pv.Variables)
+	if pv.Variables == nil {
+		pv.Variables = []PromptVariable{}
+	}
+	return pv, nil
+}
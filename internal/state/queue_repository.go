@@ -15,10 +15,17 @@ type QueueRepository interface {
 	UpdateQueueItem(item *QueueItem) error
 	DeleteQueueItem(id string) error
 	GetQueueStats() (*QueueStats, error)
+	LeaseQueueItem() (*QueueItem, error)
+	ReleaseLeasedQueueItems() (int, error)
 }
 
 // CreateQueueItem creates a new queue item
 func (r *postgresRepository) CreateQueueItem(item *QueueItem) error {
+	return insertQueueItem(r.db, item)
+}
+
+// insertQueueItem inserts a queue item through ex
+func insertQueueItem(ex execer, item *QueueItem) error {
 	if item.ID == "" {
 		item.ID = NewUUID()
 	}
@@ -30,7 +37,7 @@ func (r *postgresRepository) CreateQueueItem(item *QueueItem) error {
 
 	query := `INSERT INTO queue_items (id, job_id, state, data, created_at, updated_at, leased_at, completed_at)
 	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
-	_, err := r.db.Exec(query, item.ID, item.JobID, item.State, string(dataJSON),
+	_, err := ex.Exec(query, item.ID, item.JobID, item.State, string(dataJSON),
 		item.CreatedAt, item.UpdatedAt, item.LeasedAt, item.CompletedAt)
 	return err
 }
@@ -145,6 +152,51 @@ func (r *postgresRepository) DeleteQueueItem(id string) error {
 	return err
 }
 
+// LeaseQueueItem atomically leases the oldest pending queue item.
+// It returns nil when the queue is empty.
+func (r *postgresRepository) LeaseQueueItem() (*QueueItem, error) {
+	item := &QueueItem{}
+	var dataJSON string
+	var leasedAt, completedAt sql.NullTime
+
+	query := `UPDATE queue_items SET state = 'leased', leased_at = $1, updated_at = $1
+	          WHERE id = (SELECT id FROM queue_items WHERE state = 'pending'
+	                      ORDER BY created_at ASC LIMIT 1 FOR UPDATE SKIP LOCKED)
+	          RETURNING id, job_id, state, data, created_at, updated_at, leased_at, completed_at`
+	err := r.db.QueryRow(query, time.Now()).Scan(
+		&item.ID, &item.JobID, &item.State, &dataJSON,
+		&item.CreatedAt, &item.UpdatedAt, &leasedAt, &completedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	json.Unmarshal([]byte(dataJSON), &item.Data)
+	if leasedAt.Valid {
+		item.LeasedAt = &leasedAt.Time
+	}
+	if completedAt.Valid {
+		item.CompletedAt = &completedAt.Time
+	}
+
+	return item, nil
+}
+
+// ReleaseLeasedQueueItems returns every leased queue item to pending.
+// It is used on startup, when no lease can still be held by a live worker.
+func (r *postgresRepository) ReleaseLeasedQueueItems() (int, error) {
+	query := `UPDATE queue_items SET state = 'pending', leased_at = NULL, updated_at = $1
+	          WHERE state = 'leased'`
+	res, err := r.db.Exec(query, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
 // GetQueueStats retrieves queue statistics
 func (r *postgresRepository) GetQueueStats() (*QueueStats, error) {
 	stats := &QueueStats{}
@@ -0,0 +1,63 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SignalRepository defines database operations for job signals
+type SignalRepository interface {
+	CreateSignal(signal *Signal) error
+	ConsumeSignal(jobID, name, stepID string) (*Signal, error)
+}
+
+// CreateSignal buffers a signal for a job
+func (r *postgresRepository) CreateSignal(signal *Signal) error {
+	if signal.ID == "" {
+		signal.ID = NewUUID()
+	}
+	signal.CreatedAt = time.Now()
+	if signal.Payload == nil {
+		signal.Payload = JSONMap{}
+	}
+	payloadJSON, _ := json.Marshal(signal.Payload)
+
+	query := `INSERT INTO signals (id, job_id, name, payload, created_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err := r.db.Exec(query, signal.ID, signal.JobID, signal.Name, string(payloadJSON), signal.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create signal: %w", err)
+	}
+	return nil
+}
+
+// ConsumeSignal marks the oldest unconsumed signal with the given name as consumed by a step.
+// It returns nil when no such signal is buffered.
+func (r *postgresRepository) ConsumeSignal(jobID, name, stepID string) (*Signal, error) {
+	signal := &Signal{}
+	var payloadJSON string
+	var consumedAt time.Time
+
+	query := `UPDATE signals SET consumed_at = $4, step_id = $3
+	          WHERE id = (
+	              SELECT id FROM signals
+	              WHERE job_id = $1 AND name = $2 AND consumed_at IS NULL
+	              ORDER BY created_at
+	              LIMIT 1
+	              FOR UPDATE SKIP LOCKED
+	          )
+	          RETURNING id, job_id, name, payload, step_id, created_at, consumed_at`
+	err := r.db.QueryRow(query, jobID, name, stepID, time.Now()).Scan(
+		&signal.ID, &signal.JobID, &signal.Name, &payloadJSON, &signal.StepID, &signal.CreatedAt, &consumedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to consume signal: %w", err)
+	}
+
+	json.Unmarshal([]byte(payloadJSON), &signal.Payload)
+	signal.ConsumedAt = &consumedAt
+	return signal, nil
+}
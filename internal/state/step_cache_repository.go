@@ -0,0 +1,75 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// StepCacheRepository defines database operations for the step result cache
+type StepCacheRepository interface {
+	GetStepCacheEntry(key string) (*StepCacheEntry, error)
+	SaveStepCacheEntry(entry *StepCacheEntry) error
+	RecordStepCacheHit(key string) error
+}
+
+// GetStepCacheEntry retrieves a cached step result by key.
+// It returns nil when there is no entry.
+func (r *postgresRepository) GetStepCacheEntry(key string) (*StepCacheEntry, error) {
+	entry := &StepCacheEntry{}
+	var outputJSON, artifactsJSON string
+	var sourceJobID, sourceStepID sql.NullString
+	var lastHitAt sql.NullTime
+
+	query := `SELECT key, step_type, output, artifact_ids, source_job_id, source_step_id, hits, created_at, last_hit_at
+	          FROM step_cache WHERE key = $1`
+	err := r.db.QueryRow(query, key).Scan(
+		&entry.Key, &entry.StepType, &outputJSON, &artifactsJSON, &sourceJobID, &sourceStepID,
+		&entry.Hits, &entry.CreatedAt, &lastHitAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	json.Unmarshal([]byte(outputJSON), &entry.Output)
+	json.Unmarshal([]byte(artifactsJSON), &entry.ArtifactIDs)
+	entry.SourceJobID = sourceJobID.String
+	entry.SourceStepID = sourceStepID.String
+	if lastHitAt.Valid {
+		entry.LastHitAt = &lastHitAt.Time
+	}
+
+	return entry, nil
+}
+
+// SaveStepCacheEntry stores a step result, replacing any entry with the same key
+func (r *postgresRepository) SaveStepCacheEntry(entry *StepCacheEntry) error {
+	entry.CreatedAt = time.Now()
+	if entry.ArtifactIDs == nil {
+		entry.ArtifactIDs = []string{}
+	}
+	outputJSON, _ := json.Marshal(entry.Output)
+	artifactsJSON, _ := json.Marshal(entry.ArtifactIDs)
+
+	query := `INSERT INTO step_cache (key, step_type, output, artifact_ids, source_job_id, source_step_id, hits, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, 0, $7)
+	          ON CONFLICT (key) DO UPDATE SET step_type = EXCLUDED.step_type, output = EXCLUDED.output,
+	              artifact_ids = EXCLUDED.artifact_ids, source_job_id = EXCLUDED.source_job_id,
+	              source_step_id = EXCLUDED.source_step_id, hits = 0, created_at = EXCLUDED.created_at,
+	              last_hit_at = NULL`
+	_, err := r.db.Exec(query, entry.Key, entry.StepType, string(outputJSON), string(artifactsJSON),
+		NullIfEmpty(entry.SourceJobID), NullIfEmpty(entry.SourceStepID), entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save step cache entry: %w", err)
+	}
+	return nil
+}
+
+// RecordStepCacheHit counts a reuse of a cached step result
+func (r *postgresRepository) RecordStepCacheHit(key string) error {
+	_, err := r.db.Exec(`UPDATE step_cache SET hits = hits + 1, last_hit_at = $1 WHERE key = $2`, time.Now(), key)
+	return err
+}
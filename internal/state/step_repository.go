@@ -12,6 +12,7 @@ type StepRepository interface {
 	CreateStep(step *Step) error
 	GetStep(id string) (*Step, error)
 	ListSteps(jobID string) ([]*Step, error)
+	ListStepsByStatus(status string) ([]*Step, error)
 	UpdateStep(step *Step) error
 	DeleteStep(id string) error
 }
@@ -27,12 +28,17 @@ func (r *postgresRepository) CreateStep(step *Step) error {
 
 	inputJSON, _ := json.Marshal(step.Input)
 	outputJSON, _ := json.Marshal(step.Output)
+	if step.ArtifactIDs == nil {
+		step.ArtifactIDs = []string{}
+	}
+	artifactsJSON, _ := json.Marshal(step.ArtifactIDs)
 
-	query := `INSERT INTO steps (id, job_id, name, status, input, output, created_at, updated_at, started_at, completed_at, error)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	query := `INSERT INTO steps (id, job_id, name, status, input, output, created_at, updated_at, started_at, completed_at, error,
+	          cached, cache_key, artifact_ids)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
 	_, err := r.db.Exec(query, step.ID, step.JobID, step.Name, step.Status,
 		string(inputJSON), string(outputJSON), step.CreatedAt, step.UpdatedAt,
-		step.StartedAt, step.CompletedAt, step.Error)
+		step.StartedAt, step.CompletedAt, step.Error, step.Cached, NullIfEmpty(step.CacheKey), string(artifactsJSON))
 	return err
 }
 
@@ -41,12 +47,16 @@ func (r *postgresRepository) GetStep(id string) (*Step, error) {
 	step := &Step{}
 	var inputJSON, outputJSON string
 	var startedAt, completedAt sql.NullTime
+	var cacheKey sql.NullString
+	var artifactsJSON string
 
-	query := `SELECT id, job_id, name, status, input, output, created_at, updated_at, started_at, completed_at, error
+	query := `SELECT id, job_id, name, status, input, output, created_at, updated_at, started_at, completed_at, error,
+	          cached, cache_key, artifact_ids
 	          FROM steps WHERE id = $1`
 	err := r.db.QueryRow(query, id).Scan(
 		&step.ID, &step.JobID, &step.Name, &step.Status, &inputJSON, &outputJSON,
-		&step.CreatedAt, &step.UpdatedAt, &startedAt, &completedAt, &step.Error)
+		&step.CreatedAt, &step.UpdatedAt, &startedAt, &completedAt, &step.Error,
+		&step.Cached, &cacheKey, &artifactsJSON)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("step not found: %s", id)
@@ -62,13 +72,16 @@ func (r *postgresRepository) GetStep(id string) (*Step, error) {
 	if completedAt.Valid {
 		step.CompletedAt = &completedAt.Time
 	}
+	step.CacheKey = cacheKey.String
+	json.Unmarshal([]byte(artifactsJSON), &step.ArtifactIDs)
 
 	return step, nil
 }
 
 // ListSteps lists all steps for a job
 func (r *postgresRepository) ListSteps(jobID string) ([]*Step, error) {
-	rows, err := r.db.Query(`SELECT id, job_id, name, status, input, output, created_at, updated_at, started_at, completed_at, error
+	rows, err := r.db.Query(`SELECT id, job_id, name, status, input, output, created_at, updated_at, started_at, completed_at, error,
+	                          cached, cache_key, artifact_ids
 	                          FROM steps WHERE job_id = $1 ORDER BY created_at`, jobID)
 	if err != nil {
 		return nil, err
@@ -80,9 +93,12 @@ func (r *postgresRepository) ListSteps(jobID string) ([]*Step, error) {
 		step := &Step{}
 		var inputJSON, outputJSON string
 		var startedAt, completedAt sql.NullTime
+		var cacheKey sql.NullString
+		var artifactsJSON string
 
 		err := rows.Scan(&step.ID, &step.JobID, &step.Name, &step.Status, &inputJSON, &outputJSON,
-			&step.CreatedAt, &step.UpdatedAt, &startedAt, &completedAt, &step.Error)
+			&step.CreatedAt, &step.UpdatedAt, &startedAt, &completedAt, &step.Error,
+			&step.Cached, &cacheKey, &artifactsJSON)
 		if err != nil {
 			return nil, err
 		}
@@ -95,6 +111,50 @@ func (r *postgresRepository) ListSteps(jobID string) ([]*Step, error) {
 		if completedAt.Valid {
 			step.CompletedAt = &completedAt.Time
 		}
+		step.CacheKey = cacheKey.String
+		json.Unmarshal([]byte(artifactsJSON), &step.ArtifactIDs)
+
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// ListStepsByStatus lists steps across all jobs that are in the given status
+func (r *postgresRepository) ListStepsByStatus(status string) ([]*Step, error) {
+	rows, err := r.db.Query(`SELECT id, job_id, name, status, input, output, created_at, updated_at, started_at, completed_at, error,
+	                          cached, cache_key, artifact_ids
+	                          FROM steps WHERE status = $1 ORDER BY created_at`, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	steps := []*Step{}
+	for rows.Next() {
+		step := &Step{}
+		var inputJSON, outputJSON string
+		var startedAt, completedAt sql.NullTime
+		var cacheKey sql.NullString
+		var artifactsJSON string
+
+		err := rows.Scan(&step.ID, &step.JobID, &step.Name, &step.Status, &inputJSON, &outputJSON,
+			&step.CreatedAt, &step.UpdatedAt, &startedAt, &completedAt, &step.Error,
+			&step.Cached, &cacheKey, &artifactsJSON)
+		if err != nil {
+			return nil, err
+		}
+
+		json.Unmarshal([]byte(inputJSON), &step.Input)
+		json.Unmarshal([]byte(outputJSON), &step.Output)
+		if startedAt.Valid {
+			step.StartedAt = &startedAt.Time
+		}
+		if completedAt.Valid {
+			step.CompletedAt = &completedAt.Time
+		}
+		step.CacheKey = cacheKey.String
+		json.Unmarshal([]byte(artifactsJSON), &step.ArtifactIDs)
 
 		steps = append(steps, step)
 	}
@@ -107,12 +167,16 @@ func (r *postgresRepository) UpdateStep(step *Step) error {
 	step.UpdatedAt = time.Now()
 	inputJSON, _ := json.Marshal(step.Input)
 	outputJSON, _ := json.Marshal(step.Output)
+	if step.ArtifactIDs == nil {
+		step.ArtifactIDs = []string{}
+	}
+	artifactsJSON, _ := json.Marshal(step.ArtifactIDs)
 
 	query := `UPDATE steps SET job_id = $1, name = $2, status = $3, input = $4, output = $5, updated_at = $6, 
-	          started_at = $7, completed_at = $8, error = $9 WHERE id = $10`
+	          started_at = $7, completed_at = $8, error = $9, cached = $10, cache_key = $11, artifact_ids = $12 WHERE id = $13`
 	_, err := r.db.Exec(query, step.JobID, step.Name, step.Status,
 		string(inputJSON), string(outputJSON), step.UpdatedAt,
-		step.StartedAt, step.CompletedAt, step.Error, step.ID)
+		step.StartedAt, step.CompletedAt, step.Error, step.Cached, NullIfEmpty(step.CacheKey), string(artifactsJSON), step.ID)
 	return err
 }
 
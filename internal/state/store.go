@@ -2,9 +2,11 @@ package state
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib" // PostgreSQL driver
@@ -16,11 +18,19 @@ type Repository interface {
 	JobRepository
 	RunRepository
 	WorkflowRepository
+	WorkflowVersionRepository
 	StepRepository
 	EventRepository
 	ArtifactRepository
 	AgentRepository
 	QueueRepository
+	StepCacheRepository
+	SignalRepository
+	MatrixRepository
+	TriggerFiringRepository
+	LLMCallRepository
+	LLMCacheRepository
+	PromptRepository
 	
 	// Migration
 	Migrate(migrationsPath string) error
@@ -32,6 +42,22 @@ type Repository interface {
 	Close() error
 }
 
+// execer is implemented by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// ErrNotFound is wrapped by lookups of workflows and workflow versions that do not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrAlreadyExists is wrapped by creates of workflows whose name is already taken
+var ErrAlreadyExists = errors.New("already exists")
+
 // Store is an alias for Repository for backward compatibility
 type Store = Repository
 
@@ -51,15 +77,23 @@ type postgresRepository struct {
 
 // Compile-time interface implementation checks
 var (
-	_ Repository         = (*postgresRepository)(nil)
-	_ JobRepository      = (*postgresRepository)(nil)
-	_ RunRepository      = (*postgresRepository)(nil)
-	_ WorkflowRepository = (*postgresRepository)(nil)
-	_ StepRepository     = (*postgresRepository)(nil)
-	_ EventRepository    = (*postgresRepository)(nil)
-	_ ArtifactRepository = (*postgresRepository)(nil)
-	_ AgentRepository    = (*postgresRepository)(nil)
-	_ QueueRepository    = (*postgresRepository)(nil)
+	_ Repository                = (*postgresRepository)(nil)
+	_ JobRepository             = (*postgresRepository)(nil)
+	_ RunRepository             = (*postgresRepository)(nil)
+	_ WorkflowRepository        = (*postgresRepository)(nil)
+	_ WorkflowVersionRepository = (*postgresRepository)(nil)
+	_ StepRepository            = (*postgresRepository)(nil)
+	_ EventRepository           = (*postgresRepository)(nil)
+	_ ArtifactRepository        = (*postgresRepository)(nil)
+	_ AgentRepository           = (*postgresRepository)(nil)
+	_ QueueRepository           = (*postgresRepository)(nil)
+	_ StepCacheRepository       = (*postgresRepository)(nil)
+	_ SignalRepository          = (*postgresRepository)(nil)
+	_ MatrixRepository          = (*postgresRepository)(nil)
+	_ TriggerFiringRepository   = (*postgresRepository)(nil)
+	_ LLMCallRepository         = (*postgresRepository)(nil)
+	_ LLMCacheRepository        = (*postgresRepository)(nil)
+	_ PromptRepository          = (*postgresRepository)(nil)
 )
 
 // NewRepository creates a new PostgreSQL repository
@@ -88,22 +122,75 @@ func NewStore(connectionString string) (Store, error) {
 	return NewRepository(connectionString)
 }
 
-// Migrate runs database migrations
+// Migrate runs database migrations.
+// Every *.sql file in migrationsPath is executed in lexical order, in its own transaction,
+// and recorded in schema_migrations so it runs only once. Databases migrated before the
+// ledger existed run every file once more, so migrations must stay idempotent (IF NOT EXISTS).
 func (r *postgresRepository) Migrate(migrationsPath string) error {
-	// Read migration file
-	migrationSQL, err := os.ReadFile(filepath.Join(migrationsPath, "0001_init.sql"))
+	files, err := filepath.Glob(filepath.Join(migrationsPath, "*.sql"))
+	if err != nil {
+		return fmt.Errorf("failed to find migration files: %w", err)
+	}
+	sort.Strings(files)
+
+	_, err = r.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+	    name VARCHAR(255) PRIMARY KEY,
+	    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	applied := map[string]bool{}
+	rows, err := r.db.Query(`SELECT name FROM schema_migrations`)
 	if err != nil {
-		return fmt.Errorf("failed to read migration file: %w", err)
+		return fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		applied[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
 	}
 
-	// Execute migration
-	if _, err := r.db.Exec(string(migrationSQL)); err != nil {
-		return fmt.Errorf("failed to execute migration: %w", err)
+	for _, file := range files {
+		name := filepath.Base(file)
+		if applied[name] {
+			continue
+		}
+		if err := r.migrate(file); err != nil {
+			return fmt.Errorf("failed to execute migration %s: %w", name, err)
+		}
 	}
 
 	return nil
 }
 
+// migrate executes one migration file and records it as applied
+func (r *postgresRepository) migrate(file string) error {
+	migrationSQL, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(string(migrationSQL)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (name) VALUES ($1)`, filepath.Base(file)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 // GetDB returns the underlying database connection
 func (r *postgresRepository) GetDB() *sql.DB {
 	return r.db
@@ -14,6 +14,7 @@ type WorkflowRepository interface {
 	ListWorkflows() ([]*Workflow, error)
 	UpdateWorkflow(workflow *Workflow) error
 	DeleteWorkflow(name string) error
+	SoftDeleteWorkflow(name string) error
 }
 
 // CreateWorkflow creates a new workflow
@@ -24,11 +25,21 @@ func (r *postgresRepository) CreateWorkflow(workflow *Workflow) error {
 
 	schemaJSON, _ := json.Marshal(workflow.Schema)
 
+	// A soft-deleted workflow with the same name is revived; its published versions are kept
 	query := `INSERT INTO workflows (name, description, schema, version, created_at, updated_at)
-	          VALUES ($1, $2, $3, $4, $5, $6)`
-	_, err := r.db.Exec(query, workflow.Name, workflow.Description, string(schemaJSON),
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description, schema = EXCLUDED.schema,
+	          updated_at = EXCLUDED.updated_at, deleted_at = NULL
+	          WHERE workflows.deleted_at IS NOT NULL`
+	res, err := r.db.Exec(query, workflow.Name, workflow.Description, string(schemaJSON),
 		workflow.Version, workflow.CreatedAt, workflow.UpdatedAt)
-	return err
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("workflow %w: %s", ErrAlreadyExists, workflow.Name)
+	}
+	return nil
 }
 
 // GetWorkflow retrieves a workflow by name
@@ -37,13 +48,13 @@ func (r *postgresRepository) GetWorkflow(name string) (*Workflow, error) {
 	var schemaJSON string
 
 	query := `SELECT name, description, schema, version, created_at, updated_at
-	          FROM workflows WHERE name = $1`
+	          FROM workflows WHERE name = $1 AND deleted_at IS NULL`
 	err := r.db.QueryRow(query, name).Scan(
 		&workflow.Name, &workflow.Description, &schemaJSON,
 		&workflow.Version, &workflow.CreatedAt, &workflow.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("workflow not found: %s", name)
+			return nil, fmt.Errorf("workflow %w: %s", ErrNotFound, name)
 		}
 		return nil, err
 	}
@@ -54,7 +65,7 @@ func (r *postgresRepository) GetWorkflow(name string) (*Workflow, error) {
 
 // ListWorkflows lists all workflows
 func (r *postgresRepository) ListWorkflows() ([]*Workflow, error) {
-	rows, err := r.db.Query(`SELECT name, description, schema, version, created_at, updated_at FROM workflows ORDER BY name`)
+	rows, err := r.db.Query(`SELECT name, description, schema, version, created_at, updated_at FROM workflows WHERE deleted_at IS NULL ORDER BY name`)
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +94,8 @@ func (r *postgresRepository) UpdateWorkflow(workflow *Workflow) error {
 	workflow.UpdatedAt = time.Now()
 	schemaJSON, _ := json.Marshal(workflow.Schema)
 
-	query := `UPDATE workflows SET description = $1, schema = $2, version = $3, updated_at = $4 WHERE name = $5`
+	query := `UPDATE workflows SET description = $1, schema = $2, version = $3, updated_at = $4
+	          WHERE name = $5 AND deleted_at IS NULL`
 	_, err := r.db.Exec(query, workflow.Description, string(schemaJSON), workflow.Version, workflow.UpdatedAt, workflow.Name)
 	return err
 }
@@ -93,3 +105,9 @@ func (r *postgresRepository) DeleteWorkflow(name string) error {
 	_, err := r.db.Exec("DELETE FROM workflows WHERE name = $1", name)
 	return err
 }
+
+// SoftDeleteWorkflow hides a workflow while keeping its row and published versions
+func (r *postgresRepository) SoftDeleteWorkflow(name string) error {
+	_, err := r.db.Exec("UPDATE workflows SET deleted_at = $1 WHERE name = $2 AND deleted_at IS NULL", time.Now(), name)
+	return err
+}
@@ -0,0 +1,183 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// WorkflowVersionRepository defines database operations for published workflow versions
+type WorkflowVersionRepository interface {
+	PublishWorkflow(name string) (*WorkflowVersion, error)
+	SaveAndPublishWorkflow(workflow *Workflow) (*WorkflowVersion, error)
+	GetWorkflowVersion(name string, version int) (*WorkflowVersion, error)
+	GetLatestWorkflowVersion(name string) (*WorkflowVersion, error)
+	ListWorkflowVersions(name string) ([]*WorkflowVersion, error)
+}
+
+// PublishWorkflow snapshots the current definition of a workflow as its next version.
+// Publishing an unchanged definition returns the latest version instead of creating a new one.
+func (r *postgresRepository) PublishWorkflow(name string) (*WorkflowVersion, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	version, err := publishWorkflow(tx, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit workflow version: %w", err)
+	}
+	return version, nil
+}
+
+// SaveAndPublishWorkflow writes a workflow's definition, creating the workflow or reviving
+// a soft-deleted one, and publishes it, in one transaction: if publishing fails the
+// workflow keeps its previous definition.
+func (r *postgresRepository) SaveAndPublishWorkflow(workflow *Workflow) (*WorkflowVersion, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	workflow.UpdatedAt = now
+	schemaJSON, _ := json.Marshal(workflow.Schema)
+	query := `INSERT INTO workflows (name, description, schema, version, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, $5)
+	          ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description, schema = EXCLUDED.schema,
+	          updated_at = EXCLUDED.updated_at, deleted_at = NULL`
+	if _, err := tx.Exec(query, workflow.Name, workflow.Description, string(schemaJSON), workflow.Version, now); err != nil {
+		return nil, fmt.Errorf("failed to save workflow: %w", err)
+	}
+
+	version, err := publishWorkflow(tx, workflow.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit workflow version: %w", err)
+	}
+	return version, nil
+}
+
+// publishWorkflow publishes the current definition of a workflow within tx
+func publishWorkflow(tx *sql.Tx, name string) (*WorkflowVersion, error) {
+	// Lock the workflow row so concurrent publishes get distinct version numbers
+	var description sql.NullString
+	var schemaJSON string
+	err := tx.QueryRow(`SELECT description, schema FROM workflows WHERE name = $1 AND deleted_at IS NULL FOR UPDATE`, name).
+		Scan(&description, &schemaJSON)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("workflow not found: %s", name)
+		}
+		return nil, err
+	}
+	schema := JSONMap{}
+	json.Unmarshal([]byte(schemaJSON), &schema)
+
+	next := 1
+	latest, err := scanWorkflowVersion(tx.QueryRow(`SELECT workflow, version, description, schema, created_at
+	          FROM workflow_versions WHERE workflow = $1 ORDER BY version DESC LIMIT 1`, name))
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return nil, err
+	default:
+		if latest.Description == description.String && reflect.DeepEqual(latest.Schema, schema) {
+			return latest, nil
+		}
+		next = latest.Version + 1
+	}
+
+	version := &WorkflowVersion{
+		Workflow:    name,
+		Version:     next,
+		Description: description.String,
+		Schema:      schema,
+		CreatedAt:   time.Now(),
+	}
+	query := `INSERT INTO workflow_versions (workflow, version, description, schema, created_at)
+	          VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.Exec(query, version.Workflow, version.Version, version.Description, schemaJSON, version.CreatedAt); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE workflows SET version = $1 WHERE name = $2`, strconv.Itoa(version.Version), name); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// GetWorkflowVersion retrieves a published version of a workflow
+func (r *postgresRepository) GetWorkflowVersion(name string, version int) (*WorkflowVersion, error) {
+	query := `SELECT workflow, version, description, schema, created_at
+	          FROM workflow_versions WHERE workflow = $1 AND version = $2`
+	wv, err := scanWorkflowVersion(r.db.QueryRow(query, name, version))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("workflow version %w: %s@%d", ErrNotFound, name, version)
+		}
+		return nil, err
+	}
+	return wv, nil
+}
+
+// GetLatestWorkflowVersion retrieves the most recently published version of a workflow
+func (r *postgresRepository) GetLatestWorkflowVersion(name string) (*WorkflowVersion, error) {
+	query := `SELECT workflow, version, description, schema, created_at
+	          FROM workflow_versions WHERE workflow = $1 ORDER BY version DESC LIMIT 1`
+	wv, err := scanWorkflowVersion(r.db.QueryRow(query, name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("published workflow version %w: %s", ErrNotFound, name)
+		}
+		return nil, err
+	}
+	return wv, nil
+}
+
+// ListWorkflowVersions lists the published versions of a workflow, oldest first
+func (r *postgresRepository) ListWorkflowVersions(name string) ([]*WorkflowVersion, error) {
+	query := `SELECT workflow, version, description, schema, created_at
+	          FROM workflow_versions WHERE workflow = $1 ORDER BY version ASC`
+	rows, err := r.db.Query(query, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []*WorkflowVersion{}
+	for rows.Next() {
+		wv, err := scanWorkflowVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, wv)
+	}
+
+	return versions, nil
+}
+
+// scanWorkflowVersion scans a workflow_versions row
+func scanWorkflowVersion(row rowScanner) (*WorkflowVersion, error) {
+	wv := &WorkflowVersion{}
+	var description sql.NullString
+	var schemaJSON string
+
+	err := row.Scan(&wv.Workflow, &wv.Version, &description, &schemaJSON, &wv.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	wv.Description = description.String
+	json.Unmarshal([]byte(schemaJSON), &wv.Schema)
+	return wv, nil
+}
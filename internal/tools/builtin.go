@@ -0,0 +1,311 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Limits of the built-in tools
+const (
+	maxListEntries = 500
+	maxGrepMatches = 100
+	maxGrepFile    = 1024 * 1024 // larger files are skipped
+)
+
+// builtins returns the tools every registry has
+func builtins() []*Tool {
+	return []*Tool{
+		{
+			Name:        "read_file",
+			Description: "Read a text file, optionally only some of its lines. Lines are returned numbered.",
+			Parameters: map[string]interface{}{
+				"type":     "object",
+				"required": []interface{}{"path"},
+				"properties": map[string]interface{}{
+					"path":      map[string]interface{}{"type": "string", "description": "file path relative to the working directory"},
+					"startLine": map[string]interface{}{"type": "integer", "minimum": 1, "description": "first line to read, from 1"},
+					"endLine":   map[string]interface{}{"type": "integer", "minimum": 1, "description": "last line to read"},
+				},
+				"additionalProperties": false,
+			},
+			Run: readFile,
+		},
+		{
+			Name:        "list_dir",
+			Description: "List the entries of a directory; subdirectories end with a slash.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{"type": "string", "default": ".", "description": "directory path relative to the working directory"},
+				},
+				"additionalProperties": false,
+			},
+			Run: listDir,
+		},
+		{
+			Name:        "grep",
+			Description: "Search files for lines matching a regular expression (RE2 syntax). Returns path:line: text for each match.",
+			Parameters: map[string]interface{}{
+				"type":     "object",
+				"required": []interface{}{"pattern"},
+				"properties": map[string]interface{}{
+					"pattern": map[string]interface{}{"type": "string", "description": "regular expression"},
+					"path":    map[string]interface{}{"type": "string", "default": ".", "description": "file or directory to search"},
+					"glob":    map[string]interface{}{"type": "string", "description": "only search files whose name matches, e.g. *.go"},
+				},
+				"additionalProperties": false,
+			},
+			Run: grep,
+		},
+		{
+			Name:        "run_tests",
+			Description: "Run the test suite, or some packages of it, and return the output with the exit status.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"packages": map[string]interface{}{
+						"type": "array", "items": map[string]interface{}{"type": "string"},
+						"default": []interface{}{"./..."}, "description": "packages to test, as paths relative to the working directory such as ./... or ./internal/parser",
+					},
+					"run": map[string]interface{}{"type": "string", "description": "only run tests matching this regular expression"},
+				},
+				"additionalProperties": false,
+			},
+			Run: runTests,
+		},
+	}
+}
+
+// readFile implements read_file
+func readFile(ctx context.Context, env *Env, args map[string]interface{}) (string, error) {
+	path, _ := args["path"].(string)
+	full, err := resolvePath(env.Root, path)
+	if err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(full)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if isBinary(content) {
+		return "", fmt.Errorf("%s is a binary file", path)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	start, end := 1, len(lines)
+	if n, ok := intArg(args, "startLine"); ok {
+		start = n
+	}
+	if n, ok := intArg(args, "endLine"); ok && n < end {
+		end = n
+	}
+	if start > len(lines) {
+		return "", fmt.Errorf("%s has only %d lines", path, len(lines))
+	}
+	if end < start {
+		return "", errors.New("endLine is before startLine")
+	}
+
+	var b strings.Builder
+	for i := start; i <= end; i++ {
+		fmt.Fprintf(&b, "%d: %s\n", i, lines[i-1])
+	}
+	return b.String(), nil
+}
+
+// listDir implements list_dir
+func listDir(ctx context.Context, env *Env, args map[string]interface{}) (string, error) {
+	path, _ := args["path"].(string)
+	full, err := resolvePath(env.Root, path)
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(full)
+	if err != nil {
+		return "", fmt.Errorf("failed to list %s: %w", path, err)
+	}
+
+	var b strings.Builder
+	for i, e := range entries {
+		if i == maxListEntries {
+			fmt.Fprintf(&b, "... (%d more entries)\n", len(entries)-maxListEntries)
+			break
+		}
+		name := e.Name()
+		if e.IsDir() {
+			name += "/"
+		}
+		b.WriteString(name + "\n")
+	}
+	if len(entries) == 0 {
+		return "(empty directory)", nil
+	}
+	return b.String(), nil
+}
+
+// grep implements grep. Hidden directories such as .git, large files and binary files
+// are skipped; results are in path order.
+func grep(ctx context.Context, env *Env, args map[string]interface{}) (string, error) {
+	pattern, _ := args["pattern"].(string)
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %w", err)
+	}
+	glob, _ := args["glob"].(string)
+	if glob != "" {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return "", fmt.Errorf("invalid glob: %w", err)
+		}
+	}
+	path, _ := args["path"].(string)
+	start, err := resolvePath(env.Root, path)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	matches := 0
+	err = filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // unreadable entries are skipped
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if p != start && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if glob != "" {
+			if ok, _ := filepath.Match(glob, d.Name()); !ok {
+				return nil
+			}
+		}
+		if info, err := d.Info(); err != nil || info.Size() > maxGrepFile {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil || isBinary(content) {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(env.Root, p)
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		scanner.Buffer(make([]byte, 64*1024), maxGrepFile)
+		for line := 1; scanner.Scan(); line++ {
+			if !re.Match(scanner.Bytes()) {
+				continue
+			}
+			if matches == maxGrepMatches {
+				return filepath.SkipAll
+			}
+			fmt.Fprintf(&b, "%s:%d: %s\n", filepath.ToSlash(rel), line, scanner.Text())
+			matches++
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case matches == 0:
+		return "no matches", nil
+	case matches == maxGrepMatches:
+		fmt.Fprintf(&b, "... (stopped after %d matches; narrow the pattern or path)\n", maxGrepMatches)
+	}
+	return b.String(), nil
+}
+
+// runTests implements run_tests. Failing tests are not an error: the model gets the
+// output and the exit status either way.
+func runTests(ctx context.Context, env *Env, args map[string]interface{}) (string, error) {
+	var packages []string
+	list, _ := args["packages"].([]interface{})
+	for _, p := range list {
+		pkg, _ := p.(string)
+		if err := checkPackage(env.Root, pkg); err != nil {
+			return "", err
+		}
+		packages = append(packages, pkg)
+	}
+
+	command := append([]string{}, env.TestCommand...)
+	if run, _ := args["run"].(string); run != "" {
+		command = append(command, "-run", run)
+	}
+	command = append(command, packages...)
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Dir = env.Root
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("tests did not finish in time: %w", ctx.Err())
+	}
+
+	// The summary and the failures are at the end of the output
+	output := string(out)
+	if len(output) > maxOutput-100 {
+		output = fmt.Sprintf("... (%d bytes cut)\n", len(output)-(maxOutput-100)) + output[len(output)-(maxOutput-100):]
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return output + "\nexit status 0 (passed)", nil
+	case errors.As(err, &exitErr):
+		return output + fmt.Sprintf("\nexit status %d (failed)", exitErr.ExitCode()), nil
+	default:
+		return "", fmt.Errorf("failed to run %s: %w", command[0], err)
+	}
+}
+
+// checkPackage makes sure a package run_tests is asked for is a directory inside the
+// working directory, such as ./... or ./internal/parser/..., so no code outside it is
+// built or run
+func checkPackage(root, pkg string) error {
+	if pkg != "." && !strings.HasPrefix(pkg, "./") {
+		return fmt.Errorf("invalid package %q: use a path relative to the working directory, such as ./... or ./internal/parser", pkg)
+	}
+	dir := strings.TrimSuffix(strings.TrimSuffix(pkg, "..."), "/")
+	if dir == "" {
+		dir = "."
+	}
+	if _, err := resolvePath(root, dir); err != nil {
+		return fmt.Errorf("invalid package %q: %w", pkg, err)
+	}
+	return nil
+}
+
+// intArg reads an integer argument; JSON numbers arrive as float64
+func intArg(args map[string]interface{}, key string) (int, bool) {
+	switch n := args[key].(type) {
+	case float64:
+		return int(n), true
+	case int:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// isBinary guesses whether content is binary from a NUL byte near its start
+func isBinary(content []byte) bool {
+	head := content
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	return bytes.IndexByte(head, 0) >= 0
+}
@@ -0,0 +1,216 @@
+// Package tools is the registry of tools llm steps let models call: reading files,
+// listing directories, searching with grep and running tests. Every tool works inside a
+// root directory; paths leading outside it, through ".." or symlinks, are refused.
+//
+// A step binds the tools it names to its working directory, which gives the
+// llm.ToolExecutor the gateway's tool loop runs:
+//
+//	box, err := registry.Bind("checkouts/api", []string{"read_file", "grep", "run_tests"})
+//	resp, err := gateway.ChatTools(ctx, call, req, box, llm.ToolLoop{MaxIterations: 8})
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"agent-project-manager/internal/jsonschema"
+	"agent-project-manager/internal/llm"
+)
+
+// maxOutput caps what a tool returns, as it all goes into the model's context
+const maxOutput = 32 * 1024
+
+// Tool is a function a model may call
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{} // a JSON Schema of the arguments object
+	// Run executes the tool with the validated arguments, schema defaults filled in.
+	// An error is shown to the model, which may try again.
+	Run func(ctx context.Context, env *Env, args map[string]interface{}) (string, error)
+}
+
+// Env is what a bound tool works with
+type Env struct {
+	Root        string   // the working directory; tools stay inside it
+	TestCommand []string // the command run_tests runs
+}
+
+// Options configures a Registry
+type Options struct {
+	Root        string   // directory the tools are confined to
+	TestCommand []string // command run_tests runs, followed by the packages; default go test
+}
+
+// Registry holds the tools steps may offer
+type Registry struct {
+	opts  Options
+	tools map[string]*Tool
+}
+
+// NewRegistry creates a registry with the built-in tools
+func NewRegistry(opts Options) *Registry {
+	if len(opts.TestCommand) == 0 {
+		opts.TestCommand = []string{"go", "test"}
+	}
+	r := &Registry{opts: opts, tools: map[string]*Tool{}}
+	for _, t := range builtins() {
+		r.Register(t)
+	}
+	return r
+}
+
+// Register adds a tool, replacing one of the same name
+func (r *Registry) Register(t *Tool) {
+	r.tools[t.Name] = t
+}
+
+// Names lists the registered tools in order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.tools))
+	for name := range r.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Bind prepares the named tools to work in dir, a directory relative to the registry's root
+// ("" is the root itself)
+func (r *Registry) Bind(dir string, names []string) (*Toolbox, error) {
+	if r.opts.Root == "" {
+		return nil, errors.New("no tools root is configured (tools.root or artifacts.workDir)")
+	}
+	root, err := filepath.Abs(r.opts.Root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tools root: %w", err)
+	}
+	workDir, err := resolvePath(root, dir)
+	if err != nil {
+		return nil, fmt.Errorf("workdir: %w", err)
+	}
+	if info, err := os.Stat(workDir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("workdir: %s is not a directory", dir)
+	}
+
+	box := &Toolbox{
+		env:     &Env{Root: workDir, TestCommand: r.opts.TestCommand},
+		tools:   map[string]*Tool{},
+		schemas: map[string]*jsonschema.Schema{},
+	}
+	for _, name := range names {
+		t, ok := r.tools[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown tool %q (available: %s)", name, strings.Join(r.Names(), ", "))
+		}
+		schema, err := jsonschema.Compile(t.Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: invalid parameters schema: %w", name, err)
+		}
+		if _, dup := box.tools[name]; !dup {
+			box.order = append(box.order, name)
+		}
+		box.tools[name] = t
+		box.schemas[name] = schema
+	}
+	return box, nil
+}
+
+// Toolbox is a set of tools bound to a working directory; it implements llm.ToolExecutor
+type Toolbox struct {
+	env     *Env
+	order   []string
+	tools   map[string]*Tool
+	schemas map[string]*jsonschema.Schema
+}
+
+// Dir returns the directory the tools work in
+func (b *Toolbox) Dir() string {
+	return b.env.Root
+}
+
+// Tools implements llm.ToolExecutor
+func (b *Toolbox) Tools() []llm.ToolSpec {
+	specs := make([]llm.ToolSpec, len(b.order))
+	for i, name := range b.order {
+		t := b.tools[name]
+		specs[i] = llm.ToolSpec{Name: t.Name, Description: t.Description, Parameters: t.Parameters}
+	}
+	return specs
+}
+
+// CallTool implements llm.ToolExecutor: it validates the arguments against the tool's
+// schema before running it
+func (b *Toolbox) CallTool(ctx context.Context, name, arguments string) (string, error) {
+	t, ok := b.tools[name]
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", name)
+	}
+	var doc interface{}
+	if strings.TrimSpace(arguments) == "" {
+		doc = map[string]interface{}{}
+	} else if err := json.Unmarshal([]byte(arguments), &doc); err != nil {
+		return "", fmt.Errorf("arguments are not valid JSON: %w", err)
+	}
+	value, verrs := b.schemas[name].Validate(doc)
+	if len(verrs) > 0 {
+		msgs := make([]string, len(verrs))
+		for i, v := range verrs {
+			msgs[i] = v.Error()
+		}
+		return "", fmt.Errorf("invalid arguments: %s", strings.Join(msgs, "; "))
+	}
+	args, _ := value.(map[string]interface{})
+	out, err := t.Run(ctx, b.env, args)
+	if err != nil {
+		return "", err
+	}
+	return truncate(out), nil
+}
+
+// resolvePath turns a path relative to root into an absolute path inside it.
+// Symlinks are followed, so a link cannot lead out of the root either.
+func resolvePath(root, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return "", fmt.Errorf("%s: use a path relative to the working directory", path)
+	}
+	full := filepath.Join(root, path)
+	if !within(root, full) {
+		return "", fmt.Errorf("%s is outside the working directory", path)
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	real, err := filepath.EvalSymlinks(full)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%s does not exist", path)
+		}
+		return "", err
+	}
+	if !within(realRoot, real) {
+		return "", fmt.Errorf("%s is outside the working directory", path)
+	}
+	return full, nil
+}
+
+// within reports whether path is root or below it
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// truncate cuts output beyond maxOutput, saying so
+func truncate(s string) string {
+	if len(s) <= maxOutput {
+		return s
+	}
+	return s[:maxOutput] + fmt.Sprintf("\n... (truncated, %d more bytes)", len(s)-maxOutput)
+}
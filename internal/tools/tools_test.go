@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRoot creates a working directory with a nested package, and a secret file and
+// a symlink to it outside the directory
+func newTestRoot(t *testing.T) string {
+	t.Helper()
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	for path, content := range map[string]string{
+		"root/main.go":              "package main\n\nfunc main() {}\n",
+		"root/internal/p/p.go":      "package p\n\n// TODO: tidy\nfunc P() {}\n",
+		"root/.git/HEAD":            "TODO: hidden\n",
+		"root/bin/tool":             "\x00\x01TODO",
+		"secret/credentials.txt":    "TODO: never read\n",
+		"root/internal/empty/.keep": "",
+	} {
+		full := filepath.Join(base, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(base, "secret"), filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("internal/p", filepath.Join(root, "inside")); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestResolvePath(t *testing.T) {
+	root := newTestRoot(t)
+	tests := []struct {
+		path string
+		err  string
+	}{
+		{path: ""},
+		{path: "main.go"},
+		{path: "internal/../main.go"},
+		{path: "inside/p.go"},
+		{path: "/etc/passwd", err: "/etc/passwd: use a path relative to the working directory"},
+		{path: "../secret/credentials.txt", err: "../secret/credentials.txt is outside the working directory"},
+		{path: "escape/credentials.txt", err: "escape/credentials.txt is outside the working directory"},
+		{path: "missing.go", err: "missing.go does not exist"},
+	}
+	for _, tc := range tests {
+		full, err := resolvePath(root, tc.path)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("resolvePath(%q) error = %v, want %q", tc.path, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || full != filepath.Join(root, tc.path) {
+			t.Errorf("resolvePath(%q) = %q, %v", tc.path, full, err)
+		}
+	}
+}
+
+func TestCheckPackage(t *testing.T) {
+	root := newTestRoot(t)
+	tests := []struct {
+		pkg string
+		ok  bool
+	}{
+		{".", true},
+		{"./...", true},
+		{"./internal/p", true},
+		{"./internal/...", true},
+		{"./internal/p/", true},
+		{"./../secret/...", false},
+		{"./escape/...", false},
+		{"./missing/...", false},
+		{"../secret", false},
+		{"/tmp/...", false},
+		{"example.com/evil/pkg", false},
+		{"-exec=sh", false},
+	}
+	for _, tc := range tests {
+		err := checkPackage(root, tc.pkg)
+		if (err == nil) != tc.ok {
+			t.Errorf("checkPackage(%q) error = %v, want ok %v", tc.pkg, err, tc.ok)
+		}
+	}
+}
+
+func TestBind(t *testing.T) {
+	root := newTestRoot(t)
+	r := NewRegistry(Options{Root: root})
+
+	if _, err := NewRegistry(Options{}).Bind("", []string{"grep"}); err == nil {
+		t.Error("binding without a root: want an error")
+	}
+	if _, err := r.Bind("escape", []string{"grep"}); err == nil {
+		t.Error("binding a workdir outside the root: want an error")
+	}
+	if _, err := r.Bind("main.go", []string{"grep"}); err == nil {
+		t.Error("binding a file as the workdir: want an error")
+	}
+	if _, err := r.Bind("", []string{"rm"}); err == nil || !strings.Contains(err.Error(), "available: grep, list_dir, read_file, run_tests") {
+		t.Errorf("binding an unknown tool: error = %v", err)
+	}
+
+	box, err := r.Bind("internal", []string{"read_file", "grep", "read_file"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	specs := box.Tools()
+	if len(specs) != 2 || specs[0].Name != "read_file" || specs[1].Name != "grep" {
+		t.Errorf("tools %v, want read_file and grep in the order named", specs)
+	}
+	if out, err := box.CallTool(context.Background(), "read_file", `{"path": "p/p.go"}`); err != nil || !strings.HasPrefix(out, "1: package p\n") {
+		t.Errorf("read_file in the bound workdir = %q, %v", out, err)
+	}
+	if _, err := box.CallTool(context.Background(), "read_file", `{"path": "../main.go"}`); err == nil {
+		t.Error("read_file above the bound workdir: want an error")
+	}
+	if _, err := box.CallTool(context.Background(), "list_dir", `{}`); err == nil {
+		t.Error("calling a tool that was not bound: want an error")
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	root := newTestRoot(t)
+	box, err := NewRegistry(Options{Root: root, TestCommand: []string{"echo", "testing"}}).
+		Bind("", []string{"read_file", "list_dir", "grep", "run_tests"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		tool, args string
+		want       string // the output, or the error when err is set
+		err        bool
+	}{
+		{tool: "read_file", args: `{"path": "internal/p/p.go", "startLine": 3, "endLine": 3}`, want: "3: // TODO: tidy\n"},
+		{tool: "read_file", args: `{"path": "main.go", "startLine": 9}`, want: "main.go has only 3 lines", err: true},
+		{tool: "read_file", args: `{"path": "bin/tool"}`, want: "bin/tool is a binary file", err: true},
+		{tool: "read_file", args: `{"path": "main.go", "extra": 1}`, want: "invalid arguments: /extra: is not an allowed property", err: true},
+		{tool: "read_file", args: `{"path": "escape/credentials.txt"}`, want: "escape/credentials.txt is outside the working directory", err: true},
+		{tool: "read_file", args: `not json`, want: "arguments are not valid JSON", err: true},
+		{tool: "list_dir", args: ``, want: ".git/\nbin/\nescape\ninside\ninternal/\nmain.go\n"},
+		{tool: "list_dir", args: `{"path": "internal"}`, want: "empty/\np/\n"},
+		{tool: "grep", args: `{"pattern": "TODO"}`, want: "internal/p/p.go:3: // TODO: tidy\n"},
+		{tool: "grep", args: `{"pattern": "func", "glob": "main.*"}`, want: "main.go:3: func main() {}\n"},
+		{tool: "grep", args: `{"pattern": "nothing here"}`, want: "no matches"},
+		{tool: "grep", args: `{"pattern": "("}`, want: "invalid pattern", err: true},
+		{tool: "run_tests", args: ``, want: "testing ./...\n\nexit status 0 (passed)"},
+		{tool: "run_tests", args: `{"packages": ["./internal/p"], "run": "TestP"}`, want: "testing -run TestP ./internal/p\n\nexit status 0 (passed)"},
+		{tool: "run_tests", args: `{"packages": ["./escape/..."]}`, want: `invalid package "./escape/..."`, err: true},
+	}
+	for _, tc := range tests {
+		out, err := box.CallTool(context.Background(), tc.tool, tc.args)
+		if tc.err {
+			if err == nil || !strings.HasPrefix(err.Error(), tc.want) {
+				t.Errorf("%s(%s) error = %v, want %q", tc.tool, tc.args, err, tc.want)
+			}
+			continue
+		}
+		if err != nil || out != tc.want {
+			t.Errorf("%s(%s) = %q, %v; want %q", tc.tool, tc.args, out, err, tc.want)
+		}
+	}
+
+	// Failing tests are reported to the model, not returned as an error
+	box, err = NewRegistry(Options{Root: root, TestCommand: []string{"false"}}).Bind("", []string{"run_tests"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err := box.CallTool(context.Background(), "run_tests", ""); err != nil || out != "\nexit status 1 (failed)" {
+		t.Errorf("failing run_tests = %q, %v", out, err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if s := truncate("short"); s != "short" {
+		t.Errorf("truncate(short) = %q", s)
+	}
+	long := strings.Repeat("x", maxOutput+10)
+	if s := truncate(long); len(s) <= maxOutput || !strings.HasSuffix(s, "\n... (truncated, 10 more bytes)") {
+		t.Errorf("truncate of %d bytes ends %q", len(long), s[len(s)-40:])
+	}
+}
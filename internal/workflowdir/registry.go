@@ -0,0 +1,246 @@
+// Package workflowdir keeps the workflows table in sync with workflow definitions
+// stored as YAML files in a directory, typically checked into git next to the code.
+//
+// Each *.yaml or *.yml file holds one workflow:
+//
+//	name: feature-dev          # defaults to the file name without extension
+//	description: Implement a feature and open a PR
+//	inputSchema: {...}
+//	steps:
+//	  - name: codegen
+//	    type: llm
+//	    input: {prompt: "Implement {{ .input.feature }}"}
+//
+// Everything except name and description is the workflow definition (see orchestrator.Definition).
+// The directory is scanned periodically; a file whose content hash changed is validated,
+// written to the workflow's draft and published as a new version. Invalid files are logged
+// and skipped, so the last good version stays active. Removing a file leaves its workflow in place.
+package workflowdir
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"agent-project-manager/internal/logger"
+	"agent-project-manager/internal/orchestrator"
+	"agent-project-manager/internal/state"
+)
+
+// Options configures a Registry
+type Options struct {
+	Dir          string        // directory holding the workflow files
+	ScanInterval time.Duration // how often to rescan; default 10s
+}
+
+// Registry syncs a directory of workflow files into the database
+type Registry struct {
+	repo  state.Repository
+	orch  *orchestrator.Orchestrator
+	opts  Options
+	mu    sync.Mutex
+	files map[string]fileState // by path
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// fileState remembers the last content of a file that was processed
+type fileState struct {
+	hash     string
+	workflow string // empty when the file was invalid
+}
+
+// New creates a registry; call Sync or Start to load the directory
+func New(repo state.Repository, orch *orchestrator.Orchestrator, opts Options) *Registry {
+	if opts.ScanInterval <= 0 {
+		opts.ScanInterval = 10 * time.Second
+	}
+	return &Registry{
+		repo:  repo,
+		orch:  orch,
+		opts:  opts,
+		files: map[string]fileState{},
+	}
+}
+
+// Start syncs the directory once and then keeps rescanning it until Stop is called
+func (r *Registry) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.Sync()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.opts.ScanInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.Sync()
+			}
+		}
+	}()
+	logger.Infof("workflowdir: watching %s every %v", r.opts.Dir, r.opts.ScanInterval)
+}
+
+// Stop stops rescanning and waits for a scan in progress to finish
+func (r *Registry) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+}
+
+// Sync processes every file whose content changed since the last scan.
+// Problems are logged per file; one bad file never blocks the others.
+func (r *Registry) Sync() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	paths, err := r.list()
+	if err != nil {
+		logger.Errorf("workflowdir: failed to scan %s: %v", r.opts.Dir, err)
+		return
+	}
+
+	// Names claimed by files that are unchanged, so a changed file cannot take them over
+	owners := map[string]string{}
+	for _, path := range paths {
+		if fs, ok := r.files[path]; ok && fs.workflow != "" {
+			owners[fs.workflow] = path
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, path := range paths {
+		seen[path] = true
+		content, err := os.ReadFile(path)
+		if err != nil {
+			logger.Errorf("workflowdir: failed to read %s: %v", path, err)
+			continue
+		}
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])
+		if r.files[path].hash == hash {
+			continue
+		}
+
+		name, err := r.apply(path, content, owners)
+		if err != nil {
+			// Remember the hash so the error is logged once per change, not on every scan
+			logger.Errorf("workflowdir: %s: %v; keeping the last good version", path, err)
+			r.files[path] = fileState{hash: hash}
+			continue
+		}
+		owners[name] = path
+		r.files[path] = fileState{hash: hash, workflow: name}
+	}
+
+	for path, fs := range r.files {
+		if !seen[path] {
+			if fs.workflow != "" {
+				logger.Warnf("workflowdir: %s was removed; workflow %s stays registered", path, fs.workflow)
+			}
+			delete(r.files, path)
+		}
+	}
+}
+
+// list returns the workflow files in the directory in a stable order
+func (r *Registry) list() ([]string, error) {
+	entries, err := os.ReadDir(r.opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		paths = append(paths, filepath.Join(r.opts.Dir, e.Name()))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// apply validates one file and writes it to the database, publishing a new version
+// when the definition changed. It returns the workflow name.
+func (r *Registry) apply(path string, content []byte, owners map[string]string) (string, error) {
+	name, description, schema, err := parseFile(path, content)
+	if err != nil {
+		return "", err
+	}
+	if owner, ok := owners[name]; ok && owner != path {
+		return "", fmt.Errorf("workflow %s is already defined in %s", name, owner)
+	}
+	if errs := r.orch.CheckDefinition(name, schema); len(errs) > 0 {
+		return "", fmt.Errorf("invalid workflow %s: %s", name, strings.Join(errs, "; "))
+	}
+
+	// The definition and its version are written together, so a failure leaves the last
+	// good version active and the draft unchanged
+	wf, err := r.repo.GetWorkflow(name)
+	switch {
+	case errors.Is(err, state.ErrNotFound):
+		wf = &state.Workflow{Name: name}
+	case err != nil:
+		return "", fmt.Errorf("failed to load workflow %s: %w", name, err)
+	}
+	wf.Description = description
+	wf.Schema = schema
+	wv, err := r.repo.SaveAndPublishWorkflow(wf)
+	if err != nil {
+		return "", fmt.Errorf("failed to publish workflow %s: %w", name, err)
+	}
+	logger.Infof("workflowdir: %s: workflow %s is at version %d", path, name, wv.Version)
+	return name, nil
+}
+
+// parseFile decodes a workflow file into its name, description and definition.
+// The definition is normalized through JSON so it compares equal to what the database returns.
+func parseFile(path string, content []byte) (string, string, state.JSONMap, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return "", "", nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	if doc == nil {
+		return "", "", nil, fmt.Errorf("file is empty")
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if v, ok := doc["name"]; ok {
+		s, isString := v.(string)
+		if !isString || s == "" {
+			return "", "", nil, fmt.Errorf("name must be a non-empty string")
+		}
+		name = s
+	}
+	description, _ := doc["description"].(string)
+	delete(doc, "name")
+	delete(doc, "description")
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("definition cannot be represented as JSON: %w", err)
+	}
+	var schema state.JSONMap
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return "", "", nil, fmt.Errorf("definition cannot be represented as JSON: %w", err)
+	}
+	return name, description, schema, nil
+}
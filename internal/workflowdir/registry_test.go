@@ -0,0 +1,172 @@
+package workflowdir
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"agent-project-manager/internal/orchestrator"
+	"agent-project-manager/internal/state"
+)
+
+// fakeRepo keeps workflows and their published versions in memory
+type fakeRepo struct {
+	state.Repository
+	workflows map[string]*state.Workflow
+	versions  map[string][]*state.WorkflowVersion
+	getErr    error // returned by GetWorkflow when set
+	saves     int
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{workflows: map[string]*state.Workflow{}, versions: map[string][]*state.WorkflowVersion{}}
+}
+
+func (f *fakeRepo) GetWorkflow(name string) (*state.Workflow, error) {
+	if f.getErr != nil {
+		return nil, f.getErr
+	}
+	wf, ok := f.workflows[name]
+	if !ok {
+		return nil, fmt.Errorf("workflow %w: %s", state.ErrNotFound, name)
+	}
+	c := *wf
+	return &c, nil
+}
+
+// SaveAndPublishWorkflow publishes a new version only when the definition changed, as the database does
+func (f *fakeRepo) SaveAndPublishWorkflow(wf *state.Workflow) (*state.WorkflowVersion, error) {
+	f.saves++
+	c := *wf
+	f.workflows[wf.Name] = &c
+	versions := f.versions[wf.Name]
+	if n := len(versions); n > 0 && versions[n-1].Description == wf.Description && reflect.DeepEqual(versions[n-1].Schema, wf.Schema) {
+		return versions[n-1], nil
+	}
+	wv := &state.WorkflowVersion{Workflow: wf.Name, Version: len(versions) + 1, Description: wf.Description, Schema: wf.Schema}
+	f.versions[wf.Name] = append(versions, wv)
+	return wv, nil
+}
+
+func (f *fakeRepo) GetWorkflowVersion(name string, version int) (*state.WorkflowVersion, error) {
+	versions := f.versions[name]
+	if version <= 0 || version > len(versions) {
+		return nil, fmt.Errorf("workflow version %w: %s@%d", state.ErrNotFound, name, version)
+	}
+	return versions[version-1], nil
+}
+
+func (f *fakeRepo) GetLatestWorkflowVersion(name string) (*state.WorkflowVersion, error) {
+	versions := f.versions[name]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("published workflow version %w: %s", state.ErrNotFound, name)
+	}
+	return versions[len(versions)-1], nil
+}
+
+func newTestRegistry(t *testing.T) (*Registry, *fakeRepo, string) {
+	t.Helper()
+	dir := t.TempDir()
+	repo := newFakeRepo()
+	return New(repo, orchestrator.New(repo, orchestrator.Options{}), Options{Dir: dir}), repo, dir
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSync(t *testing.T) {
+	r, repo, dir := newTestRegistry(t)
+	writeFile(t, dir, "review.yaml", "description: Review a change\nsteps:\n  - name: gate\n    type: approval\n")
+	writeFile(t, dir, "notes.txt", "not a workflow")
+
+	r.Sync()
+	wf := repo.workflows["review"]
+	if wf == nil || wf.Description != "Review a change" || len(repo.versions["review"]) != 1 {
+		t.Fatalf("workflow %+v with %d versions after the first sync", wf, len(repo.versions["review"]))
+	}
+	if _, ok := wf.Schema["description"]; ok {
+		t.Errorf("definition %v holds the description", wf.Schema)
+	}
+
+	// Unchanged files are not written again
+	r.Sync()
+	if repo.saves != 1 {
+		t.Errorf("%d saves after syncing an unchanged directory, want 1", repo.saves)
+	}
+
+	// An invalid change keeps the last good version
+	writeFile(t, dir, "review.yaml", "steps:\n  - name: gate\n    type: approval\n    timeout: soon\n")
+	r.Sync()
+	if len(repo.versions["review"]) != 1 || repo.saves != 1 {
+		t.Errorf("an invalid file was written: %d versions, %d saves", len(repo.versions["review"]), repo.saves)
+	}
+
+	writeFile(t, dir, "review.yml", "name: review\nsteps: []\n")
+	writeFile(t, dir, "review.yaml", "steps:\n  - name: gate\n    type: approval\n    timeout: 1h\n")
+	r.Sync()
+	if n := len(repo.versions["review"]); n != 2 {
+		t.Fatalf("%d versions after a valid change, want 2", n)
+	}
+	if steps := repo.workflows["review"].Schema["steps"].([]interface{}); steps[0].(map[string]interface{})["timeout"] != "1h" {
+		t.Errorf("the draft did not take the change: %v", steps)
+	}
+	if r.files[filepath.Join(dir, "review.yml")].workflow != "" {
+		t.Error("a second file claimed the workflow of review.yaml")
+	}
+
+	// Removing the file leaves the workflow in place
+	os.Remove(filepath.Join(dir, "review.yaml"))
+	r.Sync()
+	if repo.workflows["review"] == nil || len(r.files) != 1 {
+		t.Errorf("after removing the file: workflow %v, tracked files %v", repo.workflows["review"], r.files)
+	}
+}
+
+func TestSyncLoadError(t *testing.T) {
+	r, repo, dir := newTestRegistry(t)
+	writeFile(t, dir, "review.yaml", "steps:\n  - name: gate\n    type: approval\n")
+	repo.getErr = errors.New("connection refused")
+
+	// A failed lookup must not be taken for a missing workflow
+	r.Sync()
+	if repo.saves != 0 {
+		t.Errorf("%d saves while the workflow could not be loaded, want none", repo.saves)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	tests := []struct {
+		content string
+		name    string
+		err     string
+	}{
+		{content: "steps: []\n", name: "from-file"},
+		{content: "name: other\ndescription: d\nsteps: []\n", name: "other"},
+		{content: "", err: "file is empty"},
+		{content: "name: [a]\n", err: "name must be a non-empty string"},
+		{content: "steps: [\n", err: "invalid YAML"},
+	}
+	for _, tc := range tests {
+		name, _, schema, err := parseFile("/dir/from-file.yaml", []byte(tc.content))
+		if tc.err != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+				t.Errorf("parseFile(%q) error = %v, want %q", tc.content, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || name != tc.name {
+			t.Errorf("parseFile(%q) = %q, %v; want %q", tc.content, name, err, tc.name)
+		}
+		if _, ok := schema["name"]; ok {
+			t.Errorf("parseFile(%q) kept the name in the definition", tc.content)
+		}
+	}
+}
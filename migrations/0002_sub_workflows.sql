@@ -0,0 +1,6 @@
+-- Parent/child links for jobs started by sub-workflow steps
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS parent_job_id VARCHAR(255) REFERENCES jobs(id) ON DELETE SET NULL;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS parent_step_id VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_parent_job_id ON jobs(parent_job_id);
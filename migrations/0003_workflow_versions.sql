@@ -0,0 +1,31 @@
+-- Immutable published workflow versions; jobs pin the version they started with
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS workflow_version INTEGER;
+
+-- Existing workflows are published as version 1 only when the table is created, so the
+-- backfill never publishes drafts saved later without publishing (or deleted workflows)
+DO $$
+BEGIN
+    IF to_regclass('workflow_versions') IS NOT NULL THEN
+        RETURN;
+    END IF;
+
+    CREATE TABLE workflow_versions (
+        workflow VARCHAR(255) NOT NULL REFERENCES workflows(name) ON DELETE CASCADE,
+        version INTEGER NOT NULL,
+        description TEXT,
+        schema JSONB NOT NULL DEFAULT '{}',
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (workflow, version)
+    );
+
+    INSERT INTO workflow_versions (workflow, version, description, schema, created_at)
+    SELECT name, 1, description, schema, updated_at FROM workflows;
+
+    UPDATE workflows SET version = '1';
+
+    UPDATE jobs SET workflow_version = 1
+    WHERE workflow_version IS NULL
+      AND workflow IN (SELECT name FROM workflows);
+END
+$$;
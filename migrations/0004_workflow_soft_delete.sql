@@ -0,0 +1,3 @@
+-- Workflows that are still referenced by jobs are soft-deleted so job history stays intact
+
+ALTER TABLE workflows ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
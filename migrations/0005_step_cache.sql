@@ -0,0 +1,19 @@
+-- Content-addressed memoization of step results
+
+CREATE TABLE IF NOT EXISTS step_cache (
+    key VARCHAR(64) PRIMARY KEY,
+    step_type VARCHAR(100) NOT NULL,
+    output JSONB NOT NULL DEFAULT '{}',
+    artifact_ids JSONB NOT NULL DEFAULT '[]',
+    source_job_id VARCHAR(255),
+    source_step_id VARCHAR(255),
+    hits INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_hit_at TIMESTAMP
+);
+
+ALTER TABLE steps ADD COLUMN IF NOT EXISTS cached BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE steps ADD COLUMN IF NOT EXISTS cache_key VARCHAR(64);
+
+-- Content hash of the artifact's bytes ("sha256:<hex>"), used in step cache keys
+ALTER TABLE artifacts ADD COLUMN IF NOT EXISTS digest VARCHAR(80);
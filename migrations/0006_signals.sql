@@ -0,0 +1,14 @@
+-- External signals delivered to jobs; buffered until a wait_for_signal step consumes them
+
+CREATE TABLE IF NOT EXISTS signals (
+    id VARCHAR(255) PRIMARY KEY,
+    job_id VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    step_id VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    consumed_at TIMESTAMP,
+    FOREIGN KEY (job_id) REFERENCES jobs(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_signals_pending ON signals(job_id, name, created_at) WHERE consumed_at IS NULL;
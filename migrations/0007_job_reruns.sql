@@ -0,0 +1,9 @@
+-- Reruns: a job created from another job, reusing the steps before a chosen step
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS rerun_of_job_id VARCHAR(255) REFERENCES jobs(id) ON DELETE SET NULL;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS rerun_from_step VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_rerun_of_job_id ON jobs(rerun_of_job_id);
+
+-- Artifacts each step produced, so reruns and cache hits can carry them over
+ALTER TABLE steps ADD COLUMN IF NOT EXISTS artifact_ids JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,16 @@
+-- Matrix submissions: one workflow expanded across parameter axes into sibling jobs
+
+CREATE TABLE IF NOT EXISTS matrices (
+    id VARCHAR(255) PRIMARY KEY,
+    workflow VARCHAR(255) NOT NULL,
+    workflow_version INTEGER,
+    input JSONB NOT NULL DEFAULT '{}',
+    axes JSONB NOT NULL DEFAULT '{}',
+    report_artifact_id VARCHAR(255) REFERENCES artifacts(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS matrix_id VARCHAR(255) REFERENCES matrices(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_matrix_id ON jobs(matrix_id);
@@ -0,0 +1,22 @@
+-- Trigger firings: audit trail of which event started which job
+
+CREATE TABLE IF NOT EXISTS trigger_firings (
+    id VARCHAR(255) PRIMARY KEY,
+    workflow VARCHAR(255) NOT NULL,
+    workflow_version INTEGER,
+    trigger_name VARCHAR(255) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    event_id VARCHAR(255),
+    source_job_id VARCHAR(255) REFERENCES jobs(id) ON DELETE SET NULL,
+    artifact_id VARCHAR(255),
+    webhook VARCHAR(255),
+    job_id VARCHAR(255) REFERENCES jobs(id) ON DELETE SET NULL,
+    depth INTEGER NOT NULL DEFAULT 0,
+    status VARCHAR(50) NOT NULL,
+    error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_trigger_firings_workflow ON trigger_firings(workflow, created_at);
+CREATE INDEX IF NOT EXISTS idx_trigger_firings_source_job_id ON trigger_firings(source_job_id);
+CREATE INDEX IF NOT EXISTS idx_trigger_firings_job_id ON trigger_firings(job_id);
@@ -0,0 +1,22 @@
+-- LLM calls: one row per attempt of a gateway request, so fallbacks show which
+-- provider and model actually produced a step's output
+
+CREATE TABLE IF NOT EXISTS llm_calls (
+    id VARCHAR(255) PRIMARY KEY,
+    request_id VARCHAR(255) NOT NULL,
+    job_id VARCHAR(255) REFERENCES jobs(id) ON DELETE CASCADE,
+    step_id VARCHAR(255) REFERENCES steps(id) ON DELETE CASCADE,
+    agent VARCHAR(255),
+    attempt INTEGER NOT NULL,
+    provider VARCHAR(255) NOT NULL,
+    model VARCHAR(255),
+    status VARCHAR(50) NOT NULL,
+    error_kind VARCHAR(50),
+    error TEXT,
+    latency_ms BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_calls_job_id ON llm_calls(job_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_llm_calls_step_id ON llm_calls(step_id);
+CREATE INDEX IF NOT EXISTS idx_llm_calls_request_id ON llm_calls(request_id);
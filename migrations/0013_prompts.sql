@@ -0,0 +1,21 @@
+-- Prompt template registry: named prompts with immutable published versions,
+-- loaded from prompt files or published through the API
+
+CREATE TABLE IF NOT EXISTS prompts (
+    name VARCHAR(255) PRIMARY KEY,
+    description TEXT,
+    latest_version INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS prompt_versions (
+    prompt VARCHAR(255) NOT NULL REFERENCES prompts(name) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    description TEXT,
+    template TEXT NOT NULL,
+    variables JSONB NOT NULL DEFAULT '[]',
+    source VARCHAR(1024),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (prompt, version)
+);